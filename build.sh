@@ -4,6 +4,11 @@ set -e
 OUTPUT_DIR="dist"
 MODULE="vmware-inventory"
 
+VERSION="${VERSION:-$(git describe --tags --always --dirty 2>/dev/null || echo dev)}"
+COMMIT="$(git rev-parse --short HEAD 2>/dev/null || true)"
+DATE="$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+LDFLAGS="-X main.version=$VERSION -X main.commit=$COMMIT -X main.date=$DATE"
+
 rm -rf "$OUTPUT_DIR"
 mkdir -p "$OUTPUT_DIR"
 
@@ -22,7 +27,7 @@ for platform in "${platforms[@]}"; do
         output="${output}.exe"
     fi
     echo "Building $output"
-    GOOS=$GOOS GOARCH=$GOARCH go build -o "$output"
+    GOOS=$GOOS GOARCH=$GOARCH go build -ldflags "$LDFLAGS" -o "$output"
 done
 
 echo "Done. Binaries in $OUTPUT_DIR/"
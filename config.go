@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// applyConfigFile sets flags from a YAML file whose keys are flag names
+// (without the leading dash). Flags given on the command line take
+// precedence and are left untouched. List values set repeatable flags such
+// as host once per element.
+func applyConfigFile(fs *flag.FlagSet, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var values map[string]any
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	for name, v := range values {
+		if name == "config" {
+			continue
+		}
+		if fs.Lookup(name) == nil {
+			return fmt.Errorf("%s: unknown setting %q", path, name)
+		}
+		if explicit[name] {
+			continue
+		}
+
+		items, ok := v.([]any)
+		if !ok {
+			items = []any{v}
+		}
+		for _, item := range items {
+			if err := fs.Set(name, fmt.Sprint(item)); err != nil {
+				return fmt.Errorf("%s: invalid value for %s: %w", path, name, err)
+			}
+		}
+	}
+	return nil
+}
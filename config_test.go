@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// testFlags returns a flag set with the flags the tests set, as main
+// defines them.
+func testFlags() (*flag.FlagSet, *stringList, *string, *bool, *int) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	hosts := new(stringList)
+	fs.Var(hosts, "host", "")
+	fs.String("config", "", "")
+	user := fs.String("user", "", "")
+	fs.String("password", "", "")
+	insecure := fs.Bool("insecure", false, "")
+	concurrency := fs.Int("concurrency", 8, "")
+	return fs, hosts, user, insecure, concurrency
+}
+
+func TestApplyConfigFile(t *testing.T) {
+	tests := []struct {
+		name        string
+		yaml        string
+		args        []string
+		hosts       []string
+		user        string
+		insecure    bool
+		concurrency int
+		err         string
+	}{
+		{
+			name:        "sets flags",
+			yaml:        "host: vc1.example.com\nuser: admin\ninsecure: true\nconcurrency: 4\n",
+			hosts:       []string{"vc1.example.com"},
+			user:        "admin",
+			insecure:    true,
+			concurrency: 4,
+		},
+		{
+			name:        "lists repeat a flag",
+			yaml:        "host:\n  - vc1.example.com\n  - vc2.example.com\n",
+			hosts:       []string{"vc1.example.com", "vc2.example.com"},
+			concurrency: 8,
+		},
+		{
+			name:        "command line takes precedence",
+			yaml:        "host: vc1.example.com\nuser: admin\n",
+			args:        []string{"-user", "reader", "-host", "vc9.example.com"},
+			hosts:       []string{"vc9.example.com"},
+			user:        "reader",
+			concurrency: 8,
+		},
+		{
+			name:        "config key is ignored",
+			yaml:        "config: other.yaml\nuser: admin\n",
+			user:        "admin",
+			concurrency: 8,
+		},
+		{name: "unknown setting", yaml: "hosts: vc1.example.com\n", err: `unknown setting "hosts"`},
+		{name: "invalid value", yaml: "concurrency: many\n", err: "invalid value for concurrency"},
+		{name: "invalid YAML", yaml: "host: [vc1\n", err: "parsing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			fs, hosts, user, insecure, concurrency := testFlags()
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			err := applyConfigFile(fs, path)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal([]string(*hosts), tt.hosts) || *user != tt.user || *insecure != tt.insecure || *concurrency != tt.concurrency {
+				t.Errorf("host %v, user %q, insecure %t, concurrency %d; want %v, %q, %t, %d",
+					*hosts, *user, *insecure, *concurrency, tt.hosts, tt.user, tt.insecure, tt.concurrency)
+			}
+		})
+	}
+
+	if err := applyConfigFile(flag.NewFlagSet("test", flag.ContinueOnError), filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("missing config file read without error")
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"vmware-inventory/pkg/collector"
+)
+
+// setupLogging installs the default slog logger on stderr. quiet raises the
+// level so only errors are printed; verbose lowers it to debug (1) or to
+// collector.LevelTrace (2), which logs every vCenter call.
+func setupLogging(level, format string, quiet bool, verbose int) error {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return err
+	}
+	switch {
+	case quiet && verbose > 0:
+		return fmt.Errorf("-quiet cannot be combined with -v or -vv")
+	case quiet:
+		lvl = slog.LevelError
+	case verbose > 1:
+		lvl = collector.LevelTrace
+	case verbose == 1:
+		lvl = min(lvl, slog.LevelDebug)
+	}
+	opts := &slog.HandlerOptions{Level: lvl, ReplaceAttr: levelNames}
+	var h slog.Handler
+	switch format {
+	case "text":
+		h = slog.NewTextHandler(os.Stderr, opts)
+	case "json":
+		h = slog.NewJSONHandler(os.Stderr, opts)
+	default:
+		return fmt.Errorf("unknown log format %q", format)
+	}
+	slog.SetDefault(slog.New(h))
+	return nil
+}
+
+// levelNames prints collector.LevelTrace as TRACE rather than DEBUG-4.
+func levelNames(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.LevelKey && len(groups) == 0 {
+		if lvl, ok := a.Value.Any().(slog.Level); ok && lvl == collector.LevelTrace {
+			a.Value = slog.StringValue("TRACE")
+		}
+	}
+	return a
+}
+
+// fatal logs msg and its attributes at error level and exits.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -1,264 +1,1884 @@
 package main
 
 import (
+	"bufio"
+	"compress/gzip"
 	"context"
-	"encoding/csv"
-	"encoding/json"
+	"crypto/tls"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
-	"net/url"
+	"io"
+	"io/fs"
+	"log/slog"
 	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
 	"syscall"
+	"text/template"
+	"time"
 
+	"filippo.io/age"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/crypto/ssh"
 	"golang.org/x/term"
 
-	"github.com/vmware/govmomi"
-	"github.com/vmware/govmomi/property"
-	"github.com/vmware/govmomi/view"
-	"github.com/vmware/govmomi/vim25/methods"
-	"github.com/vmware/govmomi/vim25/mo"
-	"github.com/vmware/govmomi/vim25/types"
+	"vmware-inventory/pkg/collector"
+	"vmware-inventory/pkg/export"
 )
 
+// commandInfo describes what a subcommand writes and which output options
+// it supports.
+type commandInfo struct {
+	baseName   string // base name of its default output file
+	summary    bool   // -summary writes a second table
+	splittable bool   // -split-by cluster
+	rvtools    bool   // -format rvtools
+}
+
+// commands maps each subcommand to its description.
+var commands = map[string]commandInfo{
+	"hosts":         {baseName: "hosts_cpu", summary: true, splittable: true, rvtools: true},
+	"vms":           {baseName: "vms", splittable: true, rvtools: true},
+	"datastores":    {baseName: "datastores", summary: true, rvtools: true},
+	"licensing":     {baseName: "licensing", summary: true, splittable: true},
+	"licenses":      {baseName: "licenses", summary: true},
+	"consolidation": {baseName: "consolidation", summary: true, splittable: true},
+	"headroom":      {baseName: "headroom", splittable: true},
+	"perf":          {baseName: "perf", summary: true, splittable: true},
+	"dimms":         {baseName: "dimms"},
+	"vibs":          {baseName: "vibs"},
+	"nics":          {baseName: "nics"},
+	"pci":           {baseName: "pci"},
+	"vgpu":          {baseName: "vgpu"},
+	"ntpdns":        {baseName: "ntpdns"},
+	"services":      {baseName: "services"},
+	"vmknics":       {baseName: "vmknics"},
+	"iscsi":         {baseName: "iscsi"},
+	"localdisks":    {baseName: "localdisks"},
+	"mounts":        {baseName: "mounts"},
+	"paths":         {baseName: "paths"},
+	"policies":      {baseName: "policies"},
+	"vvols":         {baseName: "vvols", summary: true},
+	"check":         {baseName: "hosts_cpu", splittable: true, rvtools: true}, // reports what a hosts run would write
+}
+
+// commandsWith lists the commands with a capability, in alphabetical order,
+// as "a, b, and c".
+func commandsWith(has func(commandInfo) bool) string {
+	var names []string
+	for name, c := range commands {
+		if has(c) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	switch len(names) {
+	case 1:
+		return names[0]
+	case 2:
+		return names[0] + " and " + names[1]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + ", and " + names[len(names)-1]
+}
+
+// inventory accumulates records across vCenters.
+type inventory struct {
+	hosts      []collector.Host
+	vms        []collector.VM
+	datastores []collector.Datastore
+	licenses   []collector.License
+	assigned   []collector.LicenseAssignment
+	vsanUsage  []collector.VsanUsage
+	perf       []collector.PerfSample
+	dimms      []collector.DIMM
+	vibs       []collector.VIB
+	nics       []collector.PhysicalNIC
+	pci        []collector.PCIDevice
+	vgpus      []collector.VGPUProfile
+	ntpdns     []collector.NTPDNSConfig
+	services   []collector.HostService
+	vmknics    []collector.VMKernelAdapter
+	iscsi      []collector.ISCSIAdapter
+	localDisks []collector.LocalDisk
+	mounts     []collector.DatastoreMount
+	paths      []collector.MultipathLUN
+	policies   []collector.StoragePolicy
+	providers  []collector.VASAProvider
+	vvols      []collector.VVolContainer
+	checks     []*collector.CheckResult
+	vcenters   []collector.VCenterInfo
+}
+
+// keyServices are the host services the services command reports by
+// default: NTP, SSH, the vCenter agent, SLP, and the CIM server.
+var keyServices = []string{"ntpd", "TSM-SSH", "vpxa", "slpd", "sfcbd-watchdog"}
+
+// stringList is a flag that may be repeated or given a comma-separated list.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(s string) error {
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
 func main() {
-	host := flag.String("host", "", "vCenter hostname or IP (required)")
+	command := "hosts"
+	if len(os.Args) > 1 && os.Args[1] != "" && os.Args[1][0] != '-' {
+		command = os.Args[1]
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+
+	var hosts stringList
+	flag.Var(&hosts, "host", "vCenter hostname or IP (required; repeat or comma-separate for several vCenters)")
+	hostFile := flag.String("host-file", "", "file listing vCenter hostnames, one per line")
 	user := flag.String("user", "", "vCenter username (required)")
 	password := flag.String("password", "", "vCenter password (prompted if not provided)")
-	output := flag.String("output", "hosts_cpu.csv", "output CSV file path")
-	insecure := flag.Bool("insecure", true, "allow self-signed TLS certificates")
-	anonymize := flag.Bool("anonymize", false, "omit hostnames from CSV output")
+	output := flag.String("output", "", "output file path, or sqlite://<path> or postgres://<dsn> to store in a database (default <command base name>.<format>, e.g. hosts_cpu.csv)")
+	format := flag.String("format", "csv", "output format: csv, json, ndjson (one record per line, written as collected, so the hosts of a vCenter that fails partway stay in it), parquet, xlsx, html, markdown, ansible (hosts command), rvtools (an xlsx workbook laid out like RVTools), or template (see -template-file)")
+	templateFile := flag.String("template-file", "", "Go text/template file that renders the collected data for -format template")
+	delimiter := flag.String("delimiter", "comma", "field separator of -format csv: comma, tab, semicolon, or pipe (tab writes .tsv by default)")
+	compress := flag.Bool("compress", false, "gzip output files, adding .gz to their names")
+	timestampOutput := flag.Bool("timestamp-output", false, "insert the collection date and time into output file names, e.g. hosts_cpu_2024-05-01_093000.csv")
+	appendOutput := flag.Bool("append", false, "append to the output file instead of replacing it, with a Collected At column so runs accumulate for trending (csv and ndjson)")
+	edition := flag.String("edition", "vcf", "subscription of the licensing command, for its vSAN entitlement: vcf (1 TiB per core) or vvf (0.25 TiB per core)")
+	perCPU := flag.Bool("per-cpu", false, "also count licenses under the legacy per-CPU terms of perpetual licenses, one per 32 cores of each CPU (licensing command)")
+	cpuTarget := flag.Float64("cpu-target", 80, "highest CPU utilization to plan for, in percent (headroom command)")
+	memoryTarget := flag.Float64("memory-target", 80, "highest memory utilization to plan for, in percent (headroom command)")
+	vsanTarget := flag.Float64("vsan-target", 70, "highest vSAN datastore utilization to plan for, in percent (headroom command)")
+	quickStats := flag.Bool("quickstats", false, "add each host's CPU and memory use at collection time, from its quick stats (hosts command)")
+	expectPowerPolicy := flag.String("expect-power-policy", "", "add whether each host's power policy is this one: high-performance, balanced, low-power, or custom (hosts command)")
+	certWarning := flag.String("cert-warning", "30d", "report host certificates expiring within this window, e.g. 30d, as expiring")
+	utilization := flag.String("utilization", "", "add average and peak CPU and memory utilization over this window ending now, e.g. 30d or 12h, from the performance manager's rollups (hosts command)")
+	perfFrom := flag.String("from", "7d", "start of the perf command's range: a date (2024-05-01), a date and time (RFC 3339), or a window before -to, e.g. 30d")
+	perfTo := flag.String("to", "", "end of the perf command's range: a date or a date and time (default now)")
+	var counters stringList
+	flag.Var(&counters, "counters", "counters the perf command exports: cpu.usage, mem.usage, and disk.usage (default all three)")
+	var expectNTP, expectDNS, expectDomains stringList
+	flag.Var(&expectNTP, "expect-ntp", "NTP servers every host should use, reporting others as an issue (ntpdns command)")
+	flag.Var(&expectDNS, "expect-dns", "DNS servers every host should use, reporting others as an issue (ntpdns command)")
+	flag.Var(&expectDomains, "expect-search-domains", "DNS search domains every host should use, reporting others as an issue (ntpdns command)")
+	var services stringList
+	flag.Var(&services, "services", "keys of the host services the services command reports, or all (default "+strings.Join(keyServices, ",")+")")
+	entitlementFile := flag.String("entitlements", "", "CSV file of the licenses owned (SKU,Quantity[,Product]) to compare with those needed, writing <output>_entitlements.<ext> for single-table formats (licensing command)")
+	unitSystem := flag.String("units", "", "units of memory and capacity columns: binary (GiB, TiB) or decimal (GB, TB); by default GB columns hold GiB")
+	precision := flag.Int("precision", -1, "round memory and capacity columns to this many decimal places")
+	var columns stringList
+	flag.Var(&columns, "columns", "write only these columns, in this order, named by header or JSON key, e.g. Hostname,Cluster,TotalCores,MemoryGB")
+	insecure := flag.Bool("insecure", false, "skip TLS certificate verification (prefer -thumbprint)")
+	tokenFile := flag.String("token", "", "log in with this SAML token file (bearer, or holder-of-key with -cert) instead of a password")
+	certFile := flag.String("cert", "", "PEM certificate for holder-of-key token login, e.g. a solution user's")
+	keyFile := flag.String("key", "", "PEM private key for -cert")
+	sessionCache := flag.Bool("session-cache", false, "reuse the vCenter session across runs, cached in ~/.govmomi/sessions like govc")
+	caCert := flag.String("cacert", "", "PEM file of CA certificates used to verify the vCenter certificate")
+	var thumbprints stringList
+	flag.Var(&thumbprints, "thumbprint", "accept a vCenter certificate with this SHA-1 or SHA-256 fingerprint; use host=fingerprint when collecting several vCenters")
+	anonymize := flag.Bool("anonymize", false, "replace vCenter, host, cluster, VM, datastore, switch, port group, NTP and DNS server, domain, storage policy, storage provider and array, and iSCSI names and hardware identifiers with generic labels")
+	var encryptTo stringList
+	flag.Var(&encryptTo, "encrypt-to", "encrypt output files with age to this recipient: an age1... or ssh- public key, or a file of age public keys (repeat for several; adds .age)")
+	redactIPs := flag.Bool("redact-ips", false, "replace IP addresses in names, IP address columns, errors, and -debug output with labels such as \"IP 1\"")
+	anonymizeMode := flag.String("anonymize-mode", "sequential", "how -anonymize labels names: sequential (Host 1, Host 2, ...) or hmac (stable labels derived from -anonymize-key)")
+	anonymizeKey := flag.String("anonymize-key", "", "secret key for -anonymize-mode hmac")
+	anonymizeMap := flag.String("anonymize-map", "", "with -anonymize, also write the label-to-real-name mapping to this CSV file, readable only by the owner")
+	manifest := flag.Bool("manifest", false, "also write the SHA-256 of every output file to <output>_manifest.sha256")
+	signKey := flag.String("sign-key", "", "sign the manifest with this unencrypted SSH private key, writing <output>_manifest.sha256.sig (implies -manifest)")
+	upload := flag.String("upload", "", "also upload output files to s3://bucket/prefix/, with credentials from the AWS SDK default chain")
+	uploadEndpoint := flag.String("upload-endpoint", "", "endpoint URL of an S3-compatible store for -upload, e.g. https://minio.example.com:9000")
+	var mailTo stringList
+	flag.Var(&mailTo, "mail-to", "email the report to this address when done (repeat or comma-separate for several)")
+	mailFrom := flag.String("mail-from", "", "sender address of the report email (required with -mail-to)")
+	mailAttach := flag.Bool("mail-attach", true, "attach the output files to the report email; with -upload, false sends only their URLs")
+	smtpServer := flag.String("smtp-server", "", "SMTP server as host:port for -mail-to; port 465 uses TLS, others STARTTLS when offered")
+	smtpUser := flag.String("smtp-user", "", "SMTP user, if the server requires authentication")
+	smtpPassword := flag.String("smtp-password", "", "SMTP password for -smtp-user")
+	var webhooks stringList
+	flag.Var(&webhooks, "notify-webhook", "post a run summary to this Slack or Microsoft Teams incoming webhook URL (repeat for several)")
+	webhookURL := flag.String("webhook-url", "", "when done, post a JSON run summary to this URL for orchestration systems")
+	webhookData := flag.Bool("webhook-data", false, "include the full dataset in the -webhook-url summary")
+	webhookSecret := flag.String("webhook-secret", "", "sign -webhook-url requests with HMAC-SHA256 using this secret, in an X-Signature-256 header")
+	netboxURL := flag.String("netbox", "", "also create or update the collected hosts (as devices and clusters) or VMs in the NetBox instance at this URL")
+	netboxToken := flag.String("netbox-token", "", "NetBox API token for -netbox")
+	netboxSite := flag.String("netbox-site", "", "name or slug of the existing NetBox site hosts and VMs are placed in (required with -netbox)")
+	netboxRole := flag.String("netbox-role", "Hypervisor", "NetBox device role of hosts, created if missing")
+	syslogTarget := flag.String("syslog", "", "also send one event per record to this syslog collector: udp://host[:port], tcp://host[:port], or tls://host[:port]")
+	syslogFormat := flag.String("syslog-format", "kv", "text of -syslog events: kv (key=\"value\" pairs) or cef (ArcSight Common Event Format)")
+	kafkaURL := flag.String("kafka-rest", "", "also publish each record as a JSON message through the Kafka REST proxy at this URL")
+	kafkaTopic := flag.String("kafka-topic", "", "Kafka topic for -kafka-rest (required with it)")
+	kafkaKey := flag.String("kafka-key", "", "column whose value keys -kafka-rest messages, by JSON key (default biosUUID for the hosts command; none for no key)")
+	splitBy := flag.String("split-by", "", "write a file per cluster, named after it, instead of one output file: cluster ("+commandsWith(func(c commandInfo) bool { return c.splittable })+" commands)")
+	metadata := flag.Bool("metadata", false, "record when and against which vCenters the report was collected, with the tool version and filters: under \"run\" in JSON output, otherwise in <output>_run.json")
+	summaryFile := flag.Bool("summary", false, "also write the per-cluster rollup to <output>_clusters.<ext> (hosts, datastores, and licensing commands), the license assignments to <output>_assignments.<ext> (licenses command), or the VASA providers to <output>_providers.<ext> (vvols command)")
+	concurrency := flag.Int("concurrency", 8, "maximum number of hosts queried in parallel")
+	retries := flag.Int("retries", 3, "retry vCenter calls that fail with a transient network or host communication error this many times")
+	retryBackoff := flag.Duration("retry-backoff", time.Second, "wait before the first retry; doubled for each later retry")
+	timeout := flag.Duration("timeout", 5*time.Minute, "maximum time for each vCenter call, including login (0 for no limit)")
+	keepAlive := flag.Duration("keepalive", 10*time.Minute, "interval of session keepalive requests during long collections (0 to disable)")
+	totalTimeout := flag.Duration("total-timeout", 0, "maximum time for the whole run (0 for no limit)")
+	partial := flag.Bool("partial", false, "on Ctrl-C or SIGTERM, write the results of vCenters already collected instead of discarding them")
+	otlpEndpoint := flag.String("otlp-endpoint", "", "send OpenTelemetry traces of the collection to this OTLP/HTTP endpoint, e.g. http://localhost:4318")
 	debug := flag.Bool("debug", false, "print raw vSAN config JSON per host to stderr")
+	quiet := flag.Bool("quiet", false, "print nothing but errors")
+	verbose := flag.Bool("v", false, "also log debug messages, such as each vCenter's version and the hosts selected")
+	veryVerbose := flag.Bool("vv", false, "also log every vCenter call with its target object and duration")
+	logLevel := flag.String("log-level", "info", "minimum level of log messages: debug, info, warn, or error")
+	logFormat := flag.String("log-format", "text", "format of log messages on stderr: text or json")
+	var clusters stringList
+	flag.Var(&clusters, "cluster", "only collect hosts in clusters matching this glob pattern, e.g. \"Prod-*\" (repeat or comma-separate for several)")
+	var tags stringList
+	flag.Var(&tags, "tag", "only collect hosts carrying this vSphere tag, as Category:Value (repeat for several; any match)")
+	skipDisconnected := flag.Bool("skip-disconnected", false, "skip hosts that are disconnected or not responding")
+	skipMaintenance := flag.Bool("skip-maintenance", false, "skip hosts in maintenance mode")
+	datacenter := flag.String("datacenter", "", "only collect from this datacenter (default all datacenters)")
+	showVersion := flag.Bool("version", false, "print the version and exit")
+	configFile := flag.String("config", "", "YAML file of flag values; command-line flags and environment variables take precedence")
+	flag.Usage = usage
 	flag.Parse()
+	if *showVersion {
+		fmt.Println(versionString())
+		return
+	}
+
+	// Precedence: command line, then environment, then config file
+	if err := applyEnv(flag.CommandLine); err != nil {
+		fatal("Error reading environment", "err", err)
+	}
+	if *configFile != "" {
+		if err := applyConfigFile(flag.CommandLine, *configFile); err != nil {
+			fatal("Error reading config file", "file", *configFile, "err", err)
+		}
+	}
+
+	verbosity := 0
+	if *verbose {
+		verbosity = 1
+	}
+	if *veryVerbose {
+		verbosity = 2
+	}
+	if err := setupLogging(*logLevel, *logFormat, *quiet, verbosity); err != nil {
+		fatal("Invalid logging flags", "err", err)
+	}
+	slog.Debug("Starting", "version", versionString())
+
+	if command == "schema" {
+		// vmware-inventory schema vms prints the schema of the vms command
+		schemaOf := "hosts"
+		if flag.NArg() > 0 {
+			schemaOf = flag.Arg(0)
+		}
+		if err := export.WriteSchema(os.Stdout, schemaOf); err != nil {
+			fatal("Error writing schema", "err", err)
+		}
+		return
+	}
 
-	if *host == "" || *user == "" {
+	info, ok := commands[command]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", command)
 		flag.Usage()
 		os.Exit(1)
 	}
 
-	if *password == "" {
-		fmt.Fprint(os.Stderr, "Password: ")
-		b, err := term.ReadPassword(int(syscall.Stdin))
-		fmt.Fprintln(os.Stderr)
+	if *hostFile != "" {
+		fileHosts, err := readHostFile(*hostFile)
 		if err != nil {
-			log.Fatalf("Error reading password: %v", err)
+			fatal("Error reading host file", "file", *hostFile, "err", err)
 		}
-		*password = string(b)
+		hosts = append(hosts, fileHosts...)
 	}
 
-	ctx := context.Background()
-
-	// Build vCenter SDK URL
-	u, err := url.Parse(fmt.Sprintf("https://%s/sdk", *host))
+	tokenAuth := *tokenFile != "" || *certFile != ""
+	if len(hosts) == 0 || (*user == "" && !tokenAuth) {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if (*certFile == "") != (*keyFile == "") {
+		fatal("-cert and -key must be given together")
+	}
+	for _, p := range clusters {
+		if _, err := path.Match(p, ""); err != nil {
+			fatal("Invalid -cluster pattern", "pattern", p, "err", err)
+		}
+	}
+	hostThumbprints, err := parseThumbprints(thumbprints)
 	if err != nil {
-		log.Fatalf("Error parsing URL: %v", err)
+		fatal("Invalid -thumbprint", "err", err)
+	}
+	for _, t := range tags {
+		if !strings.Contains(t, ":") {
+			fatal("Invalid -tag: expected Category:Value", "tag", t)
+		}
+	}
+	if *anonymizeMap != "" && !*anonymize && !*redactIPs {
+		fatal("-anonymize-map requires -anonymize or -redact-ips")
+	}
+	switch *anonymizeMode {
+	case "sequential":
+	case "hmac":
+		if !*anonymize {
+			fatal("-anonymize-mode hmac requires -anonymize")
+		}
+		if *anonymizeKey == "" {
+			fatal("-anonymize-mode hmac requires -anonymize-key")
+		}
+	default:
+		fatal("Unknown -anonymize-mode", "mode", *anonymizeMode)
+	}
+	if *summaryFile && !info.summary {
+		fatal("-summary is only supported by the " + commandsWith(func(c commandInfo) bool { return c.summary }) + " commands")
+	}
+	database := export.IsDatabaseURL(*output)
+	if *summaryFile && database {
+		fatal("-summary cannot be used with database output; the " + summaryName(command) + " are always stored")
 	}
-	u.User = url.UserPassword(*user, *password)
 
-	// Connect and login
-	client, err := govmomi.NewClient(ctx, u, *insecure)
+	recipients, err := export.ParseRecipients(encryptTo)
 	if err != nil {
-		log.Fatalf("Error connecting to vCenter: %v", err)
+		fatal("Invalid -encrypt-to", "err", err)
+	}
+	if len(recipients) > 0 && database {
+		fatal("-encrypt-to cannot be used with database output")
+	}
+	if *compress && database {
+		fatal("-compress cannot be used with database output")
+	}
+	// Added to output file names: compression comes before encryption
+	encSuffix := ""
+	if *compress {
+		encSuffix = ".gz"
+	}
+	if len(recipients) > 0 {
+		encSuffix += ".age"
+	}
+	var signer ssh.Signer
+	if *signKey != "" {
+		if signer, err = export.ParseSigningKey(*signKey); err != nil {
+			fatal("Invalid -sign-key", "err", err)
+		}
+		*manifest = true
+	}
+	if *manifest && database {
+		fatal("-manifest and -sign-key cannot be used with database output")
+	}
+	var uploader *export.S3Uploader
+	if *upload != "" {
+		if database {
+			fatal("-upload cannot be used with database output")
+		}
+		if uploader, err = export.NewS3Uploader(context.Background(), *upload, *uploadEndpoint); err != nil {
+			fatal("Invalid -upload", "err", err)
+		}
+	} else if *uploadEndpoint != "" {
+		fatal("-upload-endpoint requires -upload")
+	}
+	var syslogSender *export.SyslogSender
+	if *syslogTarget != "" {
+		if syslogSender, err = export.NewSyslogSender(*syslogTarget, *syslogFormat, version); err != nil {
+			fatal("Invalid -syslog", "err", err)
+		}
+	}
+	var kafka *export.KafkaPublisher
+	if *kafkaURL != "" {
+		key := *kafkaKey
+		switch {
+		case key == "none":
+			key = ""
+		case key == "" && command == "hosts":
+			key = "biosUUID"
+		}
+		if kafka, err = export.NewKafkaPublisher(*kafkaURL, *kafkaTopic, key); err != nil {
+			fatal("Invalid -kafka-rest", "err", err)
+		}
+	}
+	if *webhookData && *webhookURL == "" {
+		fatal("-webhook-data requires -webhook-url")
+	}
+	var netbox *export.NetBox
+	if *netboxURL != "" {
+		if command != "hosts" && command != "vms" {
+			fatal("-netbox is only supported by the hosts and vms commands")
+		}
+		if *anonymize || *redactIPs {
+			fatal("-netbox records real names; it cannot be combined with -anonymize or -redact-ips")
+		}
+		if *netboxToken == "" || *netboxSite == "" {
+			fatal("-netbox requires -netbox-token and -netbox-site")
+		}
+		netbox = export.NewNetBox(*netboxURL, *netboxToken, *netboxSite, *netboxRole)
+	}
+	if len(mailTo) > 0 {
+		if *smtpServer == "" || *mailFrom == "" {
+			fatal("-mail-to requires -smtp-server and -mail-from")
+		}
+		if database {
+			fatal("-mail-to cannot be used with database output")
+		}
+		if !*mailAttach && uploader == nil {
+			fatal("-mail-attach=false requires -upload")
+		}
 	}
-	defer client.Logout(ctx)
 
-	// Create a container view of all HostSystem objects
-	m := view.NewManager(client.Client)
-	v, err := m.CreateContainerView(ctx, client.ServiceContent.RootFolder, []string{"HostSystem"}, true)
+	if !export.ValidFormat(*format) {
+		fatal("Unknown output format", "format", *format)
+	}
+	// With -output -, the report goes to stdout and logs stay on stderr
+	stdout := *output == "-"
+	if stdout {
+		if *summaryFile || *manifest || uploader != nil || len(mailTo) > 0 {
+			fatal("-output - cannot be combined with -summary, -manifest, -sign-key, -upload, or -mail-to, which need output files")
+		}
+		binary := *format == "xlsx" || *format == "rvtools" || *format == "parquet" || *compress || len(recipients) > 0
+		if binary && term.IsTerminal(int(os.Stdout.Fd())) {
+			fatal("Not writing binary output to a terminal; redirect or pipe stdout")
+		}
+	}
+	if *compress && (*format == "xlsx" || *format == "rvtools" || *format == "parquet") {
+		fatal("-compress is not supported by -format " + *format + ", which is already compressed")
+	}
+	if *splitBy != "" {
+		switch {
+		case *splitBy != "cluster":
+			fatal("Invalid -split-by; only cluster is supported", "split-by", *splitBy)
+		case !info.splittable:
+			fatal("-split-by cluster is not supported by the " + command + " command")
+		case database || stdout:
+			fatal("-split-by needs file output")
+		case *format == "rvtools":
+			fatal("-split-by cannot be used with -format rvtools")
+		case *appendOutput:
+			fatal("-split-by cannot be combined with -append")
+		}
+	}
+	if *metadata && (database || (stdout && *format != "json")) {
+		fatal("-metadata needs JSON or file output; the database records each run's time in its runs table")
+	}
+	if *timestampOutput && (database || stdout) {
+		fatal("-timestamp-output needs an output file")
+	}
+	if *appendOutput {
+		switch {
+		case *format != "csv" && *format != "ndjson":
+			fatal("-append is only supported by -format csv and ndjson")
+		case database || stdout:
+			fatal("-append needs an output file")
+		case *compress || len(recipients) > 0:
+			fatal("-append cannot be combined with -compress or -encrypt-to")
+		case *timestampOutput:
+			fatal("-append and -timestamp-output cannot be combined; one accumulates runs in a file, the other writes a file per run")
+		}
+	}
+	e, ok := collector.Editions[strings.ToLower(*edition)]
+	if !ok {
+		fatal("Invalid -edition; choose vcf or vvf", "edition", *edition)
+	}
+	lic := export.LicenseOptions{Edition: e, PerCPU: *perCPU}
+	if *entitlementFile != "" {
+		if command != "licensing" {
+			fatal("-entitlements is only supported by the licensing command")
+		}
+		if lic.Entitlements, err = collector.ReadEntitlements(*entitlementFile); err != nil {
+			fatal("Invalid -entitlements", "path", *entitlementFile, "err", err)
+		}
+	}
+	targets := collector.Targets{CPU: *cpuTarget, Memory: *memoryTarget, Vsan: *vsanTarget}
+	for _, t := range []float64{targets.CPU, targets.Memory, targets.Vsan} {
+		if t <= 0 || t > 100 {
+			fatal("Invalid -cpu-target, -memory-target, or -vsan-target; targets are percentages up to 100", "target", t)
+		}
+	}
+	ro := reportOptions{license: lic, targets: targets}
+	if *quickStats {
+		if command != "hosts" {
+			fatal("-quickstats is only supported by the hosts command")
+		}
+		ro.hostColumns = export.QuickStatsColumns
+	}
+	if *expectPowerPolicy != "" {
+		if command != "hosts" {
+			fatal("-expect-power-policy is only supported by the hosts command")
+		}
+		policy := collector.PowerPolicyName(*expectPowerPolicy)
+		if policy == "" {
+			fatal("Unknown -expect-power-policy; choose high-performance, balanced, low-power, or custom", "policy", *expectPowerPolicy)
+		}
+		ro.hostColumns = append(ro.hostColumns, export.PowerPolicyColumns(policy)...)
+	}
+	certWindow, err := parseWindow(*certWarning)
 	if err != nil {
-		log.Fatalf("Error creating container view: %v", err)
+		fatal("Invalid -cert-warning", "window", *certWarning, "err", err)
 	}
-	defer v.Destroy(ctx)
-
-	// Retrieve host summary, hardware, and configManager properties
-	var hosts []mo.HostSystem
-	err = v.Retrieve(ctx, []string{"HostSystem"}, []string{"summary", "hardware", "configManager", "parent"}, &hosts)
+	var window time.Duration
+	if *utilization != "" {
+		if command != "hosts" {
+			fatal("-utilization is only supported by the hosts command")
+		}
+		if window, err = parseWindow(*utilization); err != nil {
+			fatal("Invalid -utilization", "window", *utilization, "err", err)
+		}
+		ro.hostColumns = append(ro.hostColumns, export.UtilizationColumns...)
+	}
+	var perf collector.PerfOptions
+	if command == "perf" {
+		if perf, err = parsePerfOptions(*perfFrom, *perfTo, counters); err != nil {
+			fatal("Invalid -from, -to, or -counters", "err", err)
+		}
+		ro.counters = perf.Counters
+	}
+	expected := collector.NTPDNSExpected{NTPServers: expectNTP, DNSServers: expectDNS, SearchDomains: expectDomains}
+	if command != "ntpdns" && (len(expectNTP) > 0 || len(expectDNS) > 0 || len(expectDomains) > 0) {
+		fatal("-expect-ntp, -expect-dns, and -expect-search-domains are only supported by the ntpdns command")
+	}
+	switch {
+	case len(services) > 0 && command != "services":
+		fatal("-services is only supported by the services command")
+	case len(services) == 0:
+		services = keyServices
+	case slices.Contains(services, "all"):
+		services = nil
+	}
+	if *format == "rvtools" && !info.rvtools {
+		fatal("-format rvtools is not supported by the " + command + " command")
+	}
+	if *format == "ansible" && (command != "hosts" || *summaryFile) {
+		fatal("-format ansible is only supported by the hosts command, without -summary")
+	}
+	if err := export.ParseUnits(*unitSystem); err != nil {
+		fatal("Invalid -units", "err", err)
+	}
+	units := export.Units{System: *unitSystem, Precision: *precision}
+	convertUnits := units.System != "" || units.Precision >= 0
+	if convertUnits && (database || *format == "rvtools") {
+		fatal("-units and -precision cannot be used with database output or -format rvtools, whose columns are fixed")
+	}
+	// The image profile takes a call per host, so it is only queried
+	// when its column is written
+	imageProfile := command == "hosts" && *format != "rvtools"
+	if len(columns) > 0 {
+		if database {
+			fatal("-columns cannot be used with database output")
+		}
+		if *format == "rvtools" {
+			fatal("-columns cannot be used with -format rvtools, whose columns are fixed")
+		}
+		empty := reportTables(command, &inventory{}, ro)[0]
+		if convertUnits {
+			empty = empty.WithUnits(units)
+		}
+		selected, err := empty.Select(columns)
+		if err != nil {
+			fatal("Invalid -columns", "err", err)
+		}
+		imageProfile = imageProfile && slices.Contains(selected.Keys, "imageProfile")
+	}
+	var tmpl *template.Template
+	if *format == "template" {
+		if *templateFile == "" {
+			fatal("-format template requires -template-file")
+		}
+		if *summaryFile {
+			fatal("-summary cannot be used with -format template; the template receives the cluster rollup")
+		}
+		var err error
+		if tmpl, err = export.ParseTemplate(*templateFile); err != nil {
+			fatal("Invalid -template-file", "err", err)
+		}
+	} else if *templateFile != "" {
+		fatal("-template-file requires -format template")
+	}
+	delim, err := export.ParseDelimiter(*delimiter)
 	if err != nil {
-		log.Fatalf("Error retrieving hosts: %v", err)
+		fatal("Invalid -delimiter", "err", err)
+	}
+	if delim != ',' && *format != "csv" {
+		fatal("-delimiter is only supported by -format csv")
+	}
+	if *output == "" {
+		*output = info.baseName + "." + export.FileExtension(*format)
+		if delim == '\t' {
+			*output = info.baseName + ".tsv"
+		}
+		// report.md.tmpl writes hosts_cpu.md
+		if ext := filepath.Ext(strings.TrimSuffix(*templateFile, filepath.Ext(*templateFile))); tmpl != nil && ext != "" {
+			*output = info.baseName + ext
+		}
+	}
+	outPath := *output + encSuffix
+	if stdout {
+		outPath = "-"
+	}
+	// Appended rows must line up with those already in the file
+	var omitHeader, omitSummaryHeader bool
+	if *appendOutput && *format == "csv" && command != "check" {
+		empty := reportTables(command, &inventory{}, ro)
+		if convertUnits {
+			for i, t := range empty {
+				empty[i] = t.WithUnits(units)
+			}
+		}
+		if len(columns) > 0 {
+			t, _ := empty[0].Select(columns)
+			empty = append([]*export.Table{t}, empty[1:]...)
+		}
+		omitHeader = checkAppendHeader(outPath, empty[0].WithCollectedAt(time.Time{}), delim)
+		if *summaryFile {
+			omitSummaryHeader = checkAppendHeader(summaryPath(*output, command, encSuffix), empty[1].WithCollectedAt(time.Time{}), delim)
+		}
 	}
 
-	pc := property.DefaultCollector(client.Client)
+	if *password == "" && !tokenAuth {
+		fmt.Fprint(os.Stderr, "Password: ")
+		b, err := term.ReadPassword(int(syscall.Stdin))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			fatal("Error reading password", "err", err)
+		}
+		*password = string(b)
+	}
+
+	// Ctrl-C or SIGTERM cancels the collection so sessions are logged out
+	// cleanly; a second signal exits immediately
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigCtx.Done()
+		stop()
+	}()
+	ctx := sigCtx
+	if *totalTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *totalTimeout)
+		defer cancel()
+	}
+	stopTracing := func() {}
+	if *otlpEndpoint != "" {
+		if stopTracing, err = startTracing(ctx, *otlpEndpoint); err != nil {
+			fatal("Invalid -otlp-endpoint", "endpoint", *otlpEndpoint, "err", err)
+		}
+	}
+	anon := collector.NewAnonymizer(*anonymize)
+	if *anonymizeMode == "hmac" {
+		anon = collector.NewHMACAnonymizer([]byte(*anonymizeKey))
+	}
+	anon.SetRedactIPs(*redactIPs)
+	var debugOut io.Writer
+	if *debug {
+		debugOut = redactingWriter{os.Stderr, anon}
+	}
+	var prog *progress
+	if slog.Default().Enabled(ctx, slog.LevelInfo) {
+		// Rewriting the progress line would garble debug messages
+		prog = newProgress(*logFormat == "text" && !slog.Default().Enabled(ctx, slog.LevelDebug))
+	}
+	collectedAt := time.Now()
+	if *timestampOutput {
+		*output = timestampedName(*output, collectedAt)
+		outPath = *output + encSuffix
+	}
+	outOpts := outputOptions{compress: *compress, append: *appendOutput, recipients: recipients}
 
-	// Retrieve cluster/parent names for hosts
-	parentNames := make(map[string]string) // parent MoRef Value -> name
+	// NDJSON is written as records arrive rather than once at the end, so
+	// the hosts of a vCenter that fails after them are kept
+	var stream *export.NDJSONStream
+	var finishStream func()
+	streamable := command == "hosts" || command == "vms" || command == "datastores"
+	if *format == "ndjson" && !database && streamable && *splitBy == "" {
+		var w io.Writer
+		w, finishStream = createOutput(outPath, outOpts)
+		streamColumns := columns
+		if *appendOutput && len(columns) > 0 {
+			streamColumns = append([]string{"collectedAt"}, columns...)
+		}
+		stream = export.NewNDJSONStream(w, streamColumns)
+	}
+	streamTable := func(t *export.Table) {
+		if convertUnits {
+			t = t.WithUnits(units)
+		}
+		if *appendOutput {
+			t = t.WithCollectedAt(collectedAt)
+		}
+		if err := stream.Write(t); err != nil {
+			fatal("Error writing output", "format", *format, "err", err)
+		}
+	}
+
+	var inv inventory
+	collected := 0
+	var failures []collector.Failure
+	runCtx, runSpan := tracer().Start(ctx, "collect", trace.WithAttributes(attribute.String("command", command)))
 	for _, h := range hosts {
-		if h.Parent == nil {
-			continue
+		if sigCtx.Err() != nil {
+			break
 		}
-		if _, ok := parentNames[h.Parent.Value]; ok {
-			continue
+		opts := collector.Options{
+			VCenter:            h,
+			Anonymizer:         anon,
+			Debug:              debugOut,
+			Concurrency:        *concurrency,
+			Clusters:           clusters,
+			Datacenter:         *datacenter,
+			Tags:               tags,
+			SkipDisconnected:   *skipDisconnected,
+			SkipMaintenance:    *skipMaintenance,
+			Utilization:        window,
+			ImageProfile:       imageProfile,
+			CertificateWarning: certWindow,
+			Services:           services,
+		}
+		co := collector.ConnectOptions{
+			Insecure:     *insecure,
+			Thumbprint:   hostThumbprints[h],
+			CACert:       *caCert,
+			TokenFile:    *tokenFile,
+			CertFile:     *certFile,
+			KeyFile:      *keyFile,
+			Retries:      *retries,
+			RetryBackoff: *retryBackoff,
+			Timeout:      *timeout,
+			KeepAlive:    *keepAlive,
+		}
+		slog.Info("Collecting", "vcenter", h, "op", command)
+		if prog != nil {
+			opts.Progress = prog.reporter(h)
 		}
-		var parent mo.ManagedEntity
-		if err := pc.RetrieveOne(ctx, *h.Parent, []string{"name"}, &parent); err != nil {
-			log.Printf("Warning: could not retrieve cluster name for %s: %v", h.Summary.Config.Name, err)
+		var hostFailures []collector.Failure
+		opts.OnFailure = func(f collector.Failure) { hostFailures = append(hostFailures, f) }
+		if stream != nil && command == "hosts" {
+			opts.OnHost = func(h collector.Host) {
+				streamTable(export.NewTable("hosts", "Hosts", append(slices.Clone(export.HostColumns), ro.hostColumns...), []collector.Host{h}))
+			}
+		}
+		vmsBefore, datastoresBefore := len(inv.vms), len(inv.datastores)
+		if *sessionCache {
+			co.SessionDir = sessionDir()
+		}
+		if co.Thumbprint == "" {
+			co.Thumbprint = hostThumbprints[""]
+		}
+		vcCtx, span := tracer().Start(runCtx, "vcenter", trace.WithAttributes(attribute.String("vcenter", anon.Name("vCenter", h))))
+		err := collectVCenter(vcCtx, command, h, *user, *password, co, opts, perf, expected, &inv)
+		endSpan(span, err)
+		if err != nil {
+			if sigCtx.Err() != nil {
+				slog.Warn("Interrupted", "vcenter", h)
+				break
+			}
+			args := []any{"vcenter", h, "op", command, "err", err}
+			var certErr *tls.CertificateVerificationError
+			if errors.As(err, &certErr) {
+				args = append(args, "hint", "certificate not trusted; pass -cacert with the issuing CA, -thumbprint with its fingerprint, or -insecure to skip verification")
+			}
+			slog.Error("Error collecting from vCenter", args...)
+			failures = append(failures, collector.Failure{VCenter: anon.Name("vCenter", h), Op: command, Err: anon.Error(err)})
 			continue
 		}
-		parentNames[h.Parent.Value] = parent.Name
+		collected++
+		failures = append(failures, hostFailures...)
+		// VMs and datastores come without per-record queries, so each
+		// vCenter's are streamed together
+		if stream != nil && command == "vms" {
+			streamTable(export.VMTables(inv.vms[vmsBefore:])[0])
+		}
+		if stream != nil && command == "datastores" {
+			streamTable(export.DatastoreTables(inv.datastores[datastoresBefore:])[0])
+		}
+	}
+	runSpan.End()
+	stopTracing()
+	if command == "check" {
+		if collected < len(hosts) {
+			fatal(fmt.Sprintf("Check failed for %d of %d vCenters", len(hosts)-collected, len(hosts)))
+		}
+		selected := 0
+		for _, r := range inv.checks {
+			selected += r.Selected
+		}
+		fmt.Printf("A hosts run would collect %d hosts from %d vCenters and write them to %s\n", selected, collected, *output)
+		return
+	}
+	interrupted := sigCtx.Err() != nil
+	if interrupted {
+		if !*partial || collected == 0 {
+			if stream != nil {
+				finishStream()
+				slog.Error("Interrupted; output holds only the records streamed so far", "path", outPath)
+				os.Exit(130)
+			}
+			slog.Error("Interrupted; no output written")
+			os.Exit(130)
+		}
+		slog.Warn("Interrupted; writing partial results", "vcenters", collected)
+	}
+	if collected == 0 {
+		fatal("No vCenters could be collected")
+	}
+	status := "complete"
+	if interrupted {
+		status = "interrupted"
+	} else if len(failures) > 0 {
+		status = "partial"
 	}
 
-	// Build anonymized cluster name mapping
-	anonClusters := make(map[string]string)
-	if *anonymize {
-		idx := 0
-		for _, h := range hosts {
-			if h.Parent == nil {
-				continue
+	if command == "services" {
+		// Across all vCenters collected
+		collector.MarkNonstandardServices(inv.services)
+	}
+	tables := reportTables(command, &inv, ro)
+	var rvTables []*export.Table
+	var entitlements *export.Table // the -entitlements comparison
+	var summary string
+	rvtools := *format == "rvtools" && !database
+	switch command {
+	case "hosts":
+		if rvtools {
+			rvTables = export.RVToolsHostTables(inv.hosts)
+		}
+		summary = fmt.Sprintf("%d hosts", len(inv.hosts))
+	case "vms":
+		if rvtools {
+			rvTables = export.RVToolsVMTables(inv.vms)
+		}
+		summary = fmt.Sprintf("%d VMs", len(inv.vms))
+	case "datastores":
+		if rvtools {
+			rvTables = export.RVToolsDatastoreTables(inv.datastores)
+		}
+		vmfs5 := 0
+		for _, d := range inv.datastores {
+			if d.VMFS5() {
+				slog.Warn("Datastore on VMFS-5", "vcenter", d.VCenter, "datastore", d.Name, "version", d.VMFSVersion)
+				vmfs5++
+			}
+		}
+		summary = fmt.Sprintf("%d datastores, %d on VMFS-5", len(inv.datastores), vmfs5)
+	case "licensing":
+		total := collector.TotalLicenses(collector.LicenseClusters(collector.RollupClusters(inv.hosts), lic.Edition))
+		summary = fmt.Sprintf("%s licensing of %d hosts, needing %d cores and %g TiB of vSAN add-on", lic.Edition.Name, total.Hosts, total.LicenseCores, total.VsanAddOnTiB)
+		if lic.PerCPU {
+			summary += fmt.Sprintf(", or %d per-CPU licenses", total.CPULicenses)
+		}
+		if lic.Entitlements != nil {
+			gaps := collector.CompareEntitlements(total, lic.Edition, lic.Entitlements)
+			shortfalls := 0
+			for _, g := range gaps {
+				if g.Shortfall() {
+					slog.Warn("Entitlement shortfall", "product", g.Name, "unit", g.Unit, "deployed", g.Deployed, "entitled", g.Entitled)
+					shortfalls++
+				}
 			}
-			realName := parentNames[h.Parent.Value]
-			if _, ok := anonClusters[realName]; !ok {
-				idx++
-				anonClusters[realName] = fmt.Sprintf("Cluster %d", idx)
+			summary += fmt.Sprintf(", with %d entitlement shortfalls", shortfalls)
+			entitlements = export.EntitlementTable(gaps)
+		}
+	case "licenses":
+		summary = fmt.Sprintf("%d license keys and %d assignments", len(inv.licenses), len(inv.assigned))
+	case "headroom":
+		over := 0
+		for _, c := range collector.Headroom(collector.RollupClusters(inv.hosts), inv.vsanUsage, targets) {
+			if o := c.Over(); len(o) > 0 {
+				slog.Warn("Cluster over target", "vcenter", c.VCenter, "cluster", c.Cluster.Cluster, "over", strings.Join(o, ","))
+				over++
 			}
 		}
+		summary = fmt.Sprintf("headroom of %d clusters, %d over target", len(tables[0].Rows), over)
+	case "perf":
+		summary = fmt.Sprintf("%d performance samples of %d hosts from %s to %s", len(inv.perf), countHosts(inv.perf, perfSampleHost), perf.From.Format(time.DateTime), perf.To.Format(time.DateTime))
+	case "dimms":
+		summary = fmt.Sprintf("%d memory modules of %d hosts", len(inv.dimms), countHosts(inv.dimms, dimmHost))
+	case "vibs":
+		summary = fmt.Sprintf("%d VIBs of %d hosts", len(inv.vibs), countHosts(inv.vibs, vibHost))
+	case "nics":
+		summary = fmt.Sprintf("%d physical NICs of %d hosts", len(inv.nics), countHosts(inv.nics, nicHost))
+	case "pci":
+		summary = fmt.Sprintf("%d GPUs and passthrough devices of %d hosts", len(inv.pci), countHosts(inv.pci, pciDeviceHost))
+	case "vgpu":
+		summary = fmt.Sprintf("%d vGPU profiles of %d hosts", len(inv.vgpus), countHosts(inv.vgpus, vgpuHost))
+	case "ntpdns":
+		issues := 0
+		for _, c := range inv.ntpdns {
+			if len(c.Issues) > 0 {
+				slog.Warn("NTP or DNS issue", "vcenter", c.VCenter, "host", c.Host, "issues", strings.Join(c.Issues, "; "))
+				issues++
+			}
+		}
+		summary = fmt.Sprintf("NTP and DNS configuration of %d hosts, %d with issues", len(inv.ntpdns), issues)
+	case "services":
+		nonstandard := 0
+		for _, s := range inv.services {
+			if s.Nonstandard {
+				nonstandard++
+			}
+		}
+		summary = fmt.Sprintf("%d services of %d hosts, %d nonstandard", len(inv.services), countHosts(inv.services, serviceHost), nonstandard)
+	case "vmknics":
+		summary = fmt.Sprintf("%d VMkernel adapters of %d hosts", len(inv.vmknics), countHosts(inv.vmknics, vmkernelAdapterHost))
+	case "iscsi":
+		summary = fmt.Sprintf("%d iSCSI adapters of %d hosts", len(inv.iscsi), countHosts(inv.iscsi, iscsiAdapterHost))
+	case "localdisks":
+		summary = fmt.Sprintf("%d local disks of %d hosts", len(inv.localDisks), countHosts(inv.localDisks, localDiskHost))
+	case "mounts":
+		missing := 0
+		for _, m := range inv.mounts {
+			if m.Missing {
+				slog.Warn("Datastore not mounted", "vcenter", m.VCenter, "host", m.Host, "cluster", m.Cluster, "datastore", m.Datastore)
+				missing++
+			}
+		}
+		summary = fmt.Sprintf("%d datastore mounts of %d hosts, %d missing", len(inv.mounts)-missing, countHosts(inv.mounts, datastoreMountHost), missing)
+	case "paths":
+		single := 0
+		for _, l := range inv.paths {
+			if l.SinglePath {
+				slog.Warn("LUN without redundant paths", "vcenter", l.VCenter, "host", l.Host, "device", l.Device, "paths", l.Paths, "dead", l.DeadPaths)
+				single++
+			}
+		}
+		summary = fmt.Sprintf("%d LUNs of %d hosts, %d with a single path", len(inv.paths), countHosts(inv.paths, multipathLUNHost), single)
+	case "policies":
+		inUse := 0
+		for _, p := range inv.policies {
+			if p.Objects > 0 {
+				inUse++
+			}
+		}
+		summary = fmt.Sprintf("%d storage policies, %d in use", len(inv.policies), inUse)
+	case "vvols":
+		offline := 0
+		for _, p := range inv.providers {
+			if p.Status != "" && p.Status != "online" {
+				slog.Warn("VASA provider not online", "vcenter", p.VCenter, "provider", p.Name, "status", p.Status)
+				offline++
+			}
+		}
+		summary = fmt.Sprintf("%d vVol containers and %d VASA providers, %d not online", len(inv.vvols), len(inv.providers), offline)
+	case "consolidation":
+		_, clusters := collector.Consolidation(inv.hosts, inv.vms)
+		var vcpus, cores int
+		var vmMemGB, memGB float64
+		for _, c := range clusters {
+			vcpus += c.VCPUs
+			cores += c.TotalCores
+			vmMemGB += c.VMMemoryGB
+			memGB += float64(c.MemoryGB)
+		}
+		summary = fmt.Sprintf("%d VMs on %d hosts, %.1f vCPUs per core and %.2f GB of VM memory per GB", len(inv.vms), len(inv.hosts), float64(vcpus)/float64(max(cores, 1)), vmMemGB/max(memGB, 1))
 	}
-
-	// Retrieve vSAN disk info per host
-	type vsanHostInfo struct {
-		capacityTiB float64
-		totalDisks  int
-		cacheDisks  int
-		clusterType string // "OSA" or "ESA"
+	if len(hosts) > 1 {
+		summary += fmt.Sprintf(" from %d vCenters", collected)
 	}
-	vsanInfo := make(map[string]vsanHostInfo)
-	for _, h := range hosts {
-		vsanRef := h.ConfigManager.VsanSystem
-		if vsanRef == nil {
-			continue
+	// Events use the standard columns whatever the file format
+	records := tables[0]
+	dataTables := tables
+	if rvTables != nil {
+		tables = rvTables
+	}
+	if convertUnits {
+		converted := make([]*export.Table, len(tables))
+		for i, t := range tables {
+			converted[i] = t.WithUnits(units)
 		}
-		var vsanSys mo.HostVsanSystem
-		err = pc.RetrieveOne(ctx, *vsanRef, nil, &vsanSys)
+		tables = converted
+	}
+	unselected := tables // keep the cluster column for -split-by
+	if len(columns) > 0 {
+		t, err := tables[0].Select(columns)
 		if err != nil {
-			log.Printf("Warning: could not retrieve vSAN config for %s: %v", h.Summary.Config.Name, err)
-			continue
+			fatal("Invalid -columns", "err", err)
 		}
-		if *debug {
-			j, _ := json.MarshalIndent(vsanSys, "", "  ")
-			fmt.Printf("=== vSAN system for %s ===\n%s\n\n", h.Summary.Config.Name, j)
+		tables = append([]*export.Table{t}, tables[1:]...)
+	}
+	if *appendOutput {
+		stamped := make([]*export.Table, len(tables))
+		for i, t := range tables {
+			stamped[i] = t.WithCollectedAt(collectedAt)
 		}
+		tables = stamped
+	}
 
-		isESA := vsanSys.Config.VsanEsaEnabled != nil && *vsanSys.Config.VsanEsaEnabled
+	rep := &export.Report{CollectedAt: collectedAt, Generator: versionString(), Tables: tables, Delimiter: delim, Template: tmpl, OmitHeader: omitHeader}
+	var meta *export.RunMetadata
+	if *metadata {
+		meta = &export.RunMetadata{
+			Command:     command,
+			Status:      status,
+			CollectedAt: collectedAt,
+			Generator:   rep.Generator,
+			VCenters:    inv.vcenters,
+			Filters: export.RunFilters{
+				Clusters:         clusters,
+				Datacenter:       *datacenter,
+				Tags:             tags,
+				SkipDisconnected: *skipDisconnected,
+				SkipMaintenance:  *skipMaintenance,
+				Columns:          columns,
+				Units:            units,
+				Anonymized:       *anonymize,
+				RedactedIPs:      *redactIPs,
+			},
+		}
+		if *format == "json" {
+			rep.Metadata = meta
+		}
+	}
+	var written []string // output files, for the manifest and -upload
+	var runID int64
+	if database {
+		// Always stored, so an empty errors table marks a clean run
+		rep.Tables = append(rep.Tables, export.FailureTable(failures))
+		var err error
+		runID, err = export.WriteDatabase(*output, rep)
+		if err != nil {
+			fatal("Error writing to database", "err", err)
+		}
+		slog.Info("Stored "+summary, "run", runID)
+	} else {
+		switch {
+		case stream != nil:
+			finishStream()
+		case *splitBy != "":
+			paths := writeSplit(*output, encSuffix, *format, rep, unselected, columns, outOpts)
+			written = append(written, paths...)
+		default:
+			writeOutput(outPath, *format, rep, outOpts)
+		}
+		switch {
+		case stdout:
+			slog.Info("Wrote "+summary, "path", "stdout")
+		case *splitBy != "":
+			slog.Info(fmt.Sprintf("Wrote %s to %d files", summary, len(written)), "split-by", *splitBy)
+		default:
+			slog.Info("Wrote "+summary, "path", outPath)
+			written = append(written, outPath)
+		}
+		if !stdout {
+			// A sidecar lists what is missing from the output; one left
+			// by an earlier run is removed so it is not mistaken for this
+			// run's. With stdout output, failures are only logged
+			ext := filepath.Ext(*output)
+			path := strings.TrimSuffix(*output, ext) + "_errors.json" + encSuffix
+			if len(failures) > 0 {
+				writeOutput(path, "json", &export.Report{CollectedAt: collectedAt, Generator: rep.Generator, Tables: []*export.Table{export.FailureTable(failures)}}, outputOptions{compress: *compress, recipients: recipients})
+				slog.Warn(fmt.Sprintf("Wrote %d failures", len(failures)), "path", path)
+				written = append(written, path)
+			} else if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				slog.Warn("Could not remove stale errors file", "path", path, "err", err)
+			}
 
-		var info vsanHostInfo
-		var capacityBytes int64
+			if meta != nil && rep.Metadata == nil {
+				path := strings.TrimSuffix(*output, ext) + "_run.json" + encSuffix
+				w, finish := createOutput(path, outputOptions{compress: *compress, recipients: recipients})
+				if err := meta.WriteJSON(w); err != nil {
+					fatal("Error writing run metadata", "err", err)
+				}
+				finish()
+				slog.Info("Wrote run metadata", "path", path)
+				written = append(written, path)
+			}
+		}
+	}
 
-		if isESA {
-			// ESA: no disk groups, query disks directly
-			info.clusterType = "ESA"
-			res, err := methods.QueryDisksForVsan(ctx, client.Client, &types.QueryDisksForVsan{
-				This: *vsanRef,
-			})
+	if *summaryFile {
+		path := summaryPath(*output, command, encSuffix)
+		writeOutput(path, *format, &export.Report{CollectedAt: collectedAt, Generator: rep.Generator, Tables: tables[1:], Delimiter: delim, OmitHeader: omitSummaryHeader, Metadata: rep.Metadata}, outOpts)
+		slog.Info(fmt.Sprintf("Wrote %d %s", len(tables[1].Rows), summaryName(command)), "path", path)
+		written = append(written, path)
+	}
+	// Formats with several tables hold the comparison already
+	if entitlements != nil && !database && !stdout && !export.MultiTable(*format) {
+		ext := filepath.Ext(*output)
+		path := strings.TrimSuffix(*output, ext) + "_entitlements" + ext + encSuffix
+		writeOutput(path, *format, &export.Report{CollectedAt: collectedAt, Generator: rep.Generator, Tables: []*export.Table{entitlements}, Delimiter: delim}, outputOptions{compress: *compress, recipients: recipients})
+		slog.Info("Wrote entitlement comparison", "path", path)
+		written = append(written, path)
+	}
+	if *manifest {
+		// The anonymization mapping is left out: it is private and not
+		// part of the report
+		ext := filepath.Ext(*output)
+		path := strings.TrimSuffix(*output, ext) + "_manifest.sha256"
+		if err := export.WriteManifest(path, written); err != nil {
+			fatal("Error writing manifest", "path", path, "err", err)
+		}
+		slog.Info("Wrote manifest", "path", path)
+		written = append(written, path)
+		if signer != nil {
+			sigPath, err := export.SignFile(path, signer)
 			if err != nil {
-				log.Printf("Warning: could not query vSAN disks for %s: %v", h.Summary.Config.Name, err)
-			} else {
-				if *debug {
-					j, _ := json.MarshalIndent(res.Returnval, "", "  ")
-					fmt.Printf("=== vSAN disks for %s ===\n%s\n\n", h.Summary.Config.Name, j)
-				}
-				for _, dr := range res.Returnval {
-					// For ESA, disks in use have vsanDiskInfo populated
-					inUse := dr.Disk.VsanDiskInfo != nil
-					if inUse {
-						info.totalDisks++
-						capacityBytes += int64(dr.Disk.Capacity.BlockSize) * int64(dr.Disk.Capacity.Block)
-					}
-				}
+				fatal("Error signing manifest", "path", path, "err", err)
 			}
+			slog.Info("Signed manifest", "path", sigPath)
+			written = append(written, sigPath)
+		}
+	}
+	if syslogSender != nil {
+		n, err := syslogSender.Send(records, collectedAt)
+		if err != nil {
+			fatal("Error sending syslog events", "sent", n, "err", err)
+		}
+		slog.Info(fmt.Sprintf("Sent %d syslog events", n), "collector", *syslogTarget)
+	}
+	if kafka != nil {
+		n, err := kafka.Publish(context.WithoutCancel(ctx), records, collectedAt)
+		if err != nil {
+			fatal("Error publishing to Kafka", "published", n, "err", err)
+		}
+		slog.Info(fmt.Sprintf("Published %d messages", n), "topic", *kafkaTopic)
+	}
+	if netbox != nil {
+		var st export.SyncStats
+		var err error
+		nctx := context.WithoutCancel(ctx)
+		if command == "hosts" {
+			st, err = netbox.SyncHosts(nctx, inv.hosts)
 		} else {
-			// OSA: disk groups with cache SSD + capacity disks
-			if vsanSys.Config.StorageInfo == nil || len(vsanSys.Config.StorageInfo.DiskMapping) == 0 {
-				continue
+			st, err = netbox.SyncVMs(nctx, inv.vms)
+		}
+		if err != nil {
+			fatal("Error updating NetBox", "created", st.Created, "updated", st.Updated, "err", err)
+		}
+		slog.Info("Updated NetBox", "created", st.Created, "updated", st.Updated)
+	}
+	var uploaded []string
+	if uploader != nil {
+		// Uploads go ahead after an interrupt with -partial; a second
+		// signal still exits
+		uctx := context.WithoutCancel(ctx)
+		for _, path := range written {
+			url, err := uploader.Upload(uctx, path)
+			if err != nil {
+				fatal("Error uploading output", "err", err)
 			}
-			info.clusterType = "OSA"
-			info.cacheDisks = len(vsanSys.Config.StorageInfo.DiskMapping)
-			for _, dm := range vsanSys.Config.StorageInfo.DiskMapping {
-				info.totalDisks += len(dm.NonSsd)
-				for _, d := range dm.NonSsd {
-					capacityBytes += int64(d.Capacity.BlockSize) * int64(d.Capacity.Block)
-				}
+			slog.Info("Uploaded", "path", path, "url", url)
+			uploaded = append(uploaded, url)
+		}
+	}
+	title := "VMware inventory: " + summary
+	if interrupted {
+		title += " (interrupted)"
+	} else if len(failures) > 0 {
+		title += fmt.Sprintf(" (%d failures)", len(failures))
+	}
+	if len(mailTo) > 0 {
+		var attached []string
+		if *mailAttach {
+			attached = written
+		}
+		var names []string
+		for _, path := range attached {
+			names = append(names, filepath.Base(path))
+		}
+		err := export.SendMail(export.Mail{
+			Server:      *smtpServer,
+			User:        *smtpUser,
+			Password:    *smtpPassword,
+			From:        *mailFrom,
+			To:          mailTo,
+			Subject:     title,
+			Body:        mailBody(summary, collectedAt, rep.Generator, totals(command, &inv, ro), len(failures), names, uploaded),
+			Attachments: attached,
+		})
+		if err != nil {
+			fatal("Error sending report email", "server", *smtpServer, "err", err)
+		}
+		slog.Info("Mailed report", "to", strings.Join(mailTo, ","))
+	}
+	if len(webhooks) > 0 {
+		n := export.Notification{
+			Title: title,
+			Facts: append(totals(command, &inv, ro), export.Fact{Key: "failures", Name: "Failures", Value: len(failures)}),
+		}
+		n.Notes = append(n.Notes, uploaded...)
+		for _, w := range webhooks {
+			if err := export.PostWebhook(context.WithoutCancel(ctx), w, n); err != nil {
+				fatal("Error posting notification", "err", err)
 			}
 		}
+		slog.Info("Posted notification", "webhooks", len(webhooks))
+	}
+	if *webhookURL != "" {
+		run := export.RunSummary{
+			Command:     command,
+			Status:      status,
+			CollectedAt: collectedAt,
+			Generator:   rep.Generator,
+			RunID:       runID,
+			Totals:      totals(command, &inv, ro),
+			Failures:    failures,
+			Files:       written,
+			Uploads:     uploaded,
+		}
+		if *webhookData {
+			run.Data = &export.Report{CollectedAt: collectedAt, Generator: rep.Generator, Tables: dataTables}
+		}
+		if err := export.PostCallback(context.WithoutCancel(ctx), *webhookURL, *webhookSecret, run); err != nil {
+			fatal("Error posting run summary", "err", err)
+		}
+		slog.Info("Posted run summary", "status", status)
+	}
+	if *anonymizeMap != "" {
+		if err := writeMapping(*anonymizeMap, anon); err != nil {
+			fatal("Error writing anonymization mapping", "path", *anonymizeMap, "err", err)
+		}
+		slog.Info(fmt.Sprintf("Wrote %d anonymized names", len(anon.Mapping())), "path", *anonymizeMap)
+	}
+	if interrupted {
+		os.Exit(130)
+	}
+	if len(failures) > 0 {
+		os.Exit(2)
+	}
+}
+
+// reportOptions are the settings of the reports that need more than the
+// inventory: the licensing terms, the headroom targets, the columns added
+// to the host report, and the counters of the perf report.
+type reportOptions struct {
+	license     export.LicenseOptions
+	targets     collector.Targets
+	hostColumns []export.Column[collector.Host]
+	counters    []string // of the perf report
+}
 
-		info.capacityTiB = float64(capacityBytes) / (1024 * 1024 * 1024 * 1024)
-		vsanInfo[h.Summary.Config.Name] = info
+// reportTables returns the tables that command writes for inv. The check
+// command has those of the hosts command, whose run it checks.
+func reportTables(command string, inv *inventory, ro reportOptions) []*export.Table {
+	switch command {
+	case "vms":
+		return export.VMTables(inv.vms)
+	case "datastores":
+		return export.DatastoreTables(inv.datastores)
+	case "licensing":
+		return export.LicenseTables(inv.hosts, ro.license)
+	case "licenses":
+		return export.LicenseKeyTables(inv.licenses, inv.assigned)
+	case "consolidation":
+		return export.ConsolidationTables(inv.hosts, inv.vms)
+	case "headroom":
+		return export.HeadroomTables(inv.hosts, inv.vsanUsage, ro.targets)
+	case "perf":
+		return export.PerfTables(inv.perf, ro.counters)
+	case "dimms":
+		return export.DIMMTables(inv.dimms)
+	case "vibs":
+		return export.VIBTables(inv.vibs)
+	case "nics":
+		return export.NICTables(inv.nics)
+	case "pci":
+		return export.PCIDeviceTables(inv.pci)
+	case "vgpu":
+		return export.VGPUTables(inv.vgpus)
+	case "ntpdns":
+		return export.NTPDNSTables(inv.ntpdns)
+	case "services":
+		return export.ServiceTables(inv.services)
+	case "vmknics":
+		return export.VMKernelAdapterTables(inv.vmknics)
+	case "iscsi":
+		return export.ISCSIAdapterTables(inv.iscsi)
+	case "localdisks":
+		return export.LocalDiskTables(inv.localDisks)
+	case "mounts":
+		return export.DatastoreMountTables(inv.mounts)
+	case "paths":
+		return export.MultipathLUNTables(inv.paths)
+	case "policies":
+		return export.StoragePolicyTables(inv.policies)
+	case "vvols":
+		return export.VVolTables(inv.vvols, inv.providers)
 	}
+	return export.HostTables(inv.hosts, ro.hostColumns...)
+}
 
-	// Write CSV
-	f, err := os.Create(*output)
+// collectVCenter connects to one vCenter and appends the records for command
+// to inv, with the samples selected by po for the perf command and hosts
+// checked against expected for the ntpdns command.
+func collectVCenter(ctx context.Context, command, host, user, password string, co collector.ConnectOptions, opts collector.Options, po collector.PerfOptions, expected collector.NTPDNSExpected, inv *inventory) error {
+	client, stop, err := collector.Connect(ctx, host, user, password, co)
 	if err != nil {
-		log.Fatalf("Error creating output file: %v", err)
+		return err
+	}
+	defer stop()
+	if co.SessionDir == "" {
+		defer release(ctx, client.Logout)
 	}
 
-	w := csv.NewWriter(f)
+	if len(opts.Tags) > 0 {
+		rc, err := collector.ConnectREST(ctx, client.Client, user, password, co)
+		if err != nil {
+			return err
+		}
+		defer release(ctx, rc.Logout)
+		opts.Tagging = rc
+	}
 
-	w.Write([]string{"Hostname", "Cluster", "Server Model", "ESXi Version", "CPU Model", "Socket Count", "Cores per Socket", "Total Cores", "Memory GB", "vSAN Type", "vSAN Capacity Disks", "vSAN Cache Disks", "vSAN Capacity TiB"})
+	switch command {
+	case "hosts", "licensing":
+		hosts, err := collector.CollectHosts(ctx, client.Client, opts)
+		if err != nil {
+			return fmt.Errorf("collecting hosts: %w", err)
+		}
+		inv.hosts = append(inv.hosts, hosts...)
+	case "headroom":
+		hosts, err := collector.CollectHosts(ctx, client.Client, opts)
+		if err != nil {
+			return fmt.Errorf("collecting hosts: %w", err)
+		}
+		usage, err := collector.CollectVsanUsage(ctx, client.Client, opts)
+		if err != nil {
+			return fmt.Errorf("collecting vSAN usage: %w", err)
+		}
+		inv.hosts = append(inv.hosts, hosts...)
+		inv.vsanUsage = append(inv.vsanUsage, usage...)
+	case "consolidation":
+		// Hosts first, so anonymized names are labeled as in a hosts run
+		hosts, err := collector.CollectHosts(ctx, client.Client, opts)
+		if err != nil {
+			return fmt.Errorf("collecting hosts: %w", err)
+		}
+		vms, err := collector.CollectVMs(ctx, client.Client, opts)
+		if err != nil {
+			return fmt.Errorf("collecting VMs: %w", err)
+		}
+		inv.hosts = append(inv.hosts, hosts...)
+		inv.vms = append(inv.vms, vms...)
+	case "perf":
+		samples, err := collector.CollectPerf(ctx, client.Client, opts, po)
+		if err != nil {
+			return fmt.Errorf("collecting performance: %w", err)
+		}
+		inv.perf = append(inv.perf, samples...)
+	case "dimms":
+		dimms, err := collector.CollectDIMMs(ctx, client.Client, opts)
+		if err != nil {
+			return fmt.Errorf("collecting memory modules: %w", err)
+		}
+		inv.dimms = append(inv.dimms, dimms...)
+	case "vibs":
+		vibs, err := collector.CollectVIBs(ctx, client.Client, opts)
+		if err != nil {
+			return fmt.Errorf("collecting VIBs: %w", err)
+		}
+		inv.vibs = append(inv.vibs, vibs...)
+	case "nics":
+		nics, err := collector.CollectNICs(ctx, client.Client, opts)
+		if err != nil {
+			return fmt.Errorf("collecting physical NICs: %w", err)
+		}
+		inv.nics = append(inv.nics, nics...)
+	case "pci":
+		devices, err := collector.CollectPCIDevices(ctx, client.Client, opts)
+		if err != nil {
+			return fmt.Errorf("collecting PCI devices: %w", err)
+		}
+		inv.pci = append(inv.pci, devices...)
+	case "vgpu":
+		profiles, err := collector.CollectVGPUs(ctx, client.Client, opts)
+		if err != nil {
+			return fmt.Errorf("collecting vGPU profiles: %w", err)
+		}
+		inv.vgpus = append(inv.vgpus, profiles...)
+	case "ntpdns":
+		configs, err := collector.CollectNTPDNS(ctx, client.Client, opts, expected)
+		if err != nil {
+			return fmt.Errorf("collecting NTP and DNS configuration: %w", err)
+		}
+		inv.ntpdns = append(inv.ntpdns, configs...)
+	case "services":
+		services, err := collector.CollectHostServices(ctx, client.Client, opts)
+		if err != nil {
+			return fmt.Errorf("collecting host services: %w", err)
+		}
+		inv.services = append(inv.services, services...)
+	case "vmknics":
+		adapters, err := collector.CollectVMKernelAdapters(ctx, client.Client, opts)
+		if err != nil {
+			return fmt.Errorf("collecting VMkernel adapters: %w", err)
+		}
+		inv.vmknics = append(inv.vmknics, adapters...)
+	case "iscsi":
+		adapters, err := collector.CollectISCSIAdapters(ctx, client.Client, opts)
+		if err != nil {
+			return fmt.Errorf("collecting iSCSI adapters: %w", err)
+		}
+		inv.iscsi = append(inv.iscsi, adapters...)
+	case "localdisks":
+		disks, err := collector.CollectLocalDisks(ctx, client.Client, opts)
+		if err != nil {
+			return fmt.Errorf("collecting local disks: %w", err)
+		}
+		inv.localDisks = append(inv.localDisks, disks...)
+	case "mounts":
+		mounts, err := collector.CollectDatastoreMounts(ctx, client.Client, opts)
+		if err != nil {
+			return fmt.Errorf("collecting datastore mounts: %w", err)
+		}
+		inv.mounts = append(inv.mounts, mounts...)
+	case "paths":
+		luns, err := collector.CollectMultipathLUNs(ctx, client.Client, opts)
+		if err != nil {
+			return fmt.Errorf("collecting LUN paths: %w", err)
+		}
+		inv.paths = append(inv.paths, luns...)
+	case "policies":
+		policies, err := collector.CollectStoragePolicies(ctx, client.Client, opts)
+		if err != nil {
+			return fmt.Errorf("collecting storage policies: %w", err)
+		}
+		inv.policies = append(inv.policies, policies...)
+	case "vvols":
+		providers, containers, err := collector.CollectVVols(ctx, client.Client, opts)
+		if err != nil {
+			return fmt.Errorf("collecting vVols: %w", err)
+		}
+		inv.providers = append(inv.providers, providers...)
+		inv.vvols = append(inv.vvols, containers...)
+	case "vms":
+		vms, err := collector.CollectVMs(ctx, client.Client, opts)
+		if err != nil {
+			return fmt.Errorf("collecting VMs: %w", err)
+		}
+		inv.vms = append(inv.vms, vms...)
+	case "datastores":
+		datastores, err := collector.CollectDatastores(ctx, client.Client, opts)
+		if err != nil {
+			return fmt.Errorf("collecting datastores: %w", err)
+		}
+		inv.datastores = append(inv.datastores, datastores...)
+	case "licenses":
+		licenses, assigned, err := collector.CollectLicenses(ctx, client.Client, opts)
+		if err != nil {
+			return fmt.Errorf("collecting licenses: %w", err)
+		}
+		inv.licenses = append(inv.licenses, licenses...)
+		inv.assigned = append(inv.assigned, assigned...)
+	case "check":
+		r, err := collector.Check(ctx, client.Client, opts)
+		if err != nil {
+			return fmt.Errorf("check failed: %w", err)
+		}
+		printCheck(host, r)
+		inv.checks = append(inv.checks, r)
+		return nil
+	}
+	inv.vcenters = append(inv.vcenters, collector.About(client.Client, opts))
+	return nil
+}
 
-	for i, h := range hosts {
-		hostname := h.Summary.Config.Name
-		if *anonymize {
-			hostname = fmt.Sprintf("Host %d", i+1)
+// printCheck prints the result of the check command for one vCenter.
+func printCheck(host string, r *collector.CheckResult) {
+	fmt.Printf("%s: OK\n", host)
+	fmt.Printf("  Product:  %s\n", r.Product)
+	fmt.Printf("  User:     %s\n", r.User)
+	fmt.Printf("  Hosts:    %d selected of %d, in %d clusters\n", r.Selected, r.Hosts, r.Clusters)
+	if r.VsanProbe != "" {
+		fmt.Printf("  vSAN:     %d hosts; queried %s\n", r.VsanHosts, r.VsanProbe)
+	} else {
+		fmt.Printf("  vSAN:     %d hosts; none connected to query\n", r.VsanHosts)
+	}
+}
+
+// release calls fn, typically a Logout, with a context that outlives
+// cancellation of ctx, so an interrupted run does not leave sessions open.
+func release(ctx context.Context, fn func(context.Context) error) {
+	ctx, cancel := collector.Cleanup(ctx)
+	defer cancel()
+	_ = fn(ctx)
+}
+
+// parseThumbprints maps vCenter host to certificate fingerprint from
+// -thumbprint values of the form host=fingerprint. A bare fingerprint is
+// stored under the empty host and applies to every vCenter.
+func parseThumbprints(values []string) (map[string]string, error) {
+	thumbprints := make(map[string]string)
+	for _, v := range values {
+		host, tp, ok := strings.Cut(v, "=")
+		if !ok {
+			host, tp = "", v
+		}
+		norm, err := collector.NormalizeThumbprint(tp)
+		if err != nil {
+			return nil, err
 		}
+		thumbprints[host] = norm
+	}
+	return thumbprints, nil
+}
 
-		cluster := ""
-		if h.Parent != nil {
-			cluster = parentNames[h.Parent.Value]
-			if *anonymize {
-				cluster = anonClusters[cluster]
-			}
+// parseWindow parses a time window given as a number of days, such as 30d,
+// or as a Go duration, such as 12h.
+func parseWindow(s string) (time.Duration, error) {
+	var d time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number of days %q", days)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if d, err = time.ParseDuration(s); err != nil {
+			return 0, err
 		}
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("window must be positive")
+	}
+	return d, nil
+}
 
-		serverModel := ""
-		if h.Summary.Hardware != nil {
-			serverModel = h.Summary.Hardware.Model
+// parsePerfOptions parses the -from, -to, and -counters flags of the perf
+// command. From may also be a window before To, such as 30d.
+func parsePerfOptions(from, to string, counters []string) (collector.PerfOptions, error) {
+	po := collector.PerfOptions{To: time.Now(), Counters: counters}
+	var err error
+	if to != "" {
+		if po.To, err = parseTime(to); err != nil {
+			return po, err
+		}
+	}
+	if window, werr := parseWindow(from); werr == nil {
+		po.From = po.To.Add(-window)
+	} else if po.From, err = parseTime(from); err != nil {
+		return po, err
+	}
+	if !po.From.Before(po.To) {
+		return po, fmt.Errorf("-from must be before -to")
+	}
+	if len(po.Counters) == 0 {
+		po.Counters = collector.PerfCounterOrder
+	}
+	for _, c := range po.Counters {
+		if _, ok := collector.PerfCounters[c]; !ok {
+			return po, fmt.Errorf("unknown counter %q; choose from %s", c, strings.Join(collector.PerfCounterOrder, ", "))
 		}
+	}
+	return po, nil
+}
+
+// parseTime parses a date, in local time, or an RFC 3339 date and time.
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.ParseInLocation(time.DateOnly, s, time.Local); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return t, fmt.Errorf("invalid time %q; use a date such as 2024-05-01 or a date and time such as 2024-05-01T08:00:00Z", s)
+	}
+	return t, nil
+}
+
+// countHosts returns the number of hosts records are of, as told apart by
+// the vCenter and name host returns for each.
+func countHosts[T any](records []T, host func(T) [2]string) int {
+	hosts := make(map[[2]string]bool)
+	for _, r := range records {
+		hosts[host(r)] = true
+	}
+	return len(hosts)
+}
+
+func perfSampleHost(s collector.PerfSample) [2]string { return [2]string{s.VCenter, s.Host} }
+
+func dimmHost(d collector.DIMM) [2]string { return [2]string{d.VCenter, d.Host} }
+
+func vibHost(v collector.VIB) [2]string { return [2]string{v.VCenter, v.Host} }
+
+func nicHost(n collector.PhysicalNIC) [2]string { return [2]string{n.VCenter, n.Host} }
+
+func pciDeviceHost(d collector.PCIDevice) [2]string { return [2]string{d.VCenter, d.Host} }
+
+func vgpuHost(p collector.VGPUProfile) [2]string { return [2]string{p.VCenter, p.Host} }
+
+func ntpDNSHost(c collector.NTPDNSConfig) [2]string { return [2]string{c.VCenter, c.Host} }
+
+func serviceHost(s collector.HostService) [2]string { return [2]string{s.VCenter, s.Host} }
+
+func vmkernelAdapterHost(a collector.VMKernelAdapter) [2]string { return [2]string{a.VCenter, a.Host} }
 
-		esxiVersion := ""
-		if h.Summary.Config.Product != nil {
-			esxiVersion = h.Summary.Config.Product.Version
+func iscsiAdapterHost(a collector.ISCSIAdapter) [2]string { return [2]string{a.VCenter, a.Host} }
+
+func localDiskHost(d collector.LocalDisk) [2]string { return [2]string{d.VCenter, d.Host} }
+
+func datastoreMountHost(m collector.DatastoreMount) [2]string { return [2]string{m.VCenter, m.Host} }
+
+func multipathLUNHost(l collector.MultipathLUN) [2]string { return [2]string{l.VCenter, l.Host} }
+
+// sessionDir returns govc's session cache directory, $GOVMOMI_HOME/sessions
+// or ~/.govmomi/sessions, so sessions are shared with govc.
+func sessionDir() string {
+	home := os.Getenv("GOVMOMI_HOME")
+	if home == "" {
+		dir, _ := os.UserHomeDir()
+		home = filepath.Join(dir, ".govmomi")
+	}
+	return filepath.Join(home, "sessions")
+}
+
+// readHostFile reads vCenter hostnames from path, one per line. Blank lines
+// and lines starting with # are ignored.
+func readHostFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var hosts []string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
 		}
+		hosts = append(hosts, line)
+	}
+	return hosts, s.Err()
+}
 
-		cpuModel := ""
-		if h.Hardware != nil && len(h.Hardware.CpuPkg) > 0 {
-			cpuModel = h.Hardware.CpuPkg[0].Description
+// writeSplit writes rep as a file per cluster of tables, which are rep's
+// tables before columns are selected, and returns their paths. Each file is
+// named after its cluster, and also its vCenter when clusters of that name
+// are in several vCenters; each holds the cluster's rows of every table
+// with a cluster column. Clusters whose names give the same file name, such
+// as "a/b" and "a:b", or "Prod" and "prod" on a case-insensitive file
+// system, are numbered to keep their files apart.
+func writeSplit(output, encSuffix, format string, rep *export.Report, tables []*export.Table, columns []string, opts outputOptions) []string {
+	keys := []string{"vcenter", "cluster"}
+	groups := tables[0].Split(keys...)
+	vcenters := make(map[string]map[string]bool)
+	for _, g := range groups {
+		if vcenters[g[1]] == nil {
+			vcenters[g[1]] = make(map[string]bool)
 		}
+		vcenters[g[1]][g[0]] = true
+	}
 
-		var sockets, totalCores, coresPerSocket int16
-		var memoryGB int64
-		if h.Hardware != nil {
-			sockets = h.Hardware.CpuInfo.NumCpuPackages
-			totalCores = h.Hardware.CpuInfo.NumCpuCores
-			if sockets > 0 {
-				coresPerSocket = totalCores / sockets
+	ext := filepath.Ext(output)
+	used := make(map[string]bool) // lowercased file name parts
+	var paths []string
+	for _, g := range groups {
+		name := g[1]
+		if len(vcenters[name]) > 1 {
+			name = g[0] + "_" + name
+		}
+		file := fileNamePart(name)
+		for n := 2; used[strings.ToLower(file)]; n++ {
+			file = fmt.Sprintf("%s_%d", fileNamePart(name), n)
+		}
+		used[strings.ToLower(file)] = true
+		part := *rep
+		part.Tables = nil
+		for _, t := range tables {
+			if t, ok := t.Where(keys, g); ok {
+				part.Tables = append(part.Tables, t)
 			}
-			memoryGB = h.Hardware.MemorySize / (1024 * 1024 * 1024)
-		}
-
-		info := vsanInfo[h.Summary.Config.Name]
-
-		w.Write([]string{
-			hostname,
-			cluster,
-			serverModel,
-			esxiVersion,
-			cpuModel,
-			strconv.Itoa(int(sockets)),
-			strconv.Itoa(int(coresPerSocket)),
-			strconv.Itoa(int(totalCores)),
-			strconv.FormatInt(memoryGB, 10),
-			info.clusterType,
-			strconv.Itoa(info.totalDisks),
-			strconv.Itoa(info.cacheDisks),
-			fmt.Sprintf("%.1f", info.capacityTiB),
-		})
+		}
+		if len(columns) > 0 {
+			// Checked before collection
+			part.Tables[0], _ = part.Tables[0].Select(columns)
+		}
+		path := strings.TrimSuffix(output, ext) + "_" + file + ext + encSuffix
+		writeOutput(path, format, &part, opts)
+		slog.Debug("Wrote cluster", "cluster", g[1], "path", path)
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// timestampedName returns output with the date and time of at
+// inserted before its extension, for -timestamp-output.
+func timestampedName(output string, at time.Time) string {
+	ext := filepath.Ext(output)
+	return strings.TrimSuffix(output, ext) + at.Format("_2006-01-02_150405") + ext
+}
+
+// fileNamePart returns name with the characters that are not allowed in
+// file names on every platform, and spaces, replaced by underscores.
+func fileNamePart(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r < ' ' || strings.ContainsRune(`<>:"/\|?* `, r) {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// summaryName returns what the -summary file of command holds: the cluster
+// rollup, the license assignments for the licenses command, or the VASA
+// providers for the vvols command.
+func summaryName(command string) string {
+	switch command {
+	case "licenses":
+		return "assignments"
+	case "vvols":
+		return "providers"
 	}
+	return "clusters"
+}
+
+// summaryPath returns the path of the -summary file of command for output.
+func summaryPath(output, command, encSuffix string) string {
+	ext := filepath.Ext(output)
+	return strings.TrimSuffix(output, ext) + "_" + summaryName(command) + ext + encSuffix
+}
 
-	w.Flush()
-	if err := w.Error(); err != nil {
-		log.Fatalf("Error writing CSV: %v", err)
+// checkAppendHeader reports whether the CSV file at path already has the
+// header of t, so -append should leave it out, exiting if the file holds
+// different columns.
+func checkAppendHeader(path string, t *export.Table, delimiter rune) bool {
+	header, err := export.CSVHeader(path, delimiter)
+	if err != nil {
+		fatal("Error reading file to append to", "path", path, "err", err)
+	}
+	if header == nil {
+		return false
 	}
-	if err := f.Close(); err != nil {
-		log.Fatalf("Error closing output file: %v", err)
+	if !slices.Equal(header, t.Headers) {
+		fatal("Cannot append to a file with different columns", "path", path, "columns", strings.Join(header, ","))
 	}
+	return true
+}
+
+// outputOptions control how createOutput writes a file.
+type outputOptions struct {
+	compress   bool // gzip
+	append     bool // to an existing file
+	recipients []age.Recipient
+}
+
+// writeOutput writes rep to path in the given format, as for createOutput,
+// exiting on failure.
+func writeOutput(path, format string, rep *export.Report, opts outputOptions) {
+	w, finish := createOutput(path, opts)
+	if err := export.Write(w, format, rep); err != nil {
+		fatal("Error writing output", "format", format, "err", err)
+	}
+	finish()
+}
+
+// createOutput creates the file at path, or appends to it with
+// opts.append, or uses stdout for "-". Its contents are gzip-compressed
+// with opts.compress and encrypted to opts.recipients if any. It returns a
+// writer for the contents and a func that completes the file.
+func createOutput(path string, opts outputOptions) (io.Writer, func()) {
+	f := os.Stdout
+	var err error
+	switch {
+	case path == "-":
+	case opts.append:
+		if f, err = os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o666); err != nil {
+			fatal("Error opening output file", "err", err)
+		}
+	default:
+		if f, err = os.Create(path); err != nil {
+			fatal("Error creating output file", "err", err)
+		}
+	}
+	var w io.Writer = f
+	var enc io.WriteCloser
+	if len(opts.recipients) > 0 {
+		if enc, err = export.Encrypt(f, opts.recipients); err != nil {
+			fatal("Error encrypting output", "err", err)
+		}
+		w = enc
+	}
+	var zw *gzip.Writer
+	if opts.compress {
+		zw = gzip.NewWriter(w)
+		w = zw
+	}
+	return w, func() {
+		if zw != nil {
+			if err := zw.Close(); err != nil {
+				fatal("Error compressing output", "err", err)
+			}
+		}
+		if enc != nil {
+			if err := enc.Close(); err != nil {
+				fatal("Error encrypting output", "err", err)
+			}
+		}
+		if f == os.Stdout {
+			return
+		}
+		if err := f.Close(); err != nil {
+			fatal("Error closing output file", "err", err)
+		}
+	}
+}
+
+// redactingWriter passes each write through an Anonymizer's Text, so -debug
+// dumps honor -redact-ips. Each dump is written in a single call, so
+// addresses are not split across writes.
+type redactingWriter struct {
+	w    io.Writer
+	anon *collector.Anonymizer
+}
+
+func (r redactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(r.w, r.anon.Text(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// writeMapping writes the labels assigned by anon to path as CSV. The file
+// identifies the real hosts behind an anonymized report, so it is created
+// readable only by the owner, and an existing file is restricted before it
+// is overwritten.
+func writeMapping(path string, anon *collector.Anonymizer) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := f.Chmod(0o600); err != nil {
+		f.Close()
+		return err
+	}
+	rep := &export.Report{Tables: []*export.Table{export.MappingTable(anon.Mapping())}}
+	if err := export.Write(f, "csv", rep); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
 
-	fmt.Fprintf(os.Stderr, "Wrote %d hosts to %s\n", len(hosts), *output)
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [command] -host <vcenter> (-user <username> | -token <file> | -cert <file> -key <file>) [flags]\n\n", os.Args[0])
+	fmt.Fprintln(os.Stderr, "Commands:")
+	fmt.Fprintln(os.Stderr, "  hosts          ESXi host hardware and vSAN inventory (default)")
+	fmt.Fprintln(os.Stderr, "  vms            virtual machine sizing inventory")
+	fmt.Fprintln(os.Stderr, "  datastores     datastore type, VMFS version, capacity, and host attachment, with VMFS-5 datastores per cluster")
+	fmt.Fprintln(os.Stderr, "  licensing      cores to license per host, cluster, and environment under VCF and VVF")
+	fmt.Fprintln(os.Stderr, "  licenses       installed license keys, their capacity and use, and what each is assigned to")
+	fmt.Fprintln(os.Stderr, "  consolidation  vCPU:pCore ratios and VM density per host and cluster")
+	fmt.Fprintln(os.Stderr, "  headroom       CPU, memory, and vSAN use per cluster against utilization targets")
+	fmt.Fprintln(os.Stderr, "  perf           historical CPU, memory, and disk use per host and cluster over a date range")
+	fmt.Fprintln(os.Stderr, "  dimms          memory modules per host and slot, where the hardware health reports them")
+	fmt.Fprintln(os.Stderr, "  vibs           installed VIBs per host, with version, vendor, and acceptance level")
+	fmt.Fprintln(os.Stderr, "  nics           physical NICs per host, with driver, firmware, link speed, switch, and SR-IOV")
+	fmt.Fprintln(os.Stderr, "  pci            GPUs and PCI devices enabled for passthrough, per host")
+	fmt.Fprintln(os.Stderr, "  vgpu           vGPU profiles offered and used per host, with the VMs using them")
+	fmt.Fprintln(os.Stderr, "  ntpdns         NTP servers and service, DNS servers, and search domains per host, with issues found")
+	fmt.Fprintln(os.Stderr, "  services       state and startup policy of key host services, marking those unlike most hosts'")
+	fmt.Fprintln(os.Stderr, "  vmknics        VMkernel adapters per host, with IP, MTU, enabled services, and port group")
+	fmt.Fprintln(os.Stderr, "  iscsi          iSCSI adapters per host, with IQN, bound ports, targets, and CHAP mode")
+	fmt.Fprintln(os.Stderr, "  localdisks     local disks not claimed by vSAN per host, with model, capacity, and VMFS datastores")
+	fmt.Fprintln(os.Stderr, "  mounts         datastores mounted per host, with access mode, marking those missing from a host of a cluster")
+	fmt.Fprintln(os.Stderr, "  paths          path counts, path selection policy, and dead paths per host and LUN, marking single-path LUNs")
+	fmt.Fprintln(os.Stderr, "  policies       storage policies with their rules (FTT, RAID level, encryption) and the VMs and objects using each")
+	fmt.Fprintln(os.Stderr, "  vvols          registered VASA providers and their status, and vVol storage containers with their capacity")
+	fmt.Fprintln(os.Stderr, "  check          verify connectivity, credentials, and permissions without collecting")
+	fmt.Fprintln(os.Stderr, "  schema         print the JSON Schema of -format json output: schema [hosts|vms|datastores|licensing|licenses|consolidation|headroom|perf|dimms|vibs|nics|pci|vgpu|ntpdns|services|vmknics|iscsi|localdisks|mounts|paths|policies|vvols]")
+	fmt.Fprintln(os.Stderr, "\nFlags:")
+	flag.PrintDefaults()
 }
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"vmware-inventory/pkg/collector"
+	"vmware-inventory/pkg/export"
+)
+
+// totals sums the collected records, e.g. the host, socket, and core counts
+// of a hosts run.
+func totals(command string, inv *inventory, ro reportOptions) []export.Fact {
+	switch command {
+	case "hosts":
+		var sockets, cores int
+		var memGB int64
+		var vsanTiB float64
+		certificates := 0 // expired or expiring
+		for _, h := range inv.hosts {
+			sockets += h.Sockets
+			cores += h.TotalCores
+			memGB += h.MemoryGB
+			vsanTiB += h.VsanCapacityTiB
+			if h.CertificateStatus == "expired" || h.CertificateStatus == "expiring" {
+				certificates++
+			}
+		}
+		return []export.Fact{
+			{Key: "hosts", Name: "Hosts", Value: len(inv.hosts)},
+			{Key: "clusters", Name: "Clusters", Value: len(collector.RollupClusters(inv.hosts))},
+			{Key: "socketCount", Name: "Sockets", Value: sockets},
+			{Key: "totalCores", Name: "Cores", Value: cores},
+			{Key: "memoryGB", Name: "Memory GB", Value: memGB},
+			{Key: "vsanCapacityTiB", Name: "vSAN capacity TiB", Value: math.Round(vsanTiB*100) / 100},
+			{Key: "expiringCertificates", Name: "Expired or expiring certificates", Value: certificates},
+		}
+	case "licensing":
+		total := collector.TotalLicenses(collector.LicenseClusters(collector.RollupClusters(inv.hosts), ro.license.Edition))
+		facts := []export.Fact{
+			{Key: "hosts", Name: "Hosts", Value: total.Hosts},
+			{Key: "clusters", Name: "Clusters", Value: total.Clusters},
+			{Key: "socketCount", Name: "Sockets", Value: total.Sockets},
+			{Key: "totalCores", Name: "Cores", Value: total.TotalCores},
+			{Key: "licenseCores", Name: "License cores", Value: total.LicenseCores},
+			{Key: "vsanCapacityTiB", Name: "vSAN capacity TiB", Value: math.Round(total.VsanCapacityTiB*100) / 100},
+			{Key: "vsanAddOnTiB", Name: "vSAN add-on TiB", Value: total.VsanAddOnTiB},
+		}
+		if ro.license.PerCPU {
+			facts = append(facts, export.Fact{Key: "cpuLicenses", Name: "Per-CPU licenses", Value: total.CPULicenses})
+		}
+		if ro.license.Entitlements != nil {
+			shortfalls := 0
+			for _, g := range collector.CompareEntitlements(total, ro.license.Edition, ro.license.Entitlements) {
+				if g.Shortfall() {
+					shortfalls++
+				}
+			}
+			facts = append(facts, export.Fact{Key: "entitlementShortfalls", Name: "Entitlement shortfalls", Value: shortfalls})
+		}
+		return facts
+	case "licenses":
+		return []export.Fact{
+			{Key: "licenses", Name: "License keys", Value: len(inv.licenses)},
+			{Key: "assignments", Name: "Assignments", Value: len(inv.assigned)},
+		}
+	case "headroom":
+		clusters := collector.Headroom(collector.RollupClusters(inv.hosts), inv.vsanUsage, ro.targets)
+		over := 0
+		for _, c := range clusters {
+			if len(c.Over()) > 0 {
+				over++
+			}
+		}
+		return []export.Fact{
+			{Key: "clusters", Name: "Clusters", Value: len(clusters)},
+			{Key: "overTarget", Name: "Clusters over target", Value: over},
+		}
+	case "consolidation":
+		_, clusters := collector.Consolidation(inv.hosts, inv.vms)
+		var cores, vcpus int
+		var memGB int64
+		var vmMemGB, reservedGB float64
+		for _, c := range clusters {
+			cores += c.TotalCores
+			vcpus += c.VCPUs
+			memGB += c.MemoryGB
+			vmMemGB += c.VMMemoryGB
+			reservedGB += c.ReservedGB
+		}
+		return []export.Fact{
+			{Key: "hosts", Name: "Hosts", Value: len(inv.hosts)},
+			{Key: "vms", Name: "VMs", Value: len(inv.vms)},
+			{Key: "totalCores", Name: "Cores", Value: cores},
+			{Key: "vcpus", Name: "Powered-on vCPUs", Value: vcpus},
+			{Key: "memoryGB", Name: "Memory GB", Value: memGB},
+			{Key: "vmMemoryGB", Name: "VM memory GB", Value: math.Round(vmMemGB)},
+			{Key: "reservedMemoryGB", Name: "Reserved memory GB", Value: math.Round(reservedGB)},
+		}
+	case "vms":
+		var vcpus int
+		var memGB float64
+		for _, v := range inv.vms {
+			vcpus += v.VCPUs
+			memGB += v.MemoryGB
+		}
+		return []export.Fact{
+			{Key: "vms", Name: "VMs", Value: len(inv.vms)},
+			{Key: "vcpus", Name: "vCPUs", Value: vcpus},
+			{Key: "memoryGB", Name: "Memory GB", Value: math.Round(memGB)},
+		}
+	case "datastores":
+		var capGB, freeGB float64
+		vmfs5 := 0
+		for _, d := range inv.datastores {
+			capGB += d.CapacityGB
+			freeGB += d.FreeGB
+			if d.VMFS5() {
+				vmfs5++
+			}
+		}
+		return []export.Fact{
+			{Key: "datastores", Name: "Datastores", Value: len(inv.datastores)},
+			{Key: "capacityGB", Name: "Capacity GB", Value: math.Round(capGB)},
+			{Key: "freeGB", Name: "Free GB", Value: math.Round(freeGB)},
+			{Key: "vmfs5Datastores", Name: "VMFS-5 datastores", Value: vmfs5},
+		}
+	case "perf":
+		return []export.Fact{
+			{Key: "hosts", Name: "Hosts", Value: countHosts(inv.perf, perfSampleHost)},
+			{Key: "samples", Name: "Samples", Value: len(inv.perf)},
+		}
+	case "dimms":
+		sizeGB := 0
+		for _, d := range inv.dimms {
+			sizeGB += d.SizeGB
+		}
+		return []export.Fact{
+			{Key: "hosts", Name: "Hosts", Value: countHosts(inv.dimms, dimmHost)},
+			{Key: "modules", Name: "Memory modules", Value: len(inv.dimms)},
+			{Key: "memoryGB", Name: "Memory GB", Value: sizeGB},
+		}
+	case "vibs":
+		community := 0
+		for _, v := range inv.vibs {
+			if v.AcceptanceLevel == "community" {
+				community++
+			}
+		}
+		return []export.Fact{
+			{Key: "hosts", Name: "Hosts", Value: countHosts(inv.vibs, vibHost)},
+			{Key: "vibs", Name: "VIBs", Value: len(inv.vibs)},
+			{Key: "communityVIBs", Name: "Community-supported VIBs", Value: community},
+		}
+	case "nics":
+		down, sriov := 0, 0
+		for _, n := range inv.nics {
+			if n.LinkSpeedMb == 0 {
+				down++
+			}
+			if n.SRIOVEnabled {
+				sriov++
+			}
+		}
+		return []export.Fact{
+			{Key: "hosts", Name: "Hosts", Value: countHosts(inv.nics, nicHost)},
+			{Key: "nics", Name: "Physical NICs", Value: len(inv.nics)},
+			{Key: "linksDown", Name: "Links down", Value: down},
+			{Key: "sriovEnabled", Name: "SR-IOV enabled NICs", Value: sriov},
+		}
+	case "pci":
+		gpus, passthrough := 0, 0
+		for _, d := range inv.pci {
+			if d.GPU {
+				gpus++
+			}
+			if d.PassthroughEnabled {
+				passthrough++
+			}
+		}
+		return []export.Fact{
+			{Key: "hosts", Name: "Hosts", Value: countHosts(inv.pci, pciDeviceHost)},
+			{Key: "gpus", Name: "GPUs", Value: gpus},
+			{Key: "passthroughDevices", Name: "Passthrough devices", Value: passthrough},
+		}
+	case "vgpu":
+		vms := 0
+		for _, p := range inv.vgpus {
+			vms += len(p.VMs)
+		}
+		return []export.Fact{
+			{Key: "hosts", Name: "Hosts", Value: countHosts(inv.vgpus, vgpuHost)},
+			{Key: "vgpuVMs", Name: "VMs using vGPUs", Value: vms},
+		}
+	case "ntpdns":
+		issues := 0
+		for _, c := range inv.ntpdns {
+			if len(c.Issues) > 0 {
+				issues++
+			}
+		}
+		return []export.Fact{
+			{Key: "hosts", Name: "Hosts", Value: countHosts(inv.ntpdns, ntpDNSHost)},
+			{Key: "hostsWithIssues", Name: "Hosts with NTP or DNS issues", Value: issues},
+		}
+	case "services":
+		nonstandard := 0
+		for _, s := range inv.services {
+			if s.Nonstandard {
+				nonstandard++
+			}
+		}
+		return []export.Fact{
+			{Key: "hosts", Name: "Hosts", Value: countHosts(inv.services, serviceHost)},
+			{Key: "nonstandardServices", Name: "Nonstandard services", Value: nonstandard},
+		}
+	case "vmknics":
+		vmotion, vsan := 0, 0
+		for _, a := range inv.vmknics {
+			if slices.Contains(a.Services, "vMotion") {
+				vmotion++
+			}
+			if slices.Contains(a.Services, "vSAN") {
+				vsan++
+			}
+		}
+		return []export.Fact{
+			{Key: "hosts", Name: "Hosts", Value: countHosts(inv.vmknics, vmkernelAdapterHost)},
+			{Key: "adapters", Name: "VMkernel adapters", Value: len(inv.vmknics)},
+			{Key: "vmotionAdapters", Name: "vMotion adapters", Value: vmotion},
+			{Key: "vsanAdapters", Name: "vSAN adapters", Value: vsan},
+		}
+	case "iscsi":
+		unbound := 0
+		for _, a := range inv.iscsi {
+			if a.Type == "software" && len(a.BoundPorts) == 0 {
+				unbound++
+			}
+		}
+		return []export.Fact{
+			{Key: "hosts", Name: "Hosts", Value: countHosts(inv.iscsi, iscsiAdapterHost)},
+			{Key: "adapters", Name: "iSCSI adapters", Value: len(inv.iscsi)},
+			{Key: "unboundSoftwareAdapters", Name: "Software adapters without bound ports", Value: unbound},
+		}
+	case "localdisks":
+		boot, usb := 0, 0
+		for _, d := range inv.localDisks {
+			if d.Boot {
+				boot++
+				if d.USB {
+					usb++
+				}
+			}
+		}
+		return []export.Fact{
+			{Key: "hosts", Name: "Hosts", Value: countHosts(inv.localDisks, localDiskHost)},
+			{Key: "disks", Name: "Local disks", Value: len(inv.localDisks)},
+			{Key: "bootDisks", Name: "Boot disks", Value: boot},
+			{Key: "usbBootDisks", Name: "USB or SD boot disks", Value: usb},
+		}
+	case "mounts":
+		missing := 0
+		for _, m := range inv.mounts {
+			if m.Missing {
+				missing++
+			}
+		}
+		return []export.Fact{
+			{Key: "hosts", Name: "Hosts", Value: countHosts(inv.mounts, datastoreMountHost)},
+			{Key: "mounts", Name: "Datastore mounts", Value: len(inv.mounts) - missing},
+			{Key: "missingMounts", Name: "Missing mounts", Value: missing},
+		}
+	case "paths":
+		single, dead := 0, 0
+		for _, l := range inv.paths {
+			if l.SinglePath {
+				single++
+			}
+			dead += l.DeadPaths
+		}
+		return []export.Fact{
+			{Key: "hosts", Name: "Hosts", Value: countHosts(inv.paths, multipathLUNHost)},
+			{Key: "luns", Name: "LUNs", Value: len(inv.paths)},
+			{Key: "singlePathLuns", Name: "Single-path LUNs", Value: single},
+			{Key: "deadPaths", Name: "Dead paths", Value: dead},
+		}
+	case "policies":
+		inUse, vms := 0, 0
+		for _, p := range inv.policies {
+			if p.Objects > 0 {
+				inUse++
+			}
+			vms += p.VMs
+		}
+		return []export.Fact{
+			{Key: "policies", Name: "Storage policies", Value: len(inv.policies)},
+			{Key: "policiesInUse", Name: "Policies in use", Value: inUse},
+			{Key: "vms", Name: "VM assignments", Value: vms},
+		}
+	case "vvols":
+		offline := 0
+		var capacityGB float64
+		for _, p := range inv.providers {
+			if p.Status != "" && p.Status != "online" {
+				offline++
+			}
+		}
+		for _, c := range inv.vvols {
+			capacityGB += c.CapacityGB
+		}
+		return []export.Fact{
+			{Key: "providers", Name: "VASA providers", Value: len(inv.providers)},
+			{Key: "providersNotOnline", Name: "Providers not online", Value: offline},
+			{Key: "containers", Name: "vVol containers", Value: len(inv.vvols)},
+			{Key: "capacityGB", Name: "Capacity GB", Value: math.Round(capacityGB)},
+		}
+	}
+	return nil
+}
+
+// mailBody returns the plain-text body of the report email: when and by
+// what the report was collected, the totals, any failures, and the files
+// attached or uploaded.
+func mailBody(summary string, collectedAt time.Time, generator string, facts []export.Fact, failures int, attached, uploaded []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Collected %s by %s: %s.\n\n", collectedAt.Format("2006-01-02 15:04 MST"), generator, summary)
+	for _, f := range facts {
+		b.WriteString(f.Name + ": " + f.Text() + "\n")
+	}
+	if failures > 0 {
+		fmt.Fprintf(&b, "\n%d hosts or vCenters could not be fully collected; see the errors file.\n", failures)
+	}
+	if len(attached) > 0 {
+		b.WriteString("\nAttached:\n")
+		for _, f := range attached {
+			b.WriteString("  " + f + "\n")
+		}
+	}
+	if len(uploaded) > 0 {
+		b.WriteString("\nUploaded:\n")
+		for _, u := range uploaded {
+			b.WriteString("  " + u + "\n")
+		}
+	}
+	return b.String()
+}
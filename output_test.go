@@ -0,0 +1,99 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"vmware-inventory/pkg/export"
+)
+
+func TestWriteSplit(t *testing.T) {
+	dir := t.TempDir()
+	hosts := &export.Table{
+		Name:    "hosts",
+		Keys:    []string{"vcenter", "cluster", "name"},
+		Headers: []string{"vCenter", "Cluster", "Name"},
+		Rows: [][]any{
+			{"vc1", "Prod", "h1"},
+			{"vc1", "prod", "h2"},
+			{"vc1", "a/b", "h3"},
+			{"vc1", "a:b", "h4"},
+			{"vc1", "Dev", "h5"},
+			{"vc2", "Dev", "h6"},
+			{"vc1", `../east\west`, "h7"},
+			{"vc1", "Prod", "h8"},
+		},
+	}
+	rep := &export.Report{Tables: []*export.Table{hosts}}
+	paths := writeSplit(filepath.Join(dir, "hosts.csv"), "", "csv", rep, rep.Tables, nil, outputOptions{})
+
+	want := map[string][]string{
+		"hosts_Prod.csv":         {"h1", "h8"},
+		"hosts_prod_2.csv":       {"h2"},
+		"hosts_a_b.csv":          {"h3"},
+		"hosts_a_b_2.csv":        {"h4"},
+		"hosts_vc1_Dev.csv":      {"h5"},
+		"hosts_vc2_Dev.csv":      {"h6"},
+		"hosts_.._east_west.csv": {"h7"},
+	}
+	if len(paths) != len(want) {
+		t.Errorf("wrote %d files, want %d: %v", len(paths), len(want), paths)
+	}
+	for _, path := range paths {
+		if filepath.Dir(path) != dir {
+			t.Errorf("%s written outside %s", path, dir)
+		}
+		names, ok := want[filepath.Base(path)]
+		if !ok {
+			t.Errorf("unexpected file %s", filepath.Base(path))
+			continue
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Count(string(b), "\n") - 1; got != len(names) {
+			t.Errorf("%s has %d rows, want %d", filepath.Base(path), got, len(names))
+		}
+		for _, name := range names {
+			if !strings.Contains(string(b), ","+name+"\n") {
+				t.Errorf("%s is missing host %s:\n%s", filepath.Base(path), name, b)
+			}
+		}
+	}
+}
+
+func TestFileNamePart(t *testing.T) {
+	for name, want := range map[string]string{
+		"Prod":            "Prod",
+		"Prod Cluster":    "Prod_Cluster",
+		"a/b":             "a_b",
+		`a\b`:             "a_b",
+		"../etc":          ".._etc",
+		`<>:"|?*`:         "_______",
+		"line\nbreak":     "line_break",
+		"vc1.example.com": "vc1.example.com",
+	} {
+		if got := fileNamePart(name); got != want {
+			t.Errorf("fileNamePart(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestTimestampedName(t *testing.T) {
+	at := time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)
+	for output, want := range map[string]string{
+		"hosts.csv":             "hosts_2024-05-01_093000.csv",
+		"inventory.json":        "inventory_2024-05-01_093000.json",
+		"hosts":                 "hosts_2024-05-01_093000",
+		"out.d/hosts":           "out.d/hosts_2024-05-01_093000",
+		"reports/hosts.v2.xlsx": "reports/hosts.v2_2024-05-01_093000.xlsx",
+	} {
+		if got := timestampedName(output, at); got != want {
+			t.Errorf("timestampedName(%q) = %q, want %q", output, got, want)
+		}
+	}
+}
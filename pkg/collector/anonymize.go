@@ -0,0 +1,209 @@
+package collector
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Anonymizer replaces real names with generic, numbered labels. Labels are
+// assigned in order of first use, so callers should visit objects in a stable
+// order (the container view order) to get the same labels across commands.
+// Share one Anonymizer across vCenters so labels stay unique.
+type Anonymizer struct {
+	mu        sync.Mutex
+	enabled   bool
+	redactIPs bool
+	key       []byte                       // HMAC key; nil for numbered labels
+	labels    map[string]map[string]string // kind -> real name -> label
+	perVC     map[string]bool              // kinds labeled per vCenter
+	order     []MappingEntry               // labels in order of assignment
+}
+
+// MappingEntry pairs a label with the real name it replaces. VCenter is set
+// for kinds whose names are only unique within one vCenter.
+type MappingEntry struct {
+	Kind    string
+	Label   string
+	VCenter string
+	Name    string
+}
+
+// NewAnonymizer returns an Anonymizer. When enabled is false it returns
+// names unchanged.
+func NewAnonymizer(enabled bool) *Anonymizer {
+	return &Anonymizer{enabled: enabled, labels: make(map[string]map[string]string), perVC: make(map[string]bool)}
+}
+
+// NewHMACAnonymizer returns an Anonymizer whose labels are derived from an
+// HMAC-SHA256 of each real name under key, e.g. "Host 3f9a1c2b04de". The
+// same name always gets the same label, regardless of inventory order, so
+// anonymized reports from different runs can be compared.
+func NewHMACAnonymizer(key []byte) *Anonymizer {
+	a := NewAnonymizer(true)
+	a.key = key
+	return a
+}
+
+// SetRedactIPs turns IP redaction on or off. When on, IP addresses are
+// replaced with labels such as "IP 3" even if names are not anonymized: in
+// names, in text passed to Text, and in IP address columns.
+func (a *Anonymizer) SetRedactIPs(on bool) {
+	a.redactIPs = on
+}
+
+// Name returns the label for a real name of the given kind (e.g. "Host"),
+// or, when anonymization is off, the real name with any IP addresses
+// redacted. Empty names stay empty.
+func (a *Anonymizer) Name(kind, real string) string {
+	if real == "" {
+		return real
+	}
+	if !a.enabled {
+		return a.Text(real)
+	}
+	return a.label(kind, real)
+}
+
+// ipCandidate matches text that may be an IPv4 or IPv6 address; matches are
+// confirmed with netip.ParseAddr, which rejects times and MAC addresses.
+var ipCandidate = regexp.MustCompile(`\d{1,3}(?:\.\d{1,3}){3}|[0-9A-Fa-f]*:[0-9A-Fa-f:.]*[0-9A-Fa-f]`)
+
+// Text returns s with every IP address replaced by its label when IP
+// redaction is on, e.g. for error messages and debug output.
+func (a *Anonymizer) Text(s string) string {
+	if !a.redactIPs {
+		return s
+	}
+	return ipCandidate.ReplaceAllStringFunc(s, func(m string) string {
+		if _, err := netip.ParseAddr(m); err != nil {
+			return m
+		}
+		return a.label("IP", m)
+	})
+}
+
+// Error returns err with IP addresses in its message redacted by Text.
+func (a *Anonymizer) Error(err error) error {
+	if s := a.Text(err.Error()); s != err.Error() {
+		return errors.New(s)
+	}
+	return err
+}
+
+// label returns the label for real, assigning the next one if needed.
+func (a *Anonymizer) label(kind, real string) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	m, ok := a.labels[kind]
+	if !ok {
+		m = make(map[string]string)
+		a.labels[kind] = m
+	}
+	label, ok := m[real]
+	if !ok {
+		if a.key != nil {
+			label = kind + " " + a.digest(kind, real)
+		} else {
+			label = fmt.Sprintf("%s %d", kind, len(m)+1)
+		}
+		m[real] = label
+		e := MappingEntry{Kind: kind, Label: label, Name: real}
+		if a.perVC[kind] {
+			e.VCenter, e.Name, _ = strings.Cut(real, "/")
+		}
+		a.order = append(a.order, e)
+	}
+	return label
+}
+
+// digest returns the first 12 hex digits of the HMAC of kind and real,
+// enough to keep collisions unlikely in the largest inventories.
+func (a *Anonymizer) digest(kind, real string) string {
+	h := hmac.New(sha256.New, a.key)
+	h.Write([]byte(kind + "\x00" + real))
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+// Mapping returns every label assigned so far with the real name it
+// replaces, in order of assignment. It is empty unless anonymization or IP
+// redaction is on.
+func (a *Anonymizer) Mapping() []MappingEntry {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return slices.Clone(a.order)
+}
+
+func (a *Anonymizer) vcenter(real string) string { return a.Name("vCenter", real) }
+func (a *Anonymizer) host(real string) string    { return a.Name("Host", real) }
+func (a *Anonymizer) vm(real string) string      { return a.Name("VM", real) }
+
+// names returns the labels of the real names of the given kind, as Name
+// does, such as the NTP servers of a host.
+func (a *Anonymizer) names(kind string, real []string) []string {
+	if real == nil {
+		return nil
+	}
+	labels := make([]string, len(real))
+	for i, r := range real {
+		labels[i] = a.Name(kind, r)
+	}
+	return labels
+}
+
+// ip returns the label of an address in an IP address column when IP
+// redaction is on, matching those Text gives, or else the address.
+func (a *Anonymizer) ip(real string) string {
+	if real == "" || !a.redactIPs {
+		return real
+	}
+	return a.label("IP", real)
+}
+
+// Hardware and license identifiers are anonymized along with names, since
+// any of them would identify the systems behind a report.
+func (a *Anonymizer) uuid(real string) string     { return a.Name("UUID", real) }
+func (a *Anonymizer) serial(real string) string   { return a.Name("Serial", real) }
+func (a *Anonymizer) assetTag(real string) string { return a.Name("Asset Tag", real) }
+
+// licenseKey returns the masked license key, or when anonymizing, a label
+// for the full key, since even the last group identifies it.
+func (a *Anonymizer) licenseKey(real string) string {
+	if !a.enabled {
+		return MaskLicenseKey(real)
+	}
+	return a.Name("License", real)
+}
+
+// Cluster, datastore, switch, and port group names are only unique within
+// one vCenter, so they are labeled per vCenter.
+func (a *Anonymizer) cluster(vcenter, real string) string { return a.scoped("Cluster", vcenter, real) }
+func (a *Anonymizer) datastore(vcenter, real string) string {
+	return a.scoped("Datastore", vcenter, real)
+}
+func (a *Anonymizer) networkSwitch(vcenter, real string) string {
+	return a.scoped("Switch", vcenter, real)
+}
+func (a *Anonymizer) portgroup(vcenter, real string) string {
+	return a.scoped("Port Group", vcenter, real)
+}
+
+func (a *Anonymizer) scoped(kind, vcenter, real string) string {
+	if real == "" {
+		return real
+	}
+	if !a.enabled {
+		return a.Text(real)
+	}
+	a.mu.Lock()
+	a.perVC[kind] = true
+	a.mu.Unlock()
+	return a.label(kind, vcenter+"/"+real)
+}
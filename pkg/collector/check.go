@@ -0,0 +1,96 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/session"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// CheckResult describes what a host collection would cover, as found by
+// Check.
+type CheckResult struct {
+	Product   string // vCenter product name, version, and build
+	User      string // logged-in user
+	Hosts     int    // hosts in the Datacenter scope
+	Selected  int    // hosts passing the filters
+	Clusters  int    // clusters among the selected hosts
+	VsanHosts int    // selected hosts with a vSAN system
+	VsanProbe string // host whose vSAN system was queried, empty if none
+}
+
+// Check verifies that c's session can do what CollectHosts needs without
+// collecting anything: it reads the session, lists the hosts in scope and
+// applies the filters, and queries the vSAN system of one selected host.
+// Names in the result are not anonymized.
+func Check(ctx context.Context, c *vim25.Client, opts Options) (*CheckResult, error) {
+	r := &CheckResult{Product: c.ServiceContent.About.FullName}
+	us, err := session.NewManager(c).UserSession(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("reading session: %w", err)
+	}
+	if us != nil {
+		r.User = us.UserName
+	}
+
+	root, err := containerRoot(ctx, c, opts)
+	if err != nil {
+		return nil, err
+	}
+	v, err := view.NewManager(c).CreateContainerView(ctx, root, []string{"HostSystem"}, true)
+	if err != nil {
+		return nil, fmt.Errorf("creating container view: %w", err)
+	}
+	defer destroyView(ctx, v)
+
+	var hosts []mo.HostSystem
+	err = v.Retrieve(ctx, []string{"HostSystem"}, []string{"summary.config.name", "summary.runtime", "configManager.vsanSystem", "parent"}, &hosts)
+	if err != nil {
+		return nil, fmt.Errorf("retrieving hosts: %w", err)
+	}
+	r.Hosts = len(hosts)
+
+	pc := property.DefaultCollector(c)
+	parentNames := retrieveParentNames(ctx, pc, hosts, opts)
+	tagged, err := taggedHosts(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	hosts = filterHosts(hosts, parentNames, tagged, opts)
+	r.Selected = len(hosts)
+
+	clusters := make(map[string]bool)
+	var probe *mo.HostSystem
+	for i, h := range hosts {
+		if h.Parent != nil && h.Parent.Type == "ClusterComputeResource" {
+			clusters[h.Parent.Value] = true
+		}
+		if h.ConfigManager.VsanSystem == nil {
+			continue
+		}
+		r.VsanHosts++
+		if probe == nil && h.Summary.Runtime != nil && h.Summary.Runtime.ConnectionState == types.HostSystemConnectionStateConnected {
+			probe = &hosts[i]
+		}
+	}
+	r.Clusters = len(clusters)
+
+	if probe != nil {
+		r.VsanProbe = probe.Summary.Config.Name
+		ref := *probe.ConfigManager.VsanSystem
+		var vsanSys mo.HostVsanSystem
+		if err := pc.RetrieveOne(ctx, ref, []string{"config"}, &vsanSys); err != nil {
+			return r, fmt.Errorf("retrieving vSAN config of %s: %w", r.VsanProbe, err)
+		}
+		if _, err := methods.QueryDisksForVsan(ctx, c, &types.QueryDisksForVsan{This: ref}); err != nil {
+			return r, fmt.Errorf("querying vSAN disks of %s: %w", r.VsanProbe, err)
+		}
+	}
+	return r, nil
+}
@@ -0,0 +1,328 @@
+// Package collector retrieves ESXi host, VM, and datastore inventory from
+// vCenter.
+package collector
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vapi/rest"
+	"github.com/vmware/govmomi/vapi/tags"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// Options holds the settings shared by every collection function.
+type Options struct {
+	// VCenter is the name recorded in each record's VCenter field.
+	VCenter string
+	// Anonymizer replaces names; share one across vCenters so labels stay
+	// unique. Nil disables anonymization.
+	Anonymizer *Anonymizer
+	// Debug, if non-nil, receives raw vSAN JSON per host.
+	Debug io.Writer
+	// Concurrency is the maximum number of per-host calls in flight.
+	Concurrency int
+	// Clusters, if non-empty, restricts collection to hosts whose cluster
+	// name matches one of these path.Match patterns, e.g. "Prod-*".
+	Clusters []string
+	// Datacenter, if set, limits collection to the named datacenter instead
+	// of the whole inventory.
+	Datacenter string
+	// Tags, if non-empty, restricts collection to hosts carrying at least
+	// one of these "Category:Value" tags. Tags are resolved through Tagging,
+	// which must then be a logged-in vAPI client (see ConnectREST).
+	Tags    []string
+	Tagging *rest.Client
+	// SkipDisconnected and SkipMaintenance drop hosts that are not connected
+	// or are in maintenance mode.
+	SkipDisconnected bool
+	SkipMaintenance  bool
+	// Progress, if non-nil, is called as per-host queries complete with the
+	// number of hosts done so far and the total, starting at 0. Calls are
+	// serialized.
+	Progress func(done, total int)
+	// OnFailure, if non-nil, is called for each host whose record is
+	// incomplete, after the failure is logged. Calls are serialized.
+	OnFailure func(Failure)
+	// OnHost, if non-nil, is called by CollectHosts with each host's
+	// record as soon as it and the hosts before it are complete, in
+	// inventory order. Calls are serialized.
+	OnHost func(Host)
+	// Utilization, if positive, is the window ending now over which
+	// CollectHosts reads each host's CPU and memory use from the
+	// performance manager.
+	Utilization time.Duration
+	// ImageProfile makes CollectHosts query each host's installed image
+	// profile, one call per host.
+	ImageProfile bool
+	// CertificateWarning is the window before its expiry within which
+	// CollectHosts reports a host certificate as expiring.
+	CertificateWarning time.Duration
+	// Services, if non-empty, restricts CollectHostServices to the services
+	// with these keys, e.g. "ntpd".
+	Services []string
+}
+
+// Failure describes data that could not be collected: part of one host's
+// record, or, when Host is empty, data of the vCenter as a whole, such as
+// its storage providers, or the whole vCenter when Op is the command. Names
+// are anonymized like the records.
+type Failure struct {
+	VCenter string
+	Host    string
+	Op      string // what failed, e.g. "vsan"
+	Err     error
+}
+
+// VCenterInfo identifies a vCenter that was collected from. Name is
+// anonymized like the records.
+type VCenterInfo struct {
+	Name       string
+	Product    string // e.g. VMware vCenter Server 8.0.2 build-22617221
+	Version    string
+	Build      string
+	APIVersion string
+}
+
+// About returns what c reports about the vCenter named by opts.VCenter.
+func About(c *vim25.Client, opts Options) VCenterInfo {
+	about := c.ServiceContent.About
+	return VCenterInfo{
+		Name:       opts.anonymizer().vcenter(opts.VCenter),
+		Product:    about.FullName,
+		Version:    about.Version,
+		Build:      about.Build,
+		APIVersion: about.ApiVersion,
+	}
+}
+
+// apiAtLeast reports whether the API version c reports, such as "8.0.2.0",
+// is version or a later one.
+func apiAtLeast(c *vim25.Client, version string) bool {
+	have := strings.Split(c.ServiceContent.About.ApiVersion, ".")
+	for i, part := range strings.Split(version, ".") {
+		want, _ := strconv.Atoi(part)
+		got := 0
+		if i < len(have) {
+			got, _ = strconv.Atoi(have[i])
+		}
+		if got != want {
+			return got > want
+		}
+	}
+	return true
+}
+
+func (o Options) anonymizer() *Anonymizer {
+	if o.Anonymizer == nil {
+		return NewAnonymizer(false)
+	}
+	return o.Anonymizer
+}
+
+// fail logs that op failed for host and reports it through OnFailure.
+func (o Options) fail(host, op string, err error) {
+	slog.Warn("Host data incomplete", "vcenter", o.VCenter, "host", host, "op", op, "err", err)
+	if o.OnFailure != nil {
+		anon := o.anonymizer()
+		o.OnFailure(Failure{VCenter: anon.vcenter(o.VCenter), Host: anon.host(host), Op: op, Err: anon.Error(err)})
+	}
+}
+
+// tracker reports progress through the Progress option and returns a func
+// to call, from any goroutine, as each of total items completes.
+func (o Options) tracker(total int) func() {
+	if o.Progress == nil {
+		return func() {}
+	}
+	var mu sync.Mutex
+	done := 0
+	o.Progress(0, total)
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		done++
+		o.Progress(done, total)
+	}
+}
+
+// matchCluster reports whether a cluster name passes the Clusters filter.
+func (o Options) matchCluster(name string) bool {
+	if len(o.Clusters) == 0 {
+		return true
+	}
+	for _, p := range o.Clusters {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// filtered reports whether any host filter is set.
+func (o Options) filtered() bool {
+	return len(o.Clusters) > 0 || len(o.Tags) > 0 || o.SkipDisconnected || o.SkipMaintenance
+}
+
+// taggedHosts returns the hosts carrying any of the Tags option's tags,
+// keyed by MoRef value, or nil when no tags are given.
+func taggedHosts(ctx context.Context, opts Options) (map[string]bool, error) {
+	if len(opts.Tags) == 0 {
+		return nil, nil
+	}
+	if opts.Tagging == nil {
+		return nil, fmt.Errorf("tag filter requires a vAPI session")
+	}
+	m := tags.NewManager(opts.Tagging)
+	tagged := make(map[string]bool)
+	for _, t := range opts.Tags {
+		category, name, ok := strings.Cut(t, ":")
+		if !ok {
+			return nil, fmt.Errorf("tag %q is not in Category:Value form", t)
+		}
+		tag, err := m.GetTagForCategory(ctx, name, category)
+		if err != nil {
+			return nil, fmt.Errorf("finding tag %q: %w", t, err)
+		}
+		refs, err := m.ListAttachedObjects(ctx, tag.ID)
+		if err != nil {
+			return nil, fmt.Errorf("listing objects tagged %q: %w", t, err)
+		}
+		for _, ref := range refs {
+			if r := ref.Reference(); r.Type == "HostSystem" {
+				tagged[r.Value] = true
+			}
+		}
+	}
+	return tagged, nil
+}
+
+// filterHosts drops hosts whose cluster does not pass the Clusters filter,
+// that are not in tagged (when tagged is non-nil), or whose runtime state is
+// excluded by SkipDisconnected or SkipMaintenance. Standalone hosts are
+// matched on their compute resource name, which is the host name. Hosts must
+// have been retrieved with summary.runtime.
+func filterHosts(hosts []mo.HostSystem, parentNames map[string]string, tagged map[string]bool, opts Options) []mo.HostSystem {
+	if !opts.filtered() {
+		return hosts
+	}
+	var kept []mo.HostSystem
+	for _, h := range hosts {
+		if h.Parent == nil || !opts.matchCluster(parentNames[h.Parent.Value]) {
+			continue
+		}
+		if tagged != nil && !tagged[h.Self.Value] {
+			continue
+		}
+		if rt := h.Summary.Runtime; rt != nil {
+			if opts.SkipDisconnected && rt.ConnectionState != types.HostSystemConnectionStateConnected {
+				continue
+			}
+			if opts.SkipMaintenance && rt.InMaintenanceMode {
+				continue
+			}
+		}
+		kept = append(kept, h)
+	}
+	return kept
+}
+
+// selectedHost is a host that passes the host filters, with its name and
+// cluster labeled as in the records.
+type selectedHost struct {
+	mo.HostSystem
+	Host    string
+	Cluster string // empty if the cluster name could not be retrieved
+}
+
+// selectHosts retrieves props of the hosts visible to c, besides the name,
+// summary.runtime, and parent every host report needs, and returns those
+// that pass the host filters in inventory order. Names and clusters are
+// labeled in that order, as the hosts report labels them.
+func selectHosts(ctx context.Context, c *vim25.Client, opts Options, props ...string) ([]selectedHost, error) {
+	root, err := containerRoot(ctx, c, opts)
+	if err != nil {
+		return nil, err
+	}
+	props = append([]string{"name", "summary.runtime", "parent"}, props...)
+	hosts, parentNames, err := filteredHosts(ctx, c, root, opts, props)
+	if err != nil {
+		return nil, err
+	}
+
+	anon := opts.anonymizer()
+	selected := make([]selectedHost, len(hosts))
+	for i, h := range hosts {
+		selected[i] = selectedHost{HostSystem: h, Host: anon.host(h.Name)}
+		if h.Parent != nil && parentNames[h.Parent.Value] != "" {
+			selected[i].Cluster = anon.cluster(opts.VCenter, parentNames[h.Parent.Value])
+		}
+	}
+	return selected, nil
+}
+
+// filteredHosts retrieves props of the hosts under root, which must include
+// summary.runtime and parent, and returns those that pass the host filters
+// and the names of their parents by MoRef value.
+func filteredHosts(ctx context.Context, c *vim25.Client, root types.ManagedObjectReference, opts Options, props []string) ([]mo.HostSystem, map[string]string, error) {
+	v, err := view.NewManager(c).CreateContainerView(ctx, root, []string{"HostSystem"}, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating host container view: %w", err)
+	}
+	defer destroyView(ctx, v)
+
+	var hosts []mo.HostSystem
+	if err := v.Retrieve(ctx, []string{"HostSystem"}, props, &hosts); err != nil {
+		return nil, nil, fmt.Errorf("retrieving hosts: %w", err)
+	}
+	parentNames := retrieveParentNames(ctx, property.DefaultCollector(c), hosts, opts)
+	tagged, err := taggedHosts(ctx, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return filterHosts(hosts, parentNames, tagged, opts), parentNames, nil
+}
+
+// cleanupTimeout bounds calls that release vCenter state once a collection
+// has finished or been cancelled.
+const cleanupTimeout = 30 * time.Second
+
+// Cleanup returns a context for releasing vCenter state, such as logging out
+// or destroying container views, that remains usable after ctx is cancelled
+// (e.g. by Ctrl-C) but gives up after cleanupTimeout.
+func Cleanup(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), cleanupTimeout)
+}
+
+// destroyView destroys v even if ctx has been cancelled, so interrupted runs
+// do not leave views behind in the vCenter session.
+func destroyView(ctx context.Context, v *view.ContainerView) {
+	ctx, cancel := Cleanup(ctx)
+	defer cancel()
+	_ = v.Destroy(ctx)
+}
+
+// containerRoot returns the object container views are created on: the
+// Datacenter option's datacenter, or the root folder when it is empty.
+func containerRoot(ctx context.Context, c *vim25.Client, opts Options) (types.ManagedObjectReference, error) {
+	if opts.Datacenter == "" {
+		return c.ServiceContent.RootFolder, nil
+	}
+	dc, err := find.NewFinder(c).Datacenter(ctx, opts.Datacenter)
+	if err != nil {
+		return types.ManagedObjectReference{}, fmt.Errorf("finding datacenter: %w", err)
+	}
+	return dc.Reference(), nil
+}
@@ -0,0 +1,2440 @@
+package collector_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vmware/govmomi"
+	_ "github.com/vmware/govmomi/lookup/simulator"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/pbm"
+	pbmmethods "github.com/vmware/govmomi/pbm/methods"
+	pbmsim "github.com/vmware/govmomi/pbm/simulator"
+	pbmtypes "github.com/vmware/govmomi/pbm/types"
+	"github.com/vmware/govmomi/simulator"
+	smsmethods "github.com/vmware/govmomi/sms/methods"
+	smstypes "github.com/vmware/govmomi/sms/types"
+	_ "github.com/vmware/govmomi/sts/simulator"
+	_ "github.com/vmware/govmomi/vapi/simulator"
+	"github.com/vmware/govmomi/vapi/tags"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"vmware-inventory/pkg/collector"
+	"vmware-inventory/pkg/export"
+)
+
+const tib = 1024 * 1024 * 1024 * 1024
+
+// HostVsanSystem is a simulated vSAN system. vcsim does not model vSAN, so
+// tests register one per host. The type name must match the vSphere type.
+type HostVsanSystem struct {
+	mo.HostVsanSystem
+	disks []types.VsanHostDiskResult
+	fault types.BaseMethodFault // returned by QueryDisksForVsan if set
+}
+
+func (s *HostVsanSystem) QueryDisksForVsan(*simulator.Context, *types.QueryDisksForVsan) soap.HasFault {
+	if s.fault != nil {
+		return &methods.QueryDisksForVsanBody{Fault_: simulator.Fault("", s.fault)}
+	}
+	return &methods.QueryDisksForVsanBody{Res: &types.QueryDisksForVsanResponse{Returnval: s.disks}}
+}
+
+func disk(tebibytes int64) types.HostScsiDisk {
+	return types.HostScsiDisk{Capacity: types.HostDiskDimensionsLba{BlockSize: 512, Block: tebibytes * tib / 512}}
+}
+
+// HostImageConfigManager is a simulated image configuration manager,
+// which vcsim does not model, returning profile and packages, or fault.
+type HostImageConfigManager struct {
+	mo.HostImageConfigManager
+	profile  string
+	packages []types.SoftwarePackage
+	fault    types.BaseMethodFault
+}
+
+func (m *HostImageConfigManager) HostImageConfigGetProfile(*simulator.Context, *types.HostImageConfigGetProfile) soap.HasFault {
+	if m.fault != nil {
+		return &methods.HostImageConfigGetProfileBody{Fault_: simulator.Fault("", m.fault)}
+	}
+	return &methods.HostImageConfigGetProfileBody{Res: &types.HostImageConfigGetProfileResponse{
+		Returnval: types.HostImageProfileSummary{Name: m.profile, Vendor: "VMware, Inc."},
+	}}
+}
+
+func (m *HostImageConfigManager) FetchSoftwarePackages(*simulator.Context, *types.FetchSoftwarePackages) soap.HasFault {
+	if m.fault != nil {
+		return &methods.FetchSoftwarePackagesBody{Fault_: simulator.Fault("", m.fault)}
+	}
+	return &fetchSoftwarePackagesBody{Res: &fetchSoftwarePackagesResponse{Returnval: m.packages}}
+}
+
+// fetchSoftwarePackagesResponse is types.FetchSoftwarePackagesResponse under
+// the element name vSphere uses: vcsim names a response element after its
+// Go type, which would capitalize the lowercase method's name.
+type fetchSoftwarePackagesResponse types.FetchSoftwarePackagesResponse
+
+type fetchSoftwarePackagesBody struct {
+	Res    *fetchSoftwarePackagesResponse
+	Fault_ *soap.Fault
+}
+
+func (b *fetchSoftwarePackagesBody) Fault() *soap.Fault { return b.Fault_ }
+
+// IscsiManager is a simulated iSCSI manager, which vcsim does not model,
+// returning the ports bound to each adapter by name, or fault.
+type IscsiManager struct {
+	mo.IscsiManager
+	bound map[string][]string
+	fault types.BaseMethodFault
+}
+
+func (m *IscsiManager) QueryBoundVnics(_ *simulator.Context, req *types.QueryBoundVnics) soap.HasFault {
+	if m.fault != nil {
+		return &methods.QueryBoundVnicsBody{Fault_: simulator.Fault("", m.fault)}
+	}
+	var ports []types.IscsiPortInfo
+	for _, vnic := range m.bound[req.IScsiHbaName] {
+		ports = append(ports, types.IscsiPortInfo{VnicDevice: vnic})
+	}
+	return &methods.QueryBoundVnicsBody{Res: &types.QueryBoundVnicsResponse{Returnval: ports}}
+}
+
+// PerformanceManager is the simulator's performance manager, with its
+// queries failing with fault.
+type PerformanceManager struct {
+	*simulator.PerformanceManager
+	fault types.BaseMethodFault
+}
+
+func (m *PerformanceManager) QueryPerf(*simulator.Context, *types.QueryPerf) soap.HasFault {
+	return &methods.QueryPerfBody{Fault_: simulator.Fault("", m.fault)}
+}
+
+// PbmProfileManager is the storage policy manager of the pbm simulator,
+// which also returns associated as the entities assigned its policies.
+type PbmProfileManager struct {
+	*pbmsim.ProfileManager
+	associated []pbmtypes.PbmQueryProfileResult
+}
+
+func (m *PbmProfileManager) PbmQueryAssociatedEntities(*pbmtypes.PbmQueryAssociatedEntities) soap.HasFault {
+	return &pbmmethods.PbmQueryAssociatedEntitiesBody{Res: &pbmtypes.PbmQueryAssociatedEntitiesResponse{Returnval: m.associated}}
+}
+
+// SmsServiceInstance is a simulated storage monitoring service, which vcsim
+// does not model, whose storage manager has providers registered, or fails
+// with fault.
+type SmsServiceInstance struct {
+	types.ManagedObjectReference
+	providers []smstypes.BaseSmsProviderInfo
+	fault     types.BaseMethodFault
+}
+
+func (s *SmsServiceInstance) QueryStorageManager(*smstypes.QueryStorageManager) soap.HasFault {
+	ref := types.ManagedObjectReference{Type: "SmsStorageManager", Value: "StorageManager"}
+	return &smsmethods.QueryStorageManagerBody{Res: &smstypes.QueryStorageManagerResponse{Returnval: ref}}
+}
+
+// SmsStorageManager is the storage manager of SmsServiceInstance.
+type SmsStorageManager struct {
+	types.ManagedObjectReference
+	service *SmsServiceInstance
+}
+
+func (m *SmsStorageManager) QueryProvider(*smstypes.QueryProvider) soap.HasFault {
+	if m.service.fault != nil {
+		return &smsmethods.QueryProviderBody{Fault_: simulator.Fault("", m.service.fault)}
+	}
+	var refs []types.ManagedObjectReference
+	for i := range m.service.providers {
+		refs = append(refs, types.ManagedObjectReference{Type: "SmsProvider", Value: "provider-" + strconv.Itoa(i)})
+	}
+	return &smsmethods.QueryProviderBody{Res: &smstypes.QueryProviderResponse{Returnval: refs}}
+}
+
+// SmsProvider is a storage provider registered with SmsServiceInstance.
+type SmsProvider struct {
+	types.ManagedObjectReference
+	service *SmsServiceInstance
+}
+
+func (p *SmsProvider) QueryProviderInfo(*smstypes.QueryProviderInfo) soap.HasFault {
+	i, _ := strconv.Atoi(strings.TrimPrefix(p.Value, "provider-"))
+	return &smsmethods.QueryProviderInfoBody{Res: &smstypes.QueryProviderInfoResponse{Returnval: p.service.providers[i]}}
+}
+
+// newServer starts a simulator with one standalone host and a three-host
+// cluster, and gives the cluster hosts vSAN: H0 is OSA with one disk group
+// (two 1 TiB capacity disks), H1 is ESA with three 2 TiB disks (one not
+// claimed), and H2 has vSAN enabled without any disks.
+func newServer(t *testing.T) *simulator.Server {
+	t.Helper()
+
+	model := simulator.VPX()
+	// vcsim reports API 6.5 but simulates every property
+	model.ServiceContent.About.ApiVersion = "8.0.3.0"
+	if err := model.Create(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(model.Remove)
+
+	esa := true
+	systems := map[string]*HostVsanSystem{
+		"DC0_C0_H0": {HostVsanSystem: mo.HostVsanSystem{Config: types.VsanHostConfigInfo{
+			StorageInfo: &types.VsanHostConfigInfoStorageInfo{DiskMapping: []types.VsanHostDiskMapping{
+				{Ssd: disk(1), NonSsd: []types.HostScsiDisk{disk(1), disk(1)}},
+			}},
+		}}},
+		"DC0_C0_H1": {
+			HostVsanSystem: mo.HostVsanSystem{Config: types.VsanHostConfigInfo{VsanEsaEnabled: &esa}},
+			disks: []types.VsanHostDiskResult{
+				{Disk: withVsanInfo(disk(2))},
+				{Disk: withVsanInfo(disk(2))},
+				{Disk: disk(2)},
+			},
+		},
+		"DC0_C0_H2": {},
+	}
+	for _, e := range simulator.Map.All("HostSystem") {
+		h := e.(*simulator.HostSystem)
+		if s, ok := systems[h.Name]; ok {
+			ref := simulator.Map.Put(s).Reference()
+			h.ConfigManager.VsanSystem = &ref
+		} else {
+			// vcsim's default reference has no object behind it
+			h.ConfigManager.VsanSystem = nil
+		}
+		// nor has its image configuration manager's
+		h.ConfigManager.ImageConfigManager = nil
+	}
+
+	model.Service.TLS = new(tls.Config)
+	model.Service.RegisterEndpoints = true
+	s := model.Service.NewServer()
+	t.Cleanup(s.Close)
+	return s
+}
+
+// newClient starts a simulator as newServer does and logs in to it.
+func newClient(t *testing.T) *govmomi.Client {
+	t.Helper()
+	s := newServer(t)
+	password, _ := s.URL.User.Password()
+	c, _, err := collector.Connect(context.Background(), s.URL.Host, s.URL.User.Username(), password, collector.ConnectOptions{Insecure: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Logout(context.Background()) })
+	return c
+}
+
+func withVsanInfo(d types.HostScsiDisk) types.HostScsiDisk {
+	d.VsanDiskInfo = &types.VsanHostVsanDiskInfo{}
+	return d
+}
+
+func hostsByName(hosts []collector.Host) map[string]collector.Host {
+	m := make(map[string]collector.Host)
+	for _, h := range hosts {
+		m[h.Hostname] = h
+	}
+	return m
+}
+
+func TestCollectHosts(t *testing.T) {
+	c := newClient(t)
+	hosts, err := collector.CollectHosts(context.Background(), c.Client, collector.Options{VCenter: "vc1", Concurrency: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hosts) != 4 {
+		t.Fatalf("got %d hosts, want 4", len(hosts))
+	}
+
+	byName := hostsByName(hosts)
+	tests := []struct {
+		host     string
+		cluster  string
+		vsanType string
+		capacity int
+		cache    int
+		tib      float64
+	}{
+		{"DC0_H0", "DC0_H0", "", 0, 0, 0},
+		{"DC0_C0_H0", "DC0_C0", "OSA", 2, 1, 2},
+		{"DC0_C0_H1", "DC0_C0", "ESA", 2, 0, 4},
+		{"DC0_C0_H2", "DC0_C0", "", 0, 0, 0},
+	}
+	for _, tt := range tests {
+		h, ok := byName[tt.host]
+		if !ok {
+			t.Errorf("%s: missing", tt.host)
+			continue
+		}
+		if h.VCenter != "vc1" || h.Cluster != tt.cluster {
+			t.Errorf("%s: vCenter/cluster = %q/%q, want vc1/%q", tt.host, h.VCenter, h.Cluster, tt.cluster)
+		}
+		if h.VsanType != tt.vsanType || h.VsanCapacityDisks != tt.capacity || h.VsanCacheDisks != tt.cache || h.VsanCapacityTiB != tt.tib {
+			t.Errorf("%s: vSAN = %q %d/%d %.1f TiB, want %q %d/%d %.1f TiB", tt.host,
+				h.VsanType, h.VsanCapacityDisks, h.VsanCacheDisks, h.VsanCapacityTiB,
+				tt.vsanType, tt.capacity, tt.cache, tt.tib)
+		}
+		if h.Sockets == 0 || h.TotalCores == 0 || h.MemoryGB == 0 || h.ESXiVersion == "" || h.ConnectionState != "connected" {
+			t.Errorf("%s: hardware fields not populated: %+v", tt.host, h)
+		}
+	}
+}
+
+func TestCollectHostsProgress(t *testing.T) {
+	c := newClient(t)
+	var calls []int
+	opts := collector.Options{
+		VCenter:     "vc1",
+		Concurrency: 2,
+		Progress: func(done, total int) {
+			if total != 4 {
+				t.Errorf("progress total = %d, want 4", total)
+			}
+			calls = append(calls, done)
+		},
+	}
+	if _, err := collector.CollectHosts(context.Background(), c.Client, opts); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{0, 1, 2, 3, 4}; !slices.Equal(calls, want) {
+		t.Errorf("progress calls = %v, want %v", calls, want)
+	}
+}
+
+func TestCollectHostsOnHost(t *testing.T) {
+	c := newClient(t)
+	var streamed []collector.Host
+	opts := collector.Options{
+		VCenter:     "vc1",
+		Concurrency: 4,
+		OnHost:      func(h collector.Host) { streamed = append(streamed, h) },
+	}
+	hosts, err := collector.CollectHosts(context.Background(), c.Client, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Streamed in inventory order, identical to the returned records
+	if !slices.Equal(streamed, hosts) {
+		t.Errorf("streamed %v, returned %v", streamed, hosts)
+	}
+}
+
+func TestCollectHostsCancel(t *testing.T) {
+	c := newClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	opts := collector.Options{
+		VCenter: "vc1",
+		Progress: func(done, total int) {
+			if done == 1 {
+				cancel()
+			}
+		},
+	}
+	destroyed := &destroyRecorder{RoundTripper: c.Client.RoundTripper}
+	c.Client.RoundTripper = destroyed
+	if _, err := collector.CollectHosts(ctx, c.Client, opts); !errors.Is(err, context.Canceled) {
+		t.Fatalf("CollectHosts error = %v, want context.Canceled", err)
+	}
+	if !destroyed.ok {
+		t.Error("container view not destroyed after cancellation")
+	}
+}
+
+// destroyRecorder records whether a DestroyView call succeeded.
+type destroyRecorder struct {
+	soap.RoundTripper
+	ok bool
+}
+
+func (r *destroyRecorder) RoundTrip(ctx context.Context, req, res soap.HasFault) error {
+	err := r.RoundTripper.RoundTrip(ctx, req, res)
+	if _, ok := req.(*methods.DestroyViewBody); ok && err == nil {
+		r.ok = true
+	}
+	return err
+}
+
+func TestCollectHostsFailures(t *testing.T) {
+	c := newClient(t)
+	for _, e := range simulator.Map.All("HostSystem") {
+		if h := e.(*simulator.HostSystem); h.Name == "DC0_C0_H1" {
+			simulator.Map.Get(*h.ConfigManager.VsanSystem).(*HostVsanSystem).fault = new(types.NotSupported)
+		}
+	}
+
+	var failures []collector.Failure
+	opts := collector.Options{
+		VCenter:    "vc1",
+		Anonymizer: collector.NewAnonymizer(true),
+		OnFailure:  func(f collector.Failure) { failures = append(failures, f) },
+	}
+	hosts, err := collector.CollectHosts(context.Background(), c.Client, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hosts) != 4 {
+		t.Errorf("got %d hosts, want all 4 despite the failure", len(hosts))
+	}
+	if len(failures) != 1 {
+		t.Fatalf("got %d failures, want 1: %v", len(failures), failures)
+	}
+	f := failures[0]
+	if f.VCenter != "vCenter 1" || !strings.HasPrefix(f.Host, "Host ") || f.Op != "vsan" || f.Err == nil {
+		t.Errorf("failure = %+v, want anonymized vsan failure", f)
+	}
+}
+
+func TestCollectHostsImageProfile(t *testing.T) {
+	c := newClient(t)
+	managers := map[string]*HostImageConfigManager{
+		"DC0_C0_H0": {profile: "ESXi-8.0U2b-23305546-standard"},
+		"DC0_C0_H1": {fault: new(types.NotSupported)},
+	}
+	for _, e := range simulator.Map.All("HostSystem") {
+		h := e.(*simulator.HostSystem)
+		if m, ok := managers[h.Name]; ok {
+			ref := simulator.Map.Put(m).Reference()
+			h.ConfigManager.ImageConfigManager = &ref
+		}
+	}
+
+	var failures []collector.Failure
+	opts := collector.Options{ImageProfile: true, OnFailure: func(f collector.Failure) { failures = append(failures, f) }}
+	hosts, err := collector.CollectHosts(context.Background(), c.Client, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := hostsByName(hosts)
+	if h := byName["DC0_C0_H0"]; h.ImageProfile != "ESXi-8.0U2b-23305546-standard" || h.ESXiBuild == "" {
+		t.Errorf("image profile and build = %q, %q", h.ImageProfile, h.ESXiBuild)
+	}
+	// The host whose query fails is still reported, without a profile
+	if h := byName["DC0_C0_H1"]; h.Hostname == "" || h.ImageProfile != "" {
+		t.Errorf("host with failed query = %+v", h)
+	}
+	var ops []string
+	for _, f := range failures {
+		ops = append(ops, f.Host+" "+f.Op)
+	}
+	if !slices.Equal(ops, []string{"DC0_C0_H1 imageProfile"}) {
+		t.Errorf("failures %v, want DC0_C0_H1 imageProfile", ops)
+	}
+
+	// Without the option no host is queried
+	failures = nil
+	opts.ImageProfile = false
+	if hosts, err = collector.CollectHosts(context.Background(), c.Client, opts); err != nil {
+		t.Fatal(err)
+	}
+	if h := hostsByName(hosts)["DC0_C0_H0"]; h.ImageProfile != "" || len(failures) > 0 {
+		t.Errorf("without ImageProfile: profile %q, failures %v", h.ImageProfile, failures)
+	}
+}
+
+func TestCollectHostsTPM(t *testing.T) {
+	c := newClient(t)
+	for _, e := range simulator.Map.All("HostSystem") {
+		switch h := e.(*simulator.HostSystem); h.Name {
+		case "DC0_C0_H0":
+			h.Capability.TpmSupported, h.Capability.TpmVersion = types.NewBool(true), "2.0"
+			h.Capability.UefiSecureBoot = types.NewBool(true)
+			h.Summary.TpmAttestation = &types.HostTpmAttestationInfo{Status: types.HostTpmAttestationInfoAcceptanceStatusAccepted}
+		case "DC0_C0_H1":
+			h.Capability.TpmSupported = types.NewBool(false)
+			h.Capability.UefiSecureBoot = types.NewBool(false)
+		}
+	}
+
+	hosts, err := collector.CollectHosts(context.Background(), c.Client, collector.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := hostsByName(hosts)
+	if h := byName["DC0_C0_H0"]; h.TPMVersion != "2.0" || h.TPMAttestation != "accepted" || h.SecureBoot == nil || !*h.SecureBoot {
+		t.Errorf("host with TPM: %q, %q, %v", h.TPMVersion, h.TPMAttestation, h.SecureBoot)
+	}
+	if h := byName["DC0_C0_H1"]; h.TPMVersion != "none" || h.TPMAttestation != "" || h.SecureBoot == nil || *h.SecureBoot {
+		t.Errorf("host without TPM: %q, %q, %v", h.TPMVersion, h.TPMAttestation, h.SecureBoot)
+	}
+	// Hosts that report neither, as before ESXi 8.0 Update 3
+	if h := byName["DC0_C0_H2"]; h.TPMVersion != "" || h.SecureBoot != nil {
+		t.Errorf("host without TPM and Secure Boot status: %q, %v", h.TPMVersion, h.SecureBoot)
+	}
+}
+
+func TestCollectHostsSecureBootOlderAPI(t *testing.T) {
+	s := newServer(t)
+	si := simulator.Map.Get(vim25.ServiceInstance).(*simulator.ServiceInstance)
+	si.Content.About.ApiVersion = "8.0.2.0"
+	password, _ := s.URL.User.Password()
+	c, _, err := collector.Connect(context.Background(), s.URL.Host, s.URL.User.Username(), password, collector.ConnectOptions{Insecure: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Logout(context.Background())
+	for _, e := range simulator.Map.All("HostSystem") {
+		h := e.(*simulator.HostSystem)
+		h.Capability.TpmSupported, h.Capability.TpmVersion = types.NewBool(true), "2.0"
+		h.Capability.UefiSecureBoot = types.NewBool(true)
+	}
+
+	// Secure Boot is not asked of a vCenter older than 8.0 Update 3, which
+	// would reject the retrieval
+	hosts, err := collector.CollectHosts(context.Background(), c.Client, collector.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range hosts {
+		if h.TPMVersion != "2.0" || h.SecureBoot != nil {
+			t.Errorf("%s: TPM %q, Secure Boot %v, want 2.0 and nil", h.Hostname, h.TPMVersion, h.SecureBoot)
+		}
+	}
+}
+
+func TestCollectHostsPowerPolicy(t *testing.T) {
+	c := newClient(t)
+	for _, e := range simulator.Map.All("HostSystem") {
+		switch h := e.(*simulator.HostSystem); h.Name {
+		case "DC0_C0_H0":
+			h.Config.PowerSystemInfo = &types.PowerSystemInfo{CurrentPolicy: types.HostPowerPolicy{Key: 1, ShortName: "static"}}
+		case "DC0_C0_H1":
+			h.Config.PowerSystemInfo = &types.PowerSystemInfo{CurrentPolicy: types.HostPowerPolicy{Key: 5, ShortName: "efficient"}}
+		}
+	}
+
+	hosts, err := collector.CollectHosts(context.Background(), c.Client, collector.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The simulator's hosts are Balanced; unknown policies by short name
+	want := map[string]string{"DC0_H0": "Balanced", "DC0_C0_H0": "High Performance", "DC0_C0_H1": "efficient", "DC0_C0_H2": "Balanced"}
+	for _, h := range hosts {
+		if h.PowerPolicy != want[h.Hostname] {
+			t.Errorf("power policy of %s = %q, want %q", h.Hostname, h.PowerPolicy, want[h.Hostname])
+		}
+	}
+
+	for policy, want := range map[string]string{"static": "High Performance", "high-performance": "High Performance", "Balanced": "Balanced", "LOW-POWER": "Low Power", "efficient": ""} {
+		if got := collector.PowerPolicyName(policy); got != want {
+			t.Errorf("PowerPolicyName(%q) = %q, want %q", policy, got, want)
+		}
+	}
+}
+
+func TestCollectHostsCertificate(t *testing.T) {
+	c := newClient(t)
+	now := time.Now()
+	expiry := map[string]time.Time{
+		"DC0_C0_H0": now.Add(-time.Hour),
+		"DC0_C0_H1": now.Add(10 * 24 * time.Hour),
+	}
+	for _, e := range simulator.Map.All("HostSystem") {
+		h := e.(*simulator.HostSystem)
+		if notAfter, ok := expiry[h.Name]; ok {
+			m := simulator.Map.Get(*h.ConfigManager.CertificateManager).(*simulator.HostCertificateManager)
+			m.CertificateInfo.NotAfter = &notAfter
+		}
+	}
+
+	hosts, err := collector.CollectHosts(context.Background(), c.Client, collector.Options{CertificateWarning: 30 * 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The simulator's own certificate is valid for decades
+	want := map[string]string{"DC0_C0_H0": "expired", "DC0_C0_H1": "expiring", "DC0_C0_H2": "valid"}
+	for name, h := range hostsByName(hosts) {
+		if w, ok := want[name]; ok && (h.CertificateStatus != w || h.CertificateExpiry.IsZero()) {
+			t.Errorf("certificate of %s expiring %v is %q, want %q", name, h.CertificateExpiry, h.CertificateStatus, w)
+		}
+	}
+}
+
+func TestCollectHostsCertificateFailure(t *testing.T) {
+	c := newClient(t)
+	// A certificate manager that has gone fails the batch retrieval too
+	for _, e := range simulator.Map.All("HostSystem") {
+		if h := e.(*simulator.HostSystem); h.Name == "DC0_C0_H1" {
+			h.ConfigManager.CertificateManager = &types.ManagedObjectReference{Type: "HostCertificateManager", Value: "missing"}
+		}
+	}
+
+	var failures []collector.Failure
+	opts := collector.Options{OnFailure: func(f collector.Failure) { failures = append(failures, f) }}
+	hosts, err := collector.CollectHosts(context.Background(), c.Client, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The other hosts' certificates are retrieved one at a time
+	want := map[string]string{"DC0_C0_H0": "valid", "DC0_C0_H1": "", "DC0_C0_H2": "valid"}
+	for name, h := range hostsByName(hosts) {
+		if w, ok := want[name]; ok && h.CertificateStatus != w {
+			t.Errorf("certificate of %s is %q, want %q", name, h.CertificateStatus, w)
+		}
+	}
+	var ops []string
+	for _, f := range failures {
+		ops = append(ops, f.Host+" "+f.Op)
+	}
+	if !slices.Equal(ops, []string{"DC0_C0_H1 certificate"}) {
+		t.Errorf("failures %v, want DC0_C0_H1 certificate", ops)
+	}
+}
+
+func TestCollectVIBs(t *testing.T) {
+	c := newClient(t)
+	released := time.Date(2024, 2, 29, 23, 0, 0, 0, time.UTC)
+	managers := map[string]*HostImageConfigManager{
+		"DC0_C0_H0": {packages: []types.SoftwarePackage{
+			{Name: "nmlx5-core", Version: "4.23.0.66-1OEM.800.1.0.20143090", Vendor: "MEL", AcceptanceLevel: "vmware_certified", CreationDate: &released},
+			{Name: "esx-base", Version: "8.0.2-0.25.23305546", Vendor: "VMware", AcceptanceLevel: "vmware_certified", Summary: "ESXi base"},
+		}},
+		"DC0_C0_H1": {fault: new(types.NotSupported)},
+	}
+	for _, e := range simulator.Map.All("HostSystem") {
+		h := e.(*simulator.HostSystem)
+		if m, ok := managers[h.Name]; ok {
+			ref := simulator.Map.Put(m).Reference()
+			h.ConfigManager.ImageConfigManager = &ref
+		}
+	}
+
+	var failures []collector.Failure
+	opts := collector.Options{VCenter: "vc1", OnFailure: func(f collector.Failure) { failures = append(failures, f) }}
+	vibs, err := collector.CollectVIBs(context.Background(), c.Client, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Sorted by name; hosts without a manager have none
+	want := []collector.VIB{
+		{VCenter: "vc1", Host: "DC0_C0_H0", Cluster: "DC0_C0", Name: "esx-base", Version: "8.0.2-0.25.23305546", Vendor: "VMware", AcceptanceLevel: "vmware_certified", Summary: "ESXi base"},
+		{VCenter: "vc1", Host: "DC0_C0_H0", Cluster: "DC0_C0", Name: "nmlx5-core", Version: "4.23.0.66-1OEM.800.1.0.20143090", Vendor: "MEL", AcceptanceLevel: "vmware_certified", CreationDate: "2024-02-29"},
+	}
+	if !slices.Equal(vibs, want) {
+		t.Errorf("VIBs = %+v, want %+v", vibs, want)
+	}
+	if len(failures) != 1 || failures[0].Host != "DC0_C0_H1" || failures[0].Op != "vibs" {
+		t.Errorf("failures = %v, want DC0_C0_H1 vibs", failures)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	c := newClient(t)
+	r, err := collector.Check(context.Background(), c.Client, collector.Options{Clusters: []string{"DC0_C0"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// VPX model: one standalone host plus the three cluster hosts, all of
+	// which newServer gives vSAN
+	want := collector.CheckResult{Hosts: 4, Selected: 3, Clusters: 1, VsanHosts: 3, VsanProbe: "DC0_C0_H0"}
+	got := *r
+	got.Product, got.User = "", ""
+	if got != want {
+		t.Errorf("Check = %+v, want %+v", got, want)
+	}
+	if r.Product == "" || r.User == "" {
+		t.Errorf("Check product %q, user %q; want both set", r.Product, r.User)
+	}
+}
+
+func TestAnonymizedLabelsMatchAcrossCommands(t *testing.T) {
+	c := newClient(t)
+	ctx := context.Background()
+
+	hosts, err := collector.CollectHosts(ctx, c.Client, collector.Options{VCenter: "vc1", Anonymizer: collector.NewAnonymizer(true)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vms, err := collector.CollectVMs(ctx, c.Client, collector.Options{VCenter: "vc1", Anonymizer: collector.NewAnonymizer(true)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clusters := make(map[string]string) // anonymized host -> cluster
+	for _, h := range hosts {
+		if h.VCenter != "vCenter 1" {
+			t.Errorf("vCenter = %q, want vCenter 1", h.VCenter)
+		}
+		clusters[h.Hostname] = h.Cluster
+	}
+	if _, ok := clusters["Host 1"]; !ok {
+		t.Errorf("hosts not anonymized: %v", clusters)
+	}
+	for _, vm := range vms {
+		cluster, ok := clusters[vm.Host]
+		if !ok || cluster != vm.Cluster {
+			t.Errorf("VM %s on %q in %q does not match hosts report", vm.Name, vm.Host, vm.Cluster)
+		}
+	}
+}
+
+func TestAnonymizerMapping(t *testing.T) {
+	c := newClient(t)
+	anon := collector.NewAnonymizer(true)
+	hosts, err := collector.CollectHosts(context.Background(), c.Client, collector.Options{VCenter: "vc1", Anonymizer: anon})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mapping := make(map[string]collector.MappingEntry) // label -> entry
+	for _, e := range anon.Mapping() {
+		mapping[e.Label] = e
+	}
+	if e := mapping["vCenter 1"]; e.Name != "vc1" {
+		t.Errorf("vCenter 1 maps to %+v, want vc1", e)
+	}
+	for _, h := range hosts {
+		if e := mapping[h.Hostname]; e.Kind != "Host" || !strings.HasPrefix(e.Name, "DC0_") {
+			t.Errorf("%s maps to %+v, want a real host name", h.Hostname, e)
+		}
+		if e := mapping[h.Cluster]; e.Kind != "Cluster" || e.VCenter != "vc1" || strings.Contains(e.Name, "/") {
+			t.Errorf("%s maps to %+v, want a cluster of vc1", h.Cluster, e)
+		}
+		if e := mapping[h.BIOSUUID]; e.Kind != "UUID" || e.Name == "" {
+			t.Errorf("BIOS UUID %q maps to %+v, want a real UUID", h.BIOSUUID, e)
+		}
+		if e := mapping[h.SerialNumber]; e.Kind != "Serial" || !strings.HasPrefix(e.Name, "VMware-") {
+			t.Errorf("serial %q maps to %+v, want the simulator's service tag", h.SerialNumber, e)
+		}
+	}
+	if got := collector.NewAnonymizer(false).Mapping(); len(got) != 0 {
+		t.Errorf("disabled anonymizer mapping = %v, want empty", got)
+	}
+}
+
+func TestHMACAnonymizer(t *testing.T) {
+	a := collector.NewHMACAnonymizer([]byte("secret"))
+	h1 := a.Name("Host", "esx01.example.com")
+	if !strings.HasPrefix(h1, "Host ") || len(h1) != len("Host ")+12 {
+		t.Errorf("label = %q, want Host and 12 hex digits", h1)
+	}
+	// Labels depend only on the key and name, not the order of first use
+	b := collector.NewHMACAnonymizer([]byte("secret"))
+	b.Name("Host", "esx02.example.com")
+	if got := b.Name("Host", "esx01.example.com"); got != h1 {
+		t.Errorf("label in second run = %q, want %q", got, h1)
+	}
+	if got := collector.NewHMACAnonymizer([]byte("other")).Name("Host", "esx01.example.com"); got == h1 {
+		t.Errorf("label %q does not depend on the key", got)
+	}
+}
+
+func TestRedactIPs(t *testing.T) {
+	a := collector.NewAnonymizer(false)
+	a.SetRedactIPs(true)
+	tests := []struct{ in, want string }{
+		{"esx01.example.com", "esx01.example.com"},
+		{"10.1.2.3", "IP 1"},
+		{"dial tcp 10.1.2.4:443: connection refused", "dial tcp IP 2:443: connection refused"},
+		{"vmk0 fe80::250:56ff:fe01:2 and 10.1.2.3", "vmk0 IP 3 and IP 1"},
+		{"mac 00:50:56:01:02:03 at 10:30:00", "mac 00:50:56:01:02:03 at 10:30:00"},
+	}
+	for _, tt := range tests {
+		if got := a.Text(tt.in); got != tt.want {
+			t.Errorf("Text(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	if got := a.Name("Host", "10.1.2.3"); got != "IP 1" {
+		t.Errorf("host named by IP = %q, want IP 1", got)
+	}
+}
+
+func TestCollectVMs(t *testing.T) {
+	c := newClient(t)
+	vms, err := collector.CollectVMs(context.Background(), c.Client, collector.Options{VCenter: "vc1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// VPX model: 2 VMs on the standalone host and 2 in the cluster
+	if len(vms) != 4 {
+		t.Fatalf("got %d VMs, want 4", len(vms))
+	}
+	for _, vm := range vms {
+		if vm.Host == "" || vm.Cluster == "" || vm.PowerState == "" || vm.VCPUs == 0 {
+			t.Errorf("VM fields not populated: %+v", vm)
+		}
+	}
+}
+
+func TestCollectDatastores(t *testing.T) {
+	c := newClient(t)
+	for _, e := range simulator.Map.All("Datastore") {
+		ds := e.(*simulator.Datastore)
+		ds.Info = &types.VmfsDatastoreInfo{
+			DatastoreInfo: *ds.Info.GetDatastoreInfo(),
+			Vmfs:          &types.HostVmfsVolume{Version: "5.81", MajorVersion: 5},
+		}
+	}
+	datastores, err := collector.CollectDatastores(context.Background(), c.Client, collector.Options{VCenter: "vc1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(datastores) != 1 {
+		t.Fatalf("got %d datastores, want 1", len(datastores))
+	}
+	if ds := datastores[0]; ds.VCenter != "vc1" || ds.Name != "LocalDS_0" || ds.Hosts == 0 || ds.CapacityGB < ds.FreeGB {
+		t.Errorf("unexpected datastore: %+v", ds)
+	}
+	// The simulator mounts it on the standalone host only
+	if ds := datastores[0]; ds.VMFSVersion != "5.81" || !ds.VMFS5() || !slices.Equal(ds.Clusters, []string{"DC0_H0"}) {
+		t.Errorf("datastore VMFS %s (VMFS-5 %t) in clusters %v, want 5.81 in DC0_H0", ds.VMFSVersion, ds.VMFS5(), ds.Clusters)
+	}
+
+	// Mounted on every host, but only the cluster's are selected
+	ds := simulator.Map.Any("Datastore").(*simulator.Datastore)
+	ds.Host = nil
+	for _, h := range simulator.Map.All("HostSystem") {
+		ds.Host = append(ds.Host, types.DatastoreHostMount{Key: h.Reference(), MountInfo: types.HostMountInfo{Accessible: types.NewBool(true)}})
+	}
+	datastores, err = collector.CollectDatastores(context.Background(), c.Client, collector.Options{VCenter: "vc1", Clusters: []string{"DC0_C0"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(datastores) != 1 || datastores[0].Hosts != 3 || !slices.Equal(datastores[0].Clusters, []string{"DC0_C0"}) {
+		t.Errorf("filtered datastores = %+v, want LocalDS_0 on 3 hosts in DC0_C0", datastores)
+	}
+}
+
+func TestCollectLicenses(t *testing.T) {
+	c := newClient(t)
+	licenses, assigned, err := collector.CollectLicenses(context.Background(), c.Client, collector.Options{VCenter: "vc1", Clusters: []string{"DC0_C0"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(licenses) != 1 || licenses[0].Key != "*****-*****-*****-*****-00000" || licenses[0].Edition != "eval" {
+		t.Errorf("unexpected licenses: %+v", licenses)
+	}
+	// The vCenter, the cluster, and its three hosts, but not the
+	// standalone host
+	kinds := make(map[string]int)
+	for _, a := range assigned {
+		kinds[a.EntityType]++
+	}
+	if len(assigned) != 5 || kinds["vCenter"] != 1 || kinds["Cluster"] != 1 || kinds["Host"] != 3 {
+		t.Errorf("unexpected assignments: %+v", assigned)
+	}
+	if got := collector.MaskLicenseKey("AB1CD-23EF4-GH5IJ-6KL7M-N8OP9"); got != "*****-*****-*****-*****-N8OP9" {
+		t.Errorf("MaskLicenseKey = %q", got)
+	}
+}
+
+func TestRollupDatastoreClusters(t *testing.T) {
+	datastores := []collector.Datastore{
+		{VCenter: "vc1", Name: "ds1", VMFSMajor: 5, CapacityGB: 2048, Clusters: []string{"B", "A"}},
+		{VCenter: "vc1", Name: "ds2", VMFSMajor: 6, CapacityGB: 4096, Clusters: []string{"A"}},
+		{VCenter: "vc1", Name: "nfs1", Type: "NFS", CapacityGB: 1024, Clusters: []string{"A"}},
+		{VCenter: "vc1", Name: "ds3", VMFSMajor: 5, CapacityGB: 512},
+	}
+	got := collector.RollupDatastoreClusters(datastores)
+	want := []collector.DatastoreCluster{
+		{VCenter: "vc1", Cluster: "", Datastores: 1, VMFS5: 1, VMFS5CapacityGB: 512},
+		{VCenter: "vc1", Cluster: "A", Datastores: 3, VMFS6: 1, VMFS5: 1, VMFS5CapacityGB: 2048},
+		{VCenter: "vc1", Cluster: "B", Datastores: 1, VMFS5: 1, VMFS5CapacityGB: 2048},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("clusters = %+v, want %+v", got, want)
+	}
+}
+
+func TestRollupClusters(t *testing.T) {
+	hosts := []collector.Host{
+		{VCenter: "vc1", Cluster: "B", Sockets: 2, TotalCores: 32, MemoryGB: 512, VsanCapacityTiB: 1.5, ESXiVersion: "8.0.2"},
+		{VCenter: "vc1", Cluster: "A", Sockets: 2, TotalCores: 16, MemoryGB: 256, ESXiVersion: "7.0.3"},
+		{VCenter: "vc1", Cluster: "B", Sockets: 1, TotalCores: 8, MemoryGB: 128, VsanCapacityTiB: 0.5, ESXiVersion: "7.0.3"},
+		{VCenter: "vc0", Cluster: "B", Sockets: 1, TotalCores: 4, MemoryGB: 64, ESXiVersion: "8.0.2"},
+	}
+	clusters := collector.RollupClusters(hosts)
+	if len(clusters) != 3 {
+		t.Fatalf("got %d clusters, want 3", len(clusters))
+	}
+
+	got := clusters[2]
+	if got.VCenter != "vc1" || got.Cluster != "B" || got.Hosts != 2 || got.Sockets != 3 ||
+		got.TotalCores != 40 || got.MemoryGB != 640 || got.VsanCapacityTiB != 2 {
+		t.Errorf("vc1/B rollup = %+v", got)
+	}
+	if s := got.VersionSpread(); s != "7.0.3 (1), 8.0.2 (1)" {
+		t.Errorf("VersionSpread = %q", s)
+	}
+	if clusters[0].VCenter != "vc0" || clusters[1].Cluster != "A" {
+		t.Errorf("clusters not sorted by vCenter then name: %+v", clusters)
+	}
+}
+
+func TestLicenseCores(t *testing.T) {
+	hosts := []collector.Host{
+		{VCenter: "vc1", Cluster: "A", Sockets: 2, CoresPerSocket: 8, TotalCores: 16, VsanCapacityTiB: 40},
+		{VCenter: "vc1", Cluster: "A", Sockets: 2, CoresPerSocket: 32, TotalCores: 64, VsanCapacityTiB: 50},
+		{VCenter: "vc1", Cluster: "B", Sockets: 1, CoresPerSocket: 16, TotalCores: 16, VsanCapacityTiB: 20.5},
+		{VCenter: "vc1", Cluster: "B", ConnectionState: "disconnected"},
+	}
+	for i, want := range []int{32, 64, 16, 0} {
+		if got := hosts[i].LicenseCores(); got != want {
+			t.Errorf("host %d: LicenseCores = %d, want %d", i, got, want)
+		}
+	}
+	// Under per-CPU terms each CPU needs a license per 32 cores
+	for i, want := range []int{2, 2, 1, 0} {
+		if got := hosts[i].CPULicenses(); got != want {
+			t.Errorf("host %d: CPULicenses = %d, want %d", i, got, want)
+		}
+	}
+	if got := (collector.Host{Sockets: 2, CoresPerSocket: 48}).CPULicenses(); got != 4 {
+		t.Errorf("2 x 48 cores: CPULicenses = %d, want 4", got)
+	}
+
+	// Cluster A's entitlement covers its capacity, B's does not, but
+	// pooled across both it does
+	clusters := collector.LicenseClusters(collector.RollupClusters(hosts), collector.Editions["vcf"])
+	if c := clusters[0]; c.LicenseCores != 96 || c.VsanIncludedTiB != 96 || c.VsanAddOnTiB != 0 {
+		t.Errorf("cluster A = %+v", c)
+	}
+	if c := clusters[1]; c.LicenseCores != 16 || c.VsanIncludedTiB != 16 || c.VsanAddOnTiB != 5 {
+		t.Errorf("cluster B = %+v", c)
+	}
+	total := collector.TotalLicenses(clusters)
+	want := collector.LicenseTotal{Hosts: 4, Clusters: 2, Sockets: 5, TotalCores: 96, LicenseCores: 112, CPULicenses: 5, VsanCapacityTiB: 110.5, VsanIncludedTiB: 112}
+	if total != want {
+		t.Errorf("total = %+v, want %+v", total, want)
+	}
+
+	total = collector.TotalLicenses(collector.LicenseClusters(collector.RollupClusters(hosts), collector.Editions["vvf"]))
+	if total.VsanIncludedTiB != 28 || total.VsanAddOnTiB != 83 {
+		t.Errorf("VVF total = %+v", total)
+	}
+}
+
+func TestCompareEntitlements(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "entitlements.csv")
+	data := "SKU,Quantity,Product\nVCF-CLD-FND-5,100,\nVCF-CLD-FND-5,20,\nVCF-VSAN-ADD-TIB,2,\nVS-ENT-PLUS,4,cpu\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ents, err := collector.ReadEntitlements(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ents) != 4 || ents[0].Product != "vcf" || ents[2].Product != "vsan" || ents[3].Product != "cpu" {
+		t.Fatalf("entitlements = %+v", ents)
+	}
+
+	total := collector.LicenseTotal{LicenseCores: 112, CPULicenses: 6, VsanAddOnTiB: 5}
+	gaps := collector.CompareEntitlements(total, collector.Editions["vcf"], ents)
+	want := []struct {
+		name               string
+		deployed, entitled int
+		shortfall          bool
+	}{
+		{"VCF", 112, 120, false},
+		{"vSAN add-on", 5, 2, true},
+		{"Per-CPU", 6, 4, true},
+	}
+	if len(gaps) != len(want) {
+		t.Fatalf("gaps = %+v", gaps)
+	}
+	for i, w := range want {
+		if g := gaps[i]; g.Name != w.name || g.Deployed != w.deployed || g.Entitled != w.entitled || g.Shortfall() != w.shortfall {
+			t.Errorf("gap %d = %+v, want %+v", i, g, w)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte("SKU,Quantity\nUNKNOWN-SKU,1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := collector.ReadEntitlements(path); err == nil {
+		t.Error("unknown SKU without a Product was accepted")
+	}
+}
+
+func TestReadEntitlements(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []collector.Entitlement
+		err  string
+	}{
+		{
+			name: "header in any case and order",
+			data: "quantity,Sku\n16,VCF-CLD-FND-5\n",
+			want: []collector.Entitlement{{SKU: "VCF-CLD-FND-5", Product: "vcf", Quantity: 16}},
+		},
+		{
+			name: "other columns and spaces",
+			data: "Contract, SKU, Quantity\nC-1, VCF-VSP-FND-8, 32\n",
+			want: []collector.Entitlement{{SKU: "VCF-VSP-FND-8", Product: "vvf", Quantity: 32}},
+		},
+		{
+			name: "product from SKU prefix",
+			data: "SKU,Quantity,Product\nvcf-vsan-add-tib,10,\nVCF-CLD-FND-5,0,\n",
+			want: []collector.Entitlement{
+				{SKU: "vcf-vsan-add-tib", Product: "vsan", Quantity: 10},
+				{SKU: "VCF-CLD-FND-5", Product: "vcf", Quantity: 0},
+			},
+		},
+		{
+			name: "product column over SKU prefix",
+			data: "SKU,Quantity,Product\nVCF-CLD-FND-5,8,VVF\nVS8-EPL-C,4,CPU\n",
+			want: []collector.Entitlement{
+				{SKU: "VCF-CLD-FND-5", Product: "vvf", Quantity: 8},
+				{SKU: "VS8-EPL-C", Product: "cpu", Quantity: 4},
+			},
+		},
+		{name: "no rows", data: "SKU,Quantity\n"},
+		{name: "empty file", data: "", err: "reading header"},
+		{name: "no quantity column", data: "SKU,Count\nVCF-CLD-FND-5,16\n", err: "header needs SKU and Quantity"},
+		{name: "negative quantity", data: "SKU,Quantity\nVCF-CLD-FND-5,-16\n", err: `line 2: invalid quantity "-16"`},
+		{name: "fractional quantity", data: "SKU,Quantity\nVCF-VSAN-ADD-TIB,1.5\n", err: `line 2: invalid quantity "1.5"`},
+		{name: "missing quantity", data: "SKU,Quantity\nVCF-CLD-FND-5,16\nVCF-CLD-FND-5,\n", err: `line 3: invalid quantity ""`},
+		{name: "unknown SKU", data: "SKU,Quantity\nUNKNOWN-SKU,1\n", err: `unknown product of SKU "UNKNOWN-SKU"`},
+		{name: "unknown product", data: "SKU,Quantity,Product\nVCF-CLD-FND-5,16,vxrail\n", err: "unknown product"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "entitlements.csv")
+			if err := os.WriteFile(path, []byte(tt.data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			ents, err := collector.ReadEntitlements(path)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(ents, tt.want) {
+				t.Errorf("entitlements = %+v, want %+v", ents, tt.want)
+			}
+		})
+	}
+}
+
+func TestConsolidation(t *testing.T) {
+	hosts := []collector.Host{
+		{VCenter: "vc1", Hostname: "esx1", Cluster: "A", TotalCores: 16, MemoryGB: 64},
+		{VCenter: "vc1", Hostname: "esx2", Cluster: "A", TotalCores: 16, MemoryGB: 64},
+		{VCenter: "vc2", Hostname: "esx1", Cluster: "B", TotalCores: 8},
+	}
+	vms := []collector.VM{
+		{VCenter: "vc1", Host: "esx1", PowerState: "poweredOn", VCPUs: 8, MemoryGB: 64, MemoryReservationGB: 16},
+		{VCenter: "vc1", Host: "esx1", PowerState: "poweredOn", VCPUs: 16, MemoryGB: 32},
+		{VCenter: "vc1", Host: "esx2", PowerState: "poweredOff", VCPUs: 32, MemoryGB: 256, MemoryReservationGB: 256},
+		{VCenter: "vc2", Host: "esx1", PowerState: "poweredOn", VCPUs: 4},
+		{VCenter: "vc2", Host: "esx9", PowerState: "poweredOn", VCPUs: 4}, // host filtered out
+	}
+	densities, clusters := collector.Consolidation(hosts, vms)
+	if d := densities[0]; d.VMs != 2 || d.VCPUs != 24 || d.VCPURatio() != 1.5 || d.ReservedGB != 16 || d.MemoryRatio() != 1.5 {
+		t.Errorf("vc1/esx1 = %+v", d)
+	}
+	if d := densities[1]; d.VMs != 1 || d.PoweredOnVMs != 0 || d.VCPURatio() != 0 || d.MemoryRatio() != 0 {
+		t.Errorf("vc1/esx2 = %+v", d)
+	}
+	if d := densities[2]; d.VMs != 1 || d.VCPURatio() != 0.5 {
+		t.Errorf("vc2/esx1 = %+v", d)
+	}
+	if c := clusters[0]; c.Cluster.Cluster != "A" || c.VMs != 3 || c.PoweredOnVMs != 2 || c.VCPURatio() != 0.75 || c.VMsPerHost() != 1.5 || c.MemoryRatio() != 0.75 {
+		t.Errorf("cluster A = %+v", c)
+	}
+}
+
+func TestHeadroom(t *testing.T) {
+	hosts := []collector.Host{
+		{VCenter: "vc1", Cluster: "A", CPUCapacityMHz: 50000, CPUUsageMHz: 30000, MemoryGB: 512, MemoryUsageGB: 400},
+		{VCenter: "vc1", Cluster: "A", CPUCapacityMHz: 50000, CPUUsageMHz: 20000, MemoryGB: 512, MemoryUsageGB: 300},
+		{VCenter: "vc1", Cluster: "B", CPUCapacityMHz: 50000, CPUUsageMHz: 45000, MemoryGB: 256, MemoryUsageGB: 100},
+	}
+	vsan := []collector.VsanUsage{{VCenter: "vc1", Cluster: "A", CapacityGB: 1000, FreeGB: 200}}
+	clusters := collector.Headroom(collector.RollupClusters(hosts), vsan, collector.Targets{CPU: 80, Memory: 80, Vsan: 70})
+
+	a := clusters[0]
+	if a.CPUUsedPct() != 50 || a.CPUHeadroomMHz() != 30000 || a.VsanUsedPct() != 80 || a.VsanHeadroomGB() != -100 {
+		t.Errorf("cluster A = %+v", a)
+	}
+	if over := a.Over(); !slices.Equal(over, []string{"vSAN"}) {
+		t.Errorf("cluster A over %v, want vSAN", over)
+	}
+	// B has no vSAN datastore, so only its CPU is over
+	if over := clusters[1].Over(); !slices.Equal(over, []string{"CPU"}) {
+		t.Errorf("cluster B over %v, want CPU", over)
+	}
+}
+
+func TestHostReportOutput(t *testing.T) {
+	c := newClient(t)
+	hosts, err := collector.CollectHosts(context.Background(), c.Client, collector.Options{VCenter: "vc1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rep := &export.Report{CollectedAt: time.Now(), Tables: export.HostTables(hosts)}
+
+	var buf bytes.Buffer
+	if err := export.Write(&buf, "csv", rep); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 5 {
+		t.Fatalf("got %d CSV rows, want header + 4", len(rows))
+	}
+	if rows[0][1] != "Hostname" || rows[0][len(rows[0])-1] != "Power Policy" {
+		t.Errorf("unexpected CSV header: %v", rows[0])
+	}
+	// values returns the first host's values of the named columns
+	values := func(headers ...string) []string {
+		var row []string
+		for _, h := range headers {
+			row = append(row, rows[1][slices.Index(rows[0], h)])
+		}
+		return row
+	}
+	// The simulator's hosts have a serial but only a placeholder asset
+	// tag, and a BIOS without embedded controller firmware
+	if row := values("Serial Number", "Asset Tag", "BIOS Version", "BIOS Release Date", "Firmware Version"); row[0] == "" || row[1] != "" || row[2] != "6.00" || len(row[3]) != len("2006-01-02") || row[4] != "" {
+		t.Errorf("serial, asset tag, and BIOS = %q", row)
+	}
+	// and a Xeon E5-1620, decoded from its CPUID signature
+	if row := values("CPU Family", "CPU Model Number", "CPU Stepping", "CPU Features"); !slices.Equal(row, []string{"6", "45", "7", "SSE4.2, AES-NI, AVX"}) {
+		t.Errorf("CPU family, model, stepping, and features = %q", row)
+	}
+	// which the simulator reports without hyperthreading, in one NUMA
+	// node with 1 GB of memory, and with one NIC
+	if row := values("Hyperthreading", "CPU Threads", "NUMA Nodes", "Cores per NUMA Node", "Memory per NUMA Node GB", "NICs"); !slices.Equal(row, []string{"unavailable", "2", "1", "2", "1", "1"}) {
+		t.Errorf("hyperthreading, threads, NUMA, and NICs = %q", row)
+	}
+	// and booted when the simulator started
+	row := values("Boot Time", "Uptime Days")
+	if boot, err := time.Parse(time.RFC3339, row[0]); err != nil || time.Since(boot) > time.Hour || row[1] != "0" {
+		t.Errorf("boot time and uptime = %q", row)
+	}
+	// with the defaults of a new install
+	if row := values("Lockdown Mode", "SSH Running", "ESXi Shell Running"); !slices.Equal(row, []string{"disabled", "false", "false"}) {
+		t.Errorf("lockdown mode and shell services = %q", row)
+	}
+
+	buf.Reset()
+	if err := export.Write(&buf, "json", rep); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Hosts []struct {
+			Hostname        string  `json:"hostname"`
+			VsanType        string  `json:"vsanType"`
+			VsanCapacityTiB float64 `json:"vsanCapacityTiB"`
+		} `json:"hosts"`
+		Clusters []struct {
+			Cluster string `json:"cluster"`
+			Hosts   int    `json:"hosts"`
+		} `json:"clusters"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Hosts) != 4 || len(doc.Clusters) != 2 {
+		t.Fatalf("JSON has %d hosts and %d clusters, want 4 and 2", len(doc.Hosts), len(doc.Clusters))
+	}
+	for _, h := range doc.Hosts {
+		if h.Hostname == "DC0_C0_H1" && (h.VsanType != "ESA" || h.VsanCapacityTiB != 4) {
+			t.Errorf("ESA host in JSON = %+v", h)
+		}
+	}
+}
+
+func TestClusterFilter(t *testing.T) {
+	c := newClient(t)
+	ctx := context.Background()
+	opts := collector.Options{VCenter: "vc1", Clusters: []string{"DC0_C*"}}
+
+	hosts, err := collector.CollectHosts(ctx, c.Client, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hosts) != 3 {
+		t.Errorf("got %d hosts, want the 3 in DC0_C0", len(hosts))
+	}
+	for _, h := range hosts {
+		if h.Cluster != "DC0_C0" {
+			t.Errorf("host %s in %q passed the filter", h.Hostname, h.Cluster)
+		}
+	}
+
+	vms, err := collector.CollectVMs(ctx, c.Client, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vms) != 2 {
+		t.Errorf("got %d VMs, want the 2 in DC0_C0", len(vms))
+	}
+
+	opts.Clusters = []string{"nomatch"}
+	datastores, err := collector.CollectDatastores(ctx, c.Client, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(datastores) != 0 {
+		t.Errorf("got %d datastores, want none", len(datastores))
+	}
+}
+
+func TestDatacenterFilter(t *testing.T) {
+	c := newClient(t)
+	ctx := context.Background()
+
+	hosts, err := collector.CollectHosts(ctx, c.Client, collector.Options{Datacenter: "DC0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hosts) != 4 {
+		t.Errorf("got %d hosts in DC0, want 4", len(hosts))
+	}
+
+	if _, err := collector.CollectVMs(ctx, c.Client, collector.Options{Datacenter: "missing"}); err == nil {
+		t.Error("expected an error for a missing datacenter")
+	}
+}
+
+func TestTagFilter(t *testing.T) {
+	c := newClient(t)
+	ctx := context.Background()
+
+	rc, err := collector.ConnectREST(ctx, c.Client, "user", "pass", collector.ConnectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := tags.NewManager(rc)
+	categoryID, err := m.CreateCategory(ctx, &tags.Category{Name: "Billing", AssociableTypes: []string{"HostSystem"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tagID, err := m.CreateTag(ctx, &tags.Tag{Name: "CustomerA", CategoryID: categoryID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range simulator.Map.All("HostSystem") {
+		if name := e.Entity().Name; name != "DC0_H0" && name != "DC0_C0_H1" {
+			continue
+		}
+		if err := m.AttachTag(ctx, tagID, object.NewReference(c.Client, e.Reference())); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := collector.Options{Tags: []string{"Billing:CustomerA"}, Tagging: rc}
+	hosts, err := collector.CollectHosts(ctx, c.Client, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := hostsByName(hosts)
+	if len(hosts) != 2 || names["DC0_H0"].Hostname == "" || names["DC0_C0_H1"].Hostname == "" {
+		t.Errorf("got hosts %v, want DC0_H0 and DC0_C0_H1", names)
+	}
+
+	opts.Clusters = []string{"DC0_C0"}
+	hosts, err = collector.CollectHosts(ctx, c.Client, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hosts) != 1 || hosts[0].Hostname != "DC0_C0_H1" {
+		t.Errorf("tag and cluster filters combined gave %v, want DC0_C0_H1", hosts)
+	}
+
+	opts.Tags = []string{"Billing:Missing"}
+	if _, err := collector.CollectHosts(ctx, c.Client, opts); err == nil {
+		t.Error("expected an error for an unknown tag")
+	}
+}
+
+func TestSkipDisconnectedAndMaintenance(t *testing.T) {
+	c := newClient(t)
+	ctx := context.Background()
+	for _, e := range simulator.Map.All("HostSystem") {
+		h := e.(*simulator.HostSystem)
+		switch h.Name {
+		case "DC0_C0_H0":
+			h.Summary.Runtime.ConnectionState = types.HostSystemConnectionStateDisconnected
+		case "DC0_C0_H1":
+			h.Summary.Runtime.InMaintenanceMode = true
+		}
+	}
+
+	hosts, err := collector.CollectHosts(ctx, c.Client, collector.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state := hostsByName(hosts)["DC0_C0_H0"].ConnectionState; state != "disconnected" {
+		t.Errorf("connection state = %q, want disconnected", state)
+	}
+	if h := hostsByName(hosts)["DC0_C0_H1"]; !h.InMaintenanceMode || h.PowerState != "poweredOn" {
+		t.Errorf("maintenance mode and power state = %v, %q, want true, poweredOn", h.InMaintenanceMode, h.PowerState)
+	}
+	if h := hostsByName(hosts)["DC0_C0_H2"]; h.InMaintenanceMode {
+		t.Error("DC0_C0_H2 in maintenance mode")
+	}
+
+	tests := []struct {
+		opts collector.Options
+		want int
+	}{
+		{collector.Options{SkipDisconnected: true}, 3},
+		{collector.Options{SkipMaintenance: true}, 3},
+		{collector.Options{SkipDisconnected: true, SkipMaintenance: true}, 2},
+	}
+	for _, tt := range tests {
+		hosts, err := collector.CollectHosts(ctx, c.Client, tt.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(hosts) != tt.want {
+			t.Errorf("%+v: got %d hosts, want %d", tt.opts, len(hosts), tt.want)
+		}
+	}
+}
+
+func TestConnectThumbprint(t *testing.T) {
+	s := newServer(t)
+	ctx := context.Background()
+	password, _ := s.URL.User.Password()
+	sha256 := soap.ThumbprintSHA256(s.Certificate())
+
+	tests := []struct {
+		thumbprint string
+		ok         bool
+	}{
+		{"", false}, // self-signed certificate is not trusted by default
+		{sha256, true},
+		{strings.ToLower(strings.ReplaceAll(sha256, ":", "")), true},
+		{soap.ThumbprintSHA1(s.Certificate()), true},
+		{strings.Repeat("00:", 31) + "00", false},
+	}
+	for _, tt := range tests {
+		c, _, err := collector.Connect(ctx, s.URL.Host, s.URL.User.Username(), password, collector.ConnectOptions{Thumbprint: tt.thumbprint})
+		if (err == nil) != tt.ok {
+			t.Errorf("thumbprint %q: err = %v, want success %v", tt.thumbprint, err, tt.ok)
+		}
+		if err == nil {
+			c.Logout(ctx)
+		}
+	}
+
+	if _, err := collector.NormalizeThumbprint("AB:CD"); err == nil {
+		t.Error("expected an error for a short thumbprint")
+	}
+}
+
+func TestConnectCACert(t *testing.T) {
+	s := newServer(t)
+	ctx := context.Background()
+	password, _ := s.URL.User.Password()
+
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	pemData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw})
+	if err := os.WriteFile(path, pemData, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	c, _, err := collector.Connect(ctx, s.URL.Host, s.URL.User.Username(), password, collector.ConnectOptions{CACert: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Logout(ctx)
+
+	if _, _, err := collector.Connect(ctx, s.URL.Host, s.URL.User.Username(), password, collector.ConnectOptions{CACert: filepath.Join(t.TempDir(), "missing.pem")}); err == nil {
+		t.Error("expected an error for a missing CA file")
+	}
+}
+
+func TestConnectSessionCache(t *testing.T) {
+	s := newServer(t)
+	ctx := context.Background()
+	password, _ := s.URL.User.Password()
+	co := collector.ConnectOptions{Insecure: true, SessionDir: t.TempDir()}
+
+	first, _, err := collector.Connect(ctx, s.URL.Host, s.URL.User.Username(), password, co)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, _ := os.ReadDir(co.SessionDir)
+	if len(files) != 1 {
+		t.Fatalf("got %d cached sessions, want 1", len(files))
+	}
+
+	// A wrong password still works while the cached session is valid
+	second, _, err := collector.Connect(ctx, s.URL.Host, s.URL.User.Username(), "wrong", co)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := first.SessionManager.UserSession(ctx)
+	b, _ := second.SessionManager.UserSession(ctx)
+	if a == nil || b == nil || a.Key != b.Key {
+		t.Errorf("cached session not reused: %v vs %v", a, b)
+	}
+}
+
+func TestConnectToken(t *testing.T) {
+	s := newServer(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	token := filepath.Join(dir, "token.xml")
+	assertion := `<saml2:Assertion xmlns:saml2="urn:oasis:names:tc:SAML:2.0:assertion" ID="_1">` +
+		`<saml2:Subject><saml2:NameID>svc-inventory@example.com</saml2:NameID></saml2:Subject></saml2:Assertion>`
+	if err := os.WriteFile(token, []byte(assertion), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	c, _, err := collector.Connect(ctx, s.URL.Host, "", "", collector.ConnectOptions{Insecure: true, TokenFile: token})
+	if err != nil {
+		t.Fatal(err)
+	}
+	us, err := c.SessionManager.UserSession(ctx)
+	if err != nil || us == nil || us.UserName != "svc-inventory@example.com" {
+		t.Errorf("session user = %v (%v), want svc-inventory@example.com", us, err)
+	}
+	c.Logout(ctx)
+
+	if _, _, err := collector.Connect(ctx, s.URL.Host, "", "", collector.ConnectOptions{Insecure: true, TokenFile: filepath.Join(dir, "missing.xml")}); err == nil {
+		t.Error("expected an error for a missing token file")
+	}
+
+	// Holder-of-key: the token is issued by the STS for a certificate
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "svc-inventory"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	co := collector.ConnectOptions{Insecure: true, CertFile: filepath.Join(dir, "cert.pem"), KeyFile: filepath.Join(dir, "key.pem")}
+	os.WriteFile(co.CertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(co.KeyFile, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0o600)
+
+	c, _, err = collector.Connect(ctx, s.URL.Host, "", "", co)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if us, err := c.SessionManager.UserSession(ctx); err != nil || us == nil {
+		t.Errorf("no session after holder-of-key login: %v", err)
+	}
+	c.Logout(ctx)
+}
+
+func TestConnectTimeout(t *testing.T) {
+	model := simulator.VPX()
+	model.DelayConfig.Delay = 500 // milliseconds per call
+	if err := model.Create(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(model.Remove)
+	s := model.Service.NewServer()
+	t.Cleanup(s.Close)
+
+	password, _ := s.URL.User.Password()
+	start := time.Now()
+	_, _, err := collector.Connect(context.Background(), s.URL.Host, s.URL.User.Username(), password,
+		collector.ConnectOptions{Insecure: true, Timeout: 50 * time.Millisecond})
+	if err == nil {
+		t.Fatal("expected a timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 400*time.Millisecond {
+		t.Errorf("Connect took %v despite a 50ms timeout", elapsed)
+	}
+}
+
+func TestConnectKeepAlive(t *testing.T) {
+	s := newServer(t)
+	ctx := context.Background()
+	password, _ := s.URL.User.Password()
+
+	idle := simulator.SessionIdleTimeout
+	simulator.SessionIdleTimeout = 200 * time.Millisecond
+	t.Cleanup(func() { simulator.SessionIdleTimeout = idle })
+
+	c, stop, err := collector.Connect(ctx, s.URL.Host, s.URL.User.Username(), password,
+		collector.ConnectOptions{Insecure: true, KeepAlive: 50 * time.Millisecond, SessionDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(500 * time.Millisecond)
+	if _, err := collector.CollectDatastores(ctx, c.Client, collector.Options{}); err != nil {
+		t.Errorf("session expired despite keepalive: %v", err)
+	}
+
+	// A cached session is not logged out, so only stop ends the keepalive
+	stop()
+	time.Sleep(500 * time.Millisecond)
+	if _, err := collector.CollectDatastores(ctx, c.Client, collector.Options{}); err == nil {
+		t.Error("keepalive still running after stop")
+	}
+}
+
+func TestCollectHostsUtilization(t *testing.T) {
+	c := newClient(t)
+	hosts, err := collector.CollectHosts(context.Background(), c.Client, collector.Options{VCenter: "vc1", Utilization: 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range hosts {
+		u := h.Utilization
+		if u == nil {
+			t.Fatalf("host %s has no utilization", h.Hostname)
+		}
+		if u.CPUPeakPct < u.CPUAvgPct || u.MemoryPeakPct < u.MemoryAvgPct {
+			t.Errorf("host %s utilization = %+v", h.Hostname, *u)
+		}
+	}
+
+	hosts, err = collector.CollectHosts(context.Background(), c.Client, collector.Options{VCenter: "vc1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hosts[0].Utilization != nil {
+		t.Errorf("utilization collected without a window")
+	}
+}
+
+// TestCollectHostsUtilizationFailure checks that utilization that cannot
+// be read is reported against each host rather than the vCenter.
+func TestCollectHostsUtilizationFailure(t *testing.T) {
+	c := newClient(t)
+	pm := simulator.Map.Get(*c.ServiceContent.PerfManager).(*simulator.PerformanceManager)
+	simulator.Map.Put(&PerformanceManager{PerformanceManager: pm, fault: &types.SystemError{Reason: "stats unavailable"}})
+
+	var failed []string
+	opts := collector.Options{
+		VCenter:     "vc1",
+		Utilization: 24 * time.Hour,
+		OnFailure: func(f collector.Failure) {
+			if f.Op == "perf" {
+				failed = append(failed, f.Host)
+			}
+		},
+	}
+	hosts, err := collector.CollectHosts(context.Background(), c.Client, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hosts) == 0 || len(failed) != len(hosts) || slices.Contains(failed, "") {
+		t.Errorf("perf failures for hosts %q, want one for each of %d hosts", failed, len(hosts))
+	}
+	for _, h := range hosts {
+		if h.Utilization != nil {
+			t.Errorf("host %s has utilization despite the failure", h.Hostname)
+		}
+	}
+}
+
+func TestPerfInterval(t *testing.T) {
+	for window, want := range map[time.Duration]int32{
+		time.Hour:           300,
+		7 * 24 * time.Hour:  1800,
+		30 * 24 * time.Hour: 7200,
+		90 * 24 * time.Hour: 86400,
+	} {
+		if got := collector.PerfInterval(window); got != want {
+			t.Errorf("PerfInterval(%v) = %d, want %d", window, got, want)
+		}
+	}
+}
+
+func TestCollectPerf(t *testing.T) {
+	c := newClient(t)
+	to := time.Now()
+	po := collector.PerfOptions{Counters: []string{"cpu.usage", "disk.usage"}, From: to.Add(-time.Hour), To: to}
+	samples, err := collector.CollectPerf(context.Background(), c.Client, collector.Options{VCenter: "vc1"}, po)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) == 0 {
+		t.Fatal("no samples")
+	}
+	for i, s := range samples {
+		if _, ok := s.Values["mem.usage"]; ok {
+			t.Fatalf("sample %+v has an unselected counter", s)
+		}
+		if i > 0 && s.Host == samples[i-1].Host && !s.Time.After(samples[i-1].Time) {
+			t.Fatalf("samples of %s out of time order", s.Host)
+		}
+	}
+}
+
+func TestPerfClusters(t *testing.T) {
+	at := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	hosts := []collector.PerfSample{
+		{VCenter: "vc1", Host: "h1", Cluster: "A", Time: at, Values: map[string]float64{"cpu.usage": 20, "disk.usage": 100}},
+		{VCenter: "vc1", Host: "h2", Cluster: "A", Time: at, Values: map[string]float64{"cpu.usage": 40, "disk.usage": 300}},
+		{VCenter: "vc1", Host: "h3", Cluster: "A", Time: at, Values: map[string]float64{"disk.usage": 50}},
+		{VCenter: "vc1", Host: "h1", Cluster: "A", Time: at.Add(-time.Hour), Values: map[string]float64{"cpu.usage": 10}},
+	}
+	clusters := collector.PerfClusters(hosts)
+	if len(clusters) != 2 || !clusters[0].Time.Before(clusters[1].Time) {
+		t.Fatalf("clusters = %+v, want two in time order", clusters)
+	}
+	c := clusters[1]
+	// Percentages average over the hosts that have them; rates add up
+	if c.Hosts != 3 || c.Values["cpu.usage"] != 30 || c.Values["disk.usage"] != 450 {
+		t.Errorf("cluster sample = %+v", c)
+	}
+}
+
+func TestCollectDIMMs(t *testing.T) {
+	c := newClient(t)
+	status := func(key string) types.BaseElementDescription {
+		return &types.ElementDescription{Key: key, Description: types.Description{Label: key}}
+	}
+	for _, e := range simulator.Map.All("HostSystem") {
+		if h := e.(*simulator.HostSystem); h.Name == "DC0_C0_H0" {
+			h.Runtime.HealthSystemRuntime = &types.HealthSystemRuntime{HardwareStatusInfo: &types.HostHardwareStatusInfo{
+				MemoryStatusInfo: []types.BaseHostHardwareElementInfo{
+					&types.HostHardwareElementInfo{Name: "P1-DIMMA1 16384 MB 2933 MHz Samsung", Status: status("Green")},
+					&types.HostHardwareElementInfo{Name: "Memory Device 2 DIMM_B1 32 GB", Status: status("Yellow")},
+					&types.HostHardwareElementInfo{Name: "Memory", Status: status("Unknown")},
+					&types.HostHardwareElementInfo{Name: "DIMM_C1 8 GB Crucial by Micron", Status: status("Green")},
+				},
+			}}
+		}
+	}
+
+	dimms, err := collector.CollectDIMMs(context.Background(), c.Client, collector.Options{VCenter: "vc1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The simulator's other hosts report no memory
+	want := []collector.DIMM{
+		{Slot: "P1-DIMMA1", SizeGB: 16, SpeedMTs: 2933, Vendor: "Samsung", Status: "Green", Description: "P1-DIMMA1 16384 MB 2933 MHz Samsung"},
+		{Slot: "DIMM_B1", SizeGB: 32, Status: "Yellow", Description: "Memory Device 2 DIMM_B1 32 GB"},
+		{Status: "Unknown", Description: "Memory"},
+		{Slot: "DIMM_C1", SizeGB: 8, Vendor: "Crucial", Status: "Green", Description: "DIMM_C1 8 GB Crucial by Micron"},
+	}
+	if len(dimms) != len(want) {
+		t.Fatalf("got %d DIMMs, want %d: %+v", len(dimms), len(want), dimms)
+	}
+	for i, d := range dimms {
+		if d.VCenter != "vc1" || d.Host != "DC0_C0_H0" || d.Cluster != "DC0_C0" {
+			t.Errorf("DIMM %d of %s/%s/%s", i, d.VCenter, d.Cluster, d.Host)
+		}
+		d.VCenter, d.Host, d.Cluster = "", "", ""
+		if d != want[i] {
+			t.Errorf("DIMM %d = %+v, want %+v", i, d, want[i])
+		}
+	}
+
+	// Sizes and speeds not reported are empty rather than 0
+	row := export.DIMMTables(dimms)[0].Rows[2]
+	if row[4] != nil || row[5] != nil {
+		t.Errorf("size and speed of an unparsed DIMM = %v, %v", row[4], row[5])
+	}
+}
+
+func TestCollectNICs(t *testing.T) {
+	c := newClient(t)
+	for _, e := range simulator.Map.All("HostSystem") {
+		if h := e.(*simulator.HostSystem); h.Name == "DC0_C0_H0" {
+			network := h.Config.Network
+			network.Pnic[0].DriverVersion, network.Pnic[0].FirmwareVersion = "1.3.0", "14.32.1010"
+			network.Pnic[1].LinkSpeed = nil
+			network.ProxySwitch = []types.HostProxySwitch{{DvsName: "DSwitch-Prod", Pnic: []string{network.Pnic[1].Key}}}
+			h.Config.PciPassthruInfo = []types.BaseHostPciPassthruInfo{
+				&types.HostSriovInfo{HostPciPassthruInfo: types.HostPciPassthruInfo{Id: "0000:0b:00.0"}, SriovCapable: true, SriovEnabled: true,
+					NumVirtualFunctionRequested: 16, NumVirtualFunction: 8, MaxVirtualFunctionSupported: 64},
+				&types.HostPciPassthruInfo{Id: "0000:13:00.0", PassthruCapable: true},
+			}
+		}
+	}
+
+	nics, err := collector.CollectNICs(context.Background(), c.Client, collector.Options{VCenter: "vc1", Clusters: []string{"DC0_C0"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The simulator gives each host two vmxnet3 NICs, vmnic0 on vSwitch0
+	if len(nics) != 6 {
+		t.Fatalf("got %d NICs, want 2 on each of 3 hosts: %+v", len(nics), nics)
+	}
+	want := []collector.PhysicalNIC{
+		{VCenter: "vc1", Host: "DC0_C0_H0", Cluster: "DC0_C0", Device: "vmnic0", PCI: "0000:0b:00.0", Vendor: "VMware Inc.", Model: "vmxnet3 Virtual Ethernet Controller",
+			Driver: "nvmxnet3", DriverVersion: "1.3.0", FirmwareVersion: "14.32.1010", LinkSpeedMb: 10000, SwitchType: "standard", Switch: "vSwitch0",
+			SRIOVCapable: true, SRIOVEnabled: true, VirtualFunctions: 16, ActiveVirtualFunctions: 8, MaxVirtualFunctions: 64},
+		{VCenter: "vc1", Host: "DC0_C0_H0", Cluster: "DC0_C0", Device: "vmnic1", PCI: "0000:13:00.0", Vendor: "VMware Inc.", Model: "vmxnet3 Virtual Ethernet Controller",
+			Driver: "nvmxnet3", SwitchType: "distributed", Switch: "DSwitch-Prod"},
+	}
+	var got []collector.PhysicalNIC
+	for _, n := range nics {
+		if n.Host == "DC0_C0_H0" {
+			got = append(got, n)
+		}
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("NICs of DC0_C0_H0 = %+v, want %+v", got, want)
+	}
+
+	// A NIC whose link is down has no speed rather than 0
+	if row := export.NICTables(got)[0].Rows[1]; row[10] != nil {
+		t.Errorf("link speed of a NIC that is down = %v", row[10])
+	}
+	// Nor virtual function counts when it is not SR-IOV capable
+	if row := export.NICTables(got)[0].Rows[1]; row[15] != nil || row[16] != nil || row[17] != nil {
+		t.Errorf("virtual functions of a NIC that is not SR-IOV capable = %v", row[15:])
+	}
+}
+
+func TestCollectPCIDevices(t *testing.T) {
+	c := newClient(t)
+	for _, e := range simulator.Map.All("HostSystem") {
+		if h := e.(*simulator.HostSystem); h.Name == "DC0_C0_H0" {
+			// The simulator's hardware is shared by its hosts
+			hw := *h.Hardware
+			hw.PciDevice = append(slices.Clone(hw.PciDevice),
+				types.HostPciDevice{Id: "0000:3b:00.0", ClassId: 0x0302, VendorId: 0x10de, VendorName: "NVIDIA Corporation", DeviceName: "TU104GL [Tesla T4]"},
+				types.HostPciDevice{Id: "0000:af:00.0", ClassId: 0x0302, VendorId: 0x10de, VendorName: "NVIDIA Corporation", DeviceName: "TU104GL [Tesla T4]"},
+				types.HostPciDevice{Id: "0000:03:00.0", ClassId: 0x0300, VendorId: 0x1a03, VendorName: "ASPEED Technology, Inc.", DeviceName: "ASPEED Graphics Family"},
+			)
+			h.Hardware = &hw
+			h.Config.PciPassthruInfo = []types.BaseHostPciPassthruInfo{
+				&types.HostPciPassthruInfo{Id: "0000:af:00.0", PassthruCapable: true, PassthruEnabled: true, PassthruActive: true},
+				&types.HostPciPassthruInfo{Id: "0000:13:00.0", PassthruCapable: true, PassthruEnabled: true},
+				&types.HostPciPassthruInfo{Id: "0000:0b:00.0", PassthruCapable: true},
+			}
+		}
+	}
+
+	opts := collector.Options{VCenter: "vc1"}
+	devices, err := collector.CollectPCIDevices(context.Background(), c.Client, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// GPUs and devices enabled for passthrough; not the onboard graphics
+	// or a NIC only capable of passthrough
+	vmxnet3 := "vmxnet3 Virtual Ethernet Controller"
+	want := []collector.PCIDevice{
+		{VCenter: "vc1", Host: "DC0_C0_H0", Cluster: "DC0_C0", ID: "0000:13:00.0", Vendor: "VMware Inc.", Model: vmxnet3, PassthroughEnabled: true},
+		{VCenter: "vc1", Host: "DC0_C0_H0", Cluster: "DC0_C0", ID: "0000:3b:00.0", Vendor: "NVIDIA Corporation", Model: "TU104GL [Tesla T4]", GPU: true},
+		{VCenter: "vc1", Host: "DC0_C0_H0", Cluster: "DC0_C0", ID: "0000:af:00.0", Vendor: "NVIDIA Corporation", Model: "TU104GL [Tesla T4]", GPU: true, PassthroughEnabled: true, PassthroughActive: true},
+	}
+	if !slices.Equal(devices, want) {
+		t.Errorf("PCI devices = %+v, want %+v", devices, want)
+	}
+
+	hosts, err := collector.CollectHosts(context.Background(), c.Client, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range hosts {
+		gpus, models := 0, ""
+		if h.Hostname == "DC0_C0_H0" {
+			gpus, models = 2, "NVIDIA Corporation TU104GL [Tesla T4]"
+		}
+		if h.GPUs != gpus || h.GPUModels != models {
+			t.Errorf("GPUs of %s = %d %q, want %d %q", h.Hostname, h.GPUs, h.GPUModels, gpus, models)
+		}
+	}
+}
+
+func TestCollectVGPUs(t *testing.T) {
+	c := newClient(t)
+	vgpu := func(vm *simulator.VirtualMachine, profile string) types.ManagedObjectReference {
+		vm.Config.Hardware.Device = append(vm.Config.Hardware.Device, &types.VirtualPCIPassthrough{VirtualDevice: types.VirtualDevice{
+			Key:     13000,
+			Backing: &types.VirtualPCIPassthroughVmiopBackingInfo{Vgpu: profile},
+		}})
+		return vm.Reference()
+	}
+	vms := make(map[string]*simulator.VirtualMachine)
+	for _, e := range simulator.Map.All("VirtualMachine") {
+		vm := e.(*simulator.VirtualMachine)
+		vms[vm.Name] = vm
+	}
+	for _, e := range simulator.Map.All("HostSystem") {
+		switch h := e.(*simulator.HostSystem); h.Name {
+		case "DC0_C0_H0":
+			h.Config.SharedPassthruGpuTypes = []string{"grid_t4-2b", "grid_t4-4q"}
+			h.Config.GraphicsInfo = []types.HostGraphicsInfo{
+				{DeviceName: "TU104GL [Tesla T4]", VendorName: "NVIDIA Corporation", PciId: "0000:3b:00.0", GraphicsType: "sharedDirect",
+					Vm: []types.ManagedObjectReference{vgpu(vms["DC0_C0_RP0_VM0"], "grid_t4-4q"), vgpu(vms["DC0_C0_RP0_VM1"], "grid_t4-16c")}},
+				{DeviceName: "TU104GL [Tesla T4]", VendorName: "NVIDIA Corporation", PciId: "0000:af:00.0", GraphicsType: "sharedDirect"},
+			}
+		case "DC0_C0_H1":
+			h.Config.GraphicsInfo = []types.HostGraphicsInfo{{DeviceName: "TU104GL [Tesla T4]", GraphicsType: "shared"}}
+		}
+	}
+
+	profiles, err := collector.CollectVGPUs(context.Background(), c.Client, collector.Options{VCenter: "vc1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The profiles offered, then those only in use; a host with GPUs
+	// used for vSGA has none
+	want := []collector.VGPUProfile{
+		{GPUs: 2, GraphicsType: "sharedDirect", Profile: "grid_t4-2b", License: "vPC"},
+		{GPUs: 2, GraphicsType: "sharedDirect", Profile: "grid_t4-4q", License: "vWS", VMs: []string{"DC0_C0_RP0_VM0"}},
+		{GPUs: 2, GraphicsType: "sharedDirect", Profile: "grid_t4-16c", License: "AI Enterprise", VMs: []string{"DC0_C0_RP0_VM1"}},
+		{GPUs: 1, GraphicsType: "shared"},
+	}
+	if len(profiles) != len(want) {
+		t.Fatalf("got %d profiles, want %d: %+v", len(profiles), len(want), profiles)
+	}
+	for i, p := range profiles {
+		host := "DC0_C0_H0"
+		if i == 3 {
+			host = "DC0_C0_H1"
+		}
+		if p.VCenter != "vc1" || p.Host != host || p.Cluster != "DC0_C0" {
+			t.Errorf("profile %d of %s/%s/%s, want %s", i, p.VCenter, p.Cluster, p.Host, host)
+		}
+		w := want[i]
+		if p.GPUs != w.GPUs || p.GraphicsType != w.GraphicsType || p.Profile != w.Profile || p.License != w.License || !slices.Equal(p.VMs, w.VMs) {
+			t.Errorf("profile %d = %+v, want %+v", i, p, w)
+		}
+	}
+}
+
+func TestCollectNTPDNS(t *testing.T) {
+	c := newClient(t)
+	for _, e := range simulator.Map.All("HostSystem") {
+		h := e.(*simulator.HostSystem)
+		// The simulator's services and network are shared by its hosts
+		service := *h.Config.Service
+		service.Service = slices.Clone(service.Service)
+		h.Config.Service = &service
+		switch h.Name {
+		case "DC0_C0_H0":
+			h.Config.DateTimeInfo = &types.HostDateTimeInfo{NtpConfig: &types.HostNtpConfig{Server: []string{"ntp1.example.com", "ntp2.example.com"}}}
+			for i, s := range service.Service {
+				if s.Key == "ntpd" {
+					service.Service[i].Running, service.Service[i].Policy = true, "on"
+				}
+			}
+			network := *h.Config.Network
+			network.DnsConfig = &types.HostDnsConfig{Address: []string{"10.0.0.53", "10.0.1.53"}, SearchDomain: []string{"example.com"}, DomainName: "example.com"}
+			h.Config.Network = &network
+		case "DC0_C0_H1":
+			h.Config.DateTimeInfo = &types.HostDateTimeInfo{NtpConfig: &types.HostNtpConfig{Server: []string{"pool.ntp.org"}}}
+		}
+	}
+	ctx := context.Background()
+	opts := collector.Options{VCenter: "vc1", Clusters: []string{"DC0_C0"}}
+
+	// Without expected values, only hosts whose NTP is not working
+	configs, err := collector.CollectNTPDNS(ctx, c.Client, opts, collector.NTPDNSExpected{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(configs) != 3 {
+		t.Fatalf("got %d hosts, want 3: %+v", len(configs), configs)
+	}
+	h0 := configs[0]
+	if h0.VCenter != "vc1" || h0.Host != "DC0_C0_H0" || h0.Cluster != "DC0_C0" || h0.TimeProtocol != "ntp" ||
+		!slices.Equal(h0.NTPServers, []string{"ntp1.example.com", "ntp2.example.com"}) || h0.NTPRunning == nil || !*h0.NTPRunning || h0.NTPPolicy != "on" ||
+		!slices.Equal(h0.DNSServers, []string{"10.0.0.53", "10.0.1.53"}) || !slices.Equal(h0.SearchDomains, []string{"example.com"}) || h0.Domain != "example.com" || h0.DNSFromDHCP {
+		t.Errorf("DC0_C0_H0 = %+v", h0)
+	}
+	wantIssues := [][]string{
+		nil,
+		{"NTP service not running"},
+		{"no NTP servers", "NTP service not running"},
+	}
+	for i, cfg := range configs {
+		if !slices.Equal(cfg.Issues, wantIssues[i]) {
+			t.Errorf("issues of %s = %q, want %q", cfg.Host, cfg.Issues, wantIssues[i])
+		}
+	}
+
+	// Expected values are compared regardless of order and case, before
+	// the names are anonymized
+	opts.Anonymizer = collector.NewAnonymizer(true)
+	expected := collector.NTPDNSExpected{
+		NTPServers:    []string{"NTP2.example.com", "ntp1.example.com"},
+		DNSServers:    []string{"10.0.0.53", "10.0.1.53"},
+		SearchDomains: []string{"example.com"},
+	}
+	configs, err = collector.CollectNTPDNS(ctx, c.Client, opts, expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantIssues = [][]string{
+		nil,
+		{"NTP service not running", "NTP servers not as expected", "DNS servers not as expected", "search domains not as expected"},
+		{"no NTP servers", "NTP service not running", "NTP servers not as expected", "DNS servers not as expected", "search domains not as expected"},
+	}
+	for i, cfg := range configs {
+		if !slices.Equal(cfg.Issues, wantIssues[i]) {
+			t.Errorf("anonymized issues of %s = %q, want %q", cfg.Host, cfg.Issues, wantIssues[i])
+		}
+	}
+	if h0 := configs[0]; !slices.Equal(h0.NTPServers, []string{"NTP Server 1", "NTP Server 2"}) || h0.Domain != "Domain 1" {
+		t.Errorf("anonymized DC0_C0_H0 = %+v", h0)
+	}
+}
+
+func TestCollectHostServices(t *testing.T) {
+	c := newClient(t)
+	for _, e := range simulator.Map.All("HostSystem") {
+		if h := e.(*simulator.HostSystem); h.Name == "DC0_C0_H0" {
+			// The simulator's services are shared by its hosts
+			service := *h.Config.Service
+			service.Service = slices.Clone(service.Service)
+			for i, s := range service.Service {
+				if s.Key == "TSM-SSH" {
+					service.Service[i].Running, service.Service[i].Policy = true, "on"
+				}
+			}
+			h.Config.Service = &service
+		}
+	}
+
+	// The simulator has no slpd
+	opts := collector.Options{VCenter: "vc1", Clusters: []string{"DC0_C0"}, Services: []string{"ntpd", "TSM-SSH", "slpd"}}
+	services, err := collector.CollectHostServices(context.Background(), c.Client, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.MarkNonstandardServices(services)
+	want := []collector.HostService{
+		{VCenter: "vc1", Host: "DC0_C0_H0", Cluster: "DC0_C0", Key: "TSM-SSH", Label: "SSH", Running: true, Policy: "on", Nonstandard: true},
+		{VCenter: "vc1", Host: "DC0_C0_H0", Cluster: "DC0_C0", Key: "ntpd", Label: "NTP Daemon", Policy: "off"},
+		{VCenter: "vc1", Host: "DC0_C0_H1", Cluster: "DC0_C0", Key: "TSM-SSH", Label: "SSH", Policy: "off"},
+		{VCenter: "vc1", Host: "DC0_C0_H1", Cluster: "DC0_C0", Key: "ntpd", Label: "NTP Daemon", Policy: "off"},
+		{VCenter: "vc1", Host: "DC0_C0_H2", Cluster: "DC0_C0", Key: "TSM-SSH", Label: "SSH", Policy: "off"},
+		{VCenter: "vc1", Host: "DC0_C0_H2", Cluster: "DC0_C0", Key: "ntpd", Label: "NTP Daemon", Policy: "off"},
+	}
+	if !slices.Equal(services, want) {
+		t.Errorf("services = %+v, want %+v", services, want)
+	}
+
+	// Without keys, every service; equally common configurations are
+	// not marked
+	opts.Services = nil
+	services, err = collector.CollectHostServices(context.Background(), c.Client, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 36 {
+		t.Errorf("got %d services, want 12 on each of 3 hosts", len(services))
+	}
+	tied := slices.Clone(want[:4])
+	collector.MarkNonstandardServices(tied)
+	for _, s := range tied {
+		if s.Nonstandard {
+			t.Errorf("%s of %s marked nonstandard with a tie", s.Key, s.Host)
+		}
+	}
+}
+
+func TestCollectVMKernelAdapters(t *testing.T) {
+	c := newClient(t)
+	dvs := simulator.Map.All("DistributedVirtualSwitch")[0].(*simulator.DistributedVirtualSwitch)
+	var pg *simulator.DistributedVirtualPortgroup
+	for _, e := range simulator.Map.All("DistributedVirtualPortgroup") {
+		if p := e.(*simulator.DistributedVirtualPortgroup); p.Name == "DC0_DVPG0" {
+			pg = p
+		}
+	}
+	for _, e := range simulator.Map.All("HostSystem") {
+		if h := e.(*simulator.HostSystem); h.Name == "DC0_C0_H0" {
+			// The simulator's network configuration is shared by its hosts
+			network := *h.Config.Network
+			network.ProxySwitch = []types.HostProxySwitch{{DvsUuid: dvs.Uuid, DvsName: dvs.Name}}
+			network.Vnic = append(slices.Clone(network.Vnic), types.HostVirtualNic{
+				Device: "vmk1",
+				Key:    "key-vim.host.VirtualNic-vmk1",
+				Spec: types.HostVirtualNicSpec{
+					Ip:                     &types.HostIpConfig{IpAddress: "192.168.50.11", SubnetMask: "255.255.255.0"},
+					Mtu:                    9000,
+					NetStackInstanceKey:    "defaultTcpipStack",
+					DistributedVirtualPort: &types.DistributedVirtualSwitchPortConnection{SwitchUuid: dvs.Uuid, PortgroupKey: pg.Key},
+				},
+			})
+			h.Config.Network = &network
+			var netConfig []types.VirtualNicManagerNetConfig
+			for _, nc := range h.Config.VirtualNicManagerInfo.NetConfig {
+				if nc.NicType == "vmotion" || nc.NicType == "vsan" {
+					vmk1 := types.HostVirtualNic{Device: "vmk1", Key: nc.NicType + ".key-vim.host.VirtualNic-vmk1"}
+					nc.CandidateVnic = append(slices.Clone(nc.CandidateVnic), vmk1)
+					nc.SelectedVnic = []string{vmk1.Key}
+				}
+				netConfig = append(netConfig, nc)
+			}
+			h.Config.VirtualNicManagerInfo = &types.HostVirtualNicManagerInfo{NetConfig: netConfig}
+		}
+	}
+
+	anon := collector.NewAnonymizer(false)
+	anon.SetRedactIPs(true)
+	opts := collector.Options{VCenter: "vc1", Clusters: []string{"DC0_C0"}, Anonymizer: anon}
+	adapters, err := collector.CollectVMKernelAdapters(context.Background(), c.Client, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The simulator gives each host vmk0 for management
+	if len(adapters) != 4 {
+		t.Fatalf("got %d adapters, want vmk0 of 3 hosts and vmk1: %+v", len(adapters), adapters)
+	}
+	vmk0 := collector.VMKernelAdapter{VCenter: "vc1", Host: "DC0_C0_H0", Cluster: "DC0_C0", Device: "vmk0", IPAddress: "IP 1", SubnetMask: "255.0.0.0", DHCP: true, MTU: 1500,
+		NetStack: "defaultTcpipStack", Services: []string{"Management"}, SwitchType: "standard", Switch: "vSwitch0", Portgroup: "Management Network"}
+	vmk1 := collector.VMKernelAdapter{VCenter: "vc1", Host: "DC0_C0_H0", Cluster: "DC0_C0", Device: "vmk1", IPAddress: "IP 2", SubnetMask: "255.255.255.0", MTU: 9000,
+		NetStack: "defaultTcpipStack", Services: []string{"vMotion", "vSAN"}, SwitchType: "distributed", Switch: dvs.Name, Portgroup: "DC0_DVPG0"}
+	got := export.VMKernelAdapterTables(adapters[:2])[0].Rows
+	want := export.VMKernelAdapterTables([]collector.VMKernelAdapter{vmk0, vmk1})[0].Rows
+	for i := range want {
+		if !slices.Equal(got[i], want[i]) {
+			t.Errorf("adapter %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestCollectLocalDisks(t *testing.T) {
+	c := newClient(t)
+	disk := func(name, key string, local bool) *types.HostScsiDisk {
+		return &types.HostScsiDisk{
+			ScsiLun:   types.ScsiLun{Key: key, CanonicalName: name, LunType: "disk", Vendor: "ATA     ", Model: "Micron_5300"},
+			Capacity:  types.HostDiskDimensionsLba{BlockSize: 512, Block: 468862128},
+			LocalDisk: types.NewBool(local),
+		}
+	}
+	usb := disk("mpx.vmhba32:C0:T0:L0", "key-vim.host.ScsiDisk-usb", true)
+	usb.Vendor, usb.Model, usb.Capacity.Block = "SanDisk", "Cruzer Fit", 62521344
+	vsan := disk("naa.500a0751", "key-vim.host.ScsiDisk-vsan", true)
+	vsan.VsanDiskInfo = &types.VsanHostVsanDiskInfo{VsanUuid: "52d3"}
+	san := disk("naa.600a0980", "key-vim.host.ScsiDisk-san", false)
+	vmfs := func(name, typ, diskName string) types.HostFileSystemMountInfo {
+		return types.HostFileSystemMountInfo{Volume: &types.HostVmfsVolume{
+			HostFileSystemVolume: types.HostFileSystemVolume{Type: typ, Name: name},
+			Extent:               []types.HostScsiDiskPartition{{DiskName: diskName, Partition: 1}},
+			Local:                types.NewBool(true),
+		}}
+	}
+	for _, e := range simulator.Map.All("HostSystem") {
+		if h := e.(*simulator.HostSystem); h.Name == "DC0_C0_H0" {
+			// The simulator's storage devices are shared by its hosts
+			storage := *h.Config.StorageDevice
+			storage.HostBusAdapter = append(slices.Clone(storage.HostBusAdapter), &types.HostBlockHba{
+				HostHostBusAdapter: types.HostHostBusAdapter{Key: "key-vim.host.BlockHba-vmhba32", Device: "vmhba32", Driver: "vmkusb"},
+			})
+			storage.ScsiLun = append(slices.Clone(storage.ScsiLun), usb, vsan, san)
+			topology := *storage.ScsiTopology
+			topology.Adapter = append(slices.Clone(topology.Adapter), types.HostScsiTopologyInterface{
+				Adapter: "key-vim.host.BlockHba-vmhba32",
+				Target:  []types.HostScsiTopologyTarget{{Lun: []types.HostScsiTopologyLun{{ScsiLun: usb.Key}}}},
+			})
+			storage.ScsiTopology = &topology
+			h.Config.StorageDevice = &storage
+			h.Config.FileSystemVolume = &types.HostFileSystemVolumeInfo{MountInfo: []types.HostFileSystemMountInfo{
+				vmfs("datastore1", "VMFS", "mpx.vmhba0:C0:T0:L0"),
+				vmfs("OSDATA-4f3a", "OTHER", "mpx.vmhba32:C0:T0:L0"),
+				vmfs("vsanDatastore", "vsan", "naa.500a0751"),
+			}}
+		}
+	}
+
+	disks, err := collector.CollectLocalDisks(context.Background(), c.Client, collector.Options{VCenter: "vc1", Clusters: []string{"DC0_C0"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []collector.LocalDisk
+	for _, d := range disks {
+		if d.Host == "DC0_C0_H0" {
+			got = append(got, d)
+		}
+	}
+	// Neither the disk vSAN claimed nor the SAN disk is listed
+	want := []collector.LocalDisk{
+		{VCenter: "vc1", Host: "DC0_C0_H0", Cluster: "DC0_C0", Device: "mpx.vmhba0:C0:T0:L0", Vendor: "VMware,", Model: "VMware Virtual S", CapacityGB: 32, SSD: true,
+			Adapter: "vmhba0", Driver: "pvscsi", Datastores: []string{"datastore1"}},
+		{VCenter: "vc1", Host: "DC0_C0_H0", Cluster: "DC0_C0", Device: "mpx.vmhba32:C0:T0:L0", Vendor: "SanDisk", Model: "Cruzer Fit", CapacityGB: 29.8125,
+			Adapter: "vmhba32", Driver: "vmkusb", USB: true, Boot: true},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d local disks of DC0_C0_H0, want %d: %+v", len(got), len(want), got)
+	}
+	gotRows := export.LocalDiskTables(got)[0].Rows
+	wantRows := export.LocalDiskTables(want)[0].Rows
+	for i := range wantRows {
+		if !slices.Equal(gotRows[i], wantRows[i]) {
+			t.Errorf("disk %d = %v, want %v", i, gotRows[i], wantRows[i])
+		}
+	}
+}
+
+func TestCollectDatastoreMounts(t *testing.T) {
+	c := newClient(t)
+	// The simulator's datastore is mounted on the standalone host only
+	mounts := map[string]types.HostMountInfo{
+		"DC0_C0_H0": {AccessMode: "readWrite", Mounted: types.NewBool(true), Accessible: types.NewBool(true)},
+		"DC0_C0_H1": {AccessMode: "readOnly", Mounted: types.NewBool(true), Accessible: types.NewBool(true)},
+	}
+	ds := simulator.Map.All("Datastore")[0].(*simulator.Datastore)
+	ds.Summary.MultipleHostAccess = types.NewBool(true)
+	for _, e := range simulator.Map.All("HostSystem") {
+		h := e.(*simulator.HostSystem)
+		if m, ok := mounts[h.Name]; ok {
+			ds.Host = append(slices.Clone(ds.Host), types.DatastoreHostMount{Key: h.Self, MountInfo: m})
+		}
+	}
+
+	got, err := collector.CollectDatastoreMounts(context.Background(), c.Client, collector.Options{VCenter: "vc1", Clusters: []string{"DC0_C0"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []collector.DatastoreMount{
+		{VCenter: "vc1", Host: "DC0_C0_H0", Cluster: "DC0_C0", Datastore: "LocalDS_0", Type: "OTHER", Mounted: true, AccessMode: "readWrite", Accessible: true},
+		{VCenter: "vc1", Host: "DC0_C0_H1", Cluster: "DC0_C0", Datastore: "LocalDS_0", Type: "OTHER", Mounted: true, AccessMode: "readOnly", Accessible: true},
+		{VCenter: "vc1", Host: "DC0_C0_H2", Cluster: "DC0_C0", Datastore: "LocalDS_0", Type: "OTHER", Missing: true},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("mounts = %+v, want %+v", got, want)
+	}
+}
+
+func TestCollectMultipathLUNs(t *testing.T) {
+	c := newClient(t)
+	san := func(name string, states ...string) (*types.HostScsiDisk, types.HostMultipathInfoLogicalUnit) {
+		d := &types.HostScsiDisk{
+			ScsiLun:   types.ScsiLun{Key: "key-vim.host.ScsiDisk-" + name, CanonicalName: name, LunType: "disk", Vendor: "NETAPP  ", Model: "LUN C-Mode"},
+			LocalDisk: types.NewBool(false),
+		}
+		u := types.HostMultipathInfoLogicalUnit{
+			Lun:                    d.Key,
+			Policy:                 &types.HostMultipathInfoLogicalUnitPolicy{Policy: "VMW_PSP_RR"},
+			StorageArrayTypePolicy: &types.HostMultipathInfoLogicalUnitStorageArrayTypePolicy{Policy: "VMW_SATP_ALUA"},
+		}
+		for _, state := range states {
+			u.Path = append(u.Path, types.HostMultipathInfoPath{PathState: state})
+		}
+		return d, u
+	}
+	redundant, redundantLUN := san("naa.600a0980a", "active", "active", "standby", "dead")
+	single, singleLUN := san("naa.600a0980b", "active", "dead")
+	for _, e := range simulator.Map.All("HostSystem") {
+		if h := e.(*simulator.HostSystem); h.Name == "DC0_C0_H0" {
+			// The simulator's storage devices are shared by its hosts
+			storage := *h.Config.StorageDevice
+			storage.ScsiLun = append(slices.Clone(storage.ScsiLun), redundant, single)
+			multipath := *storage.MultipathInfo
+			multipath.Lun = append(slices.Clone(multipath.Lun), redundantLUN, singleLUN)
+			storage.MultipathInfo = &multipath
+			h.Config.StorageDevice = &storage
+		}
+	}
+
+	got, err := collector.CollectMultipathLUNs(context.Background(), c.Client, collector.Options{VCenter: "vc1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The simulator's local disk and CD-ROM are left out
+	want := []collector.MultipathLUN{
+		{VCenter: "vc1", Host: "DC0_C0_H0", Cluster: "DC0_C0", Device: "naa.600a0980a", Vendor: "NETAPP", Model: "LUN C-Mode", PathPolicy: "VMW_PSP_RR", SATP: "VMW_SATP_ALUA",
+			Paths: 4, ActivePaths: 2, StandbyPaths: 1, DeadPaths: 1},
+		{VCenter: "vc1", Host: "DC0_C0_H0", Cluster: "DC0_C0", Device: "naa.600a0980b", Vendor: "NETAPP", Model: "LUN C-Mode", PathPolicy: "VMW_PSP_RR", SATP: "VMW_SATP_ALUA",
+			Paths: 2, ActivePaths: 1, DeadPaths: 1, SinglePath: true},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("LUNs = %+v, want %+v", got, want)
+	}
+}
+
+func TestCollectStoragePolicies(t *testing.T) {
+	// Replaces the pbm simulator's profile manager in this and later
+	// simulators
+	pm := new(PbmProfileManager)
+	simulator.RegisterEndpoint(func(s *simulator.Service, r *simulator.Registry) {
+		if !r.IsVPX() {
+			return
+		}
+		ref := types.ManagedObjectReference{Type: "PbmProfileProfileManager", Value: "ProfileManager"}
+		pm.ProfileManager = pbmsim.New().Get(ref).(*pbmsim.ProfileManager)
+		sdk := simulator.NewRegistry()
+		sdk.Namespace, sdk.Path = pbm.Namespace, pbm.Path
+		sdk.Put(pm)
+		s.RegisterSDK(sdk)
+	})
+	c := newClient(t)
+
+	vms := make(map[string]string) // name -> MoRef value
+	for _, e := range simulator.Map.All("VirtualMachine") {
+		vm := e.(*simulator.VirtualMachine)
+		vms[vm.Name] = vm.Self.Value
+	}
+	vsan := []pbmtypes.PbmProfileId{{UniqueId: "aa6d5a82-1c88-45da-85d3-3d74b91a5bad"}}
+	encryption := []pbmtypes.PbmProfileId{{UniqueId: pbmsim.DefaultEncryptionProfileID}}
+	pm.associated = []pbmtypes.PbmQueryProfileResult{
+		{Object: pbmtypes.PbmServerObjectRef{ObjectType: "virtualMachine", Key: vms["DC0_H0_VM0"]}, ProfileId: vsan},
+		{Object: pbmtypes.PbmServerObjectRef{ObjectType: "virtualDiskId", Key: vms["DC0_H0_VM0"] + ":2000"}, ProfileId: vsan},
+		{Object: pbmtypes.PbmServerObjectRef{ObjectType: "virtualMachine", Key: vms["DC0_C0_RP0_VM0"]}, ProfileId: encryption},
+		{Object: pbmtypes.PbmServerObjectRef{ObjectType: "virtualMachine", Key: "vm-404"}, ProfileId: vsan},
+	}
+
+	policies, err := collector.CollectStoragePolicies(context.Background(), c.Client, collector.Options{VCenter: "vc1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := make(map[string]collector.StoragePolicy)
+	for _, p := range policies {
+		byName[p.Name] = p
+	}
+	// Assignments to VMs that no longer exist are not counted
+	want := []collector.StoragePolicy{
+		{VCenter: "vc1", Name: "vSAN Default Storage Policy", FTT: new(int), RAID: "RAID-1", Encryption: new(bool), VMs: 1, Objects: 2,
+			Rules: []string{"VSAN.hostFailuresToTolerate=1", "VSAN.stripeWidth=1", "VSAN.forceProvisioning=false", "VSAN.proportionalCapacity=0", "VSAN.cacheReservation=0"}},
+		{VCenter: "vc1", Name: "VM Encryption Policy", Encryption: new(bool), VMs: 1, Objects: 1,
+			Rules: []string{"com.vmware.storageprofile.dataservice.ad5a249d-cbc2-43af-9366-694d7664fa52=ad5a249d-cbc2-43af-9366-694d7664fa52"}},
+	}
+	*want[0].FTT = 1
+	*want[1].Encryption = true
+	for _, w := range want {
+		got := export.StoragePolicyTables([]collector.StoragePolicy{byName[w.Name]})[0].Rows[0]
+		if wantRow := export.StoragePolicyTables([]collector.StoragePolicy{w})[0].Rows[0]; !slices.Equal(got, wantRow) {
+			t.Errorf("policy %s = %v, want %v", w.Name, got, wantRow)
+		}
+	}
+
+	// With a cluster filter, only VMs on its hosts are counted
+	policies, err = collector.CollectStoragePolicies(context.Background(), c.Client, collector.Options{VCenter: "vc1", Clusters: []string{"DC0_C0"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range policies {
+		if p.Name == "vSAN Default Storage Policy" && (p.VMs != 0 || p.Objects != 0) {
+			t.Errorf("vSAN policy used by %d VMs and %d objects in DC0_C0, want none", p.VMs, p.Objects)
+		}
+	}
+}
+
+func TestCollectVVols(t *testing.T) {
+	// Adds a storage monitoring service to this and later simulators
+	sms := &SmsServiceInstance{
+		ManagedObjectReference: types.ManagedObjectReference{Type: "SmsServiceInstance", Value: "ServiceInstance"},
+		providers: []smstypes.BaseSmsProviderInfo{
+			&smstypes.VasaProviderInfo{
+				SmsProviderInfo: smstypes.SmsProviderInfo{Uid: "vp-1", Name: "array1-vp", Version: "3.2"},
+				Url:             "https://array1.example.com:8443/vasa/version.xml",
+				Status:          "online",
+				VasaVersion:     "5.0",
+				LastSyncTime:    "2026-10-16T08:00:00Z",
+			},
+			&smstypes.VasaProviderInfo{
+				SmsProviderInfo: smstypes.SmsProviderInfo{Uid: "vp-2", Name: "array2-vp", Version: "3.2"},
+				Url:             "https://array2.example.com:8443/vasa/version.xml",
+				Status:          "offline",
+				VasaVersion:     "5.0",
+			},
+		},
+	}
+	simulator.RegisterEndpoint(func(s *simulator.Service, r *simulator.Registry) {
+		if !r.IsVPX() {
+			return
+		}
+		sdk := simulator.NewRegistry()
+		sdk.Namespace, sdk.Path, sdk.Cookie = "sms", "/sms/sdk", simulator.SOAPCookie
+		sdk.Put(sms)
+		sdk.Put(&SmsStorageManager{ManagedObjectReference: types.ManagedObjectReference{Type: "SmsStorageManager", Value: "StorageManager"}, service: sms})
+		for i := range sms.providers {
+			sdk.Put(&SmsProvider{ManagedObjectReference: types.ManagedObjectReference{Type: "SmsProvider", Value: "provider-" + strconv.Itoa(i)}, service: sms})
+		}
+		s.RegisterSDK(sdk)
+	})
+	c := newClient(t)
+
+	for _, e := range simulator.Map.All("Datastore") {
+		ds := e.(*simulator.Datastore)
+		if ds.Name != "LocalDS_0" {
+			continue
+		}
+		ds.Summary.Type = "VVOL"
+		ds.Summary.Capacity, ds.Summary.FreeSpace = 10<<40, 4<<40
+		ds.Info = &types.VvolDatastoreInfo{
+			DatastoreInfo: *ds.Info.GetDatastoreInfo(),
+			VvolDS: &types.HostVvolVolume{
+				ScId:                 "vvol:6a5c0e1f3d2b4a98-b1c2d3e4f5a6b7c8",
+				ProtocolEndpointType: "SCSI",
+				StorageArray:         []types.VASAStorageArray{{Name: "array1", Uuid: "a1"}},
+				VasaProviderInfo: []types.VimVasaProviderInfo{
+					{Provider: types.VimVasaProvider{Name: "array1-vp", Url: "https://array1.example.com:8443/vasa/version.xml"},
+						ArrayState: []types.VimVasaProviderStatePerArray{{ArrayId: "a1", Active: true}}},
+					{Provider: types.VimVasaProvider{Url: "https://array1-b.example.com:8443/vasa/version.xml"},
+						ArrayState: []types.VimVasaProviderStatePerArray{{ArrayId: "a1"}}},
+				},
+			},
+		}
+	}
+
+	providers, containers, err := collector.CollectVVols(context.Background(), c.Client, collector.Options{VCenter: "vc1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantProviders := []collector.VASAProvider{
+		{VCenter: "vc1", Name: "array1-vp", URL: "https://array1.example.com:8443/vasa/version.xml", Version: "3.2", VASAVersion: "5.0", Status: "online", LastSync: "2026-10-16T08:00:00Z"},
+		{VCenter: "vc1", Name: "array2-vp", URL: "https://array2.example.com:8443/vasa/version.xml", Version: "3.2", VASAVersion: "5.0", Status: "offline"},
+	}
+	if !slices.Equal(providers, wantProviders) {
+		t.Errorf("providers = %+v, want %+v", providers, wantProviders)
+	}
+	// A provider without a name is listed by its URL
+	want := collector.VVolContainer{
+		VCenter: "vc1", Datastore: "LocalDS_0", Container: "vvol:6a5c0e1f3d2b4a98-b1c2d3e4f5a6b7c8", Arrays: []string{"array1"},
+		Providers: []string{"array1-vp", "https://array1-b.example.com:8443/vasa/version.xml"}, ActiveProviders: []string{"array1-vp"},
+		ProtocolEndpoint: "SCSI", CapacityGB: 10240, FreeGB: 4096, Hosts: 1,
+	}
+	if len(containers) != 1 {
+		t.Fatalf("got %d vVol containers, want 1", len(containers))
+	}
+	got := export.VVolTables(containers, nil)[0].Rows[0]
+	if wantRow := export.VVolTables([]collector.VVolContainer{want}, nil)[0].Rows[0]; !slices.Equal(got, wantRow) {
+		t.Errorf("container = %v, want %v", got, wantRow)
+	}
+
+	// The providers are listed whatever the filters
+	providers, containers, err = collector.CollectVVols(context.Background(), c.Client, collector.Options{VCenter: "vc1", Clusters: []string{"DC0_C0"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(providers) != 2 || len(containers) != 0 {
+		t.Errorf("DC0_C0 has %d providers and %d containers, want 2 and none", len(providers), len(containers))
+	}
+
+	// A failure to list the providers leaves the containers
+	sms.fault = new(types.NotSupported)
+	var failures []collector.Failure
+	opts := collector.Options{VCenter: "vc1", OnFailure: func(f collector.Failure) { failures = append(failures, f) }}
+	providers, containers, err = collector.CollectVVols(context.Background(), c.Client, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(providers) != 0 || len(containers) != 1 {
+		t.Errorf("got %d providers and %d containers, want none and 1", len(providers), len(containers))
+	}
+	if len(failures) != 1 || failures[0].Op != "sms" {
+		t.Errorf("failures = %+v, want one sms failure", failures)
+	}
+}
+
+func TestCollectISCSIAdapters(t *testing.T) {
+	c := newClient(t)
+	software := func(device string) *types.HostInternetScsiHba {
+		return &types.HostInternetScsiHba{
+			HostHostBusAdapter:    types.HostHostBusAdapter{Device: device, Model: "iSCSI Software Adapter", Driver: "iscsi_vmk", Status: "online"},
+			IsSoftwareBased:       true,
+			NetworkBindingSupport: types.HostInternetScsiHbaNetworkBindingSupportTypeOptional,
+			IScsiName:             "iqn.1998-01.com.vmware:" + device,
+		}
+	}
+	h0 := software("vmhba64")
+	h0.ConfiguredSendTarget = []types.HostInternetScsiHbaSendTarget{{Address: "10.0.0.20", Port: 3260}}
+	h0.ConfiguredStaticTarget = []types.HostInternetScsiHbaStaticTarget{
+		{Address: "10.0.0.21", Port: 3260, IScsiName: "iqn.2001-05.com.array:vol1", DiscoveryMethod: "staticMethod"},
+		{Address: "10.0.0.20", Port: 3260, IScsiName: "iqn.2001-05.com.array:vol2", DiscoveryMethod: "sendTargetMethod"},
+	}
+	h0.AuthenticationProperties = types.HostInternetScsiHbaAuthenticationProperties{
+		ChapAuthEnabled: true, ChapName: "esx", ChapSecret: "secret", ChapAuthenticationType: "chapRequired", MutualChapAuthenticationType: "chapProhibited",
+	}
+	hardware := &types.HostInternetScsiHba{
+		HostHostBusAdapter:    types.HostHostBusAdapter{Device: "vmhba3", Model: "QLE8242", Driver: "qfle3i", Status: "online"},
+		NetworkBindingSupport: types.HostInternetScsiHbaNetworkBindingSupportTypeNotsupported,
+		IScsiName:             "iqn.2000-04.com.qlogic:vmhba3",
+	}
+	adapters := map[string][]types.BaseHostHostBusAdapter{"DC0_C0_H0": {h0, hardware}, "DC0_C0_H1": {software("vmhba65")}}
+	managers := map[string]*IscsiManager{
+		"DC0_C0_H0": {bound: map[string][]string{"vmhba64": {"vmk1", "vmk2"}}},
+		"DC0_C0_H1": {fault: new(types.NotSupported)},
+	}
+	for _, e := range simulator.Map.All("HostSystem") {
+		h := e.(*simulator.HostSystem)
+		if m, ok := managers[h.Name]; ok {
+			ref := simulator.Map.Put(m).Reference()
+			h.ConfigManager.IscsiManager = &ref
+			// The simulator's storage devices are shared by its hosts
+			storage := *h.Config.StorageDevice
+			storage.HostBusAdapter = append(slices.Clone(storage.HostBusAdapter), adapters[h.Name]...)
+			h.Config.StorageDevice = &storage
+		}
+	}
+
+	var failures []collector.Failure
+	opts := collector.Options{VCenter: "vc1", OnFailure: func(f collector.Failure) { failures = append(failures, f) }}
+	got, err := collector.CollectISCSIAdapters(context.Background(), c.Client, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Static targets found by dynamic discovery are left out, and a host
+	// whose bound ports cannot be listed has its adapters without them
+	want := []collector.ISCSIAdapter{
+		{VCenter: "vc1", Host: "DC0_C0_H0", Cluster: "DC0_C0", Device: "vmhba64", Type: "software", Model: "iSCSI Software Adapter", Driver: "iscsi_vmk", Status: "online",
+			IQN: "iqn.1998-01.com.vmware:vmhba64", BoundPorts: []string{"vmk1", "vmk2"}, SendTargets: []string{"10.0.0.20:3260"},
+			StaticTargets: []string{"10.0.0.21:3260 iqn.2001-05.com.array:vol1"}, CHAP: "required", MutualCHAP: "prohibited"},
+		{VCenter: "vc1", Host: "DC0_C0_H0", Cluster: "DC0_C0", Device: "vmhba3", Type: "hardware", Model: "QLE8242", Driver: "qfle3i", Status: "online",
+			IQN: "iqn.2000-04.com.qlogic:vmhba3"},
+		{VCenter: "vc1", Host: "DC0_C0_H1", Cluster: "DC0_C0", Device: "vmhba65", Type: "software", Model: "iSCSI Software Adapter", Driver: "iscsi_vmk", Status: "online",
+			IQN: "iqn.1998-01.com.vmware:vmhba65"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d adapters, want %d: %+v", len(got), len(want), got)
+	}
+	gotRows := export.ISCSIAdapterTables(got)[0].Rows
+	wantRows := export.ISCSIAdapterTables(want)[0].Rows
+	for i := range wantRows {
+		if !slices.Equal(gotRows[i], wantRows[i]) {
+			t.Errorf("adapter %d = %v, want %v", i, gotRows[i], wantRows[i])
+		}
+	}
+	if len(failures) != 1 || failures[0].Host != "DC0_C0_H1" || failures[0].Op != "iscsi" {
+		t.Errorf("failures = %v, want DC0_C0_H1 iscsi", failures)
+	}
+}
+
+func TestTraceSpans(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	c := newClient(t)
+	if _, err := collector.CollectHosts(context.Background(), c.Client, collector.Options{VCenter: "vc1"}); err != nil {
+		t.Fatal(err)
+	}
+	names := make(map[string]int)
+	for _, s := range sr.Ended() {
+		names[s.Name()]++
+	}
+	for _, want := range []string{"Connect", "CreateContainerView", "RetrievePropertiesEx", "vsan"} {
+		if names[want] == 0 {
+			t.Errorf("no %s span in %v", want, names)
+		}
+	}
+}
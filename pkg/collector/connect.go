@@ -0,0 +1,231 @@
+package collector
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/session"
+	"github.com/vmware/govmomi/session/cache"
+	"github.com/vmware/govmomi/session/keepalive"
+	"github.com/vmware/govmomi/sts"
+	"github.com/vmware/govmomi/vapi/rest"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/soap"
+)
+
+// ConnectOptions controls how Connect verifies the vCenter certificate and
+// authenticates.
+type ConnectOptions struct {
+	// Insecure disables certificate verification entirely.
+	Insecure bool
+	// Thumbprint, if set, accepts a certificate with this SHA-1 or SHA-256
+	// fingerprint even if it is not signed by a trusted CA, as govc does.
+	// Hex digits may be separated by colons and are not case-sensitive.
+	Thumbprint string
+	// CACert is a PEM file of CA certificates to trust instead of the
+	// system pool, for vCenters signed by an internal CA. Several files may
+	// be given separated by the OS path list separator (: or ;).
+	CACert string
+	// SessionDir, if set, is a directory where the session cookie is saved,
+	// keyed by URL and user in govc's format, and reused on later runs while
+	// still valid. Callers must not Logout a cached session, or the next run
+	// will have to log in again.
+	SessionDir string
+	// TokenFile is a SAML token (the XML assertion) issued by the vCenter
+	// STS or a federated identity provider. When set, Connect logs in with
+	// LoginByToken instead of a password: as a bearer token, or as a
+	// holder-of-key token when CertFile is also set.
+	TokenFile string
+	// CertFile and KeyFile are a PEM certificate and private key, such as a
+	// solution user's. Without TokenFile, Connect requests a holder-of-key
+	// token for the certificate from the vCenter STS and logs in with it.
+	CertFile string
+	KeyFile  string
+	// Retries is how many times a call that fails with a transient network
+	// error or host communication fault is retried, waiting RetryBackoff
+	// before the first retry and twice as long before each later one.
+	Retries      int
+	RetryBackoff time.Duration
+	// Timeout bounds each HTTP request to vCenter, including login, so a
+	// hung vCenter fails the call instead of blocking forever. A timed-out
+	// call counts as transient and is retried. Zero means no limit.
+	Timeout time.Duration
+	// KeepAlive, if non-zero, sends a request at this interval until the
+	// session is logged out or the stop func Connect returns is called, so
+	// long collections are not logged out by the vCenter idle timeout (30
+	// minutes by default).
+	KeepAlive time.Duration
+}
+
+// TokenAuth reports whether co logs in with a SAML token rather than a
+// password.
+func (co ConnectOptions) TokenAuth() bool {
+	return co.TokenFile != "" || co.CertFile != ""
+}
+
+// credentials loads the token and certificate named by co, if any.
+func (co ConnectOptions) credentials() (token string, cert *tls.Certificate, err error) {
+	if co.TokenFile != "" {
+		b, err := os.ReadFile(co.TokenFile)
+		if err != nil {
+			return "", nil, fmt.Errorf("reading token: %w", err)
+		}
+		token = string(b)
+	}
+	if co.CertFile != "" {
+		c, err := tls.LoadX509KeyPair(co.CertFile, co.KeyFile)
+		if err != nil {
+			return "", nil, fmt.Errorf("loading certificate: %w", err)
+		}
+		cert = &c
+	}
+	return token, cert, nil
+}
+
+// tokenSigner returns the signer for a token login: the given token, or a
+// holder-of-key token issued by the STS for cert when token is empty.
+func tokenSigner(ctx context.Context, vc *vim25.Client, token string, cert *tls.Certificate) (*sts.Signer, error) {
+	if token != "" {
+		return &sts.Signer{Token: token, Certificate: cert}, nil
+	}
+	stsClient, err := sts.NewClient(ctx, vc)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to STS: %w", err)
+	}
+	signer, err := stsClient.Issue(ctx, sts.TokenRequest{Certificate: cert, Delegatable: true, Renewable: true})
+	if err != nil {
+		return nil, fmt.Errorf("requesting token: %w", err)
+	}
+	return signer, nil
+}
+
+// Connect logs in to the vCenter SDK endpoint on host. When done, callers
+// should call Logout on the returned client, unless its session is cached,
+// and always call stop, which ends the keepalive requests of
+// co.KeepAlive: a cached session is not logged out, so nothing else would.
+func Connect(ctx context.Context, host, user, password string, co ConnectOptions) (_ *govmomi.Client, stop func(), err error) {
+	ctx, span := tracer().Start(ctx, "Connect")
+	defer func() { endSpan(span, err) }()
+
+	// Build vCenter SDK URL
+	u, err := url.Parse(fmt.Sprintf("https://%s/sdk", host))
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing URL: %w", err)
+	}
+
+	u.User = url.UserPassword(user, password)
+
+	var tp string
+	if co.Thumbprint != "" {
+		if tp, err = NormalizeThumbprint(co.Thumbprint); err != nil {
+			return nil, nil, err
+		}
+	}
+	token, cert, err := co.credentials()
+	if err != nil {
+		return nil, nil, err
+	}
+	configure := func(sc *soap.Client) error {
+		sc.Timeout = co.Timeout
+		if cert != nil {
+			sc.SetCertificate(*cert)
+		}
+		if co.CACert != "" {
+			if err := sc.SetRootCAs(co.CACert); err != nil {
+				return fmt.Errorf("loading CA certificates: %w", err)
+			}
+		}
+		if tp != "" {
+			sc.SetThumbprint(u.Host, tp)
+		}
+		return nil
+	}
+
+	// Connect and login, or resume a cached session
+	s := &cache.Session{URL: u, Insecure: co.Insecure, DirSOAP: co.SessionDir, Passthrough: co.SessionDir == ""}
+	if co.TokenAuth() {
+		s.LoginSOAP = func(ctx context.Context, vc *vim25.Client) error {
+			signer, err := tokenSigner(ctx, vc, token, cert)
+			if err != nil {
+				return err
+			}
+			// LoginByToken needs the service version in the SOAPAction
+			// header rather than the client's default
+			if vc.Version == vim25.Version {
+				_ = vc.UseServiceVersion()
+			}
+			header := soap.Header{Security: signer}
+			return session.NewManager(vc).LoginByToken(vc.WithHeader(ctx, header))
+		}
+	}
+	vc := new(vim25.Client)
+	if err := s.Login(ctx, vc, configure); err != nil {
+		return nil, nil, fmt.Errorf("connecting to vCenter: %w", err)
+	}
+	slog.Debug("Connected", "vcenter", host, "product", vc.ServiceContent.About.FullName, "api", vc.Version)
+	if slog.Default().Enabled(ctx, LevelTrace) {
+		vc.RoundTripper = &traceRoundTripper{rt: vc.RoundTripper, host: host}
+	}
+	vc.RoundTripper = &spanRoundTripper{rt: vc.RoundTripper}
+	if co.Retries > 0 {
+		vc.RoundTripper = &retryRoundTripper{rt: vc.RoundTripper, host: host, attempts: co.Retries + 1, backoff: co.RetryBackoff}
+	}
+	stop = func() {}
+	if co.KeepAlive > 0 {
+		// Installed after login, so the keepalive timer is started here
+		ka := keepalive.NewHandlerSOAP(vc.RoundTripper, co.KeepAlive, nil)
+		ka.Start()
+		vc.RoundTripper = ka
+		stop = ka.Stop
+	}
+	return &govmomi.Client{Client: vc, SessionManager: session.NewManager(vc)}, stop, nil
+}
+
+// NormalizeThumbprint converts a certificate fingerprint to the uppercase,
+// colon-separated form govmomi compares against. It accepts SHA-1 (20 byte)
+// and SHA-256 (32 byte) fingerprints with or without colons.
+func NormalizeThumbprint(s string) (string, error) {
+	b, err := hex.DecodeString(strings.ReplaceAll(s, ":", ""))
+	if err != nil || (len(b) != 20 && len(b) != 32) {
+		return "", fmt.Errorf("invalid thumbprint %q: want a SHA-1 or SHA-256 fingerprint in hex", s)
+	}
+	parts := make([]string, len(b))
+	for i, c := range b {
+		parts[i] = fmt.Sprintf("%02X", c)
+	}
+	return strings.Join(parts, ":"), nil
+}
+
+// ConnectREST logs in to the vAPI REST endpoint of the vCenter c is
+// connected to, as needed for tag lookups, authenticating the same way as
+// Connect. Callers should call Logout on the returned client when done.
+func ConnectREST(ctx context.Context, c *vim25.Client, user, password string, co ConnectOptions) (*rest.Client, error) {
+	rc := rest.NewClient(c)
+	if !co.TokenAuth() {
+		if err := rc.Login(ctx, url.UserPassword(user, password)); err != nil {
+			return nil, fmt.Errorf("logging in to vAPI: %w", err)
+		}
+		return rc, nil
+	}
+
+	token, cert, err := co.credentials()
+	if err != nil {
+		return nil, err
+	}
+	signer, err := tokenSigner(ctx, c, token, cert)
+	if err != nil {
+		return nil, err
+	}
+	if err := rc.LoginByToken(rc.WithSigner(ctx, signer)); err != nil {
+		return nil, fmt.Errorf("logging in to vAPI: %w", err)
+	}
+	return rc, nil
+}
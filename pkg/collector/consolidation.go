@@ -0,0 +1,100 @@
+package collector
+
+// HostDensity is the VM load on one host.
+type HostDensity struct {
+	Host
+	VMs          int // registered on the host, powered on or not
+	PoweredOnVMs int
+	VCPUs        int     // of the powered-on VMs
+	VMMemoryGB   float64 // configured memory of the powered-on VMs
+	ReservedGB   float64 // memory reserved by the powered-on VMs
+}
+
+// VCPURatio returns the vCPUs of d's powered-on VMs per physical core, or
+// 0 if the host's cores are not known.
+func (d HostDensity) VCPURatio() float64 { return ratio(d.VCPUs, d.TotalCores) }
+
+// MemoryRatio returns the configured memory of d's powered-on VMs per GB of
+// physical memory; above 1 the host's memory is overcommitted.
+func (d HostDensity) MemoryRatio() float64 { return memoryRatio(d.VMMemoryGB, d.MemoryGB) }
+
+// ClusterDensity is the VM load on one cluster.
+type ClusterDensity struct {
+	Cluster
+	VMs          int
+	PoweredOnVMs int
+	VCPUs        int
+	VMMemoryGB   float64
+	ReservedGB   float64
+}
+
+// VCPURatio returns the vCPUs of c's powered-on VMs per physical core.
+func (c ClusterDensity) VCPURatio() float64 { return ratio(c.VCPUs, c.TotalCores) }
+
+// VMsPerHost returns the average number of VMs on c's hosts.
+func (c ClusterDensity) VMsPerHost() float64 { return ratio(c.VMs, c.Hosts) }
+
+// MemoryRatio returns the configured memory of c's powered-on VMs per GB of
+// physical memory.
+func (c ClusterDensity) MemoryRatio() float64 { return memoryRatio(c.VMMemoryGB, c.MemoryGB) }
+
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return 0
+	}
+	return float64(n) / float64(d)
+}
+
+func memoryRatio(vmGB float64, physicalGB int64) float64 {
+	if physicalGB == 0 {
+		return 0
+	}
+	return vmGB / float64(physicalGB)
+}
+
+// Consolidation returns the VM load on each of hosts and on each cluster,
+// from vms collected with the same Anonymizer so their host names match.
+// VMs on hosts that are not in hosts, such as those of a host filtered
+// out, are not counted.
+func Consolidation(hosts []Host, vms []VM) ([]HostDensity, []ClusterDensity) {
+	type key struct{ vcenter, name string }
+	byHost := make(map[key]*HostDensity, len(hosts))
+	densities := make([]HostDensity, len(hosts))
+	for i, h := range hosts {
+		densities[i].Host = h
+		byHost[key{h.VCenter, h.Hostname}] = &densities[i]
+	}
+	for _, v := range vms {
+		d, ok := byHost[key{v.VCenter, v.Host}]
+		if !ok {
+			continue
+		}
+		d.VMs++
+		if v.PoweredOn() {
+			d.PoweredOnVMs++
+			d.VCPUs += v.VCPUs
+			d.VMMemoryGB += v.MemoryGB
+			d.ReservedGB += v.MemoryReservationGB
+		}
+	}
+
+	clusters := RollupClusters(hosts)
+	byCluster := make(map[key]*ClusterDensity, len(clusters))
+	rollup := make([]ClusterDensity, len(clusters))
+	for i, c := range clusters {
+		rollup[i].Cluster = c
+		byCluster[key{c.VCenter, c.Cluster}] = &rollup[i]
+	}
+	for _, d := range densities {
+		c := byCluster[key{d.VCenter, d.Cluster}]
+		c.VMs += d.VMs
+		c.PoweredOnVMs += d.PoweredOnVMs
+		c.VCPUs += d.VCPUs
+		c.VMMemoryGB += d.VMMemoryGB
+		c.ReservedGB += d.ReservedGB
+	}
+	return densities, rollup
+}
+
+// PoweredOn reports whether v is running.
+func (v VM) PoweredOn() bool { return v.PowerState == "poweredOn" }
@@ -0,0 +1,102 @@
+package collector
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// cpuFeatureFlags are the CPUID feature flags reported in Host.CPUFeatures,
+// those EVC baselines and guest workloads most often hinge on, with the
+// leaf, register, and bit they are read from.
+var cpuFeatureFlags = []struct {
+	name     string
+	leaf     int32
+	register string // ecx, ebx, or edx
+	bit      uint
+}{
+	{"SSE4.2", 1, "ecx", 20},
+	{"AES-NI", 1, "ecx", 25},
+	{"AVX", 1, "ecx", 28},
+	{"AVX2", 7, "ebx", 5},
+	{"AVX-512F", 7, "ebx", 16},
+	{"SHA", 7, "ebx", 29},
+	{"AMX", 7, "edx", 24},
+}
+
+// cpuIdentity is a host's processor as identified by CPUID.
+type cpuIdentity struct {
+	family, model, stepping int
+	features                []string
+}
+
+// hostCPUIdentity returns the family, model, stepping, and feature flags
+// of h's processors. The numbers are taken from hardware.cpuPkg where
+// vCenter reports them (8.0.3 and later) and decoded from CPUID leaf 1
+// otherwise.
+func hostCPUIdentity(h mo.HostSystem) cpuIdentity {
+	var id cpuIdentity
+	if h.Hardware == nil {
+		return id
+	}
+	regs := make(map[int32]types.HostCpuIdInfo)
+	for _, info := range h.Hardware.CpuFeature {
+		regs[info.Level] = info
+	}
+	if len(h.Hardware.CpuPkg) > 0 {
+		pkg := h.Hardware.CpuPkg[0]
+		id.family, id.model, id.stepping = int(pkg.Family), int(pkg.Model), int(pkg.Stepping)
+		for _, info := range pkg.CpuFeature {
+			if _, ok := regs[info.Level]; !ok {
+				regs[info.Level] = info
+			}
+		}
+	}
+	if leaf1, ok := regs[1]; ok && id.family == 0 {
+		if eax, ok := cpuRegister(leaf1.Eax); ok {
+			id.family, id.model, id.stepping = decodeSignature(eax)
+		}
+	}
+	for _, f := range cpuFeatureFlags {
+		info, ok := regs[f.leaf]
+		if !ok {
+			continue
+		}
+		value := info.Ecx
+		switch f.register {
+		case "ebx":
+			value = info.Ebx
+		case "edx":
+			value = info.Edx
+		}
+		if r, ok := cpuRegister(value); ok && r&(1<<f.bit) != 0 {
+			id.features = append(id.features, f.name)
+		}
+	}
+	return id
+}
+
+// cpuRegister parses a CPUID register as vCenter writes it, 32 bits most
+// significant first in groups of four, e.g. "0000:0000:0000:0010:...".
+func cpuRegister(s string) (uint32, bool) {
+	v, err := strconv.ParseUint(strings.ReplaceAll(s, ":", ""), 2, 32)
+	return uint32(v), err == nil
+}
+
+// decodeSignature returns the display family, model, and stepping of the
+// processor signature in EAX of CPUID leaf 1, with the extended family and
+// model folded in as Intel and AMD specify.
+func decodeSignature(eax uint32) (family, model, stepping int) {
+	stepping = int(eax & 0xf)
+	model = int(eax >> 4 & 0xf)
+	family = int(eax >> 8 & 0xf)
+	if family == 0xf {
+		family += int(eax >> 20 & 0xff)
+	}
+	if family == 0x6 || family >= 0xf {
+		model += int(eax>>16&0xf) << 4
+	}
+	return family, model, stepping
+}
@@ -0,0 +1,190 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// Datastore is the capacity inventory collected for a single datastore.
+type Datastore struct {
+	VCenter     string
+	Name        string
+	Type        string
+	VMFSVersion string // e.g. "6.82", empty if not VMFS
+	VMFSMajor   int    // 5 or 6, 0 if not VMFS
+	CapacityGB  float64
+	FreeGB      float64
+	Hosts       int      // mounting it; only selected ones with host filters
+	Clusters    []string // of the hosts mounting it, sorted
+}
+
+// VMFS5 reports whether d is a VMFS-5 datastore, which cannot be upgraded
+// in place and lacks VMFS-6 features such as automatic space reclamation
+// and 4Kn disk support.
+func (d Datastore) VMFS5() bool { return d.VMFSMajor == 5 }
+
+// DatastoreCluster is the VMFS versions of the datastores mounted by the
+// hosts of a cluster.
+type DatastoreCluster struct {
+	VCenter         string
+	Cluster         string
+	Datastores      int
+	VMFS6           int
+	VMFS5           int
+	VMFS5CapacityGB float64
+}
+
+// RollupDatastoreClusters counts datastores per cluster mounting them,
+// sorted by vCenter and cluster name. A datastore mounted by several
+// clusters counts in each; those mounted by no host are grouped under an
+// empty name.
+func RollupDatastoreClusters(datastores []Datastore) []DatastoreCluster {
+	type key struct{ vcenter, cluster string }
+	byName := make(map[key]*DatastoreCluster)
+	var names []key
+	for _, d := range datastores {
+		clusters := d.Clusters
+		if len(clusters) == 0 {
+			clusters = []string{""}
+		}
+		for _, cluster := range clusters {
+			k := key{d.VCenter, cluster}
+			c, ok := byName[k]
+			if !ok {
+				c = &DatastoreCluster{VCenter: d.VCenter, Cluster: cluster}
+				byName[k] = c
+				names = append(names, k)
+			}
+			c.Datastores++
+			switch d.VMFSMajor {
+			case 6:
+				c.VMFS6++
+			case 5:
+				c.VMFS5++
+				c.VMFS5CapacityGB += d.CapacityGB
+			}
+		}
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		if names[i].vcenter != names[j].vcenter {
+			return names[i].vcenter < names[j].vcenter
+		}
+		return names[i].cluster < names[j].cluster
+	})
+	clusters := make([]DatastoreCluster, 0, len(names))
+	for _, n := range names {
+		clusters = append(clusters, *byName[n])
+	}
+	return clusters
+}
+
+// datastoreTypes maps summary.type values to their product names.
+var datastoreTypes = map[string]string{
+	"vsan":  "vSAN",
+	"VVOL":  "vVol",
+	"NFS41": "NFS 4.1",
+	"PMEM":  "PMem",
+}
+
+// CollectDatastores retrieves type, VMFS version, capacity, and host
+// attachment for every datastore visible to c, with the clusters of the
+// hosts mounting it. With a Clusters or Tags filter only datastores mounted
+// by at least one selected host are returned, and only the selected hosts
+// are counted and their clusters listed.
+func CollectDatastores(ctx context.Context, c *vim25.Client, opts Options) ([]Datastore, error) {
+	root, err := containerRoot(ctx, c, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := view.NewManager(c).CreateContainerView(ctx, root, []string{"Datastore"}, true)
+	if err != nil {
+		return nil, fmt.Errorf("creating container view: %w", err)
+	}
+	defer destroyView(ctx, v)
+
+	var datastores []mo.Datastore
+	err = v.Retrieve(ctx, []string{"Datastore"}, []string{"summary", "host", "info"}, &datastores)
+	if err != nil {
+		return nil, fmt.Errorf("retrieving datastores: %w", err)
+	}
+	selected, err := selectedHosts(ctx, c, root, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	anon := opts.anonymizer()
+	vcenter := anon.vcenter(opts.VCenter)
+	records := make([]Datastore, 0, len(datastores))
+	for _, ds := range datastores {
+		if opts.filtered() && !mountedBy(ds, selected) {
+			continue
+		}
+		dsType := ds.Summary.Type
+		if name, ok := datastoreTypes[dsType]; ok {
+			dsType = name
+		}
+		d := Datastore{
+			VCenter:    vcenter,
+			Name:       anon.datastore(opts.VCenter, ds.Summary.Name),
+			Type:       dsType,
+			CapacityGB: float64(ds.Summary.Capacity) / (1024 * 1024 * 1024),
+			FreeGB:     float64(ds.Summary.FreeSpace) / (1024 * 1024 * 1024),
+		}
+		if info, ok := ds.Info.(*types.VmfsDatastoreInfo); ok && info.Vmfs != nil {
+			d.VMFSVersion = info.Vmfs.Version
+			d.VMFSMajor = int(info.Vmfs.MajorVersion)
+		}
+		for _, mount := range ds.Host {
+			name, ok := selected[mount.Key.Value]
+			if !ok {
+				continue
+			}
+			d.Hosts++
+			if name == "" {
+				continue
+			}
+			if name = anon.cluster(opts.VCenter, name); !slices.Contains(d.Clusters, name) {
+				d.Clusters = append(d.Clusters, name)
+			}
+		}
+		sort.Strings(d.Clusters)
+		records = append(records, d)
+	}
+	return records, nil
+}
+
+// selectedHosts returns the hosts under root that pass the host filters,
+// keyed by MoRef value, with the names of their clusters: empty for a host
+// whose cluster name could not be retrieved.
+func selectedHosts(ctx context.Context, c *vim25.Client, root types.ManagedObjectReference, opts Options) (map[string]string, error) {
+	hosts, parentNames, err := filteredHosts(ctx, c, root, opts, []string{"summary.runtime", "parent"})
+	if err != nil {
+		return nil, err
+	}
+	selected := make(map[string]string, len(hosts))
+	for _, h := range hosts {
+		selected[h.Self.Value] = ""
+		if h.Parent != nil {
+			selected[h.Self.Value] = parentNames[h.Parent.Value]
+		}
+	}
+	return selected, nil
+}
+
+func mountedBy(ds mo.Datastore, hosts map[string]string) bool {
+	for _, mount := range ds.Host {
+		if _, ok := hosts[mount.Key.Value]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package collector
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Entitlement is a quantity of licenses owned, from an entitlement file.
+type Entitlement struct {
+	SKU      string
+	Product  string // a key of EntitlementProducts
+	Quantity int
+}
+
+// EntitlementProduct is what an entitlement licenses.
+type EntitlementProduct struct {
+	Name string
+	Unit string // what quantities count
+}
+
+// EntitlementProducts are the products an entitlement may be for, by short
+// name.
+var EntitlementProducts = map[string]EntitlementProduct{
+	"vcf":  {"VCF", "cores"},
+	"vvf":  {"VVF", "cores"},
+	"vsan": {"vSAN add-on", "TiB"},
+	"cpu":  {"Per-CPU", "CPUs"}, // perpetual vSphere licenses
+}
+
+// entitlementProductOrder is the order of the entitlement report's rows.
+var entitlementProductOrder = []string{"vcf", "vvf", "vsan", "cpu"}
+
+// skuProducts maps SKU prefixes to the product they license, for entitlement
+// files without a Product column.
+var skuProducts = []struct{ prefix, product string }{
+	{"VCF-CLD-FND", "vcf"},
+	{"VCF-VSP-FND", "vvf"},
+	{"VCF-VSAN", "vsan"},
+}
+
+// ReadEntitlements reads an entitlement file: a CSV file with a header row
+// naming a SKU and a Quantity column, and optionally a Product column (vcf,
+// vvf, vsan, or cpu) for SKUs whose product cannot be told from their
+// prefix. Column names are matched case-insensitively.
+func ReadEntitlements(path string) ([]Entitlement, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.TrimLeadingSpace = true
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	cols := map[string]int{"sku": -1, "quantity": -1, "product": -1}
+	for i, name := range header {
+		if _, ok := cols[strings.ToLower(name)]; ok {
+			cols[strings.ToLower(name)] = i
+		}
+	}
+	if cols["sku"] < 0 || cols["quantity"] < 0 {
+		return nil, fmt.Errorf("header needs SKU and Quantity columns")
+	}
+
+	var ents []Entitlement
+	for line := 2; ; line++ {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return ents, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		e := Entitlement{SKU: rec[cols["sku"]]}
+		if e.Quantity, err = strconv.Atoi(rec[cols["quantity"]]); err != nil || e.Quantity < 0 {
+			return nil, fmt.Errorf("line %d: invalid quantity %q", line, rec[cols["quantity"]])
+		}
+		if i := cols["product"]; i >= 0 {
+			e.Product = strings.ToLower(rec[i])
+		}
+		if e.Product == "" {
+			e.Product = skuProduct(e.SKU)
+		}
+		if _, ok := EntitlementProducts[e.Product]; !ok {
+			return nil, fmt.Errorf("line %d: unknown product of SKU %q; add a Product column of vcf, vvf, vsan, or cpu", line, e.SKU)
+		}
+		ents = append(ents, e)
+	}
+}
+
+// skuProduct returns the product a SKU licenses, or "" if it is not known.
+func skuProduct(sku string) string {
+	for _, p := range skuProducts {
+		if strings.HasPrefix(strings.ToUpper(sku), p.prefix) {
+			return p.product
+		}
+	}
+	return ""
+}
+
+// EntitlementGap compares what a product is deployed on with what is owned.
+type EntitlementGap struct {
+	EntitlementProduct
+	Deployed int
+	Entitled int
+}
+
+// Gap returns the entitled quantity left over, negative for a shortfall.
+func (g EntitlementGap) Gap() int { return g.Entitled - g.Deployed }
+
+// Shortfall reports whether more is deployed than is entitled.
+func (g EntitlementGap) Shortfall() bool { return g.Gap() < 0 }
+
+// CompareEntitlements returns the gap for each product that is entitled or
+// deployed in t licensed under e: the edition's cores and vSAN add-on, and
+// per-CPU licenses if any are entitled. Other editions are only listed if
+// entitled, as deployed on nothing.
+func CompareEntitlements(t LicenseTotal, e Edition, ents []Entitlement) []EntitlementGap {
+	entitled := make(map[string]int)
+	for _, ent := range ents {
+		entitled[ent.Product] += ent.Quantity
+	}
+	deployed := map[string]int{
+		strings.ToLower(e.Name): t.LicenseCores,
+		"vsan":                  int(math.Ceil(t.VsanAddOnTiB)),
+	}
+	if _, ok := entitled["cpu"]; ok {
+		deployed["cpu"] = t.CPULicenses
+	}
+	var gaps []EntitlementGap
+	for _, p := range entitlementProductOrder {
+		d, isDeployed := deployed[p]
+		n, isEntitled := entitled[p]
+		if !isEntitled && (!isDeployed || d == 0 && p == "vsan") {
+			continue
+		}
+		gaps = append(gaps, EntitlementGap{EntitlementProducts[p], d, n})
+	}
+	return gaps
+}
@@ -0,0 +1,154 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+)
+
+// VsanUsage is the capacity and free space of a cluster's vSAN datastore.
+type VsanUsage struct {
+	VCenter    string
+	Cluster    string
+	CapacityGB float64
+	FreeGB     float64
+}
+
+// CollectVsanUsage retrieves the capacity and free space of the vSAN
+// datastore of each cluster visible to c that passes the Clusters filter.
+// Clusters without vSAN are left out.
+func CollectVsanUsage(ctx context.Context, c *vim25.Client, opts Options) ([]VsanUsage, error) {
+	root, err := containerRoot(ctx, c, opts)
+	if err != nil {
+		return nil, err
+	}
+	v, err := view.NewManager(c).CreateContainerView(ctx, root, []string{"ClusterComputeResource"}, true)
+	if err != nil {
+		return nil, fmt.Errorf("creating cluster container view: %w", err)
+	}
+	defer destroyView(ctx, v)
+
+	var clusters []mo.ClusterComputeResource
+	if err := v.Retrieve(ctx, []string{"ClusterComputeResource"}, []string{"name", "datastore"}, &clusters); err != nil {
+		return nil, fmt.Errorf("retrieving clusters: %w", err)
+	}
+	var usage []VsanUsage
+	anon := opts.anonymizer()
+	pc := property.DefaultCollector(c)
+	for _, cl := range clusters {
+		if !opts.matchCluster(cl.Name) || len(cl.Datastore) == 0 {
+			continue
+		}
+		var datastores []mo.Datastore
+		if err := pc.Retrieve(ctx, cl.Datastore, []string{"summary"}, &datastores); err != nil {
+			return nil, fmt.Errorf("retrieving datastores of cluster %s: %w", cl.Name, err)
+		}
+		for _, ds := range datastores {
+			if ds.Summary.Type != "vsan" {
+				continue
+			}
+			usage = append(usage, VsanUsage{
+				VCenter:    anon.vcenter(opts.VCenter),
+				Cluster:    anon.cluster(opts.VCenter, cl.Name),
+				CapacityGB: float64(ds.Summary.Capacity) / (1024 * 1024 * 1024),
+				FreeGB:     float64(ds.Summary.FreeSpace) / (1024 * 1024 * 1024),
+			})
+		}
+	}
+	return usage, nil
+}
+
+// Targets are the highest utilization to plan for, in percent of capacity.
+type Targets struct {
+	CPU    float64
+	Memory float64
+	Vsan   float64
+}
+
+// ClusterHeadroom compares a cluster's use of CPU, memory, and vSAN with
+// its capacity and the utilization targets.
+type ClusterHeadroom struct {
+	Cluster
+	VsanCapacityGB float64 // of the vSAN datastore, 0 without vSAN
+	VsanUsedGB     float64
+	Targets        Targets
+}
+
+// CPUUsedPct returns the CPU in use as a percentage of capacity.
+func (c ClusterHeadroom) CPUUsedPct() float64 {
+	return percent(float64(c.CPUUsageMHz), float64(c.CPUCapacityMHz))
+}
+
+// MemoryUsedPct returns the memory in use as a percentage of capacity.
+func (c ClusterHeadroom) MemoryUsedPct() float64 {
+	return percent(c.MemoryUsageGB, float64(c.MemoryGB))
+}
+
+// VsanUsedPct returns the vSAN datastore space in use as a percentage of
+// its capacity.
+func (c ClusterHeadroom) VsanUsedPct() float64 { return percent(c.VsanUsedGB, c.VsanCapacityGB) }
+
+// CPUHeadroomMHz returns the CPU that can be added before the CPU target
+// is reached, negative if it is exceeded.
+func (c ClusterHeadroom) CPUHeadroomMHz() int {
+	return int(float64(c.CPUCapacityMHz)*c.Targets.CPU/100) - c.CPUUsageMHz
+}
+
+// MemoryHeadroomGB returns the memory that can be added before the memory
+// target is reached, negative if it is exceeded.
+func (c ClusterHeadroom) MemoryHeadroomGB() float64 {
+	return float64(c.MemoryGB)*c.Targets.Memory/100 - c.MemoryUsageGB
+}
+
+// VsanHeadroomGB returns the vSAN space that can be used before the vSAN
+// target is reached, negative if it is exceeded.
+func (c ClusterHeadroom) VsanHeadroomGB() float64 {
+	return c.VsanCapacityGB*c.Targets.Vsan/100 - c.VsanUsedGB
+}
+
+// Over returns the resources whose target c exceeds: CPU, Memory, and vSAN.
+func (c ClusterHeadroom) Over() []string {
+	var over []string
+	if c.CPUCapacityMHz > 0 && c.CPUHeadroomMHz() < 0 {
+		over = append(over, "CPU")
+	}
+	if c.MemoryGB > 0 && c.MemoryHeadroomGB() < 0 {
+		over = append(over, "Memory")
+	}
+	if c.VsanCapacityGB > 0 && c.VsanHeadroomGB() < 0 {
+		over = append(over, "vSAN")
+	}
+	return over
+}
+
+func percent(used, capacity float64) float64 {
+	if capacity == 0 {
+		return 0
+	}
+	return used / capacity * 100
+}
+
+// Headroom returns the headroom of each cluster against t, with the vSAN
+// usage of the clusters in vsan.
+func Headroom(clusters []Cluster, vsan []VsanUsage, t Targets) []ClusterHeadroom {
+	type key struct{ vcenter, cluster string }
+	byCluster := make(map[key]VsanUsage, len(vsan))
+	for _, u := range vsan {
+		byCluster[key{u.VCenter, u.Cluster}] = u
+	}
+	headroom := make([]ClusterHeadroom, len(clusters))
+	for i, c := range clusters {
+		u := byCluster[key{c.VCenter, c.Cluster}]
+		headroom[i] = ClusterHeadroom{
+			Cluster:        c,
+			VsanCapacityGB: u.CapacityGB,
+			VsanUsedGB:     u.CapacityGB - u.FreeGB,
+			Targets:        t,
+		}
+	}
+	return headroom
+}
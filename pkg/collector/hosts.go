@@ -0,0 +1,804 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Host is the inventory collected for a single ESXi host.
+type Host struct {
+	VCenter             string
+	Hostname            string
+	Cluster             string
+	ServerModel         string
+	ESXiVersion         string
+	ESXiBuild           string // e.g. "22380479"
+	ImageProfile        string // installed image profile, if known and Options.ImageProfile is set
+	CPUModel            string
+	Sockets             int
+	CoresPerSocket      int
+	TotalCores          int
+	MemoryGB            int64
+	VsanType            string
+	VsanCapacityDisks   int
+	VsanCacheDisks      int
+	VsanCapacityTiB     float64
+	ConnectionState     string
+	InMaintenanceMode   bool
+	PowerState          string // poweredOn, poweredOff, standBy, or unknown
+	BIOSUUID            string // hardware.systemInfo.uuid
+	Vendor              string // server manufacturer
+	SerialNumber        string // chassis serial or service tag
+	AssetTag            string // set in the BIOS by the owner, if any
+	BIOSVersion         string // hardware.biosInfo
+	BIOSReleaseDate     string // YYYY-MM-DD
+	FirmwareVersion     string // of the embedded controller, often the BMC
+	CPUFamily           int    // CPUID display family, e.g. 6
+	CPUModelNumber      int    // CPUID display model, e.g. 143
+	CPUStepping         int    // CPUID stepping
+	CPUFeatures         string // e.g. "SSE4.2, AES-NI, AVX, AVX2"
+	MaxEVCMode          string // most capable EVC mode the host supports
+	CurrentEVCMode      string // EVC mode in effect, empty without EVC
+	Hyperthreading      string // "active", "inactive", or "unavailable"
+	CPUThreads          int    // logical processors, cores if not hyperthreaded
+	NUMANodes           int
+	CoresPerNUMANode    int          // of the smallest node
+	MemoryPerNUMANodeGB int64        // of the smallest node
+	NICs                int          // physical network adapters
+	GPUs                int          // NVIDIA and AMD display devices and 3D controllers
+	GPUModels           string       // distinct models, each with its vendor
+	CPUCapacityMHz      int          // core speed times cores
+	CPUUsageMHz         int          // from summary.quickStats, as of collection
+	MemoryUsageGB       float64      // from summary.quickStats, as of collection
+	UptimeSeconds       int          // from summary.quickStats, as of collection
+	BootTime            time.Time    // zero if vCenter does not know it
+	LockdownMode        string       // "disabled", "normal", or "strict"
+	SSHRunning          *bool        // nil if the host reports no services
+	ESXiShellRunning    *bool        // nil if the host reports no services
+	TPMVersion          string       // e.g. "2.0", "none" without a TPM, empty if unknown
+	TPMAttestation      string       // "accepted" or "notAccepted", empty if not attested
+	SecureBoot          *bool        // UEFI Secure Boot at boot; nil before vSphere 8.0 Update 3
+	CertificateExpiry   time.Time    // of the management certificate; zero if unknown
+	CertificateStatus   string       // "valid", "expiring", or "expired"; empty if unknown
+	PowerPolicy         string       // e.g. "High Performance"; empty if unknown
+	Utilization         *Utilization // nil unless Options.Utilization is set
+}
+
+// Cluster aggregates the hosts of one cluster.
+type Cluster struct {
+	VCenter         string
+	Cluster         string
+	Hosts           int
+	Sockets         int
+	TotalCores      int
+	LicenseCores    int // see Host.LicenseCores
+	CPULicenses     int // see Host.CPULicenses
+	MemoryGB        int64
+	VsanCapacityTiB float64
+	ESXiVersions    map[string]int // version -> host count
+	CPUCapacityMHz  int
+	CPUUsageMHz     int
+	MemoryUsageGB   float64
+}
+
+// VersionSpread renders ESXi version counts as "7.0.3 (2), 8.0.2 (5)".
+func (c Cluster) VersionSpread() string {
+	versions := make([]string, 0, len(c.ESXiVersions))
+	for v := range c.ESXiVersions {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+	parts := make([]string, len(versions))
+	for i, v := range versions {
+		name := v
+		if name == "" {
+			name = "unknown"
+		}
+		parts[i] = fmt.Sprintf("%s (%d)", name, c.ESXiVersions[v])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// RollupClusters sums hosts per cluster, sorted by vCenter and cluster name.
+// Hosts outside a cluster are grouped under an empty name.
+func RollupClusters(hosts []Host) []Cluster {
+	type key struct{ vcenter, cluster string }
+	byName := make(map[key]*Cluster)
+	var names []key
+	for _, h := range hosts {
+		k := key{h.VCenter, h.Cluster}
+		c, ok := byName[k]
+		if !ok {
+			c = &Cluster{VCenter: h.VCenter, Cluster: h.Cluster, ESXiVersions: make(map[string]int)}
+			byName[k] = c
+			names = append(names, k)
+		}
+		c.Hosts++
+		c.Sockets += h.Sockets
+		c.TotalCores += h.TotalCores
+		c.LicenseCores += h.LicenseCores()
+		c.CPULicenses += h.CPULicenses()
+		c.MemoryGB += h.MemoryGB
+		c.VsanCapacityTiB += h.VsanCapacityTiB
+		c.ESXiVersions[h.ESXiVersion]++
+		c.CPUCapacityMHz += h.CPUCapacityMHz
+		c.CPUUsageMHz += h.CPUUsageMHz
+		c.MemoryUsageGB += h.MemoryUsageGB
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		if names[i].vcenter != names[j].vcenter {
+			return names[i].vcenter < names[j].vcenter
+		}
+		return names[i].cluster < names[j].cluster
+	})
+	clusters := make([]Cluster, 0, len(names))
+	for _, n := range names {
+		clusters = append(clusters, *byName[n])
+	}
+	return clusters
+}
+
+// CollectHosts retrieves the inventory of every host visible to c,
+// including vSAN disk capacity. Hosts excluded by the Clusters filter are
+// skipped before any vSAN queries are made.
+func CollectHosts(ctx context.Context, c *vim25.Client, opts Options) ([]Host, error) {
+	root, err := containerRoot(ctx, c, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a container view of all HostSystem objects
+	m := view.NewManager(c)
+	v, err := m.CreateContainerView(ctx, root, []string{"HostSystem"}, true)
+	if err != nil {
+		return nil, fmt.Errorf("creating container view: %w", err)
+	}
+	defer destroyView(ctx, v)
+
+	// Retrieve host summary, hardware, and configManager properties
+	props := []string{"summary", "hardware", "capability.tpmSupported", "capability.tpmVersion", "config.hyperThread", "config.lockdownMode", "config.powerSystemInfo", "config.service", "configManager", "parent"}
+	if apiAtLeast(c, "8.0.3") {
+		// Older vCenters fail the whole retrieval on a property they lack
+		props = append(props, "capability.uefiSecureBoot")
+	}
+	var hosts []mo.HostSystem
+	err = v.Retrieve(ctx, []string{"HostSystem"}, props, &hosts)
+	if err != nil {
+		return nil, fmt.Errorf("retrieving hosts: %w", err)
+	}
+
+	pc := property.DefaultCollector(c)
+	parentNames := retrieveParentNames(ctx, pc, hosts, opts)
+	tagged, err := taggedHosts(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	selected := filterHosts(hosts, parentNames, tagged, opts)
+	slog.Debug("Selected hosts", "vcenter", opts.VCenter, "hosts", len(hosts), "selected", len(selected))
+	hosts = selected
+
+	vsanSystems := retrieveVsanSystems(ctx, pc, hosts)
+	certManagers := retrieveCertificateManagers(ctx, pc, hosts)
+	now := time.Now()
+
+	var utilization map[string]Utilization
+	var utilizationErr error // reported for each host
+	if opts.Utilization > 0 {
+		utilization, utilizationErr = hostUtilization(ctx, c, hosts, opts.Utilization)
+	}
+
+	// Records are built in inventory order as the hosts before them
+	// finish, so anonymized labels follow inventory order as they do in
+	// the other reports, and OnHost sees each host as early as it can
+	anon := opts.anonymizer()
+	records := make([]Host, len(hosts))
+	infos := make([]*vsanHostInfo, len(hosts))
+	errs := make([]error, len(hosts))
+	profiles := make([]string, len(hosts))
+	profileErrs := make([]error, len(hosts))
+	expiries := make([]time.Time, len(hosts))
+	certErrs := make([]error, len(hosts))
+	finished := make([]bool, len(hosts))
+	next := 0
+	var mu sync.Mutex
+	complete := func(i int) {
+		mu.Lock()
+		defer mu.Unlock()
+		finished[i] = true
+		for ; next < len(hosts) && finished[next]; next++ {
+			if ctx.Err() != nil {
+				return
+			}
+			h := hosts[next]
+			parent := ""
+			if h.Parent != nil {
+				parent = parentNames[h.Parent.Value]
+			}
+			records[next] = hostRecord(h, parent, infos[next], anon, opts.VCenter)
+			if u, ok := utilization[h.Self.Value]; ok {
+				records[next].Utilization = &u
+			}
+			records[next].ImageProfile = profiles[next]
+			if notAfter := expiries[next]; !notAfter.IsZero() {
+				records[next].CertificateExpiry = notAfter
+				records[next].CertificateStatus = certificateStatus(notAfter, now, opts.CertificateWarning)
+			}
+			if utilizationErr != nil {
+				opts.fail(h.Summary.Config.Name, "perf", utilizationErr)
+			}
+			if err := profileErrs[next]; err != nil {
+				opts.fail(h.Summary.Config.Name, "imageProfile", err)
+			}
+			if err := certErrs[next]; err != nil {
+				opts.fail(h.Summary.Config.Name, "certificate", err)
+			}
+			if err := errs[next]; err != nil {
+				opts.fail(h.Summary.Config.Name, "vsan", err)
+			}
+			if opts.OnHost != nil {
+				opts.OnHost(records[next])
+			}
+		}
+	}
+
+	// Query each host's image profile, retrieve certificates the batch
+	// missed, and derive its vSAN disk info, several hosts at a time since
+	// ESA hosts need a disk query each
+	done := opts.tracker(len(hosts))
+	parallel(len(hosts), opts.Concurrency, func(i int) {
+		defer done()
+		defer complete(i)
+		h := hosts[i]
+		if opts.ImageProfile {
+			profiles[i], profileErrs[i] = imageProfile(ctx, c, h)
+		}
+		if ref := h.ConfigManager.CertificateManager; ref != nil {
+			m, ok := certManagers[ref.Value]
+			if !ok {
+				// Not returned by the batch retrieval; try this host alone
+				if err := pc.RetrieveOne(ctx, *ref, []string{"certificateInfo"}, &m); err != nil {
+					certErrs[i] = fmt.Errorf("could not retrieve certificate: %w", err)
+				}
+			}
+			if notAfter := m.CertificateInfo.NotAfter; notAfter != nil {
+				expiries[i] = *notAfter
+			}
+		}
+		ref := h.ConfigManager.VsanSystem
+		if ref == nil {
+			return
+		}
+		ctx, span := tracer().Start(ctx, "vsan", trace.WithAttributes(attribute.String("vsphere.host", h.Self.Value)))
+		defer func() { endSpan(span, errs[i]) }()
+		vsanSys, ok := vsanSystems[ref.Value]
+		if !ok {
+			// Not returned by the batch retrieval; try this host alone
+			if err := pc.RetrieveOne(ctx, *ref, nil, &vsanSys); err != nil {
+				errs[i] = fmt.Errorf("could not retrieve vSAN config: %w", err)
+				return
+			}
+		}
+		infos[i], errs[i] = vsanInfo(ctx, c, h.Summary.Config.Name, vsanSys, opts.Debug)
+	})
+	if err := ctx.Err(); err != nil {
+		// Every remaining host failed with the cancellation; don't warn
+		// about each
+		return nil, err
+	}
+	return records, nil
+}
+
+// imageProfile returns the name of the image profile installed on h, or ""
+// if h is not connected or has no image configuration manager.
+func imageProfile(ctx context.Context, c *vim25.Client, h mo.HostSystem) (string, error) {
+	ref := h.ConfigManager.ImageConfigManager
+	if ref == nil || h.Summary.Runtime == nil || h.Summary.Runtime.ConnectionState != types.HostSystemConnectionStateConnected {
+		return "", nil
+	}
+	res, err := methods.HostImageConfigGetProfile(ctx, c, &types.HostImageConfigGetProfile{This: *ref})
+	if err != nil {
+		return "", fmt.Errorf("could not query image profile: %w", err)
+	}
+	return res.Returnval.Name, nil
+}
+
+// hostRecord builds the record of h, in cluster parent of vCenter vc,
+// labelling its names with anon.
+func hostRecord(h mo.HostSystem, parent string, vsan *vsanHostInfo, anon *Anonymizer, vc string) Host {
+	vcenter := anon.vcenter(vc)
+	hostname := anon.host(h.Summary.Config.Name)
+
+	cluster := ""
+	if parent != "" {
+		cluster = anon.cluster(vc, parent)
+	}
+
+	serverModel, vendor := "", ""
+	nics := 0
+	if h.Summary.Hardware != nil {
+		serverModel = h.Summary.Hardware.Model
+		vendor = h.Summary.Hardware.Vendor
+		nics = int(h.Summary.Hardware.NumNics)
+	}
+	gpuCount, gpuModels := gpus(h)
+
+	esxiVersion, esxiBuild := "", ""
+	if h.Summary.Config.Product != nil {
+		esxiVersion = h.Summary.Config.Product.Version
+		esxiBuild = h.Summary.Config.Product.Build
+	}
+
+	cpuModel := ""
+	if h.Hardware != nil && len(h.Hardware.CpuPkg) > 0 {
+		cpuModel = h.Hardware.CpuPkg[0].Description
+	}
+
+	biosUUID := ""
+	if h.Hardware != nil {
+		biosUUID = anon.uuid(h.Hardware.SystemInfo.Uuid)
+	}
+	serialNumber := anon.serial(hostSerial(h))
+	assetTag := anon.assetTag(hostAssetTag(h))
+
+	var biosVersion, biosDate, firmware string
+	if h.Hardware != nil && h.Hardware.BiosInfo != nil {
+		bios := h.Hardware.BiosInfo
+		biosVersion = bios.BiosVersion
+		if bios.ReleaseDate != nil {
+			biosDate = bios.ReleaseDate.UTC().Format(time.DateOnly)
+		}
+		// SMBIOS reports 255 for an embedded controller without firmware
+		if major := bios.FirmwareMajorRelease; major > 0 && major != 255 {
+			firmware = fmt.Sprintf("%d.%d", major, bios.FirmwareMinorRelease)
+		}
+	}
+
+	var sockets, totalCores, coresPerSocket, threads int16
+	var memoryGB int64
+	if h.Hardware != nil {
+		sockets = h.Hardware.CpuInfo.NumCpuPackages
+		totalCores = h.Hardware.CpuInfo.NumCpuCores
+		threads = h.Hardware.CpuInfo.NumCpuThreads
+		if sockets > 0 {
+			coresPerSocket = totalCores / sockets
+		}
+		memoryGB = h.Hardware.MemorySize / (1024 * 1024 * 1024)
+	}
+
+	cpu := hostCPUIdentity(h)
+
+	numa := hostNUMA(h)
+
+	hyperthreading := ""
+	if h.Config != nil && h.Config.HyperThread != nil {
+		switch ht := h.Config.HyperThread; {
+		case ht.Active:
+			hyperthreading = "active"
+		case ht.Available:
+			hyperthreading = "inactive"
+		default:
+			hyperthreading = "unavailable"
+		}
+	}
+
+	tpm, attestation := "", ""
+	var secureBoot *bool
+	if c := h.Capability; c != nil {
+		switch {
+		case c.TpmSupported == nil:
+		case *c.TpmSupported && c.TpmVersion != "":
+			tpm = c.TpmVersion
+		case !*c.TpmSupported:
+			tpm = "none"
+		}
+		secureBoot = c.UefiSecureBoot
+	}
+	if a := h.Summary.TpmAttestation; a != nil {
+		attestation = string(a.Status)
+	}
+
+	power := ""
+	if h.Config != nil && h.Config.PowerSystemInfo != nil {
+		short := h.Config.PowerSystemInfo.CurrentPolicy.ShortName
+		if power = powerPolicies[short]; power == "" {
+			power = short
+		}
+	}
+
+	lockdown := ""
+	if h.Config != nil && h.Config.LockdownMode != "" {
+		lockdown = strings.ToLower(strings.TrimPrefix(string(h.Config.LockdownMode), "lockdown"))
+	}
+
+	var cpuCapacity int
+	if h.Summary.Hardware != nil {
+		cpuCapacity = int(h.Summary.Hardware.CpuMhz) * int(h.Summary.Hardware.NumCpuCores)
+	}
+
+	connectionState, powerState := "", ""
+	maintenance := false
+	var bootTime time.Time
+	if h.Summary.Runtime != nil {
+		connectionState = string(h.Summary.Runtime.ConnectionState)
+		powerState = string(h.Summary.Runtime.PowerState)
+		maintenance = h.Summary.Runtime.InMaintenanceMode
+		if h.Summary.Runtime.BootTime != nil {
+			bootTime = *h.Summary.Runtime.BootTime
+		}
+	}
+
+	var info vsanHostInfo
+	if vsan != nil {
+		info = *vsan
+	}
+
+	return Host{
+		VCenter:             vcenter,
+		Hostname:            hostname,
+		Cluster:             cluster,
+		ServerModel:         serverModel,
+		ESXiVersion:         esxiVersion,
+		ESXiBuild:           esxiBuild,
+		CPUModel:            cpuModel,
+		Sockets:             int(sockets),
+		CoresPerSocket:      int(coresPerSocket),
+		TotalCores:          int(totalCores),
+		MemoryGB:            memoryGB,
+		VsanType:            info.clusterType,
+		VsanCapacityDisks:   info.totalDisks,
+		VsanCacheDisks:      info.cacheDisks,
+		VsanCapacityTiB:     info.capacityTiB,
+		ConnectionState:     connectionState,
+		InMaintenanceMode:   maintenance,
+		PowerState:          powerState,
+		BIOSUUID:            biosUUID,
+		Vendor:              vendor,
+		SerialNumber:        serialNumber,
+		AssetTag:            assetTag,
+		BIOSVersion:         biosVersion,
+		BIOSReleaseDate:     biosDate,
+		FirmwareVersion:     firmware,
+		CPUFamily:           cpu.family,
+		CPUModelNumber:      cpu.model,
+		CPUStepping:         cpu.stepping,
+		CPUFeatures:         strings.Join(cpu.features, ", "),
+		MaxEVCMode:          h.Summary.MaxEVCModeKey,
+		CurrentEVCMode:      h.Summary.CurrentEVCModeKey,
+		Hyperthreading:      hyperthreading,
+		CPUThreads:          int(threads),
+		NUMANodes:           numa.nodes,
+		CoresPerNUMANode:    numa.cores,
+		MemoryPerNUMANodeGB: numa.memoryGB,
+		NICs:                nics,
+		GPUs:                gpuCount,
+		GPUModels:           strings.Join(gpuModels, ", "),
+		CPUCapacityMHz:      cpuCapacity,
+		CPUUsageMHz:         int(h.Summary.QuickStats.OverallCpuUsage),
+		MemoryUsageGB:       float64(h.Summary.QuickStats.OverallMemoryUsage) / 1024,
+		UptimeSeconds:       int(h.Summary.QuickStats.Uptime),
+		BootTime:            bootTime,
+		LockdownMode:        lockdown,
+		SSHRunning:          serviceRunning(h, "TSM-SSH"),
+		ESXiShellRunning:    serviceRunning(h, "TSM"),
+		TPMVersion:          tpm,
+		TPMAttestation:      attestation,
+		SecureBoot:          secureBoot,
+		PowerPolicy:         power,
+	}
+}
+
+// powerPolicies are the host power policies by short name, with the names
+// the vSphere Client shows for them.
+var powerPolicies = map[string]string{
+	"static":  "High Performance",
+	"dynamic": "Balanced",
+	"low":     "Low Power",
+	"custom":  "Custom",
+}
+
+// PowerPolicyName returns the name of the host power policy given by short
+// name, such as "static", or by name in any case and with hyphens for
+// spaces, such as "high-performance", or "" if there is none.
+func PowerPolicyName(policy string) string {
+	if name, ok := powerPolicies[policy]; ok {
+		return name
+	}
+	for _, name := range powerPolicies {
+		if strings.EqualFold(strings.ReplaceAll(policy, "-", " "), name) {
+			return name
+		}
+	}
+	return ""
+}
+
+// serviceRunning reports whether the service of h with key, such as
+// TSM-SSH, is running, or nil if h does not list it.
+func serviceRunning(h mo.HostSystem, key string) *bool {
+	if h.Config == nil || h.Config.Service == nil {
+		return nil
+	}
+	for _, s := range h.Config.Service.Service {
+		if s.Key == key {
+			return &s.Running
+		}
+	}
+	return nil
+}
+
+// numaTopology is the number of a host's NUMA nodes and the cores and
+// memory of the smallest, the largest a VM can be to fit in one node.
+type numaTopology struct {
+	nodes, cores int
+	memoryGB     int64
+}
+
+// hostNUMA returns the NUMA topology of h from hardware.numaInfo. A node's
+// CPUs are logical processors, so they are scaled to cores by the ratio of
+// cores to threads. Its memory is memorySize from vSphere 8.0, and the
+// length of its memory range before.
+func hostNUMA(h mo.HostSystem) numaTopology {
+	var numa numaTopology
+	if h.Hardware == nil || h.Hardware.NumaInfo == nil {
+		return numa
+	}
+	numa.nodes = int(h.Hardware.NumaInfo.NumNodes)
+	cpu := h.Hardware.CpuInfo
+	for i, node := range h.Hardware.NumaInfo.NumaNode {
+		cores := len(node.CpuID)
+		if cpu.NumCpuThreads > 0 {
+			cores = cores * int(cpu.NumCpuCores) / int(cpu.NumCpuThreads)
+		}
+		memory := node.MemorySize
+		if memory == 0 {
+			memory = node.MemoryRangeLength
+		}
+		memoryGB := memory / (1024 * 1024 * 1024)
+		if i == 0 || cores < numa.cores {
+			numa.cores = cores
+		}
+		if i == 0 || memoryGB < numa.memoryGB {
+			numa.memoryGB = memoryGB
+		}
+	}
+	return numa
+}
+
+// hostSerial returns the serial number of h's chassis: hardware.systemInfo
+// on ESXi 6.7 and later, otherwise the service or serial number tag some
+// vendors report among the other identifying info.
+func hostSerial(h mo.HostSystem) string {
+	if h.Hardware != nil && h.Hardware.SystemInfo.SerialNumber != "" {
+		return h.Hardware.SystemInfo.SerialNumber
+	}
+	return identifyingInfo(h, "ServiceTag", "SerialNumberTag", "EnclosureSerialNumberTag")
+}
+
+// noAssetTag holds the placeholders BIOSes report when no asset tag has
+// been set, in lower case.
+var noAssetTag = map[string]bool{
+	"no asset tag":           true,
+	"no asset information":   true,
+	"not specified":          true,
+	"default string":         true,
+	"to be filled by o.e.m.": true,
+}
+
+// hostAssetTag returns the asset tag set in h's BIOS, or "" if there is
+// none or only a placeholder.
+func hostAssetTag(h mo.HostSystem) string {
+	tag := identifyingInfo(h, "AssetTag")
+	if noAssetTag[strings.ToLower(tag)] {
+		return ""
+	}
+	return tag
+}
+
+// identifyingInfo returns the first of h's other identifying info, from
+// its hardware or summary, of one of the types keys, trimmed of spaces.
+func identifyingInfo(h mo.HostSystem, keys ...string) string {
+	var ids []types.HostSystemIdentificationInfo
+	if h.Hardware != nil {
+		ids = h.Hardware.SystemInfo.OtherIdentifyingInfo
+	}
+	if h.Summary.Hardware != nil {
+		ids = append(ids, h.Summary.Hardware.OtherIdentifyingInfo...)
+	}
+	for _, id := range ids {
+		if slices.Contains(keys, id.IdentifierType.GetElementDescription().Key) {
+			return strings.TrimSpace(id.IdentifierValue)
+		}
+	}
+	return ""
+}
+
+// retrieveParentNames returns the cluster (or standalone compute resource)
+// name for each host parent, keyed by parent MoRef value. All parents are
+// fetched in one round trip; if that fails they are retried one at a time so
+// a single bad reference only loses its own name.
+func retrieveParentNames(ctx context.Context, pc *property.Collector, hosts []mo.HostSystem, opts Options) map[string]string {
+	var refs []types.ManagedObjectReference
+	seen := make(map[string]bool)
+	for _, h := range hosts {
+		if h.Parent == nil || seen[h.Parent.Value] {
+			continue
+		}
+		seen[h.Parent.Value] = true
+		refs = append(refs, *h.Parent)
+	}
+
+	parentNames := make(map[string]string)
+	if len(refs) == 0 {
+		return parentNames
+	}
+
+	var content []types.ObjectContent
+	if err := pc.Retrieve(ctx, refs, []string{"name"}, &content); err == nil {
+		for _, oc := range content {
+			for _, p := range oc.PropSet {
+				if name, ok := p.Val.(string); ok && p.Name == "name" {
+					parentNames[oc.Obj.Value] = name
+				}
+			}
+		}
+		return parentNames
+	}
+
+	for _, h := range hosts {
+		if h.Parent == nil {
+			continue
+		}
+		if _, ok := parentNames[h.Parent.Value]; ok {
+			continue
+		}
+		var parent mo.ManagedEntity
+		if err := pc.RetrieveOne(ctx, *h.Parent, []string{"name"}, &parent); err != nil {
+			opts.fail(h.Summary.Config.Name, "cluster name", err)
+			continue
+		}
+		parentNames[h.Parent.Value] = parent.Name
+	}
+	return parentNames
+}
+
+// retrieveVsanSystems fetches the HostVsanSystem of every host in one round
+// trip, keyed by MoRef value. It returns an empty map if the batch fails;
+// callers then fall back to per-host retrieval.
+func retrieveVsanSystems(ctx context.Context, pc *property.Collector, hosts []mo.HostSystem) map[string]mo.HostVsanSystem {
+	var refs []types.ManagedObjectReference
+	for _, h := range hosts {
+		if h.ConfigManager.VsanSystem != nil {
+			refs = append(refs, *h.ConfigManager.VsanSystem)
+		}
+	}
+
+	systems := make(map[string]mo.HostVsanSystem)
+	if len(refs) == 0 {
+		return systems
+	}
+	var list []mo.HostVsanSystem
+	if err := pc.Retrieve(ctx, refs, nil, &list); err != nil {
+		return systems
+	}
+	for _, s := range list {
+		systems[s.Self.Value] = s
+	}
+	return systems
+}
+
+// retrieveCertificateManagers fetches the certificate information of
+// every host in one round trip, keyed by the MoRef value of its certificate
+// manager. It returns an empty map if the batch fails; callers then fall
+// back to per-host retrieval.
+func retrieveCertificateManagers(ctx context.Context, pc *property.Collector, hosts []mo.HostSystem) map[string]mo.HostCertificateManager {
+	var refs []types.ManagedObjectReference
+	for _, h := range hosts {
+		if h.ConfigManager.CertificateManager != nil {
+			refs = append(refs, *h.ConfigManager.CertificateManager)
+		}
+	}
+
+	managers := make(map[string]mo.HostCertificateManager)
+	if len(refs) == 0 {
+		return managers
+	}
+	var list []mo.HostCertificateManager
+	if err := pc.Retrieve(ctx, refs, []string{"certificateInfo"}, &list); err != nil {
+		return managers
+	}
+	for _, m := range list {
+		managers[m.Self.Value] = m
+	}
+	return managers
+}
+
+// certificateStatus returns whether a certificate expiring at notAfter is
+// valid, expiring within warning of now, or expired.
+func certificateStatus(notAfter, now time.Time, warning time.Duration) string {
+	switch {
+	case !now.Before(notAfter):
+		return "expired"
+	case notAfter.Sub(now) <= warning:
+		return "expiring"
+	}
+	return "valid"
+}
+
+type vsanHostInfo struct {
+	capacityTiB float64
+	totalDisks  int
+	cacheDisks  int
+	clusterType string // "OSA" or "ESA"
+}
+
+// vsanInfo derives the vSAN disk layout of one host from its HostVsanSystem.
+// It returns nil info when the host is not contributing vSAN storage. An ESA
+// host whose disks could not be queried is returned with its type set and a
+// non-nil error.
+func vsanInfo(ctx context.Context, c *vim25.Client, hostName string, vsanSys mo.HostVsanSystem, debug io.Writer) (*vsanHostInfo, error) {
+	if debug != nil {
+		j, _ := json.MarshalIndent(vsanSys, "", "  ")
+		fmt.Fprintf(debug, "=== vSAN system for %s ===\n%s\n\n", hostName, j)
+	}
+
+	isESA := vsanSys.Config.VsanEsaEnabled != nil && *vsanSys.Config.VsanEsaEnabled
+
+	var info vsanHostInfo
+	var capacityBytes int64
+
+	if isESA {
+		// ESA: no disk groups, query disks directly
+		info.clusterType = "ESA"
+		res, err := methods.QueryDisksForVsan(ctx, c, &types.QueryDisksForVsan{
+			This: vsanSys.Self,
+		})
+		if err != nil {
+			return &info, fmt.Errorf("could not query vSAN disks: %w", err)
+		}
+		if debug != nil {
+			j, _ := json.MarshalIndent(res.Returnval, "", "  ")
+			fmt.Fprintf(debug, "=== vSAN disks for %s ===\n%s\n\n", hostName, j)
+		}
+		for _, dr := range res.Returnval {
+			// For ESA, disks in use have vsanDiskInfo populated
+			inUse := dr.Disk.VsanDiskInfo != nil
+			if inUse {
+				info.totalDisks++
+				capacityBytes += int64(dr.Disk.Capacity.BlockSize) * int64(dr.Disk.Capacity.Block)
+			}
+		}
+	} else {
+		// OSA: disk groups with cache SSD + capacity disks
+		if vsanSys.Config.StorageInfo == nil || len(vsanSys.Config.StorageInfo.DiskMapping) == 0 {
+			return nil, nil
+		}
+		info.clusterType = "OSA"
+		info.cacheDisks = len(vsanSys.Config.StorageInfo.DiskMapping)
+		for _, dm := range vsanSys.Config.StorageInfo.DiskMapping {
+			info.totalDisks += len(dm.NonSsd)
+			for _, d := range dm.NonSsd {
+				capacityBytes += int64(d.Capacity.BlockSize) * int64(d.Capacity.Block)
+			}
+		}
+	}
+
+	info.capacityTiB = float64(capacityBytes) / (1024 * 1024 * 1024 * 1024)
+	return &info, nil
+}
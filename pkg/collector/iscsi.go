@@ -0,0 +1,136 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// ISCSIAdapter is the configuration of an iSCSI adapter of a host. CHAP
+// names and secrets are not collected.
+type ISCSIAdapter struct {
+	VCenter       string
+	Host          string
+	Cluster       string
+	Device        string // e.g. "vmhba64"
+	Type          string // "software" or "hardware"
+	Model         string
+	Driver        string
+	Status        string   // e.g. "online"
+	IQN           string   // initiator name
+	BoundPorts    []string // VMkernel adapters bound to it, e.g. "vmk1"
+	SendTargets   []string // dynamic discovery addresses, "address:port"
+	StaticTargets []string // "address:port target-iqn"
+	CHAP          string   // prohibited, discouraged, preferred, or required
+	MutualCHAP    string
+}
+
+// CollectISCSIAdapters retrieves the iSCSI adapters of each host visible to
+// c that passes the host filters, with the VMkernel ports bound to those
+// that support port binding. Adapters are in inventory order of their
+// hosts, then in the order the host lists them. A host whose bound ports
+// cannot be retrieved is reported through opts.fail, and its adapters
+// listed without them.
+func CollectISCSIAdapters(ctx context.Context, c *vim25.Client, opts Options) ([]ISCSIAdapter, error) {
+	hosts, err := selectHosts(ctx, c, opts, "config.storageDevice.hostBusAdapter", "configManager.iscsiManager")
+	if err != nil {
+		return nil, err
+	}
+
+	anon := opts.anonymizer()
+	vcenter := anon.vcenter(opts.VCenter)
+	adapters := make([][]ISCSIAdapter, len(hosts))
+	hbas := make([][]*types.HostInternetScsiHba, len(hosts))
+	for i, h := range hosts {
+		if h.Config == nil || h.Config.StorageDevice == nil {
+			continue
+		}
+		for _, b := range h.Config.StorageDevice.HostBusAdapter {
+			hba, ok := b.(*types.HostInternetScsiHba)
+			if !ok {
+				continue
+			}
+			a := ISCSIAdapter{
+				VCenter:    vcenter,
+				Host:       h.Host,
+				Cluster:    h.Cluster,
+				Device:     hba.Device,
+				Type:       "hardware",
+				Model:      hba.Model,
+				Driver:     hba.Driver,
+				Status:     hba.Status,
+				IQN:        anon.Name("IQN", hba.IScsiName),
+				CHAP:       chapMode(hba.AuthenticationProperties.ChapAuthenticationType),
+				MutualCHAP: chapMode(hba.AuthenticationProperties.MutualChapAuthenticationType),
+			}
+			if hba.IsSoftwareBased {
+				a.Type = "software"
+			}
+			for _, t := range hba.ConfiguredSendTarget {
+				a.SendTargets = append(a.SendTargets, iscsiTarget(anon, t.Address, t.Port))
+			}
+			for _, t := range hba.ConfiguredStaticTarget {
+				// Those found by dynamic discovery are listed too
+				if t.DiscoveryMethod == "" || t.DiscoveryMethod == string(types.HostInternetScsiHbaStaticTargetTargetDiscoveryMethodStaticMethod) {
+					a.StaticTargets = append(a.StaticTargets, iscsiTarget(anon, t.Address, t.Port)+" "+anon.Name("IQN", t.IScsiName))
+				}
+			}
+			adapters[i] = append(adapters[i], a)
+			hbas[i] = append(hbas[i], hba)
+		}
+	}
+
+	done := opts.tracker(len(hosts))
+	parallel(len(hosts), opts.Concurrency, func(i int) {
+		defer done()
+		h := hosts[i]
+		ref := h.ConfigManager.IscsiManager
+		if ref == nil || h.Summary.Runtime == nil || h.Summary.Runtime.ConnectionState != types.HostSystemConnectionStateConnected {
+			return
+		}
+		for j, hba := range hbas[i] {
+			if hba.NetworkBindingSupport == "" || hba.NetworkBindingSupport == types.HostInternetScsiHbaNetworkBindingSupportTypeNotsupported {
+				continue
+			}
+			res, err := methods.QueryBoundVnics(ctx, c, &types.QueryBoundVnics{This: *ref, IScsiHbaName: hba.Device})
+			if err != nil {
+				opts.fail(h.Name, "iscsi", fmt.Errorf("could not list the ports bound to %s: %w", hba.Device, err))
+				return
+			}
+			for _, p := range res.Returnval {
+				if p.VnicDevice != "" {
+					adapters[i][j].BoundPorts = append(adapters[i][j].BoundPorts, p.VnicDevice)
+				}
+			}
+		}
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var all []ISCSIAdapter
+	for _, a := range adapters {
+		all = append(all, a...)
+	}
+	return all, nil
+}
+
+// iscsiTarget returns the address and port of an iSCSI target as
+// "address:port", labeled as names are.
+func iscsiTarget(anon *Anonymizer, address string, port int32) string {
+	address = anon.Name("iSCSI Target", address)
+	if port == 0 {
+		return address
+	}
+	return address + ":" + strconv.Itoa(int(port))
+}
+
+// chapMode returns a CHAP authentication type such as "chapRequired" as
+// "required".
+func chapMode(t string) string {
+	return strings.ToLower(strings.TrimPrefix(t, "chap"))
+}
@@ -0,0 +1,170 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vmware/govmomi/license"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// License is a license key installed in a vCenter.
+type License struct {
+	VCenter  string
+	Key      string // masked but for its last group, or a label when anonymized
+	Product  string // e.g. "VMware vSphere 8 Enterprise Plus"
+	Edition  string // edition key, e.g. "esx.enterprisePlus.cpuPackage"
+	CostUnit string // what Total counts, e.g. "cpuPackage" or "core"
+	Total    int    // capacity, or 0 for unlimited
+	Used     int
+	Expires  string // YYYY-MM-DD, or "" for a perpetual key
+}
+
+// LicenseAssignment is the license key assigned to a host, a cluster (for
+// vSAN), or the vCenter itself.
+type LicenseAssignment struct {
+	VCenter    string
+	EntityType string // Host, Cluster, vCenter, or Other for solutions
+	Entity     string
+	Key        string // as in License
+	Product    string
+	Edition    string
+}
+
+// CollectLicenses retrieves the license keys installed in the vCenter c is
+// connected to, with their capacity and use, and the key assigned to each
+// host, cluster, and the vCenter. Keys are never returned in full. The
+// Datacenter and host filters limit the host and cluster assignments; the
+// keys are listed whatever the filters, since they are shared by the whole
+// vCenter.
+func CollectLicenses(ctx context.Context, c *vim25.Client, opts Options) ([]License, []LicenseAssignment, error) {
+	lm := license.NewManager(c)
+	infos, err := lm.List(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("listing licenses: %w", err)
+	}
+	anon := opts.anonymizer()
+	vcenter := anon.vcenter(opts.VCenter)
+	licenses := make([]License, 0, len(infos))
+	for _, l := range infos {
+		licenses = append(licenses, License{
+			VCenter:  vcenter,
+			Key:      anon.licenseKey(l.LicenseKey),
+			Product:  l.Name,
+			Edition:  l.EditionKey,
+			CostUnit: l.CostUnit,
+			Total:    int(l.Total),
+			Used:     int(l.Used),
+			Expires:  licenseExpiry(l),
+		})
+	}
+
+	am, err := lm.AssignmentManager(ctx)
+	if err != nil {
+		// ESXi hosts connected directly have no assignment manager
+		return licenses, nil, nil
+	}
+	assigned, err := am.QueryAssigned(ctx, "")
+	if err != nil {
+		return nil, nil, fmt.Errorf("querying license assignments: %w", err)
+	}
+	entities, err := licensedEntities(ctx, c, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	var assignments []LicenseAssignment
+	for _, a := range assigned {
+		e, ok := entities[a.EntityId]
+		switch {
+		case a.EntityId == c.ServiceContent.About.InstanceUuid:
+			e = licensedEntity{"vCenter", vcenter}
+		case !ok && opts.Datacenter == "" && !opts.filtered():
+			e = licensedEntity{"Other", anon.Text(a.EntityDisplayName)}
+		case !ok:
+			continue
+		}
+		assignments = append(assignments, LicenseAssignment{
+			VCenter:    vcenter,
+			EntityType: e.kind,
+			Entity:     e.name,
+			Key:        anon.licenseKey(a.AssignedLicense.LicenseKey),
+			Product:    a.AssignedLicense.Name,
+			Edition:    a.AssignedLicense.EditionKey,
+		})
+	}
+	return licenses, assignments, nil
+}
+
+// licensedEntity is a host or cluster that may have a license assigned.
+type licensedEntity struct {
+	kind string // Host or Cluster
+	name string // anonymized
+}
+
+// licensedEntities returns the hosts and clusters under the Datacenter
+// option's container that pass the host filters, keyed by MoRef value, which
+// is the entity id of their license assignments.
+func licensedEntities(ctx context.Context, c *vim25.Client, opts Options) (map[string]licensedEntity, error) {
+	root, err := containerRoot(ctx, c, opts)
+	if err != nil {
+		return nil, err
+	}
+	v, err := view.NewManager(c).CreateContainerView(ctx, root, []string{"HostSystem", "ClusterComputeResource"}, true)
+	if err != nil {
+		return nil, fmt.Errorf("creating container view: %w", err)
+	}
+	defer destroyView(ctx, v)
+
+	var hosts []mo.HostSystem
+	if err := v.Retrieve(ctx, []string{"HostSystem"}, []string{"name", "summary.runtime", "parent"}, &hosts); err != nil {
+		return nil, fmt.Errorf("retrieving hosts: %w", err)
+	}
+	var clusters []mo.ClusterComputeResource
+	if err := v.Retrieve(ctx, []string{"ClusterComputeResource"}, []string{"name"}, &clusters); err != nil {
+		return nil, fmt.Errorf("retrieving clusters: %w", err)
+	}
+	parentNames := retrieveParentNames(ctx, property.DefaultCollector(c), hosts, opts)
+	tagged, err := taggedHosts(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	anon := opts.anonymizer()
+	entities := make(map[string]licensedEntity)
+	for _, h := range filterHosts(hosts, parentNames, tagged, opts) {
+		entities[h.Self.Value] = licensedEntity{"Host", anon.host(h.Name)}
+	}
+	for _, cl := range clusters {
+		if opts.matchCluster(cl.Name) {
+			entities[cl.Self.Value] = licensedEntity{"Cluster", anon.cluster(opts.VCenter, cl.Name)}
+		}
+	}
+	return entities, nil
+}
+
+// licenseExpiry returns the expiration date of l, from its expirationDate
+// property, or "" if it does not expire.
+func licenseExpiry(l types.LicenseManagerLicenseInfo) string {
+	for _, p := range l.Properties {
+		if t, ok := p.Value.(time.Time); ok && p.Key == "expirationDate" {
+			return t.UTC().Format(time.DateOnly)
+		}
+	}
+	return ""
+}
+
+// MaskLicenseKey hides all but the last group of a license key, as the
+// vSphere Client does, e.g. "*****-*****-*****-*****-4K2J1".
+func MaskLicenseKey(key string) string {
+	groups := strings.Split(key, "-")
+	for i := range groups[:len(groups)-1] {
+		groups[i] = strings.Repeat("*", len(groups[i]))
+	}
+	return strings.Join(groups, "-")
+}
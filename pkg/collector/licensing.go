@@ -0,0 +1,97 @@
+package collector
+
+import "math"
+
+// MinCoresPerCPU is the fewest cores licensed per CPU under VMware's
+// core-based subscriptions, VMware Cloud Foundation (VCF) and vSphere
+// Foundation (VVF): a CPU with fewer cores is licensed as if it had 16.
+const MinCoresPerCPU = 16
+
+// LicenseCores returns the cores h needs licensed under the core-based
+// subscriptions: the physical cores of each CPU, at least MinCoresPerCPU,
+// summed over its CPUs.
+func (h Host) LicenseCores() int {
+	return h.Sockets * max(h.CoresPerSocket, MinCoresPerCPU)
+}
+
+// MaxCoresPerCPULicense is the most cores one license covers under the
+// legacy per-CPU terms of perpetual vSphere licenses: a CPU with more needs
+// a license per 32 cores.
+const MaxCoresPerCPULicense = 32
+
+// CPULicenses returns the licenses h needs under the legacy per-CPU terms.
+func (h Host) CPULicenses() int {
+	return h.Sockets * ((h.CoresPerSocket + MaxCoresPerCPULicense - 1) / MaxCoresPerCPULicense)
+}
+
+// Edition is a core-based subscription.
+type Edition struct {
+	Name string
+	// VsanTiBPerCore is the raw vSAN capacity included with each licensed
+	// core; capacity beyond it needs the vSAN add-on, per TiB.
+	VsanTiBPerCore float64
+}
+
+// Editions are the core-based subscriptions by lowercase short name.
+var Editions = map[string]Edition{
+	"vcf": {Name: "VCF", VsanTiBPerCore: 1},
+	"vvf": {Name: "VVF", VsanTiBPerCore: 0.25},
+}
+
+// ClusterLicense is the licensing of one cluster under an edition.
+type ClusterLicense struct {
+	Cluster
+	VsanIncludedTiB float64 // entitlement of the cluster's license cores
+	VsanAddOnTiB    float64 // whole TiB of raw capacity beyond it
+}
+
+// LicenseClusters returns the licensing of clusters under e.
+func LicenseClusters(clusters []Cluster, e Edition) []ClusterLicense {
+	licenses := make([]ClusterLicense, len(clusters))
+	for i, c := range clusters {
+		included := float64(c.LicenseCores) * e.VsanTiBPerCore
+		licenses[i] = ClusterLicense{
+			Cluster:         c,
+			VsanIncludedTiB: included,
+			VsanAddOnTiB:    vsanAddOn(c.VsanCapacityTiB, included),
+		}
+	}
+	return licenses
+}
+
+// vsanAddOn returns the whole TiB by which raw capacity exceeds included.
+func vsanAddOn(raw, included float64) float64 {
+	// Capacity converted from bytes may be a hair over a whole TiB
+	return max(0, math.Ceil(raw-included-1e-9))
+}
+
+// LicenseTotal is the licensing of a whole environment. The vSAN
+// entitlement is pooled across clusters, so VsanAddOnTiB may be less than
+// the sum of the clusters'.
+type LicenseTotal struct {
+	Hosts           int
+	Clusters        int
+	Sockets         int
+	TotalCores      int
+	LicenseCores    int
+	CPULicenses     int
+	VsanCapacityTiB float64
+	VsanIncludedTiB float64
+	VsanAddOnTiB    float64
+}
+
+// TotalLicenses sums the licensing of clusters.
+func TotalLicenses(clusters []ClusterLicense) LicenseTotal {
+	t := LicenseTotal{Clusters: len(clusters)}
+	for _, c := range clusters {
+		t.Hosts += c.Hosts
+		t.Sockets += c.Sockets
+		t.TotalCores += c.TotalCores
+		t.LicenseCores += c.LicenseCores
+		t.CPULicenses += c.CPULicenses
+		t.VsanCapacityTiB += c.VsanCapacityTiB
+		t.VsanIncludedTiB += c.VsanIncludedTiB
+	}
+	t.VsanAddOnTiB = vsanAddOn(t.VsanCapacityTiB, t.VsanIncludedTiB)
+	return t
+}
@@ -0,0 +1,128 @@
+package collector
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// LocalDisk is a disk local to a host that vSAN has not claimed, and the
+// VMFS datastores on it.
+type LocalDisk struct {
+	VCenter    string
+	Host       string
+	Cluster    string
+	Device     string // canonical name, e.g. "naa.5000c500a1b2c3d4"
+	Vendor     string
+	Model      string
+	CapacityGB float64
+	SSD        bool
+	Adapter    string // e.g. "vmhba32"
+	Driver     string // of the adapter, e.g. "vmkusb"
+	USB        bool   // on a USB or SD card controller
+	Boot       bool   // holds the ESXi system volume (OSDATA)
+	Datastores []string
+}
+
+// CollectLocalDisks retrieves the local disks of each host visible to c that
+// passes the host filters and are not claimed by vSAN, from
+// config.storageDevice, with the VMFS datastores of config.fileSystemVolume
+// on each. A disk is local if the host reports it so or it backs a local
+// VMFS volume. Hosts that report no storage devices, such as disconnected
+// ones, are left out. Disks are in inventory order of their hosts, then in
+// the order the host lists them.
+func CollectLocalDisks(ctx context.Context, c *vim25.Client, opts Options) ([]LocalDisk, error) {
+	hosts, err := selectHosts(ctx, c, opts, "config.storageDevice", "config.fileSystemVolume")
+	if err != nil {
+		return nil, err
+	}
+
+	anon := opts.anonymizer()
+	vcenter := anon.vcenter(opts.VCenter)
+	var disks []LocalDisk
+	for _, h := range hosts {
+		if h.Config == nil || h.Config.StorageDevice == nil {
+			continue
+		}
+		storage := h.Config.StorageDevice
+
+		// The VMFS volumes on each disk, by canonical name
+		datastores := make(map[string][]string)
+		boot := make(map[string]bool)
+		localVolume := make(map[string]bool)
+		if fs := h.Config.FileSystemVolume; fs != nil {
+			for _, m := range fs.MountInfo {
+				vol, ok := m.Volume.(*types.HostVmfsVolume)
+				if !ok {
+					continue
+				}
+				for _, e := range vol.Extent {
+					if vol.Local != nil && *vol.Local {
+						localVolume[e.DiskName] = true
+					}
+					switch {
+					case strings.HasPrefix(vol.Name, "OSDATA-"):
+						boot[e.DiskName] = true
+					case vol.Type == string(types.HostFileSystemVolumeFileSystemTypeVMFS):
+						name := anon.datastore(opts.VCenter, vol.Name)
+						if !slices.Contains(datastores[e.DiskName], name) {
+							datastores[e.DiskName] = append(datastores[e.DiskName], name)
+						}
+					}
+				}
+			}
+		}
+		// The adapter each disk is reached through, by ScsiLun key
+		hbas := make(map[string]*types.HostHostBusAdapter)
+		for _, b := range storage.HostBusAdapter {
+			hbas[b.GetHostHostBusAdapter().Key] = b.GetHostHostBusAdapter()
+		}
+		adapters := make(map[string]*types.HostHostBusAdapter)
+		if t := storage.ScsiTopology; t != nil {
+			for _, a := range t.Adapter {
+				hba, ok := hbas[a.Adapter]
+				if !ok {
+					continue
+				}
+				for _, target := range a.Target {
+					for _, lun := range target.Lun {
+						if _, ok := adapters[lun.ScsiLun]; !ok {
+							adapters[lun.ScsiLun] = hba
+						}
+					}
+				}
+			}
+		}
+
+		for _, l := range storage.ScsiLun {
+			d, ok := l.(*types.HostScsiDisk)
+			if !ok || d.VsanDiskInfo != nil {
+				continue
+			}
+			if (d.LocalDisk == nil || !*d.LocalDisk) && !localVolume[d.CanonicalName] {
+				continue
+			}
+			disk := LocalDisk{
+				VCenter:    vcenter,
+				Host:       h.Host,
+				Cluster:    h.Cluster,
+				Device:     anon.Name("Disk", d.CanonicalName),
+				Vendor:     strings.TrimSpace(d.Vendor),
+				Model:      strings.TrimSpace(d.Model),
+				CapacityGB: float64(d.Capacity.Block) * float64(d.Capacity.BlockSize) / (1024 * 1024 * 1024),
+				SSD:        d.Ssd != nil && *d.Ssd,
+				Boot:       boot[d.CanonicalName],
+				Datastores: datastores[d.CanonicalName],
+			}
+			if a := adapters[d.Key]; a != nil {
+				disk.Adapter, disk.Driver = a.Device, a.Driver
+				disk.USB = a.Driver == "vmkusb"
+			}
+			disks = append(disks, disk)
+		}
+	}
+	return disks, nil
+}
@@ -0,0 +1,99 @@
+package collector
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/vmware/govmomi/vim25"
+)
+
+// DIMM is a physical memory module, or an empty slot, of a host as its
+// hardware health information lists it. Which details are known depends
+// on what the server's CIM providers report; those not found in the
+// element's name are zero.
+type DIMM struct {
+	VCenter     string
+	Host        string
+	Cluster     string
+	Slot        string // e.g. "P1-DIMMA1"
+	SizeGB      int
+	SpeedMTs    int // data rate in MT/s, or the MHz reported in its place
+	Vendor      string
+	Status      string // Green, Yellow, Red, or Unknown
+	Description string // the health element's name, as reported
+}
+
+var (
+	dimmSlot  = regexp.MustCompile(`(?i)\b(?:(?:P|CPU|PROC)\s?\d+[\s_-]?)?DIMM(?:\.Socket\.|[\s_.-])?[A-Z]{0,2}\d+[A-Z]?\b`)
+	dimmSize  = regexp.MustCompile(`(?i)\b(\d+)\s?(MB|MiB|GB|GiB)\b`)
+	dimmSpeed = regexp.MustCompile(`(?i)\b(\d+)\s?(MHz|MT/s)`)
+)
+
+// dimmVendors are the memory manufacturers recognized in a health element's
+// name, by the lowercase word that names them. The first found is used, so
+// brands come before their makers: "Crucial by Micron" is Crucial.
+var dimmVendors = []struct{ word, vendor string }{
+	{"crucial", "Crucial"},
+	{"samsung", "Samsung"},
+	{"micron", "Micron"},
+	{"hynix", "SK hynix"},
+	{"kingston", "Kingston"},
+	{"nanya", "Nanya"},
+}
+
+// CollectDIMMs retrieves the memory modules of each host visible to c that
+// passes the host filters, from runtime.healthSystemRuntime. Hosts whose
+// hardware health lists no memory are left out. Modules are in inventory
+// order of their hosts, then in the order the host lists them.
+func CollectDIMMs(ctx context.Context, c *vim25.Client, opts Options) ([]DIMM, error) {
+	hosts, err := selectHosts(ctx, c, opts, "runtime.healthSystemRuntime.hardwareStatusInfo")
+	if err != nil {
+		return nil, err
+	}
+
+	anon := opts.anonymizer()
+	vcenter := anon.vcenter(opts.VCenter)
+	var dimms []DIMM
+	for _, h := range hosts {
+		health := h.Runtime.HealthSystemRuntime
+		if health == nil || health.HardwareStatusInfo == nil {
+			continue
+		}
+		for _, e := range health.HardwareStatusInfo.MemoryStatusInfo {
+			info := e.GetHostHardwareElementInfo()
+			d := parseDIMM(info.Name)
+			d.VCenter, d.Host, d.Cluster = vcenter, h.Host, h.Cluster
+			if info.Status != nil {
+				d.Status = info.Status.GetElementDescription().Key
+			}
+			dimms = append(dimms, d)
+		}
+	}
+	return dimms, nil
+}
+
+// parseDIMM returns the slot, size, speed, and vendor found in the name of
+// a memory health element, such as "P1-DIMMA1 16384 MB 2933 MHz Samsung".
+func parseDIMM(name string) DIMM {
+	d := DIMM{Description: name, Slot: dimmSlot.FindString(name)}
+	if m := dimmSize.FindStringSubmatch(name); m != nil {
+		size, _ := strconv.Atoi(m[1])
+		if u := strings.ToUpper(m[2]); u == "MB" || u == "MIB" {
+			size /= 1024
+		}
+		d.SizeGB = size
+	}
+	if m := dimmSpeed.FindStringSubmatch(name); m != nil {
+		d.SpeedMTs, _ = strconv.Atoi(m[1])
+	}
+	lower := strings.ToLower(name)
+	for _, v := range dimmVendors {
+		if strings.Contains(lower, v.word) {
+			d.Vendor = v.vendor
+			break
+		}
+	}
+	return d
+}
@@ -0,0 +1,121 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// DatastoreMount is a datastore a host mounts, or a datastore shared by
+// other hosts of its cluster that it does not.
+type DatastoreMount struct {
+	VCenter    string
+	Host       string
+	Cluster    string
+	Datastore  string
+	Type       string
+	Mounted    bool
+	AccessMode string // "readWrite" or "readOnly", empty if not mounted
+	Accessible bool
+	Missing    bool // mounted by other hosts of the cluster but not this one
+}
+
+// CollectDatastoreMounts retrieves the datastores mounted by each host
+// visible to c that passes the host filters. A host that does not mount a
+// datastore that other hosts of its cluster do, and that more than one host
+// can access, has a record for it marked Missing, whether or not those
+// hosts pass the filters. Mounts are in inventory order of their hosts,
+// then of the datastores.
+func CollectDatastoreMounts(ctx context.Context, c *vim25.Client, opts Options) ([]DatastoreMount, error) {
+	root, err := containerRoot(ctx, c, opts)
+	if err != nil {
+		return nil, err
+	}
+	v, err := view.NewManager(c).CreateContainerView(ctx, root, []string{"HostSystem", "Datastore"}, true)
+	if err != nil {
+		return nil, fmt.Errorf("creating container view: %w", err)
+	}
+	defer destroyView(ctx, v)
+
+	var all []mo.HostSystem
+	if err := v.Retrieve(ctx, []string{"HostSystem"}, []string{"parent"}, &all); err != nil {
+		return nil, fmt.Errorf("retrieving hosts: %w", err)
+	}
+	var datastores []mo.Datastore
+	if err := v.Retrieve(ctx, []string{"Datastore"}, []string{"summary", "host"}, &datastores); err != nil {
+		return nil, fmt.Errorf("retrieving datastores: %w", err)
+	}
+
+	// The number of hosts of each cluster mounting each datastore, counted
+	// before the filters so a filtered-out peer still counts
+	parents := make(map[string]string) // host MoRef value -> parent's
+	for _, h := range all {
+		if h.Parent != nil {
+			parents[h.Self.Value] = h.Parent.Value
+		}
+	}
+	type clusterDatastore struct{ cluster, datastore string }
+	mounts := make(map[clusterDatastore]int)
+	for _, ds := range datastores {
+		for _, m := range ds.Host {
+			if p := parents[m.Key.Value]; p != "" && mounted(m.MountInfo) {
+				mounts[clusterDatastore{p, ds.Self.Value}]++
+			}
+		}
+	}
+
+	hosts, err := selectHosts(ctx, c, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	anon := opts.anonymizer()
+	vcenter := anon.vcenter(opts.VCenter)
+	var records []DatastoreMount
+	for _, h := range hosts {
+		parent := ""
+		if h.Parent != nil {
+			parent = h.Parent.Value
+		}
+		for _, ds := range datastores {
+			m := DatastoreMount{
+				VCenter:   vcenter,
+				Host:      h.Host,
+				Cluster:   h.Cluster,
+				Datastore: anon.datastore(opts.VCenter, ds.Summary.Name),
+				Type:      ds.Summary.Type,
+			}
+			if name, ok := datastoreTypes[m.Type]; ok {
+				m.Type = name
+			}
+			attached := false
+			for _, dm := range ds.Host {
+				if dm.Key.Value != h.Self.Value {
+					continue
+				}
+				attached = true
+				m.Mounted = mounted(dm.MountInfo)
+				if m.Mounted {
+					m.AccessMode = dm.MountInfo.AccessMode
+					m.Accessible = dm.MountInfo.Accessible != nil && *dm.MountInfo.Accessible
+				}
+			}
+			shared := ds.Summary.MultipleHostAccess != nil && *ds.Summary.MultipleHostAccess
+			m.Missing = !m.Mounted && shared && mounts[clusterDatastore{parent, ds.Self.Value}] > 0
+			if attached || m.Missing {
+				records = append(records, m)
+			}
+		}
+	}
+	return records, nil
+}
+
+// mounted reports whether info is of a mounted datastore. An unset mount
+// state is taken as mounted.
+func mounted(info types.HostMountInfo) bool {
+	return info.Mounted == nil || *info.Mounted
+}
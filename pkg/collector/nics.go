@@ -0,0 +1,112 @@
+package collector
+
+import (
+	"context"
+
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// PhysicalNIC is a physical network adapter of a host, the switch it is an
+// uplink of, and its SR-IOV configuration.
+type PhysicalNIC struct {
+	VCenter         string
+	Host            string
+	Cluster         string
+	Device          string // e.g. "vmnic0"
+	PCI             string // PCI address, e.g. "0000:3b:00.0"
+	Vendor          string // from the PCI device, e.g. "Intel Corporation"
+	Model           string // from the PCI device
+	Driver          string // e.g. "i40en"
+	DriverVersion   string // reported by ESXi 8.0 Update 1 and later
+	FirmwareVersion string // reported by ESXi 8.0 Update 1 and later
+	LinkSpeedMb     int    // 0 if the link is down
+	SwitchType      string // "standard" or "distributed", empty if unused
+	Switch          string // vSwitch or distributed switch name
+
+	SRIOVCapable           bool
+	SRIOVEnabled           bool
+	VirtualFunctions       int // configured; takes effect when the host reboots
+	ActiveVirtualFunctions int
+	MaxVirtualFunctions    int
+}
+
+// CollectNICs retrieves the physical NICs of each host visible to c that
+// passes the host filters, from config.network and the SR-IOV state of
+// config.pciPassthruInfo. Hosts that report no
+// network configuration, such as disconnected ones, are left out. NICs are
+// in inventory order of their hosts, then in the order the host lists them.
+func CollectNICs(ctx context.Context, c *vim25.Client, opts Options) ([]PhysicalNIC, error) {
+	hosts, err := selectHosts(ctx, c, opts, "config.network", "config.pciPassthruInfo", "hardware.pciDevice")
+	if err != nil {
+		return nil, err
+	}
+
+	anon := opts.anonymizer()
+	vcenter := anon.vcenter(opts.VCenter)
+	var nics []PhysicalNIC
+	for _, h := range hosts {
+		if h.Config == nil || h.Config.Network == nil {
+			continue
+		}
+		network := h.Config.Network
+
+		// Switches list their uplinks by NIC key
+		type uplink struct{ kind, name string }
+		uplinks := make(map[string]uplink)
+		for _, s := range network.Vswitch {
+			for _, key := range s.Pnic {
+				uplinks[key] = uplink{"standard", s.Name}
+			}
+		}
+		for _, s := range network.ProxySwitch {
+			for _, key := range s.Pnic {
+				uplinks[key] = uplink{"distributed", s.DvsName}
+			}
+		}
+		sriov := make(map[string]*types.HostSriovInfo) // by PCI address
+		for _, p := range h.Config.PciPassthruInfo {
+			if s, ok := p.(*types.HostSriovInfo); ok {
+				sriov[s.Id] = s
+			}
+		}
+		pci := make(map[string]int)
+		if h.Hardware != nil {
+			for i, d := range h.Hardware.PciDevice {
+				pci[d.Id] = i
+			}
+		}
+
+		for _, p := range network.Pnic {
+			nic := PhysicalNIC{
+				VCenter:         vcenter,
+				Host:            h.Host,
+				Cluster:         h.Cluster,
+				Device:          p.Device,
+				PCI:             p.Pci,
+				Driver:          p.Driver,
+				DriverVersion:   p.DriverVersion,
+				FirmwareVersion: p.FirmwareVersion,
+			}
+			if i, ok := pci[p.Pci]; ok {
+				d := h.Hardware.PciDevice[i]
+				nic.Vendor, nic.Model = d.VendorName, d.DeviceName
+			}
+			if p.LinkSpeed != nil {
+				nic.LinkSpeedMb = int(p.LinkSpeed.SpeedMb)
+			}
+			if s := sriov[p.Pci]; s != nil && s.SriovCapable {
+				nic.SRIOVCapable, nic.SRIOVEnabled = true, s.SriovEnabled
+				nic.VirtualFunctions = int(s.NumVirtualFunctionRequested)
+				nic.ActiveVirtualFunctions = int(s.NumVirtualFunction)
+				nic.MaxVirtualFunctions = int(s.MaxVirtualFunctionSupported)
+			}
+			if u, ok := uplinks[p.Key]; ok {
+				nic.SwitchType = u.kind
+				nic.Switch = anon.networkSwitch(opts.VCenter, u.name)
+			}
+			nics = append(nics, nic)
+		}
+	}
+	return nics, nil
+}
@@ -0,0 +1,128 @@
+package collector
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/vmware/govmomi/vim25"
+)
+
+// NTPDNSConfig is a host's time synchronization and DNS client
+// configuration, and how it departs from what is expected of it.
+type NTPDNSConfig struct {
+	VCenter       string
+	Host          string
+	Cluster       string
+	TimeProtocol  string // "ntp" or "ptp"
+	NTPServers    []string
+	NTPRunning    *bool  // nil if the host reports no services
+	NTPPolicy     string // startup policy: "on", "off", or "automatic"
+	DNSServers    []string
+	SearchDomains []string
+	Domain        string
+	DNSFromDHCP   bool
+	Issues        []string // e.g. "NTP service not running"
+}
+
+// NTPDNSExpected are the settings CollectNTPDNS checks hosts against.
+// Empty ones are not checked. Lists are compared regardless of order and
+// case.
+type NTPDNSExpected struct {
+	NTPServers    []string
+	DNSServers    []string
+	SearchDomains []string
+}
+
+// CollectNTPDNS retrieves the NTP and DNS configuration of each host
+// visible to c that passes the host filters, in inventory order. Hosts that
+// report no configuration, such as disconnected ones, are left out. Each
+// host is checked against expected; a host synchronizing time by NTP
+// without servers or a running NTP service is an issue whatever is
+// expected.
+func CollectNTPDNS(ctx context.Context, c *vim25.Client, opts Options, expected NTPDNSExpected) ([]NTPDNSConfig, error) {
+	hosts, err := selectHosts(ctx, c, opts, "config.dateTimeInfo", "config.service", "config.network.dnsConfig")
+	if err != nil {
+		return nil, err
+	}
+
+	anon := opts.anonymizer()
+	vcenter := anon.vcenter(opts.VCenter)
+	var configs []NTPDNSConfig
+	for _, h := range hosts {
+		if h.Config == nil {
+			continue
+		}
+		cfg := NTPDNSConfig{TimeProtocol: "ntp", NTPRunning: serviceRunning(h.HostSystem, "ntpd")}
+		if t := h.Config.DateTimeInfo; t != nil {
+			if t.SystemClockProtocol != "" {
+				cfg.TimeProtocol = t.SystemClockProtocol
+			}
+			if t.NtpConfig != nil {
+				cfg.NTPServers = t.NtpConfig.Server
+			}
+		}
+		if h.Config.Service != nil {
+			for _, s := range h.Config.Service.Service {
+				if s.Key == "ntpd" {
+					cfg.NTPPolicy = s.Policy
+				}
+			}
+		}
+		if h.Config.Network != nil && h.Config.Network.DnsConfig != nil {
+			dns := h.Config.Network.DnsConfig.GetHostDnsConfig()
+			cfg.DNSServers, cfg.SearchDomains = dns.Address, dns.SearchDomain
+			cfg.Domain, cfg.DNSFromDHCP = dns.DomainName, dns.Dhcp
+		}
+		cfg.Issues = ntpDNSIssues(cfg, expected)
+
+		// Checked against the real names, then labeled
+		cfg.VCenter, cfg.Host, cfg.Cluster = vcenter, h.Host, h.Cluster
+		cfg.NTPServers = anon.names("NTP Server", cfg.NTPServers)
+		cfg.DNSServers = anon.names("DNS Server", cfg.DNSServers)
+		cfg.SearchDomains = anon.names("Domain", cfg.SearchDomains)
+		cfg.Domain = anon.Name("Domain", cfg.Domain)
+		configs = append(configs, cfg)
+	}
+	return configs, nil
+}
+
+// ntpDNSIssues returns how cfg departs from expected and from working time
+// synchronization.
+func ntpDNSIssues(cfg NTPDNSConfig, expected NTPDNSExpected) []string {
+	var issues []string
+	if cfg.TimeProtocol == "ntp" {
+		if len(cfg.NTPServers) == 0 {
+			issues = append(issues, "no NTP servers")
+		}
+		if cfg.NTPRunning != nil && !*cfg.NTPRunning {
+			issues = append(issues, "NTP service not running")
+		}
+	}
+	if len(expected.NTPServers) > 0 && !sameNames(cfg.NTPServers, expected.NTPServers) {
+		issues = append(issues, "NTP servers not as expected")
+	}
+	if len(expected.DNSServers) > 0 && !sameNames(cfg.DNSServers, expected.DNSServers) {
+		issues = append(issues, "DNS servers not as expected")
+	}
+	if len(expected.SearchDomains) > 0 && !sameNames(cfg.SearchDomains, expected.SearchDomains) {
+		issues = append(issues, "search domains not as expected")
+	}
+	return issues
+}
+
+// sameNames reports whether a and b hold the same names, in any order and
+// case.
+func sameNames(a, b []string) bool {
+	normalize := func(names []string) []string {
+		var n []string
+		for _, s := range names {
+			if s = strings.ToLower(s); !slices.Contains(n, s) {
+				n = append(n, s)
+			}
+		}
+		slices.Sort(n)
+		return n
+	}
+	return slices.Equal(normalize(a), normalize(b))
+}
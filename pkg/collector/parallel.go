@@ -0,0 +1,24 @@
+package collector
+
+import "sync"
+
+// parallel calls fn(i) for every i in [0, n), running at most limit calls at
+// once. Callers write results into slots indexed by i, so no locking is
+// needed. A limit below 1 runs the calls one at a time.
+func parallel(n, limit int, fn func(i int)) {
+	if limit < 1 {
+		limit = 1
+	}
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i)
+		}()
+	}
+	wg.Wait()
+}
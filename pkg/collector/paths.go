@@ -0,0 +1,90 @@
+package collector
+
+import (
+	"context"
+	"strings"
+
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// MultipathLUN is a shared disk of a host, its paths, and the policies
+// the host selects them by.
+type MultipathLUN struct {
+	VCenter      string
+	Host         string
+	Cluster      string
+	Device       string // canonical name, e.g. "naa.600a0980..."
+	Vendor       string
+	Model        string
+	PathPolicy   string // path selection plugin, e.g. "VMW_PSP_RR"
+	SATP         string // storage array type plugin, e.g. "VMW_SATP_ALUA"
+	Paths        int
+	ActivePaths  int
+	StandbyPaths int
+	DeadPaths    int
+	SinglePath   bool // fewer than two paths that are not dead
+}
+
+// CollectMultipathLUNs retrieves the paths of the disks of each host
+// visible to c that passes the host filters, from
+// config.storageDevice.multipathInfo. Local disks, which have a single path
+// by design, are left out, as are hosts that report no storage devices,
+// such as disconnected ones. LUNs are in inventory order of their hosts,
+// then in the order the host lists them.
+func CollectMultipathLUNs(ctx context.Context, c *vim25.Client, opts Options) ([]MultipathLUN, error) {
+	hosts, err := selectHosts(ctx, c, opts, "config.storageDevice.scsiLun", "config.storageDevice.multipathInfo")
+	if err != nil {
+		return nil, err
+	}
+
+	anon := opts.anonymizer()
+	vcenter := anon.vcenter(opts.VCenter)
+	var luns []MultipathLUN
+	for _, h := range hosts {
+		if h.Config == nil || h.Config.StorageDevice == nil || h.Config.StorageDevice.MultipathInfo == nil {
+			continue
+		}
+		disks := make(map[string]*types.HostScsiDisk) // by ScsiLun key
+		for _, l := range h.Config.StorageDevice.ScsiLun {
+			if d, ok := l.(*types.HostScsiDisk); ok {
+				disks[d.Key] = d
+			}
+		}
+
+		for _, u := range h.Config.StorageDevice.MultipathInfo.Lun {
+			d := disks[u.Lun]
+			if d == nil || d.LocalDisk != nil && *d.LocalDisk {
+				continue
+			}
+			lun := MultipathLUN{
+				VCenter: vcenter,
+				Host:    h.Host,
+				Cluster: h.Cluster,
+				Device:  anon.Name("Disk", d.CanonicalName),
+				Vendor:  strings.TrimSpace(d.Vendor),
+				Model:   strings.TrimSpace(d.Model),
+				Paths:   len(u.Path),
+			}
+			if u.Policy != nil {
+				lun.PathPolicy = u.Policy.GetHostMultipathInfoLogicalUnitPolicy().Policy
+			}
+			if u.StorageArrayTypePolicy != nil {
+				lun.SATP = u.StorageArrayTypePolicy.Policy
+			}
+			for _, p := range u.Path {
+				switch types.MultipathState(p.PathState) {
+				case types.MultipathStateActive:
+					lun.ActivePaths++
+				case types.MultipathStateStandby:
+					lun.StandbyPaths++
+				case types.MultipathStateDead:
+					lun.DeadPaths++
+				}
+			}
+			lun.SinglePath = lun.Paths-lun.DeadPaths < 2
+			luns = append(luns, lun)
+		}
+	}
+	return luns, nil
+}
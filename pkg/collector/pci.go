@@ -0,0 +1,108 @@
+package collector
+
+import (
+	"context"
+	"slices"
+
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// PCIDevice is a GPU of a host, or another PCI device it has enabled for
+// passthrough to VMs (DirectPath I/O).
+type PCIDevice struct {
+	VCenter            string
+	Host               string
+	Cluster            string
+	ID                 string // PCI address, e.g. "0000:3b:00.0"
+	Vendor             string // e.g. "NVIDIA Corporation"
+	Model              string // e.g. "GA100 [A100 PCIe 40GB]"
+	GPU                bool
+	PassthroughEnabled bool // configured for passthrough
+	PassthroughActive  bool // passthrough in effect, which may need a reboot
+}
+
+// PCI display controller classes and the vendors whose display controllers
+// are GPUs. Servers' onboard graphics, from the BMC, are VGA controllers
+// of other vendors such as Matrox or ASPEED.
+const (
+	pciClassDisplay = 0x03
+	pciClass3D      = 0x0302
+)
+
+var gpuVendors = map[uint16]bool{
+	0x10de: true, // NVIDIA
+	0x1002: true, // AMD
+}
+
+// isGPU reports whether d is a GPU: a 3D controller, or a display
+// controller of a GPU vendor.
+func isGPU(d types.HostPciDevice) bool {
+	class := uint16(d.ClassId)
+	return class == pciClass3D || class>>8 == pciClassDisplay && gpuVendors[uint16(d.VendorId)]
+}
+
+// gpus returns the number of GPUs of h and their distinct models, each
+// with its vendor, in the order the host lists them.
+func gpus(h mo.HostSystem) (int, []string) {
+	if h.Hardware == nil {
+		return 0, nil
+	}
+	n := 0
+	var models []string
+	for _, d := range h.Hardware.PciDevice {
+		if !isGPU(d) {
+			continue
+		}
+		n++
+		if m := d.VendorName + " " + d.DeviceName; !slices.Contains(models, m) {
+			models = append(models, m)
+		}
+	}
+	return n, models
+}
+
+// CollectPCIDevices retrieves the GPUs and passthrough-enabled PCI devices
+// of each host visible to c that passes the host filters. Devices are in
+// inventory order of their hosts, then in the order the host lists them.
+func CollectPCIDevices(ctx context.Context, c *vim25.Client, opts Options) ([]PCIDevice, error) {
+	hosts, err := selectHosts(ctx, c, opts, "hardware.pciDevice", "config.pciPassthruInfo")
+	if err != nil {
+		return nil, err
+	}
+
+	anon := opts.anonymizer()
+	vcenter := anon.vcenter(opts.VCenter)
+	var devices []PCIDevice
+	for _, h := range hosts {
+		if h.Hardware == nil {
+			continue
+		}
+		passthru := make(map[string]*types.HostPciPassthruInfo)
+		if h.Config != nil {
+			for _, p := range h.Config.PciPassthruInfo {
+				info := p.GetHostPciPassthruInfo()
+				passthru[info.Id] = info
+			}
+		}
+		for _, d := range h.Hardware.PciDevice {
+			dev := PCIDevice{
+				VCenter: vcenter,
+				Host:    h.Host,
+				Cluster: h.Cluster,
+				ID:      d.Id,
+				Vendor:  d.VendorName,
+				Model:   d.DeviceName,
+				GPU:     isGPU(d),
+			}
+			if p := passthru[d.Id]; p != nil {
+				dev.PassthroughEnabled, dev.PassthroughActive = p.PassthruEnabled, p.PassthruActive
+			}
+			if dev.GPU || dev.PassthroughEnabled {
+				devices = append(devices, dev)
+			}
+		}
+	}
+	return devices, nil
+}
@@ -0,0 +1,304 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/vmware/govmomi/performance"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// Utilization is a host's CPU and memory use over a window, in percent. The
+// peaks are the highest of the rollup averages, not instantaneous peaks.
+type Utilization struct {
+	CPUAvgPct     float64
+	CPUPeakPct    float64
+	MemoryAvgPct  float64
+	MemoryPeakPct float64
+}
+
+// utilizationCounters are the performance counters Utilization is taken
+// from, both in hundredths of a percent.
+var utilizationCounters = []string{"cpu.usage.average", "mem.usage.average"}
+
+// PerfInterval returns the vCenter historical interval, in seconds, whose
+// rollups cover window at the finest detail: 5 minutes for a day, 30
+// minutes for a week, 2 hours for a month, and a day beyond that.
+func PerfInterval(window time.Duration) int32 {
+	switch {
+	case window <= 24*time.Hour:
+		return 300
+	case window <= 7*24*time.Hour:
+		return 1800
+	case window <= 30*24*time.Hour:
+		return 7200
+	}
+	return 86400
+}
+
+// hostUtilization returns the utilization of hosts over the window ending
+// now, keyed by MoRef value. Hosts without samples are left out.
+func hostUtilization(ctx context.Context, c *vim25.Client, hosts []mo.HostSystem, window time.Duration) (map[string]Utilization, error) {
+	pm := performance.NewManager(c)
+	ids, err := perfMetrics(ctx, pm, utilizationCounters)
+	if err != nil {
+		return nil, err
+	}
+
+	end := time.Now()
+	start := end.Add(-window)
+	specs := make([]types.PerfQuerySpec, len(hosts))
+	for i, h := range hosts {
+		specs[i] = types.PerfQuerySpec{
+			Entity:     h.Self,
+			StartTime:  &start,
+			EndTime:    &end,
+			IntervalId: PerfInterval(window),
+			MetricId:   ids,
+		}
+	}
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	series, err := pm.Query(ctx, specs)
+	if err != nil {
+		return nil, fmt.Errorf("querying performance: %w", err)
+	}
+
+	utilization := make(map[string]Utilization)
+	for _, s := range series {
+		m, ok := s.(*types.PerfEntityMetric)
+		if !ok {
+			continue
+		}
+		var u Utilization
+		found := false
+		for _, v := range m.Value {
+			iv, ok := v.(*types.PerfMetricIntSeries)
+			if !ok {
+				continue
+			}
+			avg, peak, ok := seriesStats(iv.Value)
+			if !ok {
+				continue
+			}
+			found = true
+			switch iv.Id.CounterId {
+			case ids[0].CounterId:
+				u.CPUAvgPct, u.CPUPeakPct = avg, peak
+			case ids[1].CounterId:
+				u.MemoryAvgPct, u.MemoryPeakPct = avg, peak
+			}
+		}
+		if found {
+			utilization[m.Entity.Value] = u
+		}
+	}
+	return utilization, nil
+}
+
+// perfMetrics returns the metric ids of the aggregate instance of the named
+// counters, such as cpu.usage.average.
+func perfMetrics(ctx context.Context, pm *performance.Manager, names []string) ([]types.PerfMetricId, error) {
+	counters, err := pm.CounterInfoByName(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("reading performance counters: %w", err)
+	}
+	ids := make([]types.PerfMetricId, len(names))
+	for i, name := range names {
+		counter, ok := counters[name]
+		if !ok {
+			return nil, fmt.Errorf("performance counter %s not found", name)
+		}
+		ids[i] = types.PerfMetricId{CounterId: counter.Key}
+	}
+	return ids, nil
+}
+
+// seriesStats returns the average and peak of samples in hundredths of a
+// percent, as percentages, skipping the -1 of missing samples.
+func seriesStats(samples []int64) (avg, peak float64, ok bool) {
+	var sum, n, max int64
+	for _, v := range samples {
+		if v < 0 {
+			continue
+		}
+		sum += v
+		n++
+		if v > max {
+			max = v
+		}
+	}
+	if n == 0 {
+		return 0, 0, false
+	}
+	return float64(sum) / float64(n) / 100, float64(max) / 100, true
+}
+
+// PerfCounter is a performance counter the perf report can export.
+type PerfCounter struct {
+	Name  string  // of the vCenter counter, rolled up by average
+	Unit  string  // of the exported values: "%" or "KBps"
+	Scale float64 // from the counter's values to Unit
+}
+
+// PerfCounters are the counters the perf report can export, by short name.
+var PerfCounters = map[string]PerfCounter{
+	"cpu.usage":  {"cpu.usage.average", "%", 0.01},
+	"mem.usage":  {"mem.usage.average", "%", 0.01},
+	"disk.usage": {"disk.usage.average", "KBps", 1},
+}
+
+// PerfCounterOrder is the order of the perf report's counter columns, and
+// the counters it exports by default.
+var PerfCounterOrder = []string{"cpu.usage", "mem.usage", "disk.usage"}
+
+// PerfOptions select the samples CollectPerf retrieves.
+type PerfOptions struct {
+	Counters []string // keys of PerfCounters
+	From, To time.Time
+}
+
+// PerfSample is the value of each counter of one host, or with an empty
+// Host one cluster, at one time.
+type PerfSample struct {
+	VCenter string
+	Host    string
+	Cluster string
+	Hosts   int // in a cluster's sample, the hosts it combines
+	Time    time.Time
+	Values  map[string]float64 // by PerfCounters key; absent if not sampled
+}
+
+// CollectPerf retrieves the samples of po.Counters between po.From and
+// po.To of each host visible to c that passes the host filters, in the
+// finest historical interval covering the range (see PerfInterval). Samples
+// are in inventory order of their hosts, then in time order. A host whose
+// samples cannot be retrieved is reported through opts.fail and left out.
+func CollectPerf(ctx context.Context, c *vim25.Client, opts Options, po PerfOptions) ([]PerfSample, error) {
+	hosts, err := selectHosts(ctx, c, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	pm := performance.NewManager(c)
+	names := make([]string, len(po.Counters))
+	for i, k := range po.Counters {
+		names[i] = PerfCounters[k].Name
+	}
+	ids, err := perfMetrics(ctx, pm, names)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int32]string, len(ids))
+	for i, id := range ids {
+		byID[id.CounterId] = po.Counters[i]
+	}
+
+	vcenter := opts.anonymizer().vcenter(opts.VCenter)
+	samples := make([][]PerfSample, len(hosts))
+	done := opts.tracker(len(hosts))
+	parallel(len(hosts), opts.Concurrency, func(i int) {
+		defer done()
+		h := hosts[i]
+		from, to := po.From, po.To
+		series, err := pm.Query(ctx, []types.PerfQuerySpec{{
+			Entity:     h.Self,
+			StartTime:  &from,
+			EndTime:    &to,
+			IntervalId: PerfInterval(to.Sub(from)),
+			MetricId:   ids,
+		}})
+		if err != nil {
+			opts.fail(h.Name, "perf", err)
+			return
+		}
+		for _, s := range series {
+			m, ok := s.(*types.PerfEntityMetric)
+			if !ok {
+				continue
+			}
+			for j, info := range m.SampleInfo {
+				sample := PerfSample{
+					VCenter: vcenter,
+					Host:    h.Host,
+					Cluster: h.Cluster,
+					Time:    info.Timestamp,
+					Values:  make(map[string]float64),
+				}
+				for _, v := range m.Value {
+					iv, ok := v.(*types.PerfMetricIntSeries)
+					if !ok || j >= len(iv.Value) || iv.Value[j] < 0 {
+						continue
+					}
+					k := byID[iv.Id.CounterId]
+					sample.Values[k] = float64(iv.Value[j]) * PerfCounters[k].Scale
+				}
+				samples[i] = append(samples[i], sample)
+			}
+		}
+		sort.Slice(samples[i], func(a, b int) bool { return samples[i][a].Time.Before(samples[i][b].Time) })
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var all []PerfSample
+	for _, s := range samples {
+		all = append(all, s...)
+	}
+	return all, nil
+}
+
+// PerfClusters combines the samples of hosts into a sample per cluster and
+// time, sorted by vCenter, cluster, and time: percentages are averaged over
+// the hosts sampled at that time, and rates are summed. Hosts outside a
+// cluster are grouped under an empty name, as in RollupClusters.
+func PerfClusters(hosts []PerfSample) []PerfSample {
+	type key struct {
+		vcenter, cluster string
+		time             time.Time
+	}
+	byKey := make(map[key]*PerfSample)
+	counts := make(map[key]map[string]int)
+	var keys []key
+	for _, h := range hosts {
+		k := key{h.VCenter, h.Cluster, h.Time}
+		c, ok := byKey[k]
+		if !ok {
+			c = &PerfSample{VCenter: h.VCenter, Cluster: h.Cluster, Time: h.Time, Values: make(map[string]float64)}
+			byKey[k] = c
+			counts[k] = make(map[string]int)
+			keys = append(keys, k)
+		}
+		c.Hosts++
+		for name, v := range h.Values {
+			c.Values[name] += v
+			counts[k][name]++
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.vcenter != b.vcenter {
+			return a.vcenter < b.vcenter
+		}
+		if a.cluster != b.cluster {
+			return a.cluster < b.cluster
+		}
+		return a.time.Before(b.time)
+	})
+	clusters := make([]PerfSample, len(keys))
+	for i, k := range keys {
+		c := byKey[k]
+		for name, n := range counts[k] {
+			if PerfCounters[name].Unit == "%" {
+				c.Values[name] /= float64(n)
+			}
+		}
+		clusters[i] = *c
+	}
+	return clusters
+}
@@ -0,0 +1,202 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/vmware/govmomi/pbm"
+	pbmtypes "github.com/vmware/govmomi/pbm/types"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+)
+
+// StoragePolicy is a VM storage policy (SPBM requirement profile), its
+// rules, and the VMs and objects it is assigned to.
+type StoragePolicy struct {
+	VCenter    string
+	Name       string
+	FTT        *int     // vSAN failures to tolerate, nil if not set
+	RAID       string   // vSAN RAID level: RAID-0, RAID-1, RAID-5, or RAID-6
+	Encryption *bool    // nil if it could not be checked
+	Rules      []string // "namespace.rule=value", e.g. "VSAN.stripeWidth=1"
+	VMs        int
+	Objects    int // VM homes and virtual disks
+}
+
+// dataServiceNamespace is the rule namespace of host-based data services,
+// such as VM encryption, which are provided by I/O filters.
+const dataServiceNamespace = "com.vmware.storageprofile.dataservice"
+
+// CollectStoragePolicies retrieves the storage policies of the vCenter c is
+// connected to from its storage policy (pbm) endpoint, in the order it
+// lists them, with the VMs visible to c and their virtual disks assigned to
+// each. With a Clusters or Tags filter only VMs on the selected hosts are
+// counted. A policy with host-based rules that cannot be checked for
+// encryption is reported through opts.fail.
+func CollectStoragePolicies(ctx context.Context, c *vim25.Client, opts Options) ([]StoragePolicy, error) {
+	root, err := containerRoot(ctx, c, opts)
+	if err != nil {
+		return nil, err
+	}
+	v, err := view.NewManager(c).CreateContainerView(ctx, root, []string{"VirtualMachine"}, true)
+	if err != nil {
+		return nil, fmt.Errorf("creating container view: %w", err)
+	}
+	defer destroyView(ctx, v)
+
+	var vms []mo.VirtualMachine
+	if err := v.Retrieve(ctx, []string{"VirtualMachine"}, []string{"runtime.host"}, &vms); err != nil {
+		return nil, fmt.Errorf("retrieving VMs: %w", err)
+	}
+	var selected map[string]string // host MoRef Value -> cluster name
+	if opts.filtered() {
+		if selected, err = selectedHosts(ctx, c, root, opts); err != nil {
+			return nil, err
+		}
+	}
+	visible := make(map[string]bool) // VM MoRef Value
+	for _, vm := range vms {
+		if selected != nil {
+			if vm.Runtime.Host == nil {
+				continue
+			}
+			if _, ok := selected[vm.Runtime.Host.Value]; !ok {
+				continue
+			}
+		}
+		visible[vm.Self.Value] = true
+	}
+
+	pc, err := pbm.NewClient(ctx, c)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to the storage policy service: %w", err)
+	}
+	resourceType := pbmtypes.PbmProfileResourceType{ResourceType: string(pbmtypes.PbmProfileResourceTypeEnumSTORAGE)}
+	ids, err := pc.QueryProfile(ctx, resourceType, string(pbmtypes.PbmProfileCategoryEnumREQUIREMENT))
+	if err != nil {
+		return nil, fmt.Errorf("querying storage policies: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	profiles, err := pc.RetrieveContent(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("retrieving storage policies: %w", err)
+	}
+	associated, err := pc.QueryAssociatedEntities(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("querying storage policy assignments: %w", err)
+	}
+
+	// The VMs and objects assigned each policy, by profile ID. Disks are
+	// identified by VM and device key, as "vm-123:2000".
+	type usage struct{ vms, objects int }
+	used := make(map[string]usage)
+	for _, r := range associated {
+		if vm, _, _ := strings.Cut(r.Object.Key, ":"); !visible[vm] {
+			continue
+		}
+		for _, id := range r.ProfileId {
+			u := used[id.UniqueId]
+			switch pbmtypes.PbmObjectType(r.Object.ObjectType) {
+			case pbmtypes.PbmObjectTypeVirtualMachine:
+				u.vms++
+				u.objects++
+			case pbmtypes.PbmObjectTypeVirtualDiskId:
+				u.objects++
+			}
+			used[id.UniqueId] = u
+		}
+	}
+
+	anon := opts.anonymizer()
+	vcenter := anon.vcenter(opts.VCenter)
+	var policies []StoragePolicy
+	for _, b := range profiles {
+		p, ok := b.(*pbmtypes.PbmCapabilityProfile)
+		if !ok {
+			continue
+		}
+		policy := StoragePolicy{
+			VCenter:    vcenter,
+			Name:       anon.Name("Storage Policy", p.Name),
+			Encryption: new(bool),
+			VMs:        used[p.ProfileId.UniqueId].vms,
+			Objects:    used[p.ProfileId.UniqueId].objects,
+		}
+		dataServices := false
+		var preference string
+		if sub, ok := p.Constraints.(*pbmtypes.PbmCapabilitySubProfileConstraints); ok {
+			for _, s := range sub.SubProfiles {
+				for _, capability := range s.Capability {
+					ns := capability.Id.Namespace
+					dataServices = dataServices || ns == dataServiceNamespace
+					for _, constraint := range capability.Constraint {
+						for _, prop := range constraint.PropertyInstance {
+							policy.Rules = append(policy.Rules, ns+"."+prop.Id+"="+ruleValue(prop.Value))
+							if ns != "VSAN" {
+								continue
+							}
+							switch prop.Id {
+							case "hostFailuresToTolerate":
+								if ftt, ok := prop.Value.(int32); ok {
+									n := int(ftt)
+									policy.FTT = &n
+								}
+							case "replicaPreference":
+								preference, _ = prop.Value.(string)
+							}
+						}
+					}
+				}
+			}
+		}
+		if policy.FTT != nil {
+			policy.RAID = vsanRAID(*policy.FTT, preference)
+		}
+		if dataServices {
+			encrypted, err := pc.SupportsEncryption(ctx, p.ProfileId.UniqueId)
+			if err != nil {
+				opts.fail("", "spbm", fmt.Errorf("could not check storage policy %q for encryption: %w", p.Name, err))
+				policy.Encryption = nil
+			} else {
+				*policy.Encryption = encrypted
+			}
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+// vsanRAID returns the RAID level of a vSAN policy with ftt failures to
+// tolerate and replica preference, the rule choosing between mirroring and
+// erasure coding.
+func vsanRAID(ftt int, preference string) string {
+	switch {
+	case ftt == 0:
+		return "RAID-0"
+	case strings.Contains(preference, "Erasure Coding") && ftt == 1:
+		return "RAID-5"
+	case strings.Contains(preference, "Erasure Coding") && ftt == 2:
+		return "RAID-6"
+	}
+	return "RAID-1"
+}
+
+// ruleValue returns the value of a storage policy rule as text: a range as
+// "min-max" and a set as its values, comma-separated.
+func ruleValue(v any) string {
+	switch v := v.(type) {
+	case *pbmtypes.PbmCapabilityRange:
+		return fmt.Sprintf("%v-%v", v.Min, v.Max)
+	case *pbmtypes.PbmCapabilityDiscreteSet:
+		values := make([]string, len(v.Values))
+		for i, value := range v.Values {
+			values[i] = fmt.Sprint(value)
+		}
+		return strings.Join(values, ",")
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,87 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// retryRoundTripper retries vCenter calls that fail with a transient error,
+// doubling the delay between attempts. It wraps every call made through a
+// client, so property retrieval and per-host vSAN queries are covered alike.
+type retryRoundTripper struct {
+	rt       soap.RoundTripper
+	host     string // vCenter, for log messages
+	attempts int    // total attempts, including the first
+	backoff  time.Duration
+}
+
+func (r *retryRoundTripper) RoundTrip(ctx context.Context, req, res soap.HasFault) error {
+	delay := r.backoff
+	for attempt := 1; ; attempt++ {
+		err := r.rt.RoundTrip(ctx, req, res)
+		if err == nil || attempt >= r.attempts || ctx.Err() != nil || !transient(err) {
+			return err
+		}
+		slog.Warn("Retrying vCenter call", "vcenter", r.host, "op", methodName(req), "attempt", attempt, "delay", delay, "err", err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// transient reports whether err is worth retrying: a network failure, or a
+// fault vCenter raises when it briefly cannot reach a host.
+func transient(err error) bool {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true // connection refused, reset, or unreachable
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		// HTTP status errors carry only the status text; 502-504 come
+		// from the vCenter reverse proxy while services restart
+		for _, code := range []string{"502", "503", "504"} {
+			if strings.HasPrefix(urlErr.Err.Error(), code+" ") {
+				return true
+			}
+		}
+	}
+	if soap.IsSoapFault(err) {
+		switch soap.ToSoapFault(err).VimFault().(type) {
+		case types.HostCommunication, *types.HostCommunication,
+			types.HostNotReachable, *types.HostNotReachable:
+			return true
+		}
+	}
+	return false
+}
+
+// methodName returns the vSphere method of a request body for log messages,
+// e.g. RetrievePropertiesEx.
+func methodName(req soap.HasFault) string {
+	t := reflect.TypeOf(req)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return strings.TrimSuffix(t.Name(), "Body")
+}
@@ -0,0 +1,51 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/soap"
+)
+
+// flakyRoundTripper fails with err the first failures times it is called.
+type flakyRoundTripper struct {
+	failures int
+	err      error
+	calls    int
+}
+
+func (f *flakyRoundTripper) RoundTrip(ctx context.Context, req, res soap.HasFault) error {
+	f.calls++
+	if f.calls <= f.failures {
+		return f.err
+	}
+	return nil
+}
+
+func TestRetryRoundTripper(t *testing.T) {
+	reset := &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}
+	tests := []struct {
+		name      string
+		failures  int
+		err       error
+		wantCalls int
+		wantErr   bool
+	}{
+		{"success", 0, nil, 1, false},
+		{"recovers", 2, reset, 3, false},
+		{"gives up", 5, reset, 4, true},
+		{"permanent", 5, errors.New("NotAuthenticated"), 1, true},
+	}
+	for _, tt := range tests {
+		flaky := &flakyRoundTripper{failures: tt.failures, err: tt.err}
+		rt := &retryRoundTripper{rt: flaky, attempts: 4, backoff: time.Millisecond}
+		err := rt.RoundTrip(context.Background(), &methods.RetrievePropertiesExBody{}, &methods.RetrievePropertiesExBody{})
+		if (err != nil) != tt.wantErr || flaky.calls != tt.wantCalls {
+			t.Errorf("%s: err = %v after %d calls, want error %v after %d", tt.name, err, flaky.calls, tt.wantErr, tt.wantCalls)
+		}
+	}
+}
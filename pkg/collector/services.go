@@ -0,0 +1,76 @@
+package collector
+
+import (
+	"context"
+	"slices"
+
+	"github.com/vmware/govmomi/vim25"
+)
+
+// HostService is the state and startup policy of a service of a host.
+type HostService struct {
+	VCenter     string
+	Host        string
+	Cluster     string
+	Key         string // e.g. "TSM-SSH"
+	Label       string // e.g. "SSH"
+	Running     bool
+	Policy      string // startup policy: "on", "off", or "automatic"
+	Nonstandard bool   // set by MarkNonstandardServices
+}
+
+// CollectHostServices retrieves the services of each host visible to c that
+// passes the host filters, those with the keys in opts.Services if any, in
+// inventory order of their hosts, then in the order the host lists them.
+// Hosts that report no services, such as disconnected ones, are left out.
+func CollectHostServices(ctx context.Context, c *vim25.Client, opts Options) ([]HostService, error) {
+	hosts, err := selectHosts(ctx, c, opts, "config.service")
+	if err != nil {
+		return nil, err
+	}
+
+	anon := opts.anonymizer()
+	vcenter := anon.vcenter(opts.VCenter)
+	var services []HostService
+	for _, h := range hosts {
+		if h.Config == nil || h.Config.Service == nil {
+			continue
+		}
+		for _, s := range h.Config.Service.Service {
+			if len(opts.Services) > 0 && !slices.Contains(opts.Services, s.Key) {
+				continue
+			}
+			services = append(services, HostService{
+				VCenter: vcenter,
+				Host:    h.Host,
+				Cluster: h.Cluster,
+				Key:     s.Key,
+				Label:   s.Label,
+				Running: s.Running,
+				Policy:  s.Policy,
+			})
+		}
+	}
+	return services, nil
+}
+
+// MarkNonstandardServices sets Nonstandard on each of services whose state
+// and policy are not the most common of its key across services. When
+// several are equally common, none of them is marked.
+func MarkNonstandardServices(services []HostService) {
+	type config struct {
+		key     string
+		running bool
+		policy  string
+	}
+	counts := make(map[config]int)
+	most := make(map[string]int) // highest count by key
+	for _, s := range services {
+		c := config{s.Key, s.Running, s.Policy}
+		counts[c]++
+		most[s.Key] = max(most[s.Key], counts[c])
+	}
+	for i, s := range services {
+		services[i].Nonstandard = counts[config{s.Key, s.Running, s.Policy}] < most[s.Key]
+	}
+}
@@ -0,0 +1,86 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"time"
+
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// LevelTrace is the slog level at which every vCenter call is logged, one
+// step more verbose than slog.LevelDebug. Connect installs the tracing when
+// the default logger has it enabled.
+const LevelTrace = slog.LevelDebug - 4
+
+// traceRoundTripper logs each vCenter call with its target object and
+// duration. It wraps the client directly, so each retry is logged too.
+type traceRoundTripper struct {
+	rt   soap.RoundTripper
+	host string // vCenter, for log messages
+}
+
+func (t *traceRoundTripper) RoundTrip(ctx context.Context, req, res soap.HasFault) error {
+	start := time.Now()
+	err := t.rt.RoundTrip(ctx, req, res)
+	args := []any{"vcenter", t.host, "op", methodName(req), "target", callTarget(req), "duration", time.Since(start)}
+	if err != nil {
+		args = append(args, "err", err)
+	}
+	slog.Log(ctx, LevelTrace, "vCenter call", args...)
+	return err
+}
+
+// tracer returns the OpenTelemetry tracer of collection spans, from the
+// global tracer provider, which records nothing unless one is installed.
+func tracer() trace.Tracer { return otel.Tracer("vmware-inventory/pkg/collector") }
+
+// endSpan ends span, marking it failed with err if err is non-nil.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// spanRoundTripper records each vCenter call as an OpenTelemetry span
+// named after the method, e.g. RetrievePropertiesEx, with the object it is
+// invoked on. Like traceRoundTripper it wraps the client directly, so each
+// retry is a span of its own.
+type spanRoundTripper struct {
+	rt soap.RoundTripper
+}
+
+func (s *spanRoundTripper) RoundTrip(ctx context.Context, req, res soap.HasFault) error {
+	ctx, span := tracer().Start(ctx, methodName(req), trace.WithSpanKind(trace.SpanKindClient))
+	if target := callTarget(req); target != "" && span.IsRecording() {
+		span.SetAttributes(attribute.String("vsphere.target", target))
+	}
+	err := s.rt.RoundTrip(ctx, req, res)
+	endSpan(span, err)
+	return err
+}
+
+// callTarget returns the object a request is invoked on, e.g.
+// HostVsanSystem:vsanSystem-12, or "" if it has none.
+func callTarget(req soap.HasFault) string {
+	v := reflect.Indirect(reflect.ValueOf(req))
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+	body := reflect.Indirect(v.FieldByName("Req"))
+	if body.Kind() != reflect.Struct {
+		return ""
+	}
+	if ref, ok := body.FieldByName("This").Interface().(types.ManagedObjectReference); ok {
+		return ref.String()
+	}
+	return ""
+}
@@ -0,0 +1,24 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestCallTarget(t *testing.T) {
+	ref := types.ManagedObjectReference{Type: "HostVsanSystem", Value: "vsanSystem-12"}
+	tests := []struct {
+		req  *methods.QueryDisksForVsanBody
+		want string
+	}{
+		{&methods.QueryDisksForVsanBody{Req: &types.QueryDisksForVsan{This: ref}}, "HostVsanSystem:vsanSystem-12"},
+		{&methods.QueryDisksForVsanBody{}, ""},
+	}
+	for _, tt := range tests {
+		if got := callTarget(tt.req); got != tt.want {
+			t.Errorf("callTarget(%+v) = %q, want %q", tt.req, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// VGPUProfile is a vGPU profile of a host with graphics devices, either
+// one the host offers or one its running VMs use, and those VMs. A host
+// with GPUs but no profiles, such as one using them for vSGA only, has a
+// single record without a profile.
+type VGPUProfile struct {
+	VCenter      string
+	Host         string
+	Cluster      string
+	GPUs         int    // graphics devices of the host
+	GraphicsType string // of its devices: basic, shared, direct, or sharedDirect (vGPU)
+	Profile      string // e.g. "grid_t4-4q"
+	License      string // NVIDIA license edition of the profile's series, e.g. "vWS"
+	VMs          []string
+}
+
+// vgpuSeries matches the series letter at the end of a vGPU profile name,
+// such as the q of grid_t4-4q.
+var vgpuSeries = regexp.MustCompile(`-\d+([a-z])$`)
+
+// vgpuLicenses are the NVIDIA license editions that the vGPU profiles of
+// each series need.
+var vgpuLicenses = map[string]string{
+	"a": "vApps",
+	"b": "vPC",
+	"q": "vWS",
+	"c": "AI Enterprise",
+}
+
+// vgpuLicense returns the NVIDIA license edition profile needs, or "" if
+// its series is not known.
+func vgpuLicense(profile string) string {
+	m := vgpuSeries.FindStringSubmatch(strings.ToLower(profile))
+	if m == nil {
+		return ""
+	}
+	return vgpuLicenses[m[1]]
+}
+
+// CollectVGPUs retrieves the vGPU profiles of each host visible to c that
+// passes the host filters and has graphics devices, with the running VMs
+// using each. Profiles are in inventory order of their hosts, then those
+// the host offers in its order, then any others in use by name. A host
+// whose VMs cannot be retrieved is reported through opts.fail and left out.
+func CollectVGPUs(ctx context.Context, c *vim25.Client, opts Options) ([]VGPUProfile, error) {
+	hosts, err := selectHosts(ctx, c, opts, "config.graphicsInfo", "config.sharedPassthruGpuTypes")
+	if err != nil {
+		return nil, err
+	}
+	pc := property.DefaultCollector(c)
+
+	anon := opts.anonymizer()
+	vcenter := anon.vcenter(opts.VCenter)
+	var profiles []VGPUProfile
+	for _, h := range hosts {
+		if h.Config == nil || len(h.Config.GraphicsInfo) == 0 && len(h.Config.SharedPassthruGpuTypes) == 0 {
+			continue
+		}
+		// The types of its graphics devices and the running VMs using them
+		var refs []types.ManagedObjectReference
+		var graphicsTypes []string
+		seen := make(map[types.ManagedObjectReference]bool)
+		for _, g := range h.Config.GraphicsInfo {
+			if !slices.Contains(graphicsTypes, g.GraphicsType) {
+				graphicsTypes = append(graphicsTypes, g.GraphicsType)
+			}
+			for _, vm := range g.Vm {
+				if !seen[vm] {
+					seen[vm] = true
+					refs = append(refs, vm)
+				}
+			}
+		}
+		var vms []mo.VirtualMachine
+		if len(refs) > 0 {
+			if err := pc.Retrieve(ctx, refs, []string{"name", "config.hardware.device"}, &vms); err != nil {
+				opts.fail(h.Name, "vgpu", fmt.Errorf("could not retrieve vGPU VMs: %w", err))
+				continue
+			}
+		}
+		using := make(map[string][]string) // VM names by profile
+		var inUse []string
+		for _, vm := range vms {
+			if vm.Config == nil {
+				continue
+			}
+			for _, d := range vm.Config.Hardware.Device {
+				p, ok := d.(*types.VirtualPCIPassthrough)
+				if !ok {
+					continue
+				}
+				b, ok := p.Backing.(*types.VirtualPCIPassthroughVmiopBackingInfo)
+				if !ok || b.Vgpu == "" {
+					continue
+				}
+				name := anon.vm(vm.Name)
+				if !slices.Contains(using[b.Vgpu], name) {
+					using[b.Vgpu] = append(using[b.Vgpu], name)
+				}
+				if !slices.Contains(inUse, b.Vgpu) {
+					inUse = append(inUse, b.Vgpu)
+				}
+			}
+		}
+		names := slices.Clone(h.Config.SharedPassthruGpuTypes)
+		slices.Sort(inUse)
+		for _, p := range inUse {
+			if !slices.Contains(names, p) {
+				names = append(names, p)
+			}
+		}
+		if len(names) == 0 {
+			names = []string{""}
+		}
+
+		for _, name := range names {
+			profiles = append(profiles, VGPUProfile{
+				VCenter:      vcenter,
+				Host:         h.Host,
+				Cluster:      h.Cluster,
+				GPUs:         len(h.Config.GraphicsInfo),
+				GraphicsType: strings.Join(graphicsTypes, ", "),
+				Profile:      name,
+				License:      vgpuLicense(name),
+				VMs:          using[name],
+			})
+		}
+	}
+	return profiles, nil
+}
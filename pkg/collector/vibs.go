@@ -0,0 +1,79 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// VIB is a software package installed on a host, as esxcli software vib
+// list shows it.
+type VIB struct {
+	VCenter         string
+	Host            string
+	Cluster         string
+	Name            string
+	Version         string
+	Vendor          string
+	AcceptanceLevel string // vmware_certified, vmware_accepted, partner, or community
+	Summary         string
+	CreationDate    string // YYYY-MM-DD, empty if not given
+}
+
+// CollectVIBs retrieves the VIBs installed on each connected host visible
+// to c that passes the host filters, from its image configuration manager.
+// VIBs are in inventory order of their hosts, then by name. A host whose
+// VIBs cannot be retrieved is reported through opts.fail and left out.
+func CollectVIBs(ctx context.Context, c *vim25.Client, opts Options) ([]VIB, error) {
+	hosts, err := selectHosts(ctx, c, opts, "configManager.imageConfigManager")
+	if err != nil {
+		return nil, err
+	}
+
+	vcenter := opts.anonymizer().vcenter(opts.VCenter)
+	vibs := make([][]VIB, len(hosts))
+	done := opts.tracker(len(hosts))
+	parallel(len(hosts), opts.Concurrency, func(i int) {
+		defer done()
+		h := hosts[i]
+		ref := h.ConfigManager.ImageConfigManager
+		if ref == nil || h.Summary.Runtime == nil || h.Summary.Runtime.ConnectionState != types.HostSystemConnectionStateConnected {
+			return
+		}
+		res, err := methods.FetchSoftwarePackages(ctx, c, &types.FetchSoftwarePackages{This: *ref})
+		if err != nil {
+			opts.fail(h.Name, "vibs", fmt.Errorf("could not list VIBs: %w", err))
+			return
+		}
+		for _, p := range res.Returnval {
+			vib := VIB{
+				VCenter:         vcenter,
+				Host:            h.Host,
+				Cluster:         h.Cluster,
+				Name:            p.Name,
+				Version:         p.Version,
+				Vendor:          p.Vendor,
+				AcceptanceLevel: p.AcceptanceLevel,
+				Summary:         p.Summary,
+			}
+			if p.CreationDate != nil {
+				vib.CreationDate = p.CreationDate.UTC().Format(time.DateOnly)
+			}
+			vibs[i] = append(vibs[i], vib)
+		}
+		sort.Slice(vibs[i], func(a, b int) bool { return vibs[i][a].Name < vibs[i][b].Name })
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var all []VIB
+	for _, v := range vibs {
+		all = append(all, v...)
+	}
+	return all, nil
+}
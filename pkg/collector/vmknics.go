@@ -0,0 +1,139 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+)
+
+// VMKernelAdapter is a VMkernel network adapter of a host, the traffic
+// enabled on it, and the port group it connects to.
+type VMKernelAdapter struct {
+	VCenter    string
+	Host       string
+	Cluster    string
+	Device     string // e.g. "vmk0"
+	IPAddress  string
+	SubnetMask string
+	DHCP       bool
+	MTU        int
+	NetStack   string   // TCP/IP stack, e.g. "defaultTcpipStack" or "vmotion"
+	Services   []string // traffic enabled, e.g. "Management", "vMotion"
+	SwitchType string   // "standard" or "distributed"
+	Switch     string   // vSwitch or distributed switch name
+	Portgroup  string
+}
+
+// vmkServices are the names of the traffic types a VMkernel adapter can be
+// enabled for, by the virtual NIC manager's NIC type. Others are reported
+// by NIC type.
+var vmkServices = map[string]string{
+	"management":            "Management",
+	"vmotion":               "vMotion",
+	"vsan":                  "vSAN",
+	"vsanWitness":           "vSAN Witness",
+	"vSphereProvisioning":   "Provisioning",
+	"faultToleranceLogging": "FT Logging",
+	"vSphereReplication":    "Replication",
+	"vSphereReplicationNFC": "Replication NFC",
+	"vSphereBackupNFC":      "Backup NFC",
+	"nvmeTcp":               "NVMe/TCP",
+	"nvmeRdma":              "NVMe/RDMA",
+	"ptp":                   "PTP",
+}
+
+// CollectVMKernelAdapters retrieves the VMkernel adapters of each host
+// visible to c that passes the host filters, from config.network. Hosts
+// that report no network configuration, such as disconnected ones, are left
+// out. Adapters are in inventory order of their hosts, then in the order
+// the host lists them.
+func CollectVMKernelAdapters(ctx context.Context, c *vim25.Client, opts Options) ([]VMKernelAdapter, error) {
+	hosts, err := selectHosts(ctx, c, opts, "config.network", "config.virtualNicManagerInfo")
+	if err != nil {
+		return nil, err
+	}
+	root, err := containerRoot(ctx, c, opts)
+	if err != nil {
+		return nil, err
+	}
+	v, err := view.NewManager(c).CreateContainerView(ctx, root, []string{"DistributedVirtualPortgroup"}, true)
+	if err != nil {
+		return nil, fmt.Errorf("creating container view: %w", err)
+	}
+	defer destroyView(ctx, v)
+
+	// Distributed port groups are named by key in the host's configuration
+	var pgs []mo.DistributedVirtualPortgroup
+	if err := v.Retrieve(ctx, []string{"DistributedVirtualPortgroup"}, []string{"name", "key"}, &pgs); err != nil {
+		return nil, fmt.Errorf("retrieving distributed port groups: %w", err)
+	}
+	pgNames := make(map[string]string)
+	for _, pg := range pgs {
+		pgNames[pg.Key] = pg.Name
+	}
+
+	anon := opts.anonymizer()
+	vcenter := anon.vcenter(opts.VCenter)
+	var adapters []VMKernelAdapter
+	for _, h := range hosts {
+		if h.Config == nil || h.Config.Network == nil {
+			continue
+		}
+		network := h.Config.Network
+
+		// The traffic each adapter is selected for, by device
+		services := make(map[string][]string)
+		if info := h.Config.VirtualNicManagerInfo; info != nil {
+			for _, nc := range info.NetConfig {
+				name := vmkServices[nc.NicType]
+				if name == "" {
+					name = nc.NicType
+				}
+				for _, key := range nc.SelectedVnic {
+					for _, cand := range nc.CandidateVnic {
+						if cand.Key == key {
+							services[cand.Device] = append(services[cand.Device], name)
+						}
+					}
+				}
+			}
+		}
+		vswitches := make(map[string]string) // by standard port group
+		for _, pg := range network.Portgroup {
+			vswitches[pg.Spec.Name] = pg.Spec.VswitchName
+		}
+		dvSwitches := make(map[string]string) // by UUID
+		for _, s := range network.ProxySwitch {
+			dvSwitches[s.DvsUuid] = s.DvsName
+		}
+
+		for _, n := range network.Vnic {
+			a := VMKernelAdapter{
+				VCenter:  vcenter,
+				Host:     h.Host,
+				Cluster:  h.Cluster,
+				Device:   n.Device,
+				MTU:      int(n.Spec.Mtu),
+				NetStack: n.Spec.NetStackInstanceKey,
+				Services: services[n.Device],
+			}
+			if ip := n.Spec.Ip; ip != nil {
+				a.IPAddress, a.SubnetMask, a.DHCP = anon.ip(ip.IpAddress), ip.SubnetMask, ip.Dhcp
+			}
+			if dvp := n.Spec.DistributedVirtualPort; dvp != nil {
+				a.SwitchType = "distributed"
+				a.Switch = anon.networkSwitch(opts.VCenter, dvSwitches[dvp.SwitchUuid])
+				a.Portgroup = anon.portgroup(opts.VCenter, pgNames[dvp.PortgroupKey])
+			} else if n.Portgroup != "" {
+				a.SwitchType = "standard"
+				a.Switch = anon.networkSwitch(opts.VCenter, vswitches[n.Portgroup])
+				a.Portgroup = anon.portgroup(opts.VCenter, n.Portgroup)
+			}
+			adapters = append(adapters, a)
+		}
+	}
+	return adapters, nil
+}
@@ -0,0 +1,118 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+)
+
+// VM is the sizing inventory collected for a single virtual machine.
+type VM struct {
+	VCenter    string
+	Name       string
+	Cluster    string
+	Host       string
+	PowerState string
+	GuestOS    string
+	VCPUs      int
+	MemoryGB   float64
+	// MemoryReservationGB is the memory guaranteed to the VM, which the
+	// host cannot overcommit.
+	MemoryReservationGB float64
+}
+
+// CollectVMs retrieves per-VM sizing for every VM visible to c, skipping
+// templates. With a Clusters or Tags filter only VMs running on selected
+// hosts are returned.
+func CollectVMs(ctx context.Context, c *vim25.Client, opts Options) ([]VM, error) {
+	root, err := containerRoot(ctx, c, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	m := view.NewManager(c)
+
+	// Hosts are needed to resolve each VM's host and cluster names
+	hv, err := m.CreateContainerView(ctx, root, []string{"HostSystem"}, true)
+	if err != nil {
+		return nil, fmt.Errorf("creating host container view: %w", err)
+	}
+	defer destroyView(ctx, hv)
+
+	var hosts []mo.HostSystem
+	err = hv.Retrieve(ctx, []string{"HostSystem"}, []string{"summary.config.name", "summary.runtime", "parent"}, &hosts)
+	if err != nil {
+		return nil, fmt.Errorf("retrieving hosts: %w", err)
+	}
+
+	pc := property.DefaultCollector(c)
+	parentNames := retrieveParentNames(ctx, pc, hosts, opts)
+	tagged, err := taggedHosts(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	hosts = filterHosts(hosts, parentNames, tagged, opts)
+
+	// Label hosts and clusters in the same order as CollectHosts so
+	// anonymized names line up between the two reports.
+	anon := opts.anonymizer()
+	vcenter := anon.vcenter(opts.VCenter)
+	hostNames := make(map[string]string)    // host MoRef Value -> name
+	hostClusters := make(map[string]string) // host MoRef Value -> cluster name
+	for _, h := range hosts {
+		hostNames[h.Self.Value] = anon.host(h.Summary.Config.Name)
+		if h.Parent != nil {
+			hostClusters[h.Self.Value] = anon.cluster(opts.VCenter, parentNames[h.Parent.Value])
+		}
+	}
+
+	vv, err := m.CreateContainerView(ctx, root, []string{"VirtualMachine"}, true)
+	if err != nil {
+		return nil, fmt.Errorf("creating VM container view: %w", err)
+	}
+	defer destroyView(ctx, vv)
+
+	var vms []mo.VirtualMachine
+	err = vv.Retrieve(ctx, []string{"VirtualMachine"}, []string{"summary"}, &vms)
+	if err != nil {
+		return nil, fmt.Errorf("retrieving VMs: %w", err)
+	}
+
+	records := make([]VM, 0, len(vms))
+	for _, vm := range vms {
+		cfg := vm.Summary.Config
+		if cfg.Template {
+			continue
+		}
+		ref := vm.Summary.Runtime.Host
+		if opts.filtered() {
+			// Keep only VMs on a selected host
+			if ref == nil {
+				continue
+			}
+			if _, ok := hostNames[ref.Value]; !ok {
+				continue
+			}
+		}
+
+		r := VM{
+			VCenter:             vcenter,
+			Name:                anon.vm(cfg.Name),
+			PowerState:          string(vm.Summary.Runtime.PowerState),
+			GuestOS:             cfg.GuestFullName,
+			VCPUs:               int(cfg.NumCpu),
+			MemoryGB:            float64(cfg.MemorySizeMB) / 1024,
+			MemoryReservationGB: float64(cfg.MemoryReservation) / 1024,
+		}
+		if ref != nil {
+			r.Host = hostNames[ref.Value]
+			r.Cluster = hostClusters[ref.Value]
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
@@ -0,0 +1,164 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/vmware/govmomi/sms/methods"
+	smstypes "github.com/vmware/govmomi/sms/types"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// VASAProvider is a storage provider registered with vCenter, such as the
+// VASA provider of a vVol array.
+type VASAProvider struct {
+	VCenter     string
+	Name        string
+	URL         string
+	Version     string // of the provider software
+	VASAVersion string // of the VASA API it implements, e.g. "5.0"
+	Status      string // e.g. "online", "offline", or "syncError"
+	LastSync    string // as the provider reports it
+}
+
+// VVolContainer is a vVol storage container mounted as a datastore, with
+// the storage arrays behind it and the VASA providers managing it.
+type VVolContainer struct {
+	VCenter          string
+	Datastore        string
+	Container        string // storage container ID
+	Arrays           []string
+	Providers        []string
+	ActiveProviders  []string // active for at least one of the arrays
+	ProtocolEndpoint string   // e.g. "SCSI", "NFS", or "NVMe"
+	CapacityGB       float64
+	FreeGB           float64
+	Hosts            int
+}
+
+// smsServiceInstance is the service instance of the storage monitoring
+// service (sms), which storage providers are registered with.
+var smsServiceInstance = types.ManagedObjectReference{Type: "SmsServiceInstance", Value: "ServiceInstance"}
+
+// CollectVVols retrieves the storage providers registered with the vCenter
+// c is connected to from its storage monitoring (sms) endpoint, and the vVol
+// datastores visible to c. With a Clusters or Tags filter only datastores
+// mounted by at least one selected host are returned; the providers are
+// listed whatever the filters, since they are shared by the whole vCenter.
+// Providers that cannot be listed are reported through opts.fail, and the
+// datastores returned without them. ESXi hosts connected directly have no
+// storage providers.
+func CollectVVols(ctx context.Context, c *vim25.Client, opts Options) ([]VASAProvider, []VVolContainer, error) {
+	root, err := containerRoot(ctx, c, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	v, err := view.NewManager(c).CreateContainerView(ctx, root, []string{"Datastore"}, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating container view: %w", err)
+	}
+	defer destroyView(ctx, v)
+
+	var datastores []mo.Datastore
+	if err := v.Retrieve(ctx, []string{"Datastore"}, []string{"summary", "host", "info"}, &datastores); err != nil {
+		return nil, nil, fmt.Errorf("retrieving datastores: %w", err)
+	}
+	var selected map[string]string // host MoRef Value -> cluster name
+	if opts.filtered() {
+		if selected, err = selectedHosts(ctx, c, root, opts); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	anon := opts.anonymizer()
+	vcenter := anon.vcenter(opts.VCenter)
+	var providers []VASAProvider
+	if c.IsVC() {
+		infos, err := storageProviders(ctx, c)
+		if err != nil {
+			opts.fail("", "sms", fmt.Errorf("could not list storage providers: %w", err))
+		}
+		for _, b := range infos {
+			info := b.GetSmsProviderInfo()
+			p := VASAProvider{
+				VCenter: vcenter,
+				Name:    anon.Name("VASA Provider", info.Name),
+				Version: info.Version,
+			}
+			if vp, ok := b.(*smstypes.VasaProviderInfo); ok {
+				p.URL = anon.Name("VASA Provider URL", vp.Url)
+				p.VASAVersion = vp.VasaVersion
+				p.Status = vp.Status
+				p.LastSync = vp.LastSyncTime
+			}
+			providers = append(providers, p)
+		}
+	}
+
+	var containers []VVolContainer
+	for _, ds := range datastores {
+		info, ok := ds.Info.(*types.VvolDatastoreInfo)
+		if !ok || info.VvolDS == nil {
+			continue
+		}
+		if selected != nil && !mountedBy(ds, selected) {
+			continue
+		}
+		vvol := info.VvolDS
+		container := VVolContainer{
+			VCenter:          vcenter,
+			Datastore:        anon.datastore(opts.VCenter, ds.Summary.Name),
+			Container:        anon.uuid(vvol.ScId),
+			ProtocolEndpoint: vvol.ProtocolEndpointType,
+			CapacityGB:       float64(ds.Summary.Capacity) / (1024 * 1024 * 1024),
+			FreeGB:           float64(ds.Summary.FreeSpace) / (1024 * 1024 * 1024),
+			Hosts:            len(ds.Host),
+		}
+		for _, a := range vvol.StorageArray {
+			container.Arrays = append(container.Arrays, anon.Name("Storage Array", a.Name))
+		}
+		for _, p := range vvol.VasaProviderInfo {
+			name := p.Provider.Name
+			if name == "" {
+				name = p.Provider.Url
+			}
+			name = anon.Name("VASA Provider", name)
+			container.Providers = append(container.Providers, name)
+			if slices.ContainsFunc(p.ArrayState, func(s types.VimVasaProviderStatePerArray) bool { return s.Active }) {
+				container.ActiveProviders = append(container.ActiveProviders, name)
+			}
+		}
+		containers = append(containers, container)
+	}
+	return providers, containers, nil
+}
+
+// storageProviders returns the storage providers registered with the
+// storage monitoring service of the vCenter c is connected to, in the order
+// it lists them.
+func storageProviders(ctx context.Context, c *vim25.Client) ([]smstypes.BaseSmsProviderInfo, error) {
+	sc := c.Client.NewServiceClient("/sms/sdk", "sms")
+	sc.Cookie = c.SessionCookie
+
+	sm, err := methods.QueryStorageManager(ctx, sc, &smstypes.QueryStorageManager{This: smsServiceInstance})
+	if err != nil {
+		return nil, err
+	}
+	res, err := methods.QueryProvider(ctx, sc, &smstypes.QueryProvider{This: sm.Returnval})
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]smstypes.BaseSmsProviderInfo, 0, len(res.Returnval))
+	for _, ref := range res.Returnval {
+		info, err := methods.QueryProviderInfo(ctx, sc, &smstypes.QueryProviderInfo{This: ref})
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info.Returnval)
+	}
+	return infos, nil
+}
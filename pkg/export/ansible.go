@@ -0,0 +1,76 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// writeAnsible writes the host table as an Ansible dynamic inventory, the
+// JSON an inventory script prints for --list. Every host is in a group per
+// vCenter (vcenter_<name>) and, when clustered, per cluster
+// (cluster_<name>); its other columns become hostvars keyed by column Key.
+func writeAnsible(w io.Writer, r *Report) error {
+	t := r.Tables[0]
+	col := make(map[string]int)
+	for i, k := range t.Keys {
+		col[k] = i
+	}
+	hostCol, ok := col["hostname"]
+	if !ok {
+		return fmt.Errorf("the ansible format needs a table of hosts, not %s", t.Name)
+	}
+
+	groups := make(map[string][]string)
+	hostvars := make(map[string]jsonRow, len(t.Rows))
+	for _, row := range t.Rows {
+		host := FormatValue(row[hostCol])
+		vars := make(jsonRow, 0, len(row)-1)
+		for i, v := range row {
+			if i != hostCol {
+				vars = append(vars, jsonField{t.Keys[i], v})
+			}
+		}
+		hostvars[host] = vars
+		for _, key := range []string{"vcenter", "cluster"} {
+			if i, ok := col[key]; ok {
+				if name := FormatValue(row[i]); name != "" {
+					g := ansibleGroup(key, name)
+					groups[g] = append(groups[g], host)
+				}
+			}
+		}
+	}
+
+	names := make([]string, 0, len(groups))
+	for g := range groups {
+		names = append(names, g)
+	}
+	slices.Sort(names)
+	doc := jsonRow{{"_meta", map[string]any{"hostvars": hostvars}}}
+	doc = append(doc, jsonField{"all", map[string]any{"children": append([]string{"ungrouped"}, names...)}})
+	for _, g := range names {
+		doc = append(doc, jsonField{g, map[string]any{"hosts": groups[g]}})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// ansibleGroup returns a valid Ansible group name for a vCenter or cluster:
+// the kind followed by the name in lower case, with characters other than
+// letters, digits, and underscores replaced by underscores.
+func ansibleGroup(kind, name string) string {
+	var b strings.Builder
+	b.WriteString(kind + "_")
+	for _, r := range strings.ToLower(name) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
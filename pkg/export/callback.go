@@ -0,0 +1,116 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"vmware-inventory/pkg/collector"
+)
+
+// RunSummary describes a finished collection for PostCallback.
+type RunSummary struct {
+	Command     string // hosts, vms, or datastores
+	Status      string // complete, partial (some failures), or interrupted
+	CollectedAt time.Time
+	Generator   string
+	RunID       int64 // database run, 0 for file output
+	Totals      []Fact
+	Failures    []collector.Failure
+	Files       []string // output files written
+	Uploads     []string // URLs of uploaded files
+	Data        *Report  // the full dataset, if it is to be included
+}
+
+// callbackTimeout bounds the callback request, which may carry the whole
+// dataset.
+const callbackTimeout = 5 * time.Minute
+
+// PostCallback posts s as JSON to target, so an orchestration system can
+// start downstream processing as soon as a run finishes. With a secret, the
+// body is signed with HMAC-SHA256 in an X-Signature-256 header of the form
+// sha256=<hex>, as GitHub signs its webhooks.
+func PostCallback(ctx context.Context, target, secret string, s RunSummary) error {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid callback URL")
+	}
+	body, err := json.Marshal(s.doc())
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, callbackTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		req.Header.Set("X-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// Keep credentials in the URL out of the error
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return fmt.Errorf("posting to %s: %w", u.Host, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("posting to %s: %s: %s", u.Host, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// doc returns the JSON document of s, with totals as an object keyed by
+// Fact.Key and failures as rows of the errors table.
+func (s RunSummary) doc() jsonRow {
+	totals := make(jsonRow, len(s.Totals))
+	for i, f := range s.Totals {
+		totals[i] = jsonField{f.Key, f.Value}
+	}
+	doc := jsonRow{
+		{"command", s.Command},
+		{"status", s.Status},
+		{"collectedAt", s.CollectedAt.UTC().Format(time.RFC3339)},
+		{"generator", s.Generator},
+	}
+	if s.RunID != 0 {
+		doc = append(doc, jsonField{"runId", s.RunID})
+	}
+	doc = append(doc,
+		jsonField{"totals", totals},
+		jsonField{"failures", tableRows(FailureTable(s.Failures))},
+		jsonField{"files", nonNil(s.Files)},
+		jsonField{"uploads", nonNil(s.Uploads)},
+	)
+	if s.Data != nil {
+		doc = append(doc, jsonField{"data", reportDoc(s.Data)})
+	}
+	return doc
+}
+
+// nonNil returns list, or an empty list for nil so it encodes as [].
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
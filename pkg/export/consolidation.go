@@ -0,0 +1,55 @@
+package export
+
+import (
+	"math"
+
+	"vmware-inventory/pkg/collector"
+)
+
+// ConsolidationHostColumns are the columns of the consolidation report's
+// hosts.
+var ConsolidationHostColumns = []Column[collector.HostDensity]{
+	{"vcenter", "vCenter", func(d collector.HostDensity) any { return d.VCenter }},
+	{"hostname", "Hostname", func(d collector.HostDensity) any { return d.Hostname }},
+	{"cluster", "Cluster", func(d collector.HostDensity) any { return d.Cluster }},
+	{"totalCores", "Total Cores", func(d collector.HostDensity) any { return d.TotalCores }},
+	{"vms", "VMs", func(d collector.HostDensity) any { return d.VMs }},
+	{"poweredOnVMs", "Powered-on VMs", func(d collector.HostDensity) any { return d.PoweredOnVMs }},
+	{"vcpus", "vCPUs", func(d collector.HostDensity) any { return d.VCPUs }},
+	{"vcpuRatio", "vCPU:pCore", func(d collector.HostDensity) any { return ratio(d.VCPURatio()) }},
+	{"memoryGB", "Memory GB", func(d collector.HostDensity) any { return d.MemoryGB }},
+	{"vmMemoryGB", "VM Memory GB", func(d collector.HostDensity) any { return d.VMMemoryGB }},
+	{"reservedMemoryGB", "Reserved Memory GB", func(d collector.HostDensity) any { return d.ReservedGB }},
+	{"memoryRatio", "Memory Overcommit", func(d collector.HostDensity) any { return ratio(d.MemoryRatio()) }},
+}
+
+// ConsolidationClusterColumns are the columns of the consolidation
+// report's per-cluster totals.
+var ConsolidationClusterColumns = []Column[collector.ClusterDensity]{
+	{"vcenter", "vCenter", func(c collector.ClusterDensity) any { return c.VCenter }},
+	{"cluster", "Cluster", func(c collector.ClusterDensity) any { return c.Cluster.Cluster }},
+	{"hosts", "Hosts", func(c collector.ClusterDensity) any { return c.Hosts }},
+	{"totalCores", "Total Cores", func(c collector.ClusterDensity) any { return c.TotalCores }},
+	{"vms", "VMs", func(c collector.ClusterDensity) any { return c.VMs }},
+	{"poweredOnVMs", "Powered-on VMs", func(c collector.ClusterDensity) any { return c.PoweredOnVMs }},
+	{"vcpus", "vCPUs", func(c collector.ClusterDensity) any { return c.VCPUs }},
+	{"vcpuRatio", "vCPU:pCore", func(c collector.ClusterDensity) any { return ratio(c.VCPURatio()) }},
+	{"vmsPerHost", "VMs per Host", func(c collector.ClusterDensity) any { return ratio(c.VMsPerHost()) }},
+	{"memoryGB", "Memory GB", func(c collector.ClusterDensity) any { return c.MemoryGB }},
+	{"vmMemoryGB", "VM Memory GB", func(c collector.ClusterDensity) any { return c.VMMemoryGB }},
+	{"reservedMemoryGB", "Reserved Memory GB", func(c collector.ClusterDensity) any { return c.ReservedGB }},
+	{"memoryRatio", "Memory Overcommit", func(c collector.ClusterDensity) any { return ratio(c.MemoryRatio()) }},
+}
+
+// ConsolidationTables returns the tables of the consolidation report: the
+// VM load on each host followed by the totals per cluster.
+func ConsolidationTables(hosts []collector.Host, vms []collector.VM) []*Table {
+	densities, clusters := collector.Consolidation(hosts, vms)
+	return []*Table{
+		NewTable("consolidationHosts", "Hosts", ConsolidationHostColumns, densities),
+		NewTable("consolidationClusters", "Clusters", ConsolidationClusterColumns, clusters),
+	}
+}
+
+// ratio returns f written to two decimal places.
+func ratio(f float64) Fixed { return Fixed{Number: math.Round(f*100) / 100, Decimals: 2} }
@@ -0,0 +1,177 @@
+package export
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// dialect holds the SQL differences between the supported databases.
+type dialect struct {
+	driver  string
+	runID   string // runs.id column definition
+	time    string
+	integer string
+	real    string
+	boolean string
+}
+
+var dialects = map[string]dialect{
+	"sqlite":     {"sqlite3", "INTEGER PRIMARY KEY", "TEXT", "INTEGER", "REAL", "INTEGER"},
+	"postgres":   {"postgres", "BIGSERIAL PRIMARY KEY", "TIMESTAMPTZ", "BIGINT", "DOUBLE PRECISION", "BOOLEAN"},
+	"postgresql": {"postgres", "BIGSERIAL PRIMARY KEY", "TIMESTAMPTZ", "BIGINT", "DOUBLE PRECISION", "BOOLEAN"},
+}
+
+// IsDatabaseURL reports whether an -output value names a database rather
+// than a file: sqlite://path, postgres://..., or postgresql://....
+func IsDatabaseURL(s string) bool {
+	scheme, _, ok := strings.Cut(s, "://")
+	_, known := dialects[scheme]
+	return ok && known
+}
+
+// WriteDatabase stores r in the database named by url and returns the id of
+// the new row in the runs table. Each report table is stored in a table of
+// the same name with a run_id column referencing runs, so repeated runs
+// accumulate in one database. Tables are created if they do not exist, and
+// columns added by newer versions are added to tables created by older ones.
+func WriteDatabase(url string, r *Report) (int64, error) {
+	scheme, path, _ := strings.Cut(url, "://")
+	d, ok := dialects[scheme]
+	if !ok {
+		return 0, fmt.Errorf("unsupported database %q", scheme)
+	}
+	dsn := url
+	if scheme == "sqlite" {
+		dsn = path
+	}
+
+	db, err := sql.Open(d.driver, dsn)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS runs (id %s, collected_at %s NOT NULL)", d.runID, d.time)); err != nil {
+		return 0, fmt.Errorf("creating runs table: %w", err)
+	}
+	var runID int64
+	if err := tx.QueryRow("INSERT INTO runs (collected_at) VALUES ($1) RETURNING id", r.CollectedAt).Scan(&runID); err != nil {
+		return 0, fmt.Errorf("recording run: %w", err)
+	}
+
+	for _, t := range r.Tables {
+		if err := insertTable(tx, d, runID, t); err != nil {
+			return 0, fmt.Errorf("writing %s: %w", t.Name, err)
+		}
+	}
+	return runID, tx.Commit()
+}
+
+func insertTable(tx *sql.Tx, d dialect, runID int64, t *Table) error {
+	cols := make([]string, len(t.Keys))
+	defs := []string{fmt.Sprintf("run_id %s NOT NULL REFERENCES runs(id)", d.integer)}
+	for i, k := range t.Keys {
+		cols[i] = columnName(k)
+		defs = append(defs, cols[i]+" "+columnType(d, t, i))
+	}
+	if _, err := tx.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", t.Name, strings.Join(defs, ", "))); err != nil {
+		return err
+	}
+	if err := addColumns(tx, t.Name, cols, defs[1:]); err != nil {
+		return err
+	}
+	if len(t.Rows) == 0 {
+		return nil
+	}
+
+	params := make([]string, len(cols)+1)
+	for i := range params {
+		params[i] = fmt.Sprintf("$%d", i+1)
+	}
+	stmt, err := tx.Prepare(fmt.Sprintf("INSERT INTO %s (run_id, %s) VALUES (%s)",
+		t.Name, strings.Join(cols, ", "), strings.Join(params, ", ")))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	args := make([]any, len(cols)+1)
+	args[0] = runID
+	for _, row := range t.Rows {
+		copy(args[1:], row)
+		if _, err := stmt.Exec(args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addColumns adds to table name, which may have been created by an older
+// version with fewer columns, those of cols it lacks, with the matching
+// column definitions in defs.
+func addColumns(tx *sql.Tx, name string, cols, defs []string) error {
+	rows, err := tx.Query(fmt.Sprintf("SELECT * FROM %s WHERE 1 = 0", name))
+	if err != nil {
+		return err
+	}
+	existing, err := rows.Columns()
+	rows.Close()
+	if err != nil {
+		return err
+	}
+	for i, col := range cols {
+		if slices.Contains(existing, col) {
+			continue
+		}
+		if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", name, defs[i])); err != nil {
+			return fmt.Errorf("adding column %s: %w", col, err)
+		}
+	}
+	return nil
+}
+
+// columnType picks the SQL type of column i from its first non-nil value.
+// A column with no values is TEXT.
+func columnType(d dialect, t *Table, i int) string {
+	for _, row := range t.Rows {
+		switch row[i].(type) {
+		case nil:
+			continue
+		case int, int64:
+			return d.integer
+		case float64, Fixed:
+			return d.real
+		case bool:
+			return d.boolean
+		}
+		return "TEXT"
+	}
+	return "TEXT"
+}
+
+// columnName converts a camelCase column key to snake_case, keeping a
+// trailing capital with the word before it: memoryGB becomes memory_gb and
+// vsanCapacityTiB becomes vsan_capacity_tib.
+func columnName(key string) string {
+	runes := []rune(key)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && i < len(runes)-1 && unicode.IsUpper(r) && !unicode.IsUpper(runes[i-1]) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
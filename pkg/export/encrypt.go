@@ -0,0 +1,54 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/agessh"
+)
+
+// ParseRecipients parses -encrypt-to values into age recipients. Each value
+// is an age public key (age1...), an SSH public key (ssh-ed25519 or
+// ssh-rsa), or the path of a recipients file listing age public keys one per
+// line.
+func ParseRecipients(values []string) ([]age.Recipient, error) {
+	var recipients []age.Recipient
+	for _, v := range values {
+		switch {
+		case strings.HasPrefix(v, "age1"):
+			r, err := age.ParseX25519Recipient(v)
+			if err != nil {
+				return nil, err
+			}
+			recipients = append(recipients, r)
+		case strings.HasPrefix(v, "ssh-"):
+			r, err := agessh.ParseRecipient(v)
+			if err != nil {
+				return nil, err
+			}
+			recipients = append(recipients, r)
+		default:
+			f, err := os.Open(v)
+			if err != nil {
+				return nil, fmt.Errorf("reading recipients file: %w", err)
+			}
+			rs, err := age.ParseRecipients(f)
+			f.Close()
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", v, err)
+			}
+			recipients = append(recipients, rs...)
+		}
+	}
+	return recipients, nil
+}
+
+// Encrypt returns a writer that encrypts what is written to w for
+// recipients in the age format. Close must be called to flush the last
+// chunk; it does not close w.
+func Encrypt(w io.Writer, recipients []age.Recipient) (io.WriteCloser, error) {
+	return age.Encrypt(w, recipients...)
+}
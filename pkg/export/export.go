@@ -0,0 +1,376 @@
+// Package export renders inventory tables as CSV, JSON, NDJSON, Parquet,
+// XLSX, HTML, Markdown, an Ansible inventory, or through a user's
+// text/template.
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Column describes one field of a record type T. Key is the JSON field name
+// and Header the heading used by tabular formats.
+type Column[T any] struct {
+	Key    string
+	Header string
+	Value  func(r T) any
+}
+
+// Table is a rendered set of records, independent of output format.
+type Table struct {
+	Name    string // JSON key
+	Title   string // sheet or section title
+	Keys    []string
+	Headers []string
+	Rows    [][]any
+}
+
+// NewTable renders records into a Table using cols.
+func NewTable[T any](name, title string, cols []Column[T], records []T) *Table {
+	t := &Table{Name: name, Title: title}
+	for _, c := range cols {
+		t.Keys = append(t.Keys, c.Key)
+		t.Headers = append(t.Headers, c.Header)
+	}
+	for _, r := range records {
+		row := make([]any, len(cols))
+		for i, c := range cols {
+			row[i] = c.Value(r)
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return t
+}
+
+// Select returns a copy of t with only the named columns, in the order
+// given. A name matches a column's Key or its Header without spaces,
+// ignoring case, so TotalCores, totalCores, and "Total Cores" all name the
+// same column.
+func (t *Table) Select(names []string) (*Table, error) {
+	idx := make([]int, len(names))
+	for i, name := range names {
+		idx[i] = -1
+		want := strings.ToLower(strings.ReplaceAll(name, " ", ""))
+		for j := range t.Keys {
+			if strings.ToLower(t.Keys[j]) == want || strings.ToLower(strings.ReplaceAll(t.Headers[j], " ", "")) == want {
+				idx[i] = j
+				break
+			}
+		}
+		if idx[i] < 0 {
+			return nil, fmt.Errorf("no column %q in %s; choose from %s", name, t.Name, strings.Join(t.Keys, ", "))
+		}
+	}
+	s := &Table{Name: t.Name, Title: t.Title}
+	for _, j := range idx {
+		s.Keys = append(s.Keys, t.Keys[j])
+		s.Headers = append(s.Headers, t.Headers[j])
+	}
+	for _, tr := range t.Rows {
+		row := make([]any, len(idx))
+		for i, j := range idx {
+			row[i] = tr[j]
+		}
+		s.Rows = append(s.Rows, row)
+	}
+	return s, nil
+}
+
+// WithCollectedAt returns a copy of t with a first column holding at, so
+// rows from several runs can share one file.
+func (t *Table) WithCollectedAt(at time.Time) *Table {
+	s := &Table{
+		Name:    t.Name,
+		Title:   t.Title,
+		Keys:    append([]string{"collectedAt"}, t.Keys...),
+		Headers: append([]string{"Collected At"}, t.Headers...),
+	}
+	stamp := at.UTC().Format(time.RFC3339)
+	for _, tr := range t.Rows {
+		s.Rows = append(s.Rows, append([]any{stamp}, tr...))
+	}
+	return s
+}
+
+// Split returns the distinct values, as text, of the columns keys across
+// the rows of t, in order of first appearance, for Where. A column that t
+// lacks counts as empty.
+func (t *Table) Split(keys ...string) [][]string {
+	idx := t.columnIndexes(keys)
+	var groups [][]string
+	seen := make(map[string]bool)
+	for _, tr := range t.Rows {
+		values := make([]string, len(idx))
+		for i, j := range idx {
+			if j >= 0 {
+				values[i] = FormatValue(tr[j])
+			}
+		}
+		if id := strings.Join(values, "\x00"); !seen[id] {
+			seen[id] = true
+			groups = append(groups, values)
+		}
+	}
+	return groups
+}
+
+// Where returns a copy of t with only the rows whose columns keys hold
+// values, as text, and whether t has all those columns.
+func (t *Table) Where(keys, values []string) (*Table, bool) {
+	idx := t.columnIndexes(keys)
+	if slices.Contains(idx, -1) {
+		return nil, false
+	}
+	s := &Table{Name: t.Name, Title: t.Title, Keys: t.Keys, Headers: t.Headers}
+	for _, tr := range t.Rows {
+		match := true
+		for i, j := range idx {
+			if FormatValue(tr[j]) != values[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			s.Rows = append(s.Rows, tr)
+		}
+	}
+	return s, true
+}
+
+// columnIndexes returns the index of each of keys in t, or -1 if t has no
+// such column.
+func (t *Table) columnIndexes(keys []string) []int {
+	idx := make([]int, len(keys))
+	for i, key := range keys {
+		idx[i] = slices.Index(t.Keys, key)
+	}
+	return idx
+}
+
+// Report is everything collected in one run, ready to be written.
+type Report struct {
+	CollectedAt time.Time
+	Generator   string             // tool name and version, shown where the format allows
+	Tables      []*Table           // primary table first
+	Delimiter   rune               // CSV field separator, a comma if zero
+	Template    *template.Template // for the template format
+	OmitHeader  bool               // CSV rows only, when appending to a file
+	Metadata    *RunMetadata       // written ahead of the tables by the json format
+}
+
+// delimiters maps the names accepted by ParseDelimiter to separators.
+var delimiters = map[string]rune{
+	"comma":     ',',
+	"tab":       '\t',
+	"semicolon": ';',
+	"pipe":      '|',
+}
+
+// ParseDelimiter returns the CSV field separator named by s: comma, tab,
+// semicolon, or pipe, or the character itself.
+func ParseDelimiter(s string) (rune, error) {
+	if d, ok := delimiters[strings.ToLower(s)]; ok {
+		return d, nil
+	}
+	if s == `\t` {
+		return '\t', nil
+	}
+	for _, d := range delimiters {
+		if s == string(d) {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported delimiter %q; use comma, tab, semicolon, or pipe", s)
+}
+
+// writers maps each supported -format value to its writer. Formats that hold
+// a single table write only the primary one.
+var writers = map[string]func(w io.Writer, r *Report) error{
+	"csv":      writeCSV,
+	"json":     writeJSON,
+	"ndjson":   writeNDJSON,
+	"parquet":  writeParquet,
+	"xlsx":     writeXLSX,
+	"html":     writeHTML,
+	"markdown": writeMarkdown,
+	"ansible":  writeAnsible,
+	"rvtools":  writeXLSX, // with the tables of RVToolsHostTables and the like
+	"template": writeTemplate,
+}
+
+// ValidFormat reports whether format is a supported -format value.
+func ValidFormat(format string) bool {
+	_, ok := writers[format]
+	return ok
+}
+
+// MultiTable reports whether format writes every table of a report, rather
+// than only the first.
+func MultiTable(format string) bool {
+	switch format {
+	case "json", "xlsx", "rvtools", "html", "template":
+		return true
+	}
+	return false
+}
+
+// FileExtension returns the file name extension for a -format value.
+func FileExtension(format string) string {
+	switch format {
+	case "markdown":
+		return "md"
+	case "ansible":
+		return "json"
+	case "rvtools":
+		return "xlsx"
+	case "template":
+		return "txt"
+	}
+	return format
+}
+
+// Write renders r to w in the given format.
+func Write(w io.Writer, format string, r *Report) error {
+	write, ok := writers[format]
+	if !ok {
+		return fmt.Errorf("unknown format %q", format)
+	}
+	return write(w, r)
+}
+
+func writeCSV(w io.Writer, r *Report) error {
+	t := r.Tables[0]
+	cw := csv.NewWriter(w)
+	if r.Delimiter != 0 {
+		cw.Comma = r.Delimiter
+	}
+	if !r.OmitHeader {
+		cw.Write(t.Headers)
+	}
+	for _, row := range t.Rows {
+		rec := make([]string, len(row))
+		for i, v := range row {
+			rec[i] = FormatValue(v)
+		}
+		cw.Write(rec)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// CSVHeader returns the header row of the CSV file at path, or nil if the
+// file does not exist or is empty.
+func CSVHeader(path string, delimiter rune) ([]string, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	cr := csv.NewReader(f)
+	if delimiter != 0 {
+		cr.Comma = delimiter
+	}
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	return header, err
+}
+
+// writeJSON writes one top-level key per table, each holding an array of
+// objects keyed by column Key, keeping numeric fields as JSON numbers.
+func writeJSON(w io.Writer, r *Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(reportDoc(r))
+}
+
+// reportDoc returns the JSON document of r: its schemaVersion, the run
+// metadata, if any, under "run", and an array of row objects per table,
+// keyed by table name.
+func reportDoc(r *Report) jsonRow {
+	doc := jsonRow{{"schemaVersion", SchemaVersion}}
+	if r.Metadata != nil {
+		doc = append(doc, jsonField{"run", r.Metadata.doc()})
+	}
+	for _, t := range r.Tables {
+		doc = append(doc, jsonField{t.Name, tableRows(t)})
+	}
+	return doc
+}
+
+func tableRows(t *Table) []jsonRow {
+	rows := make([]jsonRow, 0, len(t.Rows))
+	for _, tr := range t.Rows {
+		row := make(jsonRow, len(tr))
+		for i, v := range tr {
+			row[i] = jsonField{t.Keys[i], v}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+type jsonField struct {
+	key   string
+	value any
+}
+
+// jsonRow is a JSON object that keeps its fields in column order.
+type jsonRow []jsonField
+
+func (r jsonRow) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range r {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(f.key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// FormatValue renders a column value as text for tabular formats, with
+// nil, a value a record does not have, as an empty string.
+func FormatValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return fmt.Sprintf("%.1f", v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
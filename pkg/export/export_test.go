@@ -0,0 +1,1564 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"database/sql"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/crypto/ssh"
+
+	"vmware-inventory/pkg/collector"
+)
+
+type record struct {
+	name  string
+	count int
+	size  float64
+}
+
+func testReport() *Report {
+	cols := []Column[record]{
+		{"name", "Name", func(r record) any { return r.name }},
+		{"count", "Count", func(r record) any { return r.count }},
+		{"size", "Size", func(r record) any { return r.size }},
+	}
+	records := []record{{"a|b", 2, 1.25}, {"c", 10, 0}}
+	return &Report{Tables: []*Table{NewTable("records", "Records", cols, records)}}
+}
+
+func TestWriteFormats(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"csv", "Name,Count,Size\na|b,2,1.2\nc,10,0.0\n"},
+		{"json", `{
+  "schemaVersion": 1,
+  "records": [
+    {
+      "name": "a|b",
+      "count": 2,
+      "size": 1.25
+    },
+    {
+      "name": "c",
+      "count": 10,
+      "size": 0
+    }
+  ]
+}
+`},
+		{"ndjson", `{"name":"a|b","count":2,"size":1.25}` + "\n" + `{"name":"c","count":10,"size":0}` + "\n"},
+		{"markdown", "| Name | Count | Size |\n| --- | --: | --: |\n| a\\|b | 2 | 1.2 |\n| c | 10 | 0.0 |\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := Write(&buf, tt.format, testReport()); err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("%s output:\n%s\nwant:\n%s", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestDelimiter(t *testing.T) {
+	for s, want := range map[string]rune{"tab": '\t', `\t`: '\t', "Semicolon": ';', "|": '|', "comma": ','} {
+		if d, err := ParseDelimiter(s); err != nil || d != want {
+			t.Errorf("ParseDelimiter(%q) = %q, %v; want %q", s, d, err, want)
+		}
+	}
+	if _, err := ParseDelimiter("::"); err == nil {
+		t.Error("ParseDelimiter accepted ::")
+	}
+
+	rep := testReport()
+	rep.Delimiter = ';'
+	var buf bytes.Buffer
+	if err := Write(&buf, "csv", rep); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Name;Count;Size\na|b;2;1.2\nc;10;0.0\n"; buf.String() != want {
+		t.Errorf("output %q, want %q", buf.String(), want)
+	}
+	rep.Delimiter = '|'
+	buf.Reset()
+	if err := Write(&buf, "csv", rep); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Name|Count|Size\n\"a|b\"|2|1.2\nc|10|0.0\n"; buf.String() != want {
+		t.Errorf("output %q, want %q", buf.String(), want)
+	}
+}
+
+func TestAppendCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "records.csv")
+	if header, err := CSVHeader(path, ','); err != nil || header != nil {
+		t.Fatalf("CSVHeader of a missing file = %q, %v", header, err)
+	}
+
+	at := time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)
+	rep := testReport()
+	rep.Tables[0] = rep.Tables[0].WithCollectedAt(at)
+	for i := 0; i < 2; i++ {
+		header, err := CSVHeader(path, ',')
+		if err != nil {
+			t.Fatal(err)
+		}
+		rep.OmitHeader = header != nil
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := Write(f, "csv", rep); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := "2024-05-01T09:30:00Z,a|b,2,1.2\n2024-05-01T09:30:00Z,c,10,0.0\n"
+	if want := "Collected At,Name,Count,Size\n" + row + row; string(b) != want {
+		t.Errorf("file %q, want %q", b, want)
+	}
+	header, err := CSVHeader(path, ',')
+	if err != nil || !slices.Equal(header, rep.Tables[0].Headers) {
+		t.Errorf("CSVHeader = %q, %v", header, err)
+	}
+}
+
+func TestWriteTemplate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.tmpl")
+	text := `{{.Generator}}{{range .records}}
+{{upper .name}}: {{.count}} ({{format .size}}){{end}}
+total {{sum .records "count"}}, {{len .Tables}} table
+`
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := ParseTemplate(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rep := testReport()
+	rep.Generator = "vmware-inventory 1.2.3"
+	rep.Template = tmpl
+	var buf bytes.Buffer
+	if err := Write(&buf, "template", rep); err != nil {
+		t.Fatal(err)
+	}
+	if want := "vmware-inventory 1.2.3\nA|B: 2 (1.2)\nC: 10 (0.0)\ntotal 12, 1 table\n"; buf.String() != want {
+		t.Errorf("output %q, want %q", buf.String(), want)
+	}
+
+	os.WriteFile(path, []byte("{{range .hosts}"), 0o644)
+	if _, err := ParseTemplate(path); err == nil {
+		t.Error("ParseTemplate accepted a malformed template")
+	}
+}
+
+func TestNDJSONStream(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewNDJSONStream(&buf, []string{"Size", "name"})
+	tbl := testReport().Tables[0]
+	for i := range tbl.Rows {
+		one := *tbl
+		one.Rows = tbl.Rows[i : i+1]
+		if err := s.Write(&one); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := "{\"size\":1.25,\"name\":\"a|b\"}\n{\"size\":0,\"name\":\"c\"}\n"; buf.String() != want {
+		t.Errorf("output %q, want %q", buf.String(), want)
+	}
+}
+
+func TestWriteParquet(t *testing.T) {
+	rep := testReport()
+	rep.Tables[0].Rows = append(rep.Tables[0].Rows, []any{nil, 7, nil})
+	var buf bytes.Buffer
+	if err := Write(&buf, "parquet", rep); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	if !bytes.HasPrefix(b, []byte("PAR1")) || !bytes.HasSuffix(b, []byte("PAR1")) {
+		t.Fatal("missing Parquet magic")
+	}
+	n := int(binary.LittleEndian.Uint32(b[len(b)-8:]))
+	r := &thriftReader{b: b[len(b)-8-n : len(b)-8]}
+	meta := r.readStruct()
+
+	schema := meta[2].([]any)
+	var names []string
+	var types []int64
+	for _, e := range schema[1:] {
+		names = append(names, e.(map[int16]any)[4].(string))
+		types = append(types, e.(map[int16]any)[1].(int64))
+	}
+	if !slices.Equal(names, []string{"name", "count", "size"}) || !slices.Equal(types, []int64{parquetByteArray, parquetInt64, parquetDouble}) {
+		t.Errorf("schema %v %v", names, types)
+	}
+	if meta[3].(int64) != 3 {
+		t.Errorf("num_rows = %v", meta[3])
+	}
+
+	// Read back each column's page: definition levels, then PLAIN values
+	chunks := meta[4].([]any)[0].(map[int16]any)[1].([]any)
+	var got [][]any
+	for i, c := range chunks {
+		cm := c.(map[int16]any)[3].(map[int16]any)
+		pr := &thriftReader{b: b[cm[9].(int64):]}
+		header := pr.readStruct()
+		page := pr.b[pr.pos : pr.pos+int(header[3].(int64))]
+		zr, err := gzip.NewReader(bytes.NewReader(page))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(zr)
+		if len(data) != int(header[2].(int64)) {
+			t.Errorf("column %d: uncompressed size %d, header says %v", i, len(data), header[2])
+		}
+		lr := &thriftReader{b: data[4 : 4+binary.LittleEndian.Uint32(data)]}
+		var levels []byte
+		for lr.pos < len(lr.b) {
+			run := lr.varint() >> 1
+			level := lr.b[lr.pos]
+			lr.pos++
+			for range run {
+				levels = append(levels, level)
+			}
+		}
+		values := data[4+len(lr.b):]
+		var col []any
+		for _, l := range levels {
+			if l == 0 {
+				col = append(col, nil)
+				continue
+			}
+			switch types[i] {
+			case parquetByteArray:
+				n := binary.LittleEndian.Uint32(values)
+				col = append(col, string(values[4:4+n]))
+				values = values[4+n:]
+			case parquetInt64:
+				col = append(col, int(binary.LittleEndian.Uint64(values)))
+				values = values[8:]
+			case parquetDouble:
+				col = append(col, math.Float64frombits(binary.LittleEndian.Uint64(values)))
+				values = values[8:]
+			}
+		}
+		got = append(got, col)
+	}
+	want := [][]any{{"a|b", "c", nil}, {2, 10, 7}, {1.25, 0.0, nil}}
+	for i := range want {
+		if !slices.Equal(got[i], want[i]) {
+			t.Errorf("column %s = %v, want %v", names[i], got[i], want[i])
+		}
+	}
+}
+
+// thriftReader decodes the Thrift compact protocol into maps of field id
+// to value, to check the Parquet writer's metadata.
+type thriftReader struct {
+	b   []byte
+	pos int
+}
+
+func (r *thriftReader) varint() uint64 {
+	v, n := binary.Uvarint(r.b[r.pos:])
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) zigzag() int64 {
+	v := r.varint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftReader) readStruct() map[int16]any {
+	fields := make(map[int16]any)
+	var id int16
+	for {
+		h := r.b[r.pos]
+		r.pos++
+		if h == 0 {
+			return fields
+		}
+		if delta := int16(h >> 4); delta != 0 {
+			id += delta
+		} else {
+			id = int16(r.zigzag())
+		}
+		fields[id] = r.readValue(h & 0x0f)
+	}
+}
+
+func (r *thriftReader) readValue(typ byte) any {
+	switch typ {
+	case 1, 2:
+		return typ == 1
+	case 5, 6:
+		return r.zigzag()
+	case 8:
+		n := int(r.varint())
+		r.pos += n
+		return string(r.b[r.pos-n : r.pos])
+	case 9:
+		h := r.b[r.pos]
+		r.pos++
+		n := int(h >> 4)
+		if n == 15 {
+			n = int(r.varint())
+		}
+		list := make([]any, n)
+		for i := range list {
+			list[i] = r.readValue(h & 0x0f)
+		}
+		return list
+	case 12:
+		return r.readStruct()
+	}
+	panic(fmt.Sprintf("unsupported thrift type %d", typ))
+}
+
+func TestTableSelect(t *testing.T) {
+	tbl := NewTable("hosts", "Hosts", HostColumns, []collector.Host{{Hostname: "esx1", Cluster: "c1", TotalCores: 32, MemoryGB: 512}})
+	got, err := tbl.Select([]string{"TotalCores", "hostname", "Memory GB"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got.Keys, []string{"totalCores", "hostname", "memoryGB"}) || !slices.Equal(got.Headers, []string{"Total Cores", "Hostname", "Memory GB"}) {
+		t.Errorf("keys %v, headers %v", got.Keys, got.Headers)
+	}
+	if !slices.Equal(got.Rows[0], []any{32, "esx1", int64(512)}) {
+		t.Errorf("row = %v", got.Rows[0])
+	}
+	if len(tbl.Keys) != len(HostColumns) {
+		t.Error("Select modified the table")
+	}
+	if _, err := tbl.Select([]string{"Cores"}); err == nil || !strings.Contains(err.Error(), "totalCores") {
+		t.Errorf("unknown column error = %v", err)
+	}
+}
+
+func TestRunMetadata(t *testing.T) {
+	rep := testReport()
+	rep.Metadata = &RunMetadata{
+		Command:     "hosts",
+		Status:      "complete",
+		CollectedAt: time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC),
+		Generator:   "vmware-inventory 1.0",
+		VCenters:    []collector.VCenterInfo{{Name: "vc1", Product: "VMware vCenter Server 8.0.2 build-22617221", Version: "8.0.2", Build: "22617221", APIVersion: "8.0.2.0"}},
+		Filters:     RunFilters{Clusters: []string{"Prod-*"}, Units: Units{System: "decimal", Precision: -1}},
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, "json", rep); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Run struct {
+			Command     string
+			CollectedAt string
+			VCenters    []map[string]string
+			Filters     map[string]any
+		}
+		Records []map[string]any
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "{\n  \"schemaVersion\": 1,\n  \"run\": {") {
+		t.Error("run metadata does not follow the schema version")
+	}
+	if doc.Run.Command != "hosts" || doc.Run.CollectedAt != "2024-05-01T09:30:00Z" || len(doc.Records) != 2 {
+		t.Errorf("document %+v", doc)
+	}
+	if len(doc.Run.VCenters) != 1 || doc.Run.VCenters[0]["build"] != "22617221" {
+		t.Errorf("vcenters %v", doc.Run.VCenters)
+	}
+	if f := doc.Run.Filters; f["units"] != "decimal" || f["precision"] != nil || !slices.Equal(f["tags"].([]any), []any{}) {
+		t.Errorf("filters %v", f)
+	}
+
+	buf.Reset()
+	if err := rep.Metadata.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"clusters": [
+      "Prod-*"
+    ]`) {
+		t.Errorf("sidecar %s", buf.String())
+	}
+}
+
+func TestWriteSchema(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteSchema(&buf, "hosts"); err != nil {
+		t.Fatal(err)
+	}
+	type items struct {
+		Properties map[string]struct {
+			Title string
+			Type  any // a list of types for nullable columns
+		}
+		Required []string
+	}
+	var schema struct {
+		Properties struct {
+			SchemaVersion struct{ Const int }
+			Hosts         struct{ Items items }
+			Clusters      struct{ Items items }
+		}
+		Required []string
+	}
+	if err := json.Unmarshal(buf.Bytes(), &schema); err != nil {
+		t.Fatal(err)
+	}
+	if schema.Properties.SchemaVersion.Const != SchemaVersion || !slices.Equal(schema.Required, []string{"schemaVersion", "hosts", "clusters"}) {
+		t.Errorf("schema = %+v", schema)
+	}
+	hosts := schema.Properties.Hosts.Items
+	if len(hosts.Required) != len(HostColumns) || len(schema.Properties.Clusters.Items.Required) != len(ClusterColumns) {
+		t.Errorf("required %v", hosts.Required)
+	}
+	for key, want := range map[string]string{"hostname": "string", "totalCores": "integer", "memoryGB": "integer", "vsanCapacityTiB": "number"} {
+		if got := hosts.Properties[key].Type; got != want {
+			t.Errorf("%s type %q, want %q", key, got, want)
+		}
+	}
+	if got := fmt.Sprint(hosts.Properties["cpuAvgPct"].Type); got != "[number null]" {
+		t.Errorf("cpuAvgPct type %s, want number or null", got)
+	}
+	if hosts.Properties["memoryGB"].Title != "Memory GB" {
+		t.Errorf("memoryGB title %q", hosts.Properties["memoryGB"].Title)
+	}
+	if err := WriteSchema(io.Discard, "check"); err == nil {
+		t.Error("WriteSchema accepted the check command")
+	}
+}
+
+func TestWithUnits(t *testing.T) {
+	tbl := NewTable("hosts", "Hosts", HostColumns, []collector.Host{{Hostname: "esx1", MemoryGB: 512, VsanCapacityTiB: 10}})
+	cols := []string{"hostname", "memoryGB", "vsanCapacityTiB"}
+	for _, tt := range []struct {
+		units   Units
+		keys    []string
+		headers []string
+		row     []any
+		csv     string
+	}{
+		{
+			Units{System: "binary", Precision: -1},
+			[]string{"hostname", "memoryGiB", "vsanCapacityTiB"},
+			[]string{"Hostname", "Memory GiB", "vSAN Capacity TiB"},
+			[]any{"esx1", int64(512), 10.0},
+			"esx1,512,10.0",
+		},
+		{
+			Units{System: "decimal", Precision: 2},
+			[]string{"hostname", "memoryGB", "vsanCapacityTB"},
+			[]string{"Hostname", "Memory GB", "vSAN Capacity TB"},
+			[]any{"esx1", Fixed{549.76, 2}, Fixed{11, 2}},
+			"esx1,549.76,11.00",
+		},
+		{
+			Units{Precision: 0},
+			[]string{"hostname", "memoryGB", "vsanCapacityTiB"},
+			[]string{"Hostname", "Memory GB", "vSAN Capacity TiB"},
+			[]any{"esx1", Fixed{512, 0}, Fixed{10, 0}},
+			"esx1,512,10",
+		},
+	} {
+		got, err := tbl.Select(cols)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = got.WithUnits(tt.units)
+		if !slices.Equal(got.Keys, tt.keys) || !slices.Equal(got.Headers, tt.headers) {
+			t.Errorf("%+v: keys %v, headers %v", tt.units, got.Keys, got.Headers)
+		}
+		if !slices.Equal(got.Rows[0], tt.row) {
+			t.Errorf("%+v: row = %v, want %v", tt.units, got.Rows[0], tt.row)
+		}
+		var buf bytes.Buffer
+		if err := Write(&buf, "csv", &Report{Tables: []*Table{got}}); err != nil {
+			t.Fatal(err)
+		}
+		if line := strings.Split(buf.String(), "\n")[1]; line != tt.csv {
+			t.Errorf("%+v: CSV row %q, want %q", tt.units, line, tt.csv)
+		}
+	}
+	if tbl.Rows[0][slices.Index(tbl.Keys, "memoryGB")] != int64(512) {
+		t.Error("WithUnits modified the table")
+	}
+
+	b, err := json.Marshal([]any{Fixed{1.5, 2}, Fixed{3, 0}})
+	if err != nil || string(b) != "[1.50,3]" {
+		t.Errorf("JSON %s, %v", b, err)
+	}
+}
+
+func TestTableSplit(t *testing.T) {
+	tables := HostTables([]collector.Host{
+		{VCenter: "vc1", Hostname: "esx1", Cluster: "Prod", TotalCores: 32},
+		{VCenter: "vc1", Hostname: "esx2", Cluster: "Dev", TotalCores: 16},
+		{VCenter: "vc2", Hostname: "esx3", Cluster: "Prod", TotalCores: 8},
+		{VCenter: "vc1", Hostname: "esx4", Cluster: "Prod", TotalCores: 32},
+	})
+	keys := []string{"vcenter", "cluster"}
+	groups := tables[0].Split(keys...)
+	if want := [][]string{{"vc1", "Prod"}, {"vc1", "Dev"}, {"vc2", "Prod"}}; !slices.EqualFunc(groups, want, slices.Equal) {
+		t.Fatalf("groups = %v, want %v", groups, want)
+	}
+	hosts, ok := tables[0].Where(keys, groups[0])
+	if !ok || len(hosts.Rows) != 2 || hosts.Rows[1][1] != "esx4" {
+		t.Errorf("hosts of %v = %v", groups[0], hosts.Rows)
+	}
+	clusters, ok := tables[1].Where(keys, groups[2])
+	if !ok || len(clusters.Rows) != 1 || clusters.Rows[0][4] != 8 {
+		t.Errorf("clusters of %v = %v", groups[2], clusters.Rows)
+	}
+	if _, ok := FailureTable(nil).Where(keys, groups[0]); ok {
+		t.Error("Where matched a table without a cluster column")
+	}
+}
+
+func TestWriteHTMLAndXLSX(t *testing.T) {
+	rep := testReport()
+	rep.Generator = "vmware-inventory 1.2.3"
+	var buf bytes.Buffer
+	if err := Write(&buf, "html", rep); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `class="num" data-value="10"`) {
+		t.Errorf("HTML numeric cell not marked sortable:\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), "by vmware-inventory 1.2.3</p>") {
+		t.Errorf("HTML does not name the generator:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := Write(&buf, "xlsx", rep); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("PK")) {
+		t.Error("XLSX output is not a zip archive")
+	}
+}
+
+func TestWriteDatabase(t *testing.T) {
+	url := "sqlite://" + t.TempDir() + "/inventory.db"
+	for want := int64(1); want <= 2; want++ {
+		runID, err := WriteDatabase(url, testReport())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if runID != want {
+			t.Errorf("run id = %d, want %d", runID, want)
+		}
+	}
+
+	db, err := sql.Open("sqlite3", strings.TrimPrefix(url, "sqlite://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var rows, total int
+	var size float64
+	if err := db.QueryRow("SELECT COUNT(*), SUM(count), SUM(size) FROM records WHERE run_id = 2").Scan(&rows, &total, &size); err != nil {
+		t.Fatal(err)
+	}
+	if rows != 2 || total != 12 || size != 1.25 {
+		t.Errorf("run 2 has %d rows, count %d, size %v; want 2, 12, 1.25", rows, total, size)
+	}
+}
+
+// TestWriteDatabaseNewColumns writes a report to a database created by a
+// version whose tables had fewer columns.
+func TestWriteDatabaseNewColumns(t *testing.T) {
+	url := "sqlite://" + t.TempDir() + "/inventory.db"
+	if _, err := WriteDatabase(url, testReport()); err != nil {
+		t.Fatal(err)
+	}
+
+	r := testReport()
+	records := r.Tables[0]
+	records.Keys = append(records.Keys, "thin")
+	records.Headers = append(records.Headers, "Thin")
+	records.Rows[0] = append(records.Rows[0], nil)
+	records.Rows[1] = append(records.Rows[1], true)
+	if _, err := WriteDatabase(url, r); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite3", strings.TrimPrefix(url, "sqlite://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var typ string
+	if err := db.QueryRow("SELECT type FROM pragma_table_info('records') WHERE name = 'thin'").Scan(&typ); err != nil {
+		t.Fatal(err)
+	}
+	if typ != "INTEGER" {
+		t.Errorf("thin column type = %s, want INTEGER", typ)
+	}
+	var thin int
+	if err := db.QueryRow("SELECT COUNT(*) FROM records WHERE run_id = 2 AND thin = 1").Scan(&thin); err != nil {
+		t.Fatal(err)
+	}
+	if thin != 1 {
+		t.Errorf("%d thin records in run 2, want 1", thin)
+	}
+}
+
+func TestWriteDatabaseConsolidation(t *testing.T) {
+	hosts := []collector.Host{{VCenter: "vc1", Hostname: "esx1", Cluster: "A", TotalCores: 16, MemoryGB: 64}}
+	vms := []collector.VM{
+		{VCenter: "vc1", Host: "esx1", PowerState: "poweredOn", VCPUs: 8, MemoryGB: 64},
+		{VCenter: "vc1", Host: "esx1", PowerState: "poweredOn", VCPUs: 16, MemoryGB: 32},
+	}
+	url := "sqlite://" + t.TempDir() + "/inventory.db"
+	if _, err := WriteDatabase(url, &Report{Tables: ConsolidationTables(hosts, vms)}); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite3", strings.TrimPrefix(url, "sqlite://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var vcpuRatio, memoryRatio, vmsPerHost float64
+	var typ string
+	if err := db.QueryRow("SELECT vcpu_ratio, typeof(vcpu_ratio), memory_ratio FROM consolidationHosts").Scan(&vcpuRatio, &typ, &memoryRatio); err != nil {
+		t.Fatal(err)
+	}
+	if vcpuRatio != 1.5 || typ != "real" || memoryRatio != 1.5 {
+		t.Errorf("host vCPU ratio %v (%s), memory ratio %v; want 1.5 (real), 1.5", vcpuRatio, typ, memoryRatio)
+	}
+	if err := db.QueryRow("SELECT vms_per_host FROM consolidationClusters").Scan(&vmsPerHost); err != nil {
+		t.Fatal(err)
+	}
+	if vmsPerHost != 2 {
+		t.Errorf("cluster VMs per host = %v, want 2", vmsPerHost)
+	}
+}
+
+func TestWriteDatabaseHeadroom(t *testing.T) {
+	hosts := []collector.Host{
+		{VCenter: "vc1", Hostname: "esx1", Cluster: "A", CPUCapacityMHz: 50000, CPUUsageMHz: 30000, MemoryGB: 512, MemoryUsageGB: 400},
+		{VCenter: "vc1", Hostname: "esx2", Cluster: "A", CPUCapacityMHz: 50000, CPUUsageMHz: 20000, MemoryGB: 512, MemoryUsageGB: 300,
+			Utilization: &collector.Utilization{CPUAvgPct: 42.25, CPUPeakPct: 90, MemoryAvgPct: 61.04, MemoryPeakPct: 75}},
+	}
+	tables := append(HeadroomTables(hosts, nil, collector.Targets{CPU: 80, Memory: 80}), HostTables(hosts, UtilizationColumns...)[0])
+	url := "sqlite://" + t.TempDir() + "/inventory.db"
+	if _, err := WriteDatabase(url, &Report{Tables: tables}); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite3", strings.TrimPrefix(url, "sqlite://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var cpuUsed, memoryUsed float64
+	if err := db.QueryRow("SELECT cpu_used_pct, memory_used_pct FROM headroom").Scan(&cpuUsed, &memoryUsed); err != nil {
+		t.Fatal(err)
+	}
+	if cpuUsed != 50 || memoryUsed != 68.4 {
+		t.Errorf("headroom CPU used %v%%, memory used %v%%; want 50, 68.4", cpuUsed, memoryUsed)
+	}
+
+	// esx1 has no samples, so the column's type is taken from esx2
+	var typ string
+	var avg sql.NullFloat64
+	if err := db.QueryRow("SELECT type FROM pragma_table_info('hosts') WHERE name = 'cpu_avg_pct'").Scan(&typ); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow("SELECT cpu_avg_pct FROM hosts WHERE hostname = 'esx1'").Scan(&avg); err != nil {
+		t.Fatal(err)
+	}
+	if typ != "REAL" || avg.Valid {
+		t.Errorf("esx1 CPU average %v in a %s column, want NULL in a REAL column", avg, typ)
+	}
+	if err := db.QueryRow("SELECT cpu_avg_pct FROM hosts WHERE hostname = 'esx2'").Scan(&avg); err != nil {
+		t.Fatal(err)
+	}
+	if avg.Float64 != 42.3 {
+		t.Errorf("esx2 CPU average = %v, want 42.3", avg.Float64)
+	}
+}
+
+func TestColumnType(t *testing.T) {
+	d := dialects["postgres"]
+	table := &Table{Rows: [][]any{
+		{nil, nil, nil, nil, nil, nil},
+		{"a", 1, 1.5, true, Fixed{1.5, 1}, nil},
+	}}
+	for i, want := range []string{"TEXT", "BIGINT", "DOUBLE PRECISION", "BOOLEAN", "DOUBLE PRECISION", "TEXT"} {
+		if got := columnType(d, table, i); got != want {
+			t.Errorf("columnType(column %d) = %s, want %s", i, got, want)
+		}
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	for key, want := range map[string]string{
+		"vcenter":         "vcenter",
+		"memoryGB":        "memory_gb",
+		"guestOS":         "guest_os",
+		"vsanCapacityTiB": "vsan_capacity_tib",
+	} {
+		if got := columnName(key); got != want {
+			t.Errorf("columnName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestEncrypt(t *testing.T) {
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := t.TempDir() + "/recipients.txt"
+	if err := os.WriteFile(file, []byte("# team key\n"+id.Recipient().String()+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range []string{id.Recipient().String(), file} {
+		recipients, err := ParseRecipients([]string{value})
+		if err != nil {
+			t.Fatalf("%s: %v", value, err)
+		}
+
+		var buf bytes.Buffer
+		w, err := Encrypt(&buf, recipients)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := Write(w, "csv", testReport()); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		r, err := age.Decrypt(&buf, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		plain, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(plain), "Name,Count,Size\n") {
+			t.Errorf("decrypted output = %q", plain)
+		}
+	}
+
+	if _, err := ParseRecipients([]string{"age1notakey"}); err == nil {
+		t.Error("invalid age key accepted")
+	}
+}
+
+func TestManifest(t *testing.T) {
+	dir := t.TempDir()
+	var files []string
+	want := ""
+	for _, name := range []string{"hosts_cpu.csv", "hosts_cpu_errors.json"} {
+		data := []byte("contents of " + name)
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, path)
+		sum := sha256.Sum256(data)
+		want += hex.EncodeToString(sum[:]) + "  " + name + "\n"
+	}
+	manifest := filepath.Join(dir, "hosts_cpu_manifest.sha256")
+	if err := WriteManifest(manifest, files); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("manifest = %q, want %q", got, want)
+	}
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigPath, err := SignFile(manifest, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	armored, err := os.ReadFile(sigPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Check the signature as ssh-keygen -Y verify would
+	body := strings.TrimPrefix(strings.TrimSpace(string(armored)), "-----BEGIN SSH SIGNATURE-----")
+	body = strings.TrimSuffix(body, "-----END SSH SIGNATURE-----")
+	blob, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(body, "\n", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sig struct {
+		Version                      uint32
+		PublicKey                    []byte
+		Namespace, Reserved, HashAlg string
+		Signature                    []byte
+	}
+	if !bytes.HasPrefix(blob, []byte("SSHSIG")) {
+		t.Fatal("signature lacks SSHSIG magic")
+	}
+	if err := ssh.Unmarshal(blob[6:], &sig); err != nil {
+		t.Fatal(err)
+	}
+	if sig.Namespace != SignatureNamespace || sig.HashAlg != "sha512" {
+		t.Errorf("namespace %q, hash %q", sig.Namespace, sig.HashAlg)
+	}
+	pub, err := ssh.ParsePublicKey(sig.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pub.Marshal(), signer.PublicKey().Marshal()) {
+		t.Error("signature carries the wrong public key")
+	}
+	var s ssh.Signature
+	if err := ssh.Unmarshal(sig.Signature, &s); err != nil {
+		t.Fatal(err)
+	}
+	digest := sha512.Sum512(got)
+	signed := append([]byte("SSHSIG"), ssh.Marshal(struct {
+		Namespace, Reserved, HashAlg string
+		Hash                         []byte
+	}{SignatureNamespace, "", "sha512", digest[:]})...)
+	if err := pub.Verify(signed, &s); err != nil {
+		t.Errorf("signature does not verify: %v", err)
+	}
+	digest = sha512.Sum512(append(got, '#'))
+	tampered := append([]byte("SSHSIG"), ssh.Marshal(struct {
+		Namespace, Reserved, HashAlg string
+		Hash                         []byte
+	}{SignatureNamespace, "", "sha512", digest[:]})...)
+	if pub.Verify(tampered, &s) == nil {
+		t.Error("signature verifies an edited manifest")
+	}
+}
+
+func TestS3Upload(t *testing.T) {
+	var mu sync.Mutex
+	objects := make(map[string]string)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=test/") {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		objects[r.URL.Path] = string(body)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_CONFIG_FILE", os.DevNull)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", os.DevNull)
+
+	path := filepath.Join(t.TempDir(), "hosts_cpu.csv")
+	if err := os.WriteFile(path, []byte("Name\nesx1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for _, prefix := range []string{"evidence/2026", "evidence/2026/", ""} {
+		u, err := NewS3Uploader(ctx, "s3://reports/"+prefix, srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		url, err := u.Upload(ctx, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key := "evidence/2026/hosts_cpu.csv"
+		if prefix == "" {
+			key = "hosts_cpu.csv"
+		}
+		if url != "s3://reports/"+key {
+			t.Errorf("prefix %q: url = %s", prefix, url)
+		}
+		mu.Lock()
+		got, ok := objects["/reports/"+key]
+		mu.Unlock()
+		if !ok || !strings.Contains(got, "esx1") {
+			t.Errorf("prefix %q: object = %q, %v", prefix, got, ok)
+		}
+	}
+
+	for _, bad := range []string{"reports/evidence", "s3:///evidence"} {
+		if _, err := NewS3Uploader(ctx, bad, ""); err == nil {
+			t.Errorf("%q accepted", bad)
+		}
+	}
+}
+
+// fakeSMTP accepts one message without authentication or TLS and sends its
+// envelope recipients and data on the returned channel.
+func fakeSMTP(t *testing.T) (string, <-chan []string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	got := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { io.WriteString(conn, s+"\r\n") }
+		reply("220 localhost ESMTP")
+		var rcpts []string
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.Fields(line)[0])
+			switch cmd {
+			case "EHLO", "HELO":
+				reply("250 localhost")
+			case "RCPT":
+				rcpts = append(rcpts, strings.TrimSpace(line))
+				reply("250 OK")
+			case "DATA":
+				reply("354 go ahead")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				reply("250 OK")
+				got <- append(rcpts, data.String())
+			case "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+	return ln.Addr().String(), got
+}
+
+func TestSendMail(t *testing.T) {
+	addr, got := fakeSMTP(t)
+	path := filepath.Join(t.TempDir(), "hosts_cpu.csv")
+	if err := os.WriteFile(path, []byte("Name\nesx1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err := SendMail(Mail{
+		Server:      addr,
+		From:        "Inventory <inventory@example.com>",
+		To:          []string{"ops@example.com", "licensing@example.com"},
+		Subject:     "VMware inventory: 2 hosts",
+		Body:        "Hosts: 2\nCores: 64\n",
+		Attachments: []string{path},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := <-got
+	if len(res) != 3 || !strings.Contains(res[0], "ops@example.com") || !strings.Contains(res[1], "licensing@example.com") {
+		t.Fatalf("recipients = %q", res[:len(res)-1])
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(res[2]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := msg.Header.Get("Subject"); s != "VMware inventory: 2 hosts" {
+		t.Errorf("Subject = %q", s)
+	}
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	body, err := mr.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text, _ := io.ReadAll(body); !strings.Contains(string(text), "Cores: 64") {
+		t.Errorf("body = %q", text)
+	}
+	att, err := mr.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if att.FileName() != "hosts_cpu.csv" {
+		t.Errorf("attachment name = %q", att.FileName())
+	}
+	data, err := io.ReadAll(base64.NewDecoder(base64.StdEncoding, att))
+	if err != nil || string(data) != "Name\nesx1\n" {
+		t.Errorf("attachment = %q, %v", data, err)
+	}
+}
+
+func TestPostWebhook(t *testing.T) {
+	n := Notification{
+		Title: "VMware inventory: 2 hosts",
+		Facts: []Fact{{Key: "totalCores", Name: "Cores", Value: 64}, {Key: "failures", Name: "Failures", Value: 0}},
+		Notes: []string{"s3://reports/hosts_cpu.csv"},
+	}
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if r.URL.Path == "/fail" {
+			http.Error(w, "invalid_token", http.StatusForbidden)
+		}
+	}))
+	defer srv.Close()
+
+	if err := PostWebhook(context.Background(), srv.URL+"/services/T0/B0/x", n); err != nil {
+		t.Fatal(err)
+	}
+	want := "*VMware inventory: 2 hosts*\nCores: *64*\nFailures: *0*\ns3://reports/hosts_cpu.csv"
+	if got["text"] != want {
+		t.Errorf("Slack text = %q, want %q", got["text"], want)
+	}
+	err := PostWebhook(context.Background(), srv.URL+"/fail", n)
+	if err == nil || !strings.Contains(err.Error(), "invalid_token") || strings.Contains(err.Error(), "/fail") {
+		t.Errorf("error = %v", err)
+	}
+
+	if !isTeams("contoso.webhook.office.com") || !isTeams("prod-01.westus.logic.azure.com") || isTeams("hooks.slack.com") {
+		t.Error("isTeams misclassifies hosts")
+	}
+	card, err := json.Marshal(teamsPayload(n))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`"application/vnd.microsoft.card.adaptive"`, `"title":"Cores","value":"64"`, `"text":"s3://reports/hosts_cpu.csv"`} {
+		if !strings.Contains(string(card), s) {
+			t.Errorf("Teams card lacks %s: %s", s, card)
+		}
+	}
+}
+
+func TestPostCallback(t *testing.T) {
+	var body []byte
+	var sig string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		sig = r.Header.Get("X-Signature-256")
+	}))
+	defer srv.Close()
+
+	hosts := []collector.Host{{VCenter: "vc1", Hostname: "esx1", TotalCores: 32}}
+	run := RunSummary{
+		Command:     "hosts",
+		Status:      "partial",
+		CollectedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Generator:   "vmware-inventory 1.0",
+		Totals:      []Fact{{Key: "hosts", Name: "Hosts", Value: 1}, {Key: "totalCores", Name: "Cores", Value: 32}},
+		Failures:    []collector.Failure{{VCenter: "vc2", Op: "hosts", Err: errors.New("login failed")}},
+		Files:       []string{"hosts_cpu.csv"},
+		Data:        &Report{Tables: []*Table{NewTable("hosts", "Hosts", HostColumns, hosts)}},
+	}
+	if err := PostCallback(context.Background(), srv.URL, "s3cret", run); err != nil {
+		t.Fatal(err)
+	}
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); sig != want {
+		t.Errorf("signature = %q, want %q", sig, want)
+	}
+	var got struct {
+		Command     string
+		Status      string
+		CollectedAt string
+		Totals      map[string]int
+		Failures    []map[string]string
+		Files       []string
+		Uploads     []string
+		Data        struct {
+			SchemaVersion int
+			Hosts         []map[string]any
+		}
+	}
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Command != "hosts" || got.Status != "partial" || got.CollectedAt != "2024-05-01T12:00:00Z" {
+		t.Errorf("summary = %+v", got)
+	}
+	if got.Totals["totalCores"] != 32 || len(got.Failures) != 1 || got.Failures[0]["vcenter"] != "vc2" {
+		t.Errorf("totals = %v, failures = %v", got.Totals, got.Failures)
+	}
+	if got.Uploads == nil || len(got.Files) != 1 || got.Data.SchemaVersion != SchemaVersion || got.Data.Hosts[0]["hostname"] != "esx1" {
+		t.Errorf("files = %v, uploads = %v, data = %v", got.Files, got.Uploads, got.Data)
+	}
+
+	// Without a secret or data, neither is sent
+	run.Data = nil
+	if err := PostCallback(context.Background(), srv.URL, "", run); err != nil {
+		t.Fatal(err)
+	}
+	if sig != "" || bytes.Contains(body, []byte(`"data"`)) {
+		t.Errorf("signature %q, body %s", sig, body)
+	}
+}
+
+// fakeNetBox serves the subset of the NetBox REST API used by NetBox from
+// memory: filtered lists, creation, and partial updates.
+type fakeNetBox struct {
+	mu      sync.Mutex
+	objects map[string][]map[string]any // list path -> objects
+}
+
+func (f *fakeNetBox) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Header.Get("Authorization") != "Token secret" {
+		http.Error(w, "invalid token", http.StatusForbidden)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		var results []map[string]any
+	objects:
+		for _, obj := range f.objects[r.URL.Path] {
+			for k, v := range r.URL.Query() {
+				if k != "brief" && fmt.Sprint(obj[strings.TrimSuffix(k, "_id")]) != v[0] {
+					continue objects
+				}
+			}
+			results = append(results, obj)
+		}
+		json.NewEncoder(w).Encode(map[string]any{"count": len(results), "results": results})
+	case http.MethodPost:
+		var obj map[string]any
+		json.NewDecoder(r.Body).Decode(&obj)
+		obj["id"] = len(f.objects[r.URL.Path]) + 1
+		f.objects[r.URL.Path] = append(f.objects[r.URL.Path], obj)
+		json.NewEncoder(w).Encode(obj)
+	case http.MethodPatch:
+		list, id := path.Split(strings.TrimSuffix(r.URL.Path, "/"))
+		n, _ := strconv.Atoi(id)
+		var fields map[string]any
+		json.NewDecoder(r.Body).Decode(&fields)
+		for k, v := range fields {
+			f.objects[list][n-1][k] = v
+		}
+		json.NewEncoder(w).Encode(f.objects[list][n-1])
+	}
+}
+
+func TestNetBox(t *testing.T) {
+	fake := &fakeNetBox{objects: map[string][]map[string]any{
+		"/api/dcim/sites/": {{"id": 1, "name": "Ashburn DC", "slug": "ashburn"}},
+	}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	hosts := []collector.Host{
+		{VCenter: "vc1.example.com", Hostname: "esx1", Cluster: "Prod", Vendor: "Dell Inc.", ServerModel: "PowerEdge R750", SerialNumber: "ABC1234", ConnectionState: "connected"},
+		{VCenter: "vc1.example.com", Hostname: "esx2", Cluster: "Prod", Vendor: "Dell Inc.", ServerModel: "PowerEdge R750", ConnectionState: "notResponding"},
+		{VCenter: "vc2.example.com", Hostname: "esx3", Cluster: "Prod", Vendor: "HPE", ServerModel: "ProLiant DL380 Gen10"},
+	}
+	ctx := context.Background()
+	st, err := NewNetBox(srv.URL+"/api/", "secret", "Ashburn DC", "Hypervisor").SyncHosts(ctx, hosts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st != (SyncStats{Created: 3}) {
+		t.Errorf("first sync: %+v", st)
+	}
+
+	hosts[1].ConnectionState = "connected"
+	nb := NewNetBox(srv.URL, "secret", "ashburn", "Hypervisor")
+	if st, err = nb.SyncHosts(ctx, hosts); err != nil {
+		t.Fatal(err)
+	}
+	if st != (SyncStats{Updated: 3}) {
+		t.Errorf("second sync: %+v", st)
+	}
+	st, err = nb.SyncVMs(ctx, []collector.VM{{VCenter: "vc1.example.com", Name: "app01", Cluster: "Prod", Host: "esx1", PowerState: "poweredOn", VCPUs: 4, MemoryGB: 16}})
+	if err != nil || st != (SyncStats{Created: 1}) {
+		t.Errorf("VM sync: %+v, %v", st, err)
+	}
+
+	count := func(path string) int { return len(fake.objects[path]) }
+	if count("/api/dcim/manufacturers/") != 2 || count("/api/dcim/device-types/") != 2 || count("/api/dcim/device-roles/") != 1 {
+		t.Errorf("manufacturers, device types, roles: %v", fake.objects)
+	}
+	// The clusters of two vCenters share a name but not a group
+	if count("/api/virtualization/cluster-groups/") != 2 || count("/api/virtualization/clusters/") != 2 {
+		t.Errorf("cluster groups, clusters: %v", fake.objects)
+	}
+	dev := fake.objects["/api/dcim/devices/"]
+	if dev[0]["serial"] != "ABC1234" || dev[0]["site"] != 1.0 || dev[0]["cluster"] != dev[1]["cluster"] || dev[2]["cluster"] == dev[0]["cluster"] {
+		t.Errorf("devices = %v", dev)
+	}
+	if dev[1]["status"] != "active" {
+		t.Errorf("esx2 status = %v after reconnecting", dev[1]["status"])
+	}
+	vm := fake.objects["/api/virtualization/virtual-machines/"][0]
+	if vm["device"] != 1.0 || vm["cluster"] != dev[0]["cluster"] || vm["memory"] != 16384.0 {
+		t.Errorf("VM = %v", vm)
+	}
+
+	if _, err := NewNetBox(srv.URL, "secret", "nowhere", "Hypervisor").SyncHosts(ctx, hosts); err == nil || !strings.Contains(err.Error(), "nowhere") {
+		t.Errorf("missing site: %v", err)
+	}
+	if _, err := NewNetBox(srv.URL, "wrong", "ashburn", "Hypervisor").SyncHosts(ctx, hosts); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("bad token: %v", err)
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	for name, want := range map[string]string{
+		"Dell Inc.":            "dell-inc",
+		"ProLiant DL380 Gen10": "proliant-dl380-gen10",
+		"vc1.example.com":      "vc1-example-com",
+		"VMware vSphere":       "vmware-vsphere",
+	} {
+		if got := slugify(name); got != want {
+			t.Errorf("slugify(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestWriteAnsible(t *testing.T) {
+	hosts := []collector.Host{
+		{VCenter: "vc1.example.com", Hostname: "esx1", Cluster: "Prod-A", TotalCores: 32, MemoryGB: 512, ESXiVersion: "8.0.3", VsanType: "ESA"},
+		{VCenter: "vc1.example.com", Hostname: "esx2", Cluster: "Prod-A", TotalCores: 32},
+		{VCenter: "vc1.example.com", Hostname: "esx3"},
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, "ansible", &Report{Tables: HostTables(hosts)}); err != nil {
+		t.Fatal(err)
+	}
+	var inv struct {
+		Meta struct {
+			Hostvars map[string]map[string]any
+		} `json:"_meta"`
+		All     struct{ Children []string }
+		Cluster struct{ Hosts []string } `json:"cluster_prod_a"`
+		VCenter struct{ Hosts []string } `json:"vcenter_vc1_example_com"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &inv); err != nil {
+		t.Fatalf("%v: %s", err, buf.Bytes())
+	}
+	if strings.Join(inv.All.Children, ",") != "ungrouped,cluster_prod_a,vcenter_vc1_example_com" {
+		t.Errorf("groups = %v", inv.All.Children)
+	}
+	if strings.Join(inv.Cluster.Hosts, ",") != "esx1,esx2" || len(inv.VCenter.Hosts) != 3 {
+		t.Errorf("cluster hosts %v, vCenter hosts %v", inv.Cluster.Hosts, inv.VCenter.Hosts)
+	}
+	vars := inv.Meta.Hostvars["esx1"]
+	if vars["totalCores"] != 32.0 || vars["memoryGB"] != 512.0 || vars["esxiVersion"] != "8.0.3" || vars["vsanType"] != "ESA" {
+		t.Errorf("esx1 hostvars = %v", vars)
+	}
+	if _, ok := vars["hostname"]; ok {
+		t.Error("hostname repeated in hostvars")
+	}
+
+	if err := Write(io.Discard, "ansible", &Report{Tables: VMTables(nil)}); err == nil {
+		t.Error("VM table accepted")
+	}
+}
+
+func TestWriteRVTools(t *testing.T) {
+	hosts := []collector.Host{{VCenter: "vc1", Hostname: "esx1", Cluster: "Prod", Sockets: 2, CoresPerSocket: 16, TotalCores: 32, MemoryGB: 512, SerialNumber: "ABC1234", Hyperthreading: "inactive", ConnectionState: "connected", InMaintenanceMode: true}}
+	var buf bytes.Buffer
+	if err := Write(&buf, "rvtools", &Report{Tables: RVToolsHostTables(hosts)}); err != nil {
+		t.Fatal(err)
+	}
+	f, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if got := f.GetSheetList(); strings.Join(got, ",") != "vHost,vCluster" {
+		t.Errorf("sheets = %v", got)
+	}
+	// Macros address RVTools columns by letter
+	for cell, want := range map[string]string{
+		"A1": "Host", "N1": "# Cores", "P1": "# Memory", "BA1": "Model",
+		"A2": "esx1", "C2": "Prod", "B2": "", "L2": "2", "N2": "32", "P2": "524288", "BB2": "ABC1234",
+		"E2": "True", "J2": "True", "K2": "False",
+	} {
+		if got, _ := f.GetCellValue("vHost", cell); got != want {
+			t.Errorf("vHost!%s = %q, want %q", cell, got, want)
+		}
+	}
+	if got, _ := f.GetCellValue("vCluster", "G2"); got != "32" {
+		t.Errorf("vCluster NumCpuCores = %q", got)
+	}
+
+	// A value whose header is misspelt would silently be dropped
+	for _, sheet := range []struct {
+		headers []string
+		values  []string
+	}{
+		{rvtoolsHostHeaders, slices.Collect(maps.Keys(rvtoolsHostValues))},
+		{rvtoolsClusterHeaders, slices.Collect(maps.Keys(rvtoolsClusterValues))},
+		{rvtoolsVMHeaders, slices.Collect(maps.Keys(rvtoolsVMValues))},
+		{rvtoolsDatastoreHeaders, slices.Collect(maps.Keys(rvtoolsDatastoreValues))},
+	} {
+		for _, v := range sheet.values {
+			if !slices.Contains(sheet.headers, v) {
+				t.Errorf("value for unknown RVTools column %q", v)
+			}
+		}
+	}
+}
+
+func TestSyslog(t *testing.T) {
+	hosts := []collector.Host{
+		{VCenter: "vc1", Hostname: "esx1", ESXiVersion: "8.0.3", TotalCores: 32, CPUModel: `Intel "Xeon"`},
+		{VCenter: "vc1", Hostname: "esx2", ServerModel: "a=b|c"},
+	}
+	table := HostTables(hosts)[0]
+	at := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer udp.Close()
+	s, err := NewSyslogSender("udp://"+udp.LocalAddr().String(), "kv", "1.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := s.Send(table, at); err != nil || n != 2 {
+		t.Fatalf("sent %d: %v", n, err)
+	}
+	buf := make([]byte, 4096)
+	n, _, err := udp.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := string(buf[:n])
+	if !strings.HasPrefix(msg, "<134>1 2026-10-16T09:00:00Z ") || !strings.Contains(msg, " vmware-inventory ") || !strings.Contains(msg, " hosts - ") {
+		t.Errorf("header of %q", msg)
+	}
+	if !strings.Contains(msg, ` hostname="esx1" `) || !strings.Contains(msg, ` cpuModel="Intel \"Xeon\""`) || !strings.Contains(msg, " totalCores=32 ") || strings.Contains(msg, "cluster=") {
+		t.Errorf("fields of %q", msg)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		data, _ := io.ReadAll(conn)
+		conn.Close()
+		received <- string(data)
+	}()
+	if s, err = NewSyslogSender("tcp://"+ln.Addr().String(), "cef", "1.2.3"); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := s.Send(table, at); err != nil || n != 2 {
+		t.Fatalf("sent %d: %v", n, err)
+	}
+	data := <-received
+	// Octet-counted framing: the length, a space, then the message
+	var msgs []string
+	for data != "" {
+		size, rest, _ := strings.Cut(data, " ")
+		n, err := strconv.Atoi(size)
+		if err != nil || n > len(rest) {
+			t.Fatalf("bad frame in %q", data)
+		}
+		msgs = append(msgs, rest[:n])
+		data = rest[n:]
+	}
+	if len(msgs) != 2 {
+		t.Fatalf("got %d messages", len(msgs))
+	}
+	if !strings.Contains(msgs[0], "CEF:0|Carahsoft|VMware Inventory|1.2.3|inventory-hosts|Hosts inventory|1|rt=1792141200000 vcenter=vc1 hostname=esx1") {
+		t.Errorf("CEF event %q", msgs[0])
+	}
+	if !strings.Contains(msgs[1], `serverModel=a\=b|c`) {
+		t.Errorf("CEF escaping in %q", msgs[1])
+	}
+
+	for _, bad := range []string{"syslog.example.com", "http://syslog.example.com"} {
+		if _, err := NewSyslogSender(bad, "kv", ""); err == nil {
+			t.Errorf("%q accepted", bad)
+		}
+	}
+	if _, err := NewSyslogSender("udp://syslog.example.com", "leef", ""); err == nil {
+		t.Error("unknown format accepted")
+	}
+}
+
+func TestKafkaPublish(t *testing.T) {
+	var got struct {
+		Records []struct {
+			Key   any
+			Value map[string]any
+		}
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		if r.URL.Path != "/topics/inventory.hosts" || r.Header.Get("Content-Type") != "application/vnd.kafka.json.v2+json" || user != "svc" || pass != "pw" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		got.Records = nil
+		json.NewDecoder(r.Body).Decode(&got)
+		var offsets []map[string]any
+		for i, rec := range got.Records {
+			o := map[string]any{"partition": 0, "offset": i}
+			if rec.Value["hostname"] == "bad" {
+				o["error_code"], o["error"] = 40101, "record rejected"
+			}
+			offsets = append(offsets, o)
+		}
+		json.NewEncoder(w).Encode(map[string]any{"offsets": offsets})
+	}))
+	defer srv.Close()
+	proxy := strings.Replace(srv.URL, "http://", "http://svc:pw@", 1)
+
+	hosts := []collector.Host{{Hostname: "esx1", BIOSUUID: "4c4c-01", TotalCores: 32}, {Hostname: "esx2"}}
+	at := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	p, err := NewKafkaPublisher(proxy, "inventory.hosts", "biosUUID")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := p.Publish(context.Background(), HostTables(hosts)[0], at)
+	if err != nil || n != 2 {
+		t.Fatalf("published %d: %v", n, err)
+	}
+	if got.Records[0].Key != "4c4c-01" || got.Records[1].Key != nil {
+		t.Errorf("keys %v, %v", got.Records[0].Key, got.Records[1].Key)
+	}
+	if v := got.Records[0].Value; v["hostname"] != "esx1" || v["totalCores"] != 32.0 || v["collectedAt"] != "2026-10-16T09:00:00Z" {
+		t.Errorf("value = %v", v)
+	}
+
+	hosts = append(hosts, collector.Host{Hostname: "bad"})
+	if n, err := p.Publish(context.Background(), HostTables(hosts)[0], at); err == nil || n != 2 || !strings.Contains(err.Error(), "record rejected") {
+		t.Errorf("published %d: %v", n, err)
+	}
+	if p, err = NewKafkaPublisher(proxy, "inventory.hosts", "uuid"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Publish(context.Background(), HostTables(hosts)[0], at); err == nil {
+		t.Error("unknown key column accepted")
+	}
+	if _, err := NewKafkaPublisher("kafka.example.com:9092", "t", ""); err == nil {
+		t.Error("broker address accepted as proxy URL")
+	}
+}
+
+func TestPowerPolicyColumns(t *testing.T) {
+	hosts := []collector.Host{{PowerPolicy: "High Performance"}, {PowerPolicy: "Balanced"}, {}}
+	tbl := HostTables(hosts, PowerPolicyColumns("High Performance")...)[0]
+	n := len(HostColumns)
+	if got := tbl.Keys[n:]; !slices.Equal(got, []string{"powerPolicyAsExpected"}) {
+		t.Fatalf("extra keys %v", got)
+	}
+	for i, want := range []any{true, false, nil} {
+		if got := tbl.Rows[i][n]; got != want {
+			t.Errorf("host %d with %q: %v, want %v", i, hosts[i].PowerPolicy, got, want)
+		}
+	}
+}
+
+func TestQuickStatsColumns(t *testing.T) {
+	hosts := []collector.Host{
+		{Hostname: "esx1", ConnectionState: "connected", CPUUsageMHz: 5000, MemoryUsageGB: 100, UptimeSeconds: 3*86400 + 3600, BootTime: time.Date(2024, 5, 1, 10, 0, 0, 0, time.FixedZone("CEST", 2*3600))},
+		{Hostname: "esx2", ConnectionState: "disconnected"},
+	}
+	tbl := HostTables(hosts, QuickStatsColumns...)[0]
+	n := len(HostColumns)
+	if got := tbl.Keys[n:]; !slices.Equal(got, []string{"cpuUsageMHz", "memoryUsageGB"}) {
+		t.Fatalf("extra keys %v", got)
+	}
+	if got := tbl.Rows[0][n:]; !slices.Equal(got, []any{5000, 100.0}) {
+		t.Errorf("connected host %v", got)
+	}
+	if got := tbl.Rows[1][n:]; !slices.Equal(got, []any{nil, nil}) {
+		t.Errorf("disconnected host %v, want empty", got)
+	}
+	// The boot time and uptime are among the default columns
+	boot := slices.Index(tbl.Keys, "bootTime")
+	if got := tbl.Rows[0][boot : boot+2]; !slices.Equal(got, []any{"2024-05-01T08:00:00Z", 3}) {
+		t.Errorf("connected host boot time and uptime %v", got)
+	}
+	if got := tbl.Rows[1][boot : boot+2]; !slices.Equal(got, []any{"", nil}) {
+		t.Errorf("disconnected host boot time and uptime %v, want empty", got)
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, "csv", &Report{Tables: []*Table{tbl}}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if cells := strings.Split(lines[2], ","); slices.ContainsFunc(cells[boot:], func(c string) bool { return c != "" }) {
+		t.Errorf("disconnected host written as %q, want empty cells from the boot time on", lines[2])
+	}
+}
@@ -0,0 +1,43 @@
+package export
+
+import (
+	"math"
+	"strings"
+
+	"vmware-inventory/pkg/collector"
+)
+
+// HeadroomColumns are the columns of the headroom report.
+var HeadroomColumns = []Column[collector.ClusterHeadroom]{
+	{"vcenter", "vCenter", func(c collector.ClusterHeadroom) any { return c.VCenter }},
+	{"cluster", "Cluster", func(c collector.ClusterHeadroom) any { return c.Cluster.Cluster }},
+	{"hosts", "Hosts", func(c collector.ClusterHeadroom) any { return c.Hosts }},
+	{"cpuCapacityMHz", "CPU Capacity MHz", func(c collector.ClusterHeadroom) any { return c.CPUCapacityMHz }},
+	{"cpuUsedMHz", "CPU Used MHz", func(c collector.ClusterHeadroom) any { return c.CPUUsageMHz }},
+	{"cpuUsedPct", "CPU Used %", func(c collector.ClusterHeadroom) any { return pct(c.CPUUsedPct()) }},
+	{"cpuHeadroomMHz", "CPU Headroom MHz", func(c collector.ClusterHeadroom) any { return c.CPUHeadroomMHz() }},
+	{"memoryGB", "Memory GB", func(c collector.ClusterHeadroom) any { return c.MemoryGB }},
+	{"memoryUsedGB", "Memory Used GB", func(c collector.ClusterHeadroom) any { return c.MemoryUsageGB }},
+	{"memoryUsedPct", "Memory Used %", func(c collector.ClusterHeadroom) any { return pct(c.MemoryUsedPct()) }},
+	{"memoryHeadroomGB", "Memory Headroom GB", func(c collector.ClusterHeadroom) any { return c.MemoryHeadroomGB() }},
+	{"vsanCapacityGB", "vSAN Capacity GB", func(c collector.ClusterHeadroom) any { return c.VsanCapacityGB }},
+	{"vsanUsedGB", "vSAN Used GB", func(c collector.ClusterHeadroom) any { return c.VsanUsedGB }},
+	{"vsanUsedPct", "vSAN Used %", func(c collector.ClusterHeadroom) any { return pct(c.VsanUsedPct()) }},
+	{"vsanHeadroomGB", "vSAN Headroom GB", func(c collector.ClusterHeadroom) any { return c.VsanHeadroomGB() }},
+	{"status", "Status", func(c collector.ClusterHeadroom) any {
+		if over := c.Over(); len(over) > 0 {
+			return "Over target: " + strings.Join(over, ", ")
+		}
+		return "OK"
+	}},
+}
+
+// HeadroomTables returns the table of the headroom report, a row per
+// cluster measured against t.
+func HeadroomTables(hosts []collector.Host, vsan []collector.VsanUsage, t collector.Targets) []*Table {
+	clusters := collector.Headroom(collector.RollupClusters(hosts), vsan, t)
+	return []*Table{NewTable("headroom", "Headroom", HeadroomColumns, clusters)}
+}
+
+// pct returns a percentage written to one decimal place.
+func pct(f float64) Fixed { return Fixed{Number: math.Round(f*10) / 10, Decimals: 1} }
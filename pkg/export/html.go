@@ -0,0 +1,78 @@
+package export
+
+import (
+	"html/template"
+	"io"
+)
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"format":  FormatValue,
+	"numeric": isNumeric,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>VMware Inventory</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+h1 { margin-bottom: 0.2em; }
+.collected { color: #666; margin-top: 0; }
+table { border-collapse: collapse; margin-bottom: 2em; font-size: 0.9em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; }
+th { background: #f0f0f0; cursor: pointer; user-select: none; white-space: nowrap; }
+th.asc::after { content: " \25B2"; }
+th.desc::after { content: " \25BC"; }
+td.num { text-align: right; }
+tbody tr:nth-child(even) { background: #fafafa; }
+</style>
+</head>
+<body>
+<h1>VMware Inventory</h1>
+<p class="collected">Collected {{.CollectedAt.Format "2006-01-02 15:04:05 MST"}}{{with .Generator}} by {{.}}{{end}}</p>
+{{range .Tables}}
+<h2>{{.Title}}</h2>
+<table class="sortable">
+<thead><tr>{{range .Headers}}<th>{{.}}</th>{{end}}</tr></thead>
+<tbody>
+{{range .Rows}}<tr>{{range .}}{{if numeric .}}<td class="num" data-value="{{.}}">{{format .}}</td>{{else}}<td>{{.}}</td>{{end}}{{end}}</tr>
+{{end}}</tbody>
+</table>
+{{end}}
+<script>
+document.querySelectorAll("table.sortable").forEach(function (table) {
+  table.querySelectorAll("th").forEach(function (th, col) {
+    th.addEventListener("click", function () {
+      var asc = !th.classList.contains("asc");
+      table.querySelectorAll("th").forEach(function (h) { h.classList.remove("asc", "desc"); });
+      th.classList.add(asc ? "asc" : "desc");
+      var tbody = table.tBodies[0];
+      var rows = Array.prototype.slice.call(tbody.rows);
+      rows.sort(function (a, b) {
+        var x = a.cells[col], y = b.cells[col];
+        var cmp = x.dataset.value !== undefined
+          ? parseFloat(x.dataset.value) - parseFloat(y.dataset.value)
+          : x.textContent.localeCompare(y.textContent);
+        return asc ? cmp : -cmp;
+      });
+      rows.forEach(function (r) { tbody.appendChild(r); });
+    });
+  });
+});
+</script>
+</body>
+</html>
+`))
+
+// writeHTML writes a self-contained HTML report with one sortable table per
+// report table.
+func writeHTML(w io.Writer, r *Report) error {
+	return htmlTemplate.Execute(w, r)
+}
+
+func isNumeric(v any) bool {
+	switch v.(type) {
+	case int, int64, float64, Fixed:
+		return true
+	}
+	return false
+}
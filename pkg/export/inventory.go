@@ -0,0 +1,566 @@
+package export
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"vmware-inventory/pkg/collector"
+)
+
+// HostColumns are the columns of the host report, in output order.
+var HostColumns = []Column[collector.Host]{
+	{"vcenter", "vCenter", func(h collector.Host) any { return h.VCenter }},
+	{"hostname", "Hostname", func(h collector.Host) any { return h.Hostname }},
+	{"cluster", "Cluster", func(h collector.Host) any { return h.Cluster }},
+	{"serverModel", "Server Model", func(h collector.Host) any { return h.ServerModel }},
+	{"esxiVersion", "ESXi Version", func(h collector.Host) any { return h.ESXiVersion }},
+	{"esxiBuild", "ESXi Build", func(h collector.Host) any { return h.ESXiBuild }},
+	{"imageProfile", "Image Profile", func(h collector.Host) any { return h.ImageProfile }},
+	{"cpuModel", "CPU Model", func(h collector.Host) any { return h.CPUModel }},
+	{"socketCount", "Socket Count", func(h collector.Host) any { return h.Sockets }},
+	{"coresPerSocket", "Cores per Socket", func(h collector.Host) any { return h.CoresPerSocket }},
+	{"totalCores", "Total Cores", func(h collector.Host) any { return h.TotalCores }},
+	{"memoryGB", "Memory GB", func(h collector.Host) any { return h.MemoryGB }},
+	{"vsanType", "vSAN Type", func(h collector.Host) any { return h.VsanType }},
+	{"vsanCapacityDisks", "vSAN Capacity Disks", func(h collector.Host) any { return h.VsanCapacityDisks }},
+	{"vsanCacheDisks", "vSAN Cache Disks", func(h collector.Host) any { return h.VsanCacheDisks }},
+	{"vsanCapacityTiB", "vSAN Capacity TiB", func(h collector.Host) any { return h.VsanCapacityTiB }},
+	{"connectionState", "Connection State", func(h collector.Host) any { return h.ConnectionState }},
+	{"inMaintenanceMode", "In Maintenance Mode", func(h collector.Host) any { return h.InMaintenanceMode }},
+	{"powerState", "Power State", func(h collector.Host) any { return h.PowerState }},
+	{"biosUUID", "BIOS UUID", func(h collector.Host) any { return h.BIOSUUID }},
+	{"serialNumber", "Serial Number", func(h collector.Host) any { return h.SerialNumber }},
+	{"assetTag", "Asset Tag", func(h collector.Host) any { return h.AssetTag }},
+	{"biosVersion", "BIOS Version", func(h collector.Host) any { return h.BIOSVersion }},
+	{"biosReleaseDate", "BIOS Release Date", func(h collector.Host) any { return h.BIOSReleaseDate }},
+	{"firmwareVersion", "Firmware Version", func(h collector.Host) any { return h.FirmwareVersion }},
+	{"cpuFamily", "CPU Family", func(h collector.Host) any { return h.CPUFamily }},
+	{"cpuModelNumber", "CPU Model Number", func(h collector.Host) any { return h.CPUModelNumber }},
+	{"cpuStepping", "CPU Stepping", func(h collector.Host) any { return h.CPUStepping }},
+	{"cpuFeatures", "CPU Features", func(h collector.Host) any { return h.CPUFeatures }},
+	{"maxEVCMode", "Max EVC Mode", func(h collector.Host) any { return h.MaxEVCMode }},
+	{"currentEVCMode", "Current EVC Mode", func(h collector.Host) any { return h.CurrentEVCMode }},
+	{"hyperthreading", "Hyperthreading", func(h collector.Host) any { return h.Hyperthreading }},
+	{"cpuThreads", "CPU Threads", func(h collector.Host) any { return h.CPUThreads }},
+	{"numaNodes", "NUMA Nodes", func(h collector.Host) any { return h.NUMANodes }},
+	{"coresPerNumaNode", "Cores per NUMA Node", func(h collector.Host) any { return h.CoresPerNUMANode }},
+	{"memoryPerNumaNodeGB", "Memory per NUMA Node GB", func(h collector.Host) any { return h.MemoryPerNUMANodeGB }},
+	{"nics", "NICs", func(h collector.Host) any { return h.NICs }},
+	{"gpus", "GPUs", func(h collector.Host) any { return h.GPUs }},
+	{"gpuModels", "GPU Models", func(h collector.Host) any { return h.GPUModels }},
+	{"bootTime", "Boot Time", func(h collector.Host) any {
+		if h.BootTime.IsZero() {
+			return ""
+		}
+		return h.BootTime.UTC().Format(time.RFC3339)
+	}},
+	quickStatsColumn("uptimeDays", "Uptime Days", func(h collector.Host) any { return h.UptimeSeconds / 86400 }),
+	{"lockdownMode", "Lockdown Mode", func(h collector.Host) any { return h.LockdownMode }},
+	{"sshRunning", "SSH Running", func(h collector.Host) any { return boolValue(h.SSHRunning) }},
+	{"esxiShellRunning", "ESXi Shell Running", func(h collector.Host) any { return boolValue(h.ESXiShellRunning) }},
+	{"tpmVersion", "TPM Version", func(h collector.Host) any { return h.TPMVersion }},
+	{"tpmAttestation", "TPM Attestation", func(h collector.Host) any { return h.TPMAttestation }},
+	{"secureBoot", "Secure Boot", func(h collector.Host) any { return boolValue(h.SecureBoot) }},
+	{"certificateExpiry", "Certificate Expiry", func(h collector.Host) any { return certificateExpiry(h) }},
+	{"certificateStatus", "Certificate Status", func(h collector.Host) any { return h.CertificateStatus }},
+	{"powerPolicy", "Power Policy", func(h collector.Host) any { return h.PowerPolicy }},
+}
+
+// QuickStatsColumns are the columns added to the host report with
+// -quickstats: the host's use at collection time. They are empty for hosts
+// that are not connected, which report none.
+var QuickStatsColumns = []Column[collector.Host]{
+	quickStatsColumn("cpuUsageMHz", "CPU Usage MHz", func(h collector.Host) any { return h.CPUUsageMHz }),
+	quickStatsColumn("memoryUsageGB", "Memory Usage GB", func(h collector.Host) any { return h.MemoryUsageGB }),
+}
+
+// PowerPolicyColumns returns the column added to the host report with
+// -expect-power-policy: whether the host's power policy is expected, a
+// name such as "High Performance". It is empty for hosts that do not
+// report their policy.
+func PowerPolicyColumns(expected string) []Column[collector.Host] {
+	return []Column[collector.Host]{{"powerPolicyAsExpected", "Power Policy As Expected", func(h collector.Host) any {
+		if h.PowerPolicy == "" {
+			return nil
+		}
+		return h.PowerPolicy == expected
+	}}}
+}
+
+// quickStatsColumn returns a column of value, from the host's quick stats,
+// empty for hosts that are not connected.
+func quickStatsColumn(key, header string, value func(collector.Host) any) Column[collector.Host] {
+	return Column[collector.Host]{key, header, func(h collector.Host) any {
+		if h.ConnectionState != "connected" {
+			return nil
+		}
+		return value(h)
+	}}
+}
+
+// UtilizationColumns are the columns added to the host report with
+// Options.Utilization; they are empty for hosts without samples.
+var UtilizationColumns = []Column[collector.Host]{
+	utilizationColumn("cpuAvgPct", "CPU Avg %", func(u *collector.Utilization) float64 { return u.CPUAvgPct }),
+	utilizationColumn("cpuPeakPct", "CPU Peak %", func(u *collector.Utilization) float64 { return u.CPUPeakPct }),
+	utilizationColumn("memoryAvgPct", "Memory Avg %", func(u *collector.Utilization) float64 { return u.MemoryAvgPct }),
+	utilizationColumn("memoryPeakPct", "Memory Peak %", func(u *collector.Utilization) float64 { return u.MemoryPeakPct }),
+}
+
+// utilizationColumn returns a column of the percentage value of a host's
+// utilization, empty if it has none.
+func utilizationColumn(key, header string, value func(*collector.Utilization) float64) Column[collector.Host] {
+	return Column[collector.Host]{key, header, func(h collector.Host) any {
+		if h.Utilization == nil {
+			return nil
+		}
+		return pct(value(h.Utilization))
+	}}
+}
+
+// ClusterColumns are the columns of the per-cluster rollup.
+var ClusterColumns = []Column[collector.Cluster]{
+	{"vcenter", "vCenter", func(c collector.Cluster) any { return c.VCenter }},
+	{"cluster", "Cluster", func(c collector.Cluster) any { return c.Cluster }},
+	{"hosts", "Hosts", func(c collector.Cluster) any { return c.Hosts }},
+	{"socketCount", "Socket Count", func(c collector.Cluster) any { return c.Sockets }},
+	{"totalCores", "Total Cores", func(c collector.Cluster) any { return c.TotalCores }},
+	{"memoryGB", "Memory GB", func(c collector.Cluster) any { return c.MemoryGB }},
+	{"vsanCapacityTiB", "vSAN Capacity TiB", func(c collector.Cluster) any { return c.VsanCapacityTiB }},
+	{"esxiVersions", "ESXi Versions", func(c collector.Cluster) any { return c.VersionSpread() }},
+}
+
+// VMColumns are the columns of the VM report.
+var VMColumns = []Column[collector.VM]{
+	{"vcenter", "vCenter", func(v collector.VM) any { return v.VCenter }},
+	{"name", "VM Name", func(v collector.VM) any { return v.Name }},
+	{"cluster", "Cluster", func(v collector.VM) any { return v.Cluster }},
+	{"host", "Host", func(v collector.VM) any { return v.Host }},
+	{"powerState", "Power State", func(v collector.VM) any { return v.PowerState }},
+	{"guestOS", "Guest OS", func(v collector.VM) any { return v.GuestOS }},
+	{"vcpus", "vCPUs", func(v collector.VM) any { return v.VCPUs }},
+	{"memoryGB", "Memory GB", func(v collector.VM) any { return v.MemoryGB }},
+}
+
+// DatastoreColumns are the columns of the datastore report.
+var DatastoreColumns = []Column[collector.Datastore]{
+	{"vcenter", "vCenter", func(d collector.Datastore) any { return d.VCenter }},
+	{"name", "Datastore", func(d collector.Datastore) any { return d.Name }},
+	{"type", "Type", func(d collector.Datastore) any { return d.Type }},
+	{"capacityGB", "Capacity GB", func(d collector.Datastore) any { return d.CapacityGB }},
+	{"freeGB", "Free GB", func(d collector.Datastore) any { return d.FreeGB }},
+	{"hosts", "Hosts", func(d collector.Datastore) any { return d.Hosts }},
+	{"vmfsVersion", "VMFS Version", func(d collector.Datastore) any { return d.VMFSVersion }},
+	{"vmfs5", "VMFS-5", func(d collector.Datastore) any { return d.VMFS5() }},
+	{"clusters", "Clusters", func(d collector.Datastore) any { return strings.Join(d.Clusters, ", ") }},
+}
+
+// DatastoreClusterColumns are the columns of the datastore report's
+// per-cluster rollup.
+var DatastoreClusterColumns = []Column[collector.DatastoreCluster]{
+	{"vcenter", "vCenter", func(c collector.DatastoreCluster) any { return c.VCenter }},
+	{"cluster", "Cluster", func(c collector.DatastoreCluster) any { return c.Cluster }},
+	{"datastores", "Datastores", func(c collector.DatastoreCluster) any { return c.Datastores }},
+	{"vmfs6Datastores", "VMFS-6 Datastores", func(c collector.DatastoreCluster) any { return c.VMFS6 }},
+	{"vmfs5Datastores", "VMFS-5 Datastores", func(c collector.DatastoreCluster) any { return c.VMFS5 }},
+	{"vmfs5CapacityGB", "VMFS-5 Capacity GB", func(c collector.DatastoreCluster) any { return c.VMFS5CapacityGB }},
+}
+
+// DIMMColumns are the columns of the memory module report. Sizes and
+// speeds the host does not report are empty.
+var DIMMColumns = []Column[collector.DIMM]{
+	{"vcenter", "vCenter", func(d collector.DIMM) any { return d.VCenter }},
+	{"hostname", "Hostname", func(d collector.DIMM) any { return d.Host }},
+	{"cluster", "Cluster", func(d collector.DIMM) any { return d.Cluster }},
+	{"slot", "Slot", func(d collector.DIMM) any { return d.Slot }},
+	{"sizeGB", "Size GB", func(d collector.DIMM) any { return nonZero(d.SizeGB) }},
+	{"speedMTs", "Speed MT/s", func(d collector.DIMM) any { return nonZero(d.SpeedMTs) }},
+	{"vendor", "Vendor", func(d collector.DIMM) any { return d.Vendor }},
+	{"status", "Status", func(d collector.DIMM) any { return d.Status }},
+	{"description", "Description", func(d collector.DIMM) any { return d.Description }},
+}
+
+// VIBColumns are the columns of the installed VIB report.
+var VIBColumns = []Column[collector.VIB]{
+	{"vcenter", "vCenter", func(v collector.VIB) any { return v.VCenter }},
+	{"hostname", "Hostname", func(v collector.VIB) any { return v.Host }},
+	{"cluster", "Cluster", func(v collector.VIB) any { return v.Cluster }},
+	{"name", "VIB", func(v collector.VIB) any { return v.Name }},
+	{"version", "Version", func(v collector.VIB) any { return v.Version }},
+	{"vendor", "Vendor", func(v collector.VIB) any { return v.Vendor }},
+	{"acceptanceLevel", "Acceptance Level", func(v collector.VIB) any { return v.AcceptanceLevel }},
+	{"creationDate", "Creation Date", func(v collector.VIB) any { return v.CreationDate }},
+	{"summary", "Summary", func(v collector.VIB) any { return v.Summary }},
+}
+
+// NICColumns are the columns of the physical NIC report. Link speeds of
+// NICs whose link is down, and virtual function counts of NICs that are not
+// SR-IOV capable, are empty.
+var NICColumns = []Column[collector.PhysicalNIC]{
+	{"vcenter", "vCenter", func(n collector.PhysicalNIC) any { return n.VCenter }},
+	{"hostname", "Hostname", func(n collector.PhysicalNIC) any { return n.Host }},
+	{"cluster", "Cluster", func(n collector.PhysicalNIC) any { return n.Cluster }},
+	{"device", "Device", func(n collector.PhysicalNIC) any { return n.Device }},
+	{"pci", "PCI Address", func(n collector.PhysicalNIC) any { return n.PCI }},
+	{"vendor", "Vendor", func(n collector.PhysicalNIC) any { return n.Vendor }},
+	{"model", "Model", func(n collector.PhysicalNIC) any { return n.Model }},
+	{"driver", "Driver", func(n collector.PhysicalNIC) any { return n.Driver }},
+	{"driverVersion", "Driver Version", func(n collector.PhysicalNIC) any { return n.DriverVersion }},
+	{"firmwareVersion", "Firmware Version", func(n collector.PhysicalNIC) any { return n.FirmwareVersion }},
+	{"linkSpeedMb", "Link Speed Mb/s", func(n collector.PhysicalNIC) any { return nonZero(n.LinkSpeedMb) }},
+	{"switchType", "Switch Type", func(n collector.PhysicalNIC) any { return n.SwitchType }},
+	{"switch", "Switch", func(n collector.PhysicalNIC) any { return n.Switch }},
+	{"sriovCapable", "SR-IOV Capable", func(n collector.PhysicalNIC) any { return n.SRIOVCapable }},
+	{"sriovEnabled", "SR-IOV Enabled", func(n collector.PhysicalNIC) any { return n.SRIOVEnabled }},
+	{"virtualFunctions", "Virtual Functions", func(n collector.PhysicalNIC) any { return sriovCount(n, n.VirtualFunctions) }},
+	{"activeVirtualFunctions", "Active Virtual Functions", func(n collector.PhysicalNIC) any { return sriovCount(n, n.ActiveVirtualFunctions) }},
+	{"maxVirtualFunctions", "Max Virtual Functions", func(n collector.PhysicalNIC) any { return sriovCount(n, n.MaxVirtualFunctions) }},
+}
+
+// sriovCount returns a virtual function count of n, or nil if n is not
+// SR-IOV capable.
+func sriovCount(n collector.PhysicalNIC, count int) any {
+	if !n.SRIOVCapable {
+		return nil
+	}
+	return count
+}
+
+// VMKernelAdapterColumns are the columns of the VMkernel adapter inventory.
+var VMKernelAdapterColumns = []Column[collector.VMKernelAdapter]{
+	{"vcenter", "vCenter", func(a collector.VMKernelAdapter) any { return a.VCenter }},
+	{"hostname", "Hostname", func(a collector.VMKernelAdapter) any { return a.Host }},
+	{"cluster", "Cluster", func(a collector.VMKernelAdapter) any { return a.Cluster }},
+	{"device", "Device", func(a collector.VMKernelAdapter) any { return a.Device }},
+	{"ipAddress", "IP Address", func(a collector.VMKernelAdapter) any { return a.IPAddress }},
+	{"subnetMask", "Subnet Mask", func(a collector.VMKernelAdapter) any { return a.SubnetMask }},
+	{"dhcp", "DHCP", func(a collector.VMKernelAdapter) any { return a.DHCP }},
+	{"mtu", "MTU", func(a collector.VMKernelAdapter) any { return a.MTU }},
+	{"netStack", "TCP/IP Stack", func(a collector.VMKernelAdapter) any { return a.NetStack }},
+	{"services", "Services", func(a collector.VMKernelAdapter) any { return strings.Join(a.Services, ", ") }},
+	{"switchType", "Switch Type", func(a collector.VMKernelAdapter) any { return a.SwitchType }},
+	{"switch", "Switch", func(a collector.VMKernelAdapter) any { return a.Switch }},
+	{"portgroup", "Port Group", func(a collector.VMKernelAdapter) any { return a.Portgroup }},
+}
+
+// ISCSIAdapterColumns are the columns of the iSCSI configuration report.
+var ISCSIAdapterColumns = []Column[collector.ISCSIAdapter]{
+	{"vcenter", "vCenter", func(a collector.ISCSIAdapter) any { return a.VCenter }},
+	{"hostname", "Hostname", func(a collector.ISCSIAdapter) any { return a.Host }},
+	{"cluster", "Cluster", func(a collector.ISCSIAdapter) any { return a.Cluster }},
+	{"device", "Device", func(a collector.ISCSIAdapter) any { return a.Device }},
+	{"type", "Type", func(a collector.ISCSIAdapter) any { return a.Type }},
+	{"model", "Model", func(a collector.ISCSIAdapter) any { return a.Model }},
+	{"driver", "Driver", func(a collector.ISCSIAdapter) any { return a.Driver }},
+	{"status", "Status", func(a collector.ISCSIAdapter) any { return a.Status }},
+	{"iqn", "IQN", func(a collector.ISCSIAdapter) any { return a.IQN }},
+	{"boundPorts", "Bound Ports", func(a collector.ISCSIAdapter) any { return strings.Join(a.BoundPorts, ", ") }},
+	{"sendTargets", "Send Targets", func(a collector.ISCSIAdapter) any { return strings.Join(a.SendTargets, ", ") }},
+	{"staticTargets", "Static Targets", func(a collector.ISCSIAdapter) any { return strings.Join(a.StaticTargets, ", ") }},
+	{"chap", "CHAP", func(a collector.ISCSIAdapter) any { return a.CHAP }},
+	{"mutualChap", "Mutual CHAP", func(a collector.ISCSIAdapter) any { return a.MutualCHAP }},
+}
+
+// LocalDiskColumns are the columns of the local storage inventory.
+var LocalDiskColumns = []Column[collector.LocalDisk]{
+	{"vcenter", "vCenter", func(d collector.LocalDisk) any { return d.VCenter }},
+	{"hostname", "Hostname", func(d collector.LocalDisk) any { return d.Host }},
+	{"cluster", "Cluster", func(d collector.LocalDisk) any { return d.Cluster }},
+	{"device", "Device", func(d collector.LocalDisk) any { return d.Device }},
+	{"vendor", "Vendor", func(d collector.LocalDisk) any { return d.Vendor }},
+	{"model", "Model", func(d collector.LocalDisk) any { return d.Model }},
+	{"capacityGB", "Capacity GB", func(d collector.LocalDisk) any { return d.CapacityGB }},
+	{"ssd", "SSD", func(d collector.LocalDisk) any { return d.SSD }},
+	{"adapter", "Adapter", func(d collector.LocalDisk) any { return d.Adapter }},
+	{"driver", "Driver", func(d collector.LocalDisk) any { return d.Driver }},
+	{"usb", "USB/SD", func(d collector.LocalDisk) any { return d.USB }},
+	{"boot", "Boot Device", func(d collector.LocalDisk) any { return d.Boot }},
+	{"datastores", "Datastores", func(d collector.LocalDisk) any { return strings.Join(d.Datastores, ", ") }},
+}
+
+// DatastoreMountColumns are the columns of the datastore mount report.
+var DatastoreMountColumns = []Column[collector.DatastoreMount]{
+	{"vcenter", "vCenter", func(m collector.DatastoreMount) any { return m.VCenter }},
+	{"hostname", "Hostname", func(m collector.DatastoreMount) any { return m.Host }},
+	{"cluster", "Cluster", func(m collector.DatastoreMount) any { return m.Cluster }},
+	{"datastore", "Datastore", func(m collector.DatastoreMount) any { return m.Datastore }},
+	{"type", "Type", func(m collector.DatastoreMount) any { return m.Type }},
+	{"mounted", "Mounted", func(m collector.DatastoreMount) any { return m.Mounted }},
+	{"accessMode", "Access Mode", func(m collector.DatastoreMount) any { return m.AccessMode }},
+	{"accessible", "Accessible", func(m collector.DatastoreMount) any { return m.Accessible }},
+	{"missing", "Missing", func(m collector.DatastoreMount) any { return m.Missing }},
+}
+
+// MultipathLUNColumns are the columns of the LUN path report.
+var MultipathLUNColumns = []Column[collector.MultipathLUN]{
+	{"vcenter", "vCenter", func(l collector.MultipathLUN) any { return l.VCenter }},
+	{"hostname", "Hostname", func(l collector.MultipathLUN) any { return l.Host }},
+	{"cluster", "Cluster", func(l collector.MultipathLUN) any { return l.Cluster }},
+	{"device", "Device", func(l collector.MultipathLUN) any { return l.Device }},
+	{"vendor", "Vendor", func(l collector.MultipathLUN) any { return l.Vendor }},
+	{"model", "Model", func(l collector.MultipathLUN) any { return l.Model }},
+	{"pathPolicy", "Path Policy", func(l collector.MultipathLUN) any { return l.PathPolicy }},
+	{"satp", "SATP", func(l collector.MultipathLUN) any { return l.SATP }},
+	{"paths", "Paths", func(l collector.MultipathLUN) any { return l.Paths }},
+	{"activePaths", "Active Paths", func(l collector.MultipathLUN) any { return l.ActivePaths }},
+	{"standbyPaths", "Standby Paths", func(l collector.MultipathLUN) any { return l.StandbyPaths }},
+	{"deadPaths", "Dead Paths", func(l collector.MultipathLUN) any { return l.DeadPaths }},
+	{"singlePath", "Single Path", func(l collector.MultipathLUN) any { return l.SinglePath }},
+}
+
+// StoragePolicyColumns are the columns of the storage policy report. FTT is
+// empty for policies that do not set it, and Encryption for those that
+// could not be checked.
+var StoragePolicyColumns = []Column[collector.StoragePolicy]{
+	{"vcenter", "vCenter", func(p collector.StoragePolicy) any { return p.VCenter }},
+	{"name", "Policy", func(p collector.StoragePolicy) any { return p.Name }},
+	{"ftt", "FTT", func(p collector.StoragePolicy) any {
+		if p.FTT == nil {
+			return nil
+		}
+		return *p.FTT
+	}},
+	{"raid", "RAID", func(p collector.StoragePolicy) any { return p.RAID }},
+	{"encryption", "Encryption", func(p collector.StoragePolicy) any { return boolValue(p.Encryption) }},
+	{"vms", "VMs", func(p collector.StoragePolicy) any { return p.VMs }},
+	{"objects", "Objects", func(p collector.StoragePolicy) any { return p.Objects }},
+	{"rules", "Rules", func(p collector.StoragePolicy) any { return strings.Join(p.Rules, "; ") }},
+}
+
+// VVolContainerColumns are the columns of the vVol report's storage
+// containers.
+var VVolContainerColumns = []Column[collector.VVolContainer]{
+	{"vcenter", "vCenter", func(c collector.VVolContainer) any { return c.VCenter }},
+	{"datastore", "Datastore", func(c collector.VVolContainer) any { return c.Datastore }},
+	{"container", "Storage Container", func(c collector.VVolContainer) any { return c.Container }},
+	{"arrays", "Arrays", func(c collector.VVolContainer) any { return strings.Join(c.Arrays, ", ") }},
+	{"providers", "Providers", func(c collector.VVolContainer) any { return strings.Join(c.Providers, ", ") }},
+	{"activeProviders", "Active Providers", func(c collector.VVolContainer) any { return strings.Join(c.ActiveProviders, ", ") }},
+	{"protocolEndpoint", "Protocol Endpoint", func(c collector.VVolContainer) any { return c.ProtocolEndpoint }},
+	{"capacityGB", "Capacity GB", func(c collector.VVolContainer) any { return c.CapacityGB }},
+	{"freeGB", "Free GB", func(c collector.VVolContainer) any { return c.FreeGB }},
+	{"hosts", "Hosts", func(c collector.VVolContainer) any { return c.Hosts }},
+}
+
+// VASAProviderColumns are the columns of the vVol report's storage
+// providers.
+var VASAProviderColumns = []Column[collector.VASAProvider]{
+	{"vcenter", "vCenter", func(p collector.VASAProvider) any { return p.VCenter }},
+	{"name", "Provider", func(p collector.VASAProvider) any { return p.Name }},
+	{"url", "URL", func(p collector.VASAProvider) any { return p.URL }},
+	{"status", "Status", func(p collector.VASAProvider) any { return p.Status }},
+	{"version", "Version", func(p collector.VASAProvider) any { return p.Version }},
+	{"vasaVersion", "VASA Version", func(p collector.VASAProvider) any { return p.VASAVersion }},
+	{"lastSync", "Last Sync", func(p collector.VASAProvider) any { return p.LastSync }},
+}
+
+// PCIDeviceColumns are the columns of the GPU and PCI passthrough report.
+var PCIDeviceColumns = []Column[collector.PCIDevice]{
+	{"vcenter", "vCenter", func(d collector.PCIDevice) any { return d.VCenter }},
+	{"hostname", "Hostname", func(d collector.PCIDevice) any { return d.Host }},
+	{"cluster", "Cluster", func(d collector.PCIDevice) any { return d.Cluster }},
+	{"pci", "PCI Address", func(d collector.PCIDevice) any { return d.ID }},
+	{"vendor", "Vendor", func(d collector.PCIDevice) any { return d.Vendor }},
+	{"model", "Model", func(d collector.PCIDevice) any { return d.Model }},
+	{"gpu", "GPU", func(d collector.PCIDevice) any { return d.GPU }},
+	{"passthroughEnabled", "Passthrough Enabled", func(d collector.PCIDevice) any { return d.PassthroughEnabled }},
+	{"passthroughActive", "Passthrough Active", func(d collector.PCIDevice) any { return d.PassthroughActive }},
+}
+
+// VGPUColumns are the columns of the vGPU profile report.
+var VGPUColumns = []Column[collector.VGPUProfile]{
+	{"vcenter", "vCenter", func(p collector.VGPUProfile) any { return p.VCenter }},
+	{"hostname", "Hostname", func(p collector.VGPUProfile) any { return p.Host }},
+	{"cluster", "Cluster", func(p collector.VGPUProfile) any { return p.Cluster }},
+	{"gpus", "GPUs", func(p collector.VGPUProfile) any { return p.GPUs }},
+	{"graphicsType", "Graphics Type", func(p collector.VGPUProfile) any { return p.GraphicsType }},
+	{"profile", "vGPU Profile", func(p collector.VGPUProfile) any { return p.Profile }},
+	{"license", "NVIDIA License", func(p collector.VGPUProfile) any { return p.License }},
+	{"vms", "VMs", func(p collector.VGPUProfile) any { return len(p.VMs) }},
+	{"vmNames", "VM Names", func(p collector.VGPUProfile) any { return strings.Join(p.VMs, ", ") }},
+}
+
+// NTPDNSColumns are the columns of the NTP and DNS audit.
+var NTPDNSColumns = []Column[collector.NTPDNSConfig]{
+	{"vcenter", "vCenter", func(c collector.NTPDNSConfig) any { return c.VCenter }},
+	{"hostname", "Hostname", func(c collector.NTPDNSConfig) any { return c.Host }},
+	{"cluster", "Cluster", func(c collector.NTPDNSConfig) any { return c.Cluster }},
+	{"timeProtocol", "Time Protocol", func(c collector.NTPDNSConfig) any { return c.TimeProtocol }},
+	{"ntpServers", "NTP Servers", func(c collector.NTPDNSConfig) any { return strings.Join(c.NTPServers, ", ") }},
+	{"ntpRunning", "NTP Running", func(c collector.NTPDNSConfig) any { return boolValue(c.NTPRunning) }},
+	{"ntpPolicy", "NTP Policy", func(c collector.NTPDNSConfig) any { return c.NTPPolicy }},
+	{"dnsServers", "DNS Servers", func(c collector.NTPDNSConfig) any { return strings.Join(c.DNSServers, ", ") }},
+	{"searchDomains", "Search Domains", func(c collector.NTPDNSConfig) any { return strings.Join(c.SearchDomains, ", ") }},
+	{"domain", "Domain", func(c collector.NTPDNSConfig) any { return c.Domain }},
+	{"dnsFromDhcp", "DNS from DHCP", func(c collector.NTPDNSConfig) any { return c.DNSFromDHCP }},
+	{"issues", "Issues", func(c collector.NTPDNSConfig) any { return strings.Join(c.Issues, "; ") }},
+}
+
+// ServiceColumns are the columns of the host services report.
+var ServiceColumns = []Column[collector.HostService]{
+	{"vcenter", "vCenter", func(s collector.HostService) any { return s.VCenter }},
+	{"hostname", "Hostname", func(s collector.HostService) any { return s.Host }},
+	{"cluster", "Cluster", func(s collector.HostService) any { return s.Cluster }},
+	{"service", "Service", func(s collector.HostService) any { return s.Key }},
+	{"label", "Label", func(s collector.HostService) any { return s.Label }},
+	{"running", "Running", func(s collector.HostService) any { return s.Running }},
+	{"policy", "Startup Policy", func(s collector.HostService) any { return s.Policy }},
+	{"nonstandard", "Nonstandard", func(s collector.HostService) any { return s.Nonstandard }},
+}
+
+// certificateExpiry returns the date h's certificate expires, YYYY-MM-DD in
+// UTC, or "" if unknown.
+func certificateExpiry(h collector.Host) string {
+	if h.CertificateExpiry.IsZero() {
+		return ""
+	}
+	return h.CertificateExpiry.UTC().Format(time.DateOnly)
+}
+
+// boolValue returns *b, or nil if b is nil for a value that was not
+// collected.
+func boolValue(b *bool) any {
+	if b == nil {
+		return nil
+	}
+	return *b
+}
+
+// nonZero returns n, or nil if it is zero for a value that was not found.
+func nonZero(n int) any {
+	if n == 0 {
+		return nil
+	}
+	return n
+}
+
+// FailureColumns are the columns of the errors table listing incomplete
+// hosts and vCenters that could not be collected.
+var FailureColumns = []Column[collector.Failure]{
+	{"vcenter", "vCenter", func(f collector.Failure) any { return f.VCenter }},
+	{"host", "Host", func(f collector.Failure) any { return f.Host }},
+	{"operation", "Operation", func(f collector.Failure) any { return f.Op }},
+	{"error", "Error", func(f collector.Failure) any { return f.Err.Error() }},
+}
+
+// MappingColumns are the columns of the anonymization mapping file.
+var MappingColumns = []Column[collector.MappingEntry]{
+	{"kind", "Kind", func(e collector.MappingEntry) any { return e.Kind }},
+	{"label", "Label", func(e collector.MappingEntry) any { return e.Label }},
+	{"vcenter", "vCenter", func(e collector.MappingEntry) any { return e.VCenter }},
+	{"name", "Name", func(e collector.MappingEntry) any { return e.Name }},
+}
+
+// HostTables returns the tables written for a host inventory: the hosts
+// themselves, with extra columns such as UtilizationColumns after the
+// default ones, followed by the per-cluster rollup.
+func HostTables(hosts []collector.Host, extra ...Column[collector.Host]) []*Table {
+	return []*Table{
+		NewTable("hosts", "Hosts", append(slices.Clone(HostColumns), extra...), hosts),
+		NewTable("clusters", "Clusters", ClusterColumns, collector.RollupClusters(hosts)),
+	}
+}
+
+// VMTables returns the tables written for a VM inventory.
+func VMTables(vms []collector.VM) []*Table {
+	return []*Table{NewTable("vms", "VMs", VMColumns, vms)}
+}
+
+// DatastoreTables returns the tables written for a datastore inventory:
+// the datastores themselves, followed by their VMFS versions per cluster.
+func DatastoreTables(datastores []collector.Datastore) []*Table {
+	return []*Table{
+		NewTable("datastores", "Datastores", DatastoreColumns, datastores),
+		NewTable("datastoreClusters", "Clusters", DatastoreClusterColumns, collector.RollupDatastoreClusters(datastores)),
+	}
+}
+
+// DIMMTables returns the tables written for a memory module inventory.
+func DIMMTables(dimms []collector.DIMM) []*Table {
+	return []*Table{NewTable("dimms", "Memory Modules", DIMMColumns, dimms)}
+}
+
+// VIBTables returns the tables written for an installed VIB inventory.
+func VIBTables(vibs []collector.VIB) []*Table {
+	return []*Table{NewTable("vibs", "VIBs", VIBColumns, vibs)}
+}
+
+// NICTables returns the tables written for a physical NIC inventory.
+func NICTables(nics []collector.PhysicalNIC) []*Table {
+	return []*Table{NewTable("nics", "Physical NICs", NICColumns, nics)}
+}
+
+// VMKernelAdapterTables returns the tables written for a VMkernel adapter
+// inventory.
+func VMKernelAdapterTables(adapters []collector.VMKernelAdapter) []*Table {
+	return []*Table{NewTable("vmknics", "VMkernel Adapters", VMKernelAdapterColumns, adapters)}
+}
+
+// ISCSIAdapterTables returns the tables written for an iSCSI configuration
+// report.
+func ISCSIAdapterTables(adapters []collector.ISCSIAdapter) []*Table {
+	return []*Table{NewTable("iscsi", "iSCSI Adapters", ISCSIAdapterColumns, adapters)}
+}
+
+// LocalDiskTables returns the tables written for a local storage inventory.
+func LocalDiskTables(disks []collector.LocalDisk) []*Table {
+	return []*Table{NewTable("localdisks", "Local Disks", LocalDiskColumns, disks)}
+}
+
+// DatastoreMountTables returns the tables written for a datastore mount
+// report.
+func DatastoreMountTables(mounts []collector.DatastoreMount) []*Table {
+	return []*Table{NewTable("mounts", "Datastore Mounts", DatastoreMountColumns, mounts)}
+}
+
+// MultipathLUNTables returns the tables written for a LUN path report.
+func MultipathLUNTables(luns []collector.MultipathLUN) []*Table {
+	return []*Table{NewTable("paths", "LUN Paths", MultipathLUNColumns, luns)}
+}
+
+// StoragePolicyTables returns the tables written for a storage policy
+// report.
+func StoragePolicyTables(policies []collector.StoragePolicy) []*Table {
+	return []*Table{NewTable("policies", "Storage Policies", StoragePolicyColumns, policies)}
+}
+
+// VVolTables returns the tables of the vvols command: the vVol storage
+// containers followed by the storage providers.
+func VVolTables(containers []collector.VVolContainer, providers []collector.VASAProvider) []*Table {
+	return []*Table{
+		NewTable("vvolContainers", "vVol Containers", VVolContainerColumns, containers),
+		NewTable("vasaProviders", "VASA Providers", VASAProviderColumns, providers),
+	}
+}
+
+// PCIDeviceTables returns the tables written for a GPU and PCI passthrough
+// inventory.
+func PCIDeviceTables(devices []collector.PCIDevice) []*Table {
+	return []*Table{NewTable("pci", "PCI Devices", PCIDeviceColumns, devices)}
+}
+
+// VGPUTables returns the tables written for a vGPU profile report.
+func VGPUTables(profiles []collector.VGPUProfile) []*Table {
+	return []*Table{NewTable("vgpu", "vGPU Profiles", VGPUColumns, profiles)}
+}
+
+// NTPDNSTables returns the tables written for an NTP and DNS audit.
+func NTPDNSTables(configs []collector.NTPDNSConfig) []*Table {
+	return []*Table{NewTable("ntpdns", "NTP and DNS", NTPDNSColumns, configs)}
+}
+
+// ServiceTables returns the tables written for a host services report.
+func ServiceTables(services []collector.HostService) []*Table {
+	return []*Table{NewTable("services", "Host Services", ServiceColumns, services)}
+}
+
+// FailureTable returns the errors table for failures.
+func FailureTable(failures []collector.Failure) *Table {
+	return NewTable("errors", "Errors", FailureColumns, failures)
+}
+
+// MappingTable returns the anonymization mapping table for entries.
+func MappingTable(entries []collector.MappingEntry) *Table {
+	return NewTable("mapping", "Mapping", MappingColumns, entries)
+}
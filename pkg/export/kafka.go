@@ -0,0 +1,129 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// KafkaPublisher publishes records as JSON messages to a Kafka topic
+// through an HTTP proxy speaking the Kafka REST Proxy v2 API, such as the
+// Confluent REST Proxy, Redpanda's HTTP Proxy, or the Strimzi Kafka Bridge.
+type KafkaPublisher struct {
+	url    string // topic endpoint
+	key    string // column whose value keys each message, empty for none
+	client *http.Client
+}
+
+// kafkaBatch is the number of records sent per request.
+const kafkaBatch = 500
+
+// NewKafkaPublisher returns a publisher to topic through the proxy at
+// proxyURL, which may carry basic auth credentials. Messages are keyed by
+// the column named key, so all messages about one host go to the same
+// partition.
+func NewKafkaPublisher(proxyURL, topic, key string) (*KafkaPublisher, error) {
+	u, err := url.Parse(proxyURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%q is not an http:// or https:// URL", proxyURL)
+	}
+	if topic == "" {
+		return nil, errors.New("no Kafka topic given")
+	}
+	u = u.JoinPath("topics", topic)
+	return &KafkaPublisher{url: u.String(), key: key, client: &http.Client{Timeout: time.Minute}}, nil
+}
+
+// Publish sends a message per row of t, with the row's columns and the
+// collection time as JSON value, and returns the number of messages the
+// proxy accepted.
+func (p *KafkaPublisher) Publish(ctx context.Context, t *Table, at time.Time) (int, error) {
+	keyCol := -1
+	if p.key != "" {
+		if keyCol = slices.Index(t.Keys, p.key); keyCol < 0 {
+			return 0, fmt.Errorf("no column %q to key Kafka messages by; choose one of %s", p.key, strings.Join(t.Keys, ", "))
+		}
+	}
+	type record struct {
+		Key   any     `json:"key"`
+		Value jsonRow `json:"value"`
+	}
+	sent := 0
+	for start := 0; start < len(t.Rows); start += kafkaBatch {
+		rows := t.Rows[start:min(start+kafkaBatch, len(t.Rows))]
+		records := make([]record, len(rows))
+		for i, row := range rows {
+			value := make(jsonRow, 0, len(row)+1)
+			for j, v := range row {
+				value = append(value, jsonField{t.Keys[j], v})
+			}
+			value = append(value, jsonField{"collectedAt", at.UTC().Format(time.RFC3339)})
+			records[i].Value = value
+			if keyCol >= 0 && row[keyCol] != "" {
+				records[i].Key = row[keyCol]
+			}
+		}
+		n, err := p.send(ctx, records)
+		sent += n
+		if err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+func (p *KafkaPublisher) send(ctx context.Context, records any) (int, error) {
+	body, err := json.Marshal(map[string]any{"records": records})
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json, application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		// Keep credentials in the URL out of the error
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return 0, fmt.Errorf("publishing to Kafka: %w", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode/100 != 2 {
+		return 0, fmt.Errorf("publishing to Kafka: %s: %s", resp.Status, strings.TrimSpace(string(data[:min(len(data), 512)])))
+	}
+	// The proxy reports a result per record; the bridge omits error fields
+	var result struct {
+		Offsets []struct {
+			Error *string `json:"error"`
+		}
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return 0, fmt.Errorf("publishing to Kafka: reading response: %w", err)
+	}
+	ok := 0
+	err = nil
+	for _, o := range result.Offsets {
+		if o.Error != nil && *o.Error != "" {
+			if err == nil {
+				err = fmt.Errorf("publishing to Kafka: %s", *o.Error)
+			}
+			continue
+		}
+		ok++
+	}
+	return ok, err
+}
@@ -0,0 +1,138 @@
+package export
+
+import (
+	"slices"
+
+	"vmware-inventory/pkg/collector"
+)
+
+// LicenseHostColumns are the columns of the licensing report's hosts.
+var LicenseHostColumns = []Column[collector.Host]{
+	{"vcenter", "vCenter", func(h collector.Host) any { return h.VCenter }},
+	{"hostname", "Hostname", func(h collector.Host) any { return h.Hostname }},
+	{"cluster", "Cluster", func(h collector.Host) any { return h.Cluster }},
+	{"socketCount", "Socket Count", func(h collector.Host) any { return h.Sockets }},
+	{"coresPerSocket", "Cores per Socket", func(h collector.Host) any { return h.CoresPerSocket }},
+	{"totalCores", "Total Cores", func(h collector.Host) any { return h.TotalCores }},
+	{"licenseCores", "License Cores", func(h collector.Host) any { return h.LicenseCores() }},
+	{"cpuLicenses", "CPU Licenses", func(h collector.Host) any { return h.CPULicenses() }},
+	{"vsanCapacityTiB", "vSAN Capacity TiB", func(h collector.Host) any { return h.VsanCapacityTiB }},
+}
+
+// LicenseClusterColumns are the columns of the licensing report's
+// per-cluster totals.
+var LicenseClusterColumns = []Column[collector.ClusterLicense]{
+	{"vcenter", "vCenter", func(c collector.ClusterLicense) any { return c.VCenter }},
+	{"cluster", "Cluster", func(c collector.ClusterLicense) any { return c.Cluster.Cluster }},
+	{"hosts", "Hosts", func(c collector.ClusterLicense) any { return c.Hosts }},
+	{"socketCount", "Socket Count", func(c collector.ClusterLicense) any { return c.Sockets }},
+	{"totalCores", "Total Cores", func(c collector.ClusterLicense) any { return c.TotalCores }},
+	{"licenseCores", "License Cores", func(c collector.ClusterLicense) any { return c.LicenseCores }},
+	{"cpuLicenses", "CPU Licenses", func(c collector.ClusterLicense) any { return c.CPULicenses }},
+	{"vsanCapacityTiB", "vSAN Capacity TiB", func(c collector.ClusterLicense) any { return c.VsanCapacityTiB }},
+	{"vsanIncludedTiB", "vSAN Included TiB", func(c collector.ClusterLicense) any { return c.VsanIncludedTiB }},
+	{"vsanAddOnTiB", "vSAN Add-on TiB", func(c collector.ClusterLicense) any { return c.VsanAddOnTiB }},
+}
+
+// LicenseTotalColumns are the columns of the licensing report's total for
+// the whole environment.
+var LicenseTotalColumns = []Column[collector.LicenseTotal]{
+	{"hosts", "Hosts", func(t collector.LicenseTotal) any { return t.Hosts }},
+	{"clusters", "Clusters", func(t collector.LicenseTotal) any { return t.Clusters }},
+	{"socketCount", "Socket Count", func(t collector.LicenseTotal) any { return t.Sockets }},
+	{"totalCores", "Total Cores", func(t collector.LicenseTotal) any { return t.TotalCores }},
+	{"licenseCores", "License Cores", func(t collector.LicenseTotal) any { return t.LicenseCores }},
+	{"cpuLicenses", "CPU Licenses", func(t collector.LicenseTotal) any { return t.CPULicenses }},
+	{"vsanCapacityTiB", "vSAN Capacity TiB", func(t collector.LicenseTotal) any { return t.VsanCapacityTiB }},
+	{"vsanIncludedTiB", "vSAN Included TiB", func(t collector.LicenseTotal) any { return t.VsanIncludedTiB }},
+	{"vsanAddOnTiB", "vSAN Add-on TiB", func(t collector.LicenseTotal) any { return t.VsanAddOnTiB }},
+}
+
+// EntitlementColumns are the columns of the licensing report's comparison
+// with the licenses owned.
+var EntitlementColumns = []Column[collector.EntitlementGap]{
+	{"product", "Product", func(g collector.EntitlementGap) any { return g.Name }},
+	{"unit", "Unit", func(g collector.EntitlementGap) any { return g.Unit }},
+	{"deployed", "Deployed", func(g collector.EntitlementGap) any { return g.Deployed }},
+	{"entitled", "Entitled", func(g collector.EntitlementGap) any { return g.Entitled }},
+	{"gap", "Gap", func(g collector.EntitlementGap) any { return g.Gap() }},
+	{"status", "Status", func(g collector.EntitlementGap) any {
+		if g.Shortfall() {
+			return "Shortfall"
+		}
+		return "OK"
+	}},
+}
+
+// LicenseOptions select the terms of the licensing report.
+type LicenseOptions struct {
+	Edition      collector.Edition
+	PerCPU       bool                    // also count licenses under the legacy per-CPU terms
+	Entitlements []collector.Entitlement // licenses owned, to compare with those needed
+}
+
+// LicenseTables returns the tables of the licensing report: the cores each
+// host needs licensed, followed by the totals per cluster and for the
+// whole environment, with the vSAN capacity beyond the edition's
+// entitlement. With Entitlements, the comparison with what is owned
+// follows.
+func LicenseTables(hosts []collector.Host, opts LicenseOptions) []*Table {
+	clusters := collector.LicenseClusters(collector.RollupClusters(hosts), opts.Edition)
+	total := collector.TotalLicenses(clusters)
+	tables := []*Table{
+		NewTable("licenseHosts", "Hosts", licenseColumns(LicenseHostColumns, opts), hosts),
+		NewTable("licenseClusters", "Clusters", licenseColumns(LicenseClusterColumns, opts), clusters),
+		NewTable("licenseTotal", "Total", licenseColumns(LicenseTotalColumns, opts), []collector.LicenseTotal{total}),
+	}
+	if len(opts.Entitlements) > 0 {
+		tables = append(tables, EntitlementTable(collector.CompareEntitlements(total, opts.Edition, opts.Entitlements)))
+	}
+	return tables
+}
+
+// EntitlementTable returns the comparison of the licenses needed with those
+// owned.
+func EntitlementTable(gaps []collector.EntitlementGap) *Table {
+	return NewTable("entitlements", "Entitlements", EntitlementColumns, gaps)
+}
+
+// licenseColumns returns cols without the CPU Licenses column unless
+// opts.PerCPU is set.
+func licenseColumns[T any](cols []Column[T], opts LicenseOptions) []Column[T] {
+	if opts.PerCPU {
+		return cols
+	}
+	return slices.DeleteFunc(slices.Clone(cols), func(c Column[T]) bool { return c.Key == "cpuLicenses" })
+}
+
+// LicenseKeyColumns are the columns of the licenses command's keys.
+var LicenseKeyColumns = []Column[collector.License]{
+	{"vcenter", "vCenter", func(l collector.License) any { return l.VCenter }},
+	{"licenseKey", "License Key", func(l collector.License) any { return l.Key }},
+	{"product", "Product", func(l collector.License) any { return l.Product }},
+	{"edition", "Edition", func(l collector.License) any { return l.Edition }},
+	{"costUnit", "Cost Unit", func(l collector.License) any { return l.CostUnit }},
+	{"capacity", "Capacity", func(l collector.License) any { return l.Total }},
+	{"used", "Used", func(l collector.License) any { return l.Used }},
+	{"expires", "Expires", func(l collector.License) any { return l.Expires }},
+}
+
+// LicenseAssignmentColumns are the columns of the licenses command's
+// assignments.
+var LicenseAssignmentColumns = []Column[collector.LicenseAssignment]{
+	{"vcenter", "vCenter", func(a collector.LicenseAssignment) any { return a.VCenter }},
+	{"entityType", "Entity Type", func(a collector.LicenseAssignment) any { return a.EntityType }},
+	{"entity", "Entity", func(a collector.LicenseAssignment) any { return a.Entity }},
+	{"licenseKey", "License Key", func(a collector.LicenseAssignment) any { return a.Key }},
+	{"product", "Product", func(a collector.LicenseAssignment) any { return a.Product }},
+	{"edition", "Edition", func(a collector.LicenseAssignment) any { return a.Edition }},
+}
+
+// LicenseKeyTables returns the tables of the licenses command: the
+// installed keys followed by what each is assigned to.
+func LicenseKeyTables(licenses []collector.License, assignments []collector.LicenseAssignment) []*Table {
+	return []*Table{
+		NewTable("licenses", "Licenses", LicenseKeyColumns, licenses),
+		NewTable("licenseAssignments", "Assignments", LicenseAssignmentColumns, assignments),
+	}
+}
@@ -0,0 +1,146 @@
+package export
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Mail is a report email sent by SendMail.
+type Mail struct {
+	Server      string // host:port; port 465 uses implicit TLS, others STARTTLS when offered
+	User        string // SMTP AUTH user, empty to send without authentication
+	Password    string
+	From        string
+	To          []string
+	Subject     string
+	Body        string   // plain text
+	Attachments []string // file paths
+}
+
+// SendMail sends m, attaching its files.
+func SendMail(m Mail) error {
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender %q: %w", m.From, err)
+	}
+	var to []string
+	for _, addr := range m.To {
+		a, err := mail.ParseAddress(addr)
+		if err != nil {
+			return fmt.Errorf("invalid recipient %q: %w", addr, err)
+		}
+		to = append(to, a.Address)
+	}
+	msg, err := m.message()
+	if err != nil {
+		return err
+	}
+
+	host, port, err := net.SplitHostPort(m.Server)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP server %q: %w", m.Server, err)
+	}
+	var c *smtp.Client
+	if port == "465" {
+		conn, err := tls.Dial("tcp", m.Server, &tls.Config{ServerName: host})
+		if err != nil {
+			return err
+		}
+		c, err = smtp.NewClient(conn, host)
+		if err != nil {
+			conn.Close()
+			return err
+		}
+	} else if c, err = smtp.Dial(m.Server); err != nil {
+		return err
+	}
+	defer c.Close()
+	if port != "465" {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+				return err
+			}
+		}
+	}
+	if m.User != "" {
+		// PlainAuth refuses to send the password unencrypted except to localhost
+		if err := c.Auth(smtp.PlainAuth("", m.User, m.Password, host)); err != nil {
+			return fmt.Errorf("SMTP authentication: %w", err)
+		}
+	}
+	if err := c.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := c.Rcpt(addr); err != nil {
+			return fmt.Errorf("recipient %s: %w", addr, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// message renders m as a MIME message: the body as text/plain followed by
+// each attachment, base64-encoded.
+func (m Mail) message() ([]byte, error) {
+	var boundary [12]byte
+	rand.Read(boundary[:])
+	b := "inventory-" + hex.EncodeToString(boundary[:])
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", m.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(m.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%q\r\n\r\n", b)
+
+	fmt.Fprintf(&buf, "--%s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n", b)
+	buf.WriteString(strings.ReplaceAll(m.Body, "\n", "\r\n"))
+	buf.WriteString("\r\n")
+
+	for _, path := range m.Attachments {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		name := filepath.Base(path)
+		ctype := mime.TypeByExtension(filepath.Ext(name))
+		if ctype == "" {
+			ctype = "application/octet-stream"
+		}
+		fmt.Fprintf(&buf, "--%s\r\n", b)
+		fmt.Fprintf(&buf, "Content-Type: %s\r\n", ctype)
+		fmt.Fprintf(&buf, "Content-Disposition: %s\r\n", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+		buf.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+		enc := base64.StdEncoding.EncodeToString(data)
+		for len(enc) > 76 {
+			buf.WriteString(enc[:76] + "\r\n")
+			enc = enc[76:]
+		}
+		buf.WriteString(enc + "\r\n")
+	}
+	fmt.Fprintf(&buf, "--%s--\r\n", b)
+	return buf.Bytes(), nil
+}
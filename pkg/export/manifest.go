@@ -0,0 +1,130 @@
+package export
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SignatureNamespace is the namespace manifests are signed under; pass it to
+// ssh-keygen -Y verify -n.
+const SignatureNamespace = "vmware-inventory"
+
+// WriteManifest writes the SHA-256 of each file to path in the format of
+// sha256sum, naming files relative to the manifest's directory so
+// "sha256sum -c" can check them there.
+func WriteManifest(path string, files []string) error {
+	var buf bytes.Buffer
+	for _, file := range files {
+		sum, err := fileSHA256(file)
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(filepath.Dir(path), file)
+		if err != nil {
+			name = file
+		}
+		fmt.Fprintf(&buf, "%s  %s\n", sum, filepath.ToSlash(name))
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ParseSigningKey reads the unencrypted SSH private key in keyFile for
+// SignFile.
+func ParseSigningKey(keyFile string) (ssh.Signer, error) {
+	pem, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := ssh.ParsePrivateKey(pem)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		return nil, fmt.Errorf("%s is passphrase-protected; use a key without a passphrase", keyFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", keyFile, err)
+	}
+	return signer, nil
+}
+
+// SignFile writes a detached signature of path to path.sig in the SSHSIG
+// format of ssh-keygen -Y sign, so it can be checked with:
+//
+//	ssh-keygen -Y verify -f allowed_signers -I <identity> -n vmware-inventory -s <path>.sig < <path>
+//
+// It returns the signature path.
+func SignFile(path string, signer ssh.Signer) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sig, err := sshSign(signer, data)
+	if err != nil {
+		return "", err
+	}
+	sigPath := path + ".sig"
+	return sigPath, os.WriteFile(sigPath, sig, 0o644)
+}
+
+// sshSign returns an armored SSHSIG signature of data, as described in
+// OpenSSH's PROTOCOL.sshsig.
+func sshSign(signer ssh.Signer, data []byte) ([]byte, error) {
+	const hashAlg = "sha512"
+	digest := sha512.Sum512(data)
+	signed := append([]byte("SSHSIG"), ssh.Marshal(struct {
+		Namespace, Reserved, HashAlg string
+		Hash                         []byte
+	}{SignatureNamespace, "", hashAlg, digest[:]})...)
+
+	var sig *ssh.Signature
+	var err error
+	if as, ok := signer.(ssh.AlgorithmSigner); ok && signer.PublicKey().Type() == ssh.KeyAlgoRSA {
+		// ssh-keygen rejects SHA-1 RSA signatures
+		sig, err = as.SignWithAlgorithm(rand.Reader, signed, ssh.KeyAlgoRSASHA512)
+	} else {
+		sig, err = signer.Sign(rand.Reader, signed)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("signing: %w", err)
+	}
+
+	blob := append([]byte("SSHSIG"), ssh.Marshal(struct {
+		Version                      uint32
+		PublicKey                    []byte
+		Namespace, Reserved, HashAlg string
+		Signature                    []byte
+	}{1, signer.PublicKey().Marshal(), SignatureNamespace, "", hashAlg, ssh.Marshal(sig)})...)
+
+	var out bytes.Buffer
+	out.WriteString("-----BEGIN SSH SIGNATURE-----\n")
+	enc := base64.StdEncoding.EncodeToString(blob)
+	for len(enc) > 70 {
+		out.WriteString(enc[:70] + "\n")
+		enc = enc[70:]
+	}
+	out.WriteString(enc + "\n-----END SSH SIGNATURE-----\n")
+	return out.Bytes(), nil
+}
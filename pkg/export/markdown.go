@@ -0,0 +1,42 @@
+package export
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// writeMarkdown writes the primary table as a GitHub-flavored Markdown
+// table, right-aligning numeric columns.
+func writeMarkdown(w io.Writer, r *Report) error {
+	t := r.Tables[0]
+	bw := bufio.NewWriter(w)
+
+	writeMarkdownRow(bw, t.Headers)
+
+	align := make([]string, len(t.Headers))
+	for i := range align {
+		align[i] = "---"
+		if len(t.Rows) > 0 && isNumeric(t.Rows[0][i]) {
+			align[i] = "--:"
+		}
+	}
+	writeMarkdownRow(bw, align)
+
+	for _, row := range t.Rows {
+		cells := make([]string, len(row))
+		for i, v := range row {
+			cells[i] = markdownEscaper.Replace(FormatValue(v))
+		}
+		writeMarkdownRow(bw, cells)
+	}
+	return bw.Flush()
+}
+
+var markdownEscaper = strings.NewReplacer("|", `\|`, "\r\n", " ", "\n", " ")
+
+func writeMarkdownRow(w *bufio.Writer, cells []string) {
+	w.WriteString("| ")
+	w.WriteString(strings.Join(cells, " | "))
+	w.WriteString(" |\n")
+}
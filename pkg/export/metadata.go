@@ -0,0 +1,80 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"vmware-inventory/pkg/collector"
+)
+
+// RunMetadata records when, with what, and against what a report was
+// collected, for auditors.
+type RunMetadata struct {
+	Command     string // hosts, vms, or datastores
+	Status      string // complete, partial (some failures), or interrupted
+	CollectedAt time.Time
+	Generator   string
+	VCenters    []collector.VCenterInfo
+	Filters     RunFilters
+}
+
+// RunFilters are the settings that limited or altered what a run
+// collected.
+type RunFilters struct {
+	Clusters         []string // path.Match patterns
+	Datacenter       string
+	Tags             []string // Category:Value
+	SkipDisconnected bool
+	SkipMaintenance  bool
+	Columns          []string
+	Units            Units
+	Anonymized       bool
+	RedactedIPs      bool
+}
+
+// doc returns the JSON document of m.
+func (m *RunMetadata) doc() jsonRow {
+	vcenters := make([]jsonRow, len(m.VCenters))
+	for i, vc := range m.VCenters {
+		vcenters[i] = jsonRow{
+			{"name", vc.Name},
+			{"product", vc.Product},
+			{"version", vc.Version},
+			{"build", vc.Build},
+			{"apiVersion", vc.APIVersion},
+		}
+	}
+	f := m.Filters
+	filters := jsonRow{
+		{"clusters", nonNil(f.Clusters)},
+		{"datacenter", f.Datacenter},
+		{"tags", nonNil(f.Tags)},
+		{"skipDisconnected", f.SkipDisconnected},
+		{"skipMaintenance", f.SkipMaintenance},
+		{"columns", nonNil(f.Columns)},
+		{"units", f.Units.System},
+	}
+	if f.Units.Precision >= 0 {
+		filters = append(filters, jsonField{"precision", f.Units.Precision})
+	}
+	filters = append(filters,
+		jsonField{"anonymized", f.Anonymized},
+		jsonField{"redactedIPs", f.RedactedIPs},
+	)
+	return jsonRow{
+		{"command", m.Command},
+		{"status", m.Status},
+		{"collectedAt", m.CollectedAt.UTC().Format(time.RFC3339)},
+		{"generator", m.Generator},
+		{"vcenters", vcenters},
+		{"filters", filters},
+	}
+}
+
+// WriteJSON writes m as an indented JSON object, for a run.json sidecar.
+func (m *RunMetadata) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m.doc())
+}
@@ -0,0 +1,47 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// writeNDJSON writes the primary table as newline-delimited JSON: one
+// object per row, keyed by column Key, with no enclosing document.
+func writeNDJSON(w io.Writer, r *Report) error {
+	return NewNDJSONStream(w, nil).Write(r.Tables[0])
+}
+
+// NDJSONStream writes rows as newline-delimited JSON as they are
+// collected, so a consumer such as jq sees each record without waiting for
+// the run to finish.
+type NDJSONStream struct {
+	mu      sync.Mutex
+	enc     *json.Encoder
+	columns []string // as for Table.Select, nil for all
+}
+
+// NewNDJSONStream returns a stream writing to w, limited to columns if not
+// empty.
+func NewNDJSONStream(w io.Writer, columns []string) *NDJSONStream {
+	return &NDJSONStream{enc: json.NewEncoder(w), columns: columns}
+}
+
+// Write writes a line per row of t. It may be called from several
+// goroutines.
+func (s *NDJSONStream) Write(t *Table) error {
+	if len(s.columns) > 0 {
+		var err error
+		if t, err = t.Select(s.columns); err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, row := range tableRows(t) {
+		if err := s.enc.Encode(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
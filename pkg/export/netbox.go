@@ -0,0 +1,317 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"vmware-inventory/pkg/collector"
+)
+
+// NetBox creates and updates records in a NetBox instance (version 4 or
+// later) through its REST API: hosts as DCIM devices, and clusters and VMs
+// as virtualization objects, so both come from the same collection.
+type NetBox struct {
+	url    string // base URL, without /api
+	token  string
+	site   string // name or slug of the site devices are placed in
+	role   string // device role of hosts, created if missing
+	client *http.Client
+
+	siteID int
+	ids    map[string]int // lookup cache: path + query -> id
+}
+
+// SyncStats counts the NetBox objects written by a sync.
+type SyncStats struct {
+	Created int
+	Updated int
+}
+
+// NewNetBox returns a NetBox client for the instance at baseURL, with an API
+// token. Devices are placed in site, which must exist, with the given role.
+func NewNetBox(baseURL, token, site, role string) *NetBox {
+	return &NetBox{
+		url:    strings.TrimSuffix(strings.TrimSuffix(baseURL, "/"), "/api"),
+		token:  token,
+		site:   site,
+		role:   role,
+		client: &http.Client{Timeout: time.Minute},
+		ids:    make(map[string]int),
+	}
+}
+
+// clusterType is the NetBox cluster type of vSphere clusters.
+const clusterType = "VMware vSphere"
+
+// SyncHosts creates or updates a device for each host, found by name within
+// the site, along with its manufacturer, device type, and role. A host in a
+// cluster is assigned to a NetBox cluster of the same name, in a cluster
+// group named after its vCenter since cluster names are only unique within
+// one vCenter.
+func (nb *NetBox) SyncHosts(ctx context.Context, hosts []collector.Host) (SyncStats, error) {
+	var st SyncStats
+	siteID, err := nb.siteRef(ctx)
+	if err != nil {
+		return st, err
+	}
+	roleID, err := nb.ensure(ctx, "/api/dcim/device-roles/", url.Values{"slug": {slugify(nb.role)}},
+		map[string]any{"name": nb.role, "slug": slugify(nb.role), "vm_role": true})
+	if err != nil {
+		return st, err
+	}
+	for _, h := range hosts {
+		typeID, err := nb.deviceType(ctx, h.Vendor, h.ServerModel)
+		if err != nil {
+			return st, fmt.Errorf("%s: %w", h.Hostname, err)
+		}
+		var clusterID any
+		if h.Cluster != "" {
+			id, err := nb.cluster(ctx, h.VCenter, h.Cluster)
+			if err != nil {
+				return st, fmt.Errorf("%s: %w", h.Hostname, err)
+			}
+			clusterID = id
+		}
+		status := "active"
+		if h.ConnectionState != "" && h.ConnectionState != "connected" {
+			status = "offline"
+		}
+		device := map[string]any{
+			"name":        h.Hostname,
+			"device_type": typeID,
+			"role":        roleID,
+			"site":        siteID,
+			"serial":      h.SerialNumber,
+			"cluster":     clusterID,
+			"status":      status,
+		}
+		created, err := nb.upsert(ctx, "/api/dcim/devices/", url.Values{"name": {h.Hostname}, "site_id": {strconv.Itoa(siteID)}}, device)
+		if err != nil {
+			return st, fmt.Errorf("%s: %w", h.Hostname, err)
+		}
+		st.count(created)
+	}
+	return st, nil
+}
+
+// SyncVMs creates or updates a virtual machine for each VM, found by name
+// within its cluster, and links it to the device of its host when SyncHosts
+// has created one.
+func (nb *NetBox) SyncVMs(ctx context.Context, vms []collector.VM) (SyncStats, error) {
+	var st SyncStats
+	siteID, err := nb.siteRef(ctx)
+	if err != nil {
+		return st, err
+	}
+	devices := make(map[string]int) // host name -> device id, 0 if none
+	for _, v := range vms {
+		query := url.Values{"name": {v.Name}, "site_id": {strconv.Itoa(siteID)}}
+		vm := map[string]any{
+			"name":   v.Name,
+			"site":   siteID,
+			"vcpus":  v.VCPUs,
+			"memory": int(v.MemoryGB * 1024), // MB
+			"status": "active",
+		}
+		if v.PowerState != "poweredOn" {
+			vm["status"] = "offline"
+		}
+		if v.Cluster != "" {
+			id, err := nb.cluster(ctx, v.VCenter, v.Cluster)
+			if err != nil {
+				return st, fmt.Errorf("%s: %w", v.Name, err)
+			}
+			vm["cluster"] = id
+			query.Set("cluster_id", strconv.Itoa(id))
+		}
+		if v.Host != "" {
+			id, ok := devices[v.Host]
+			if !ok {
+				if id, _, err = nb.find(ctx, "/api/dcim/devices/", url.Values{"name": {v.Host}, "site_id": {strconv.Itoa(siteID)}}); err != nil {
+					return st, fmt.Errorf("%s: %w", v.Name, err)
+				}
+				devices[v.Host] = id
+			}
+			if id != 0 {
+				vm["device"] = id
+			}
+		}
+		created, err := nb.upsert(ctx, "/api/virtualization/virtual-machines/", query, vm)
+		if err != nil {
+			return st, fmt.Errorf("%s: %w", v.Name, err)
+		}
+		st.count(created)
+	}
+	return st, nil
+}
+
+func (st *SyncStats) count(created bool) {
+	if created {
+		st.Created++
+	} else {
+		st.Updated++
+	}
+}
+
+// siteRef returns the id of the configured site, looked up by slug and then
+// by name.
+func (nb *NetBox) siteRef(ctx context.Context) (int, error) {
+	if nb.siteID != 0 {
+		return nb.siteID, nil
+	}
+	for _, q := range []url.Values{{"slug": {nb.site}}, {"name": {nb.site}}} {
+		id, ok, err := nb.find(ctx, "/api/dcim/sites/", q)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			nb.siteID = id
+			return id, nil
+		}
+	}
+	return 0, fmt.Errorf("NetBox site %q not found", nb.site)
+}
+
+func (nb *NetBox) deviceType(ctx context.Context, vendor, model string) (int, error) {
+	if vendor == "" {
+		vendor = "Unknown"
+	}
+	if model == "" {
+		model = "Unknown"
+	}
+	mfrID, err := nb.ensure(ctx, "/api/dcim/manufacturers/", url.Values{"slug": {slugify(vendor)}},
+		map[string]any{"name": vendor, "slug": slugify(vendor)})
+	if err != nil {
+		return 0, err
+	}
+	return nb.ensure(ctx, "/api/dcim/device-types/", url.Values{"manufacturer_id": {strconv.Itoa(mfrID)}, "slug": {slugify(model)}},
+		map[string]any{"manufacturer": mfrID, "model": model, "slug": slugify(model)})
+}
+
+func (nb *NetBox) cluster(ctx context.Context, vcenter, name string) (int, error) {
+	typeID, err := nb.ensure(ctx, "/api/virtualization/cluster-types/", url.Values{"slug": {slugify(clusterType)}},
+		map[string]any{"name": clusterType, "slug": slugify(clusterType)})
+	if err != nil {
+		return 0, err
+	}
+	groupID, err := nb.ensure(ctx, "/api/virtualization/cluster-groups/", url.Values{"slug": {slugify(vcenter)}},
+		map[string]any{"name": vcenter, "slug": slugify(vcenter)})
+	if err != nil {
+		return 0, err
+	}
+	return nb.ensure(ctx, "/api/virtualization/clusters/", url.Values{"name": {name}, "group_id": {strconv.Itoa(groupID)}},
+		map[string]any{"name": name, "type": typeID, "group": groupID, "status": "active"})
+}
+
+// ensure returns the id of the object at path matching query, creating it
+// from create if there is none.
+func (nb *NetBox) ensure(ctx context.Context, path string, query url.Values, create map[string]any) (int, error) {
+	key := path + "?" + query.Encode()
+	if id, ok := nb.ids[key]; ok {
+		return id, nil
+	}
+	id, ok, err := nb.find(ctx, path, query)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		var obj struct{ ID int }
+		if err := nb.do(ctx, http.MethodPost, path, create, &obj); err != nil {
+			return 0, err
+		}
+		id = obj.ID
+	}
+	nb.ids[key] = id
+	return id, nil
+}
+
+// upsert updates the object at path matching query with fields, or creates
+// it, and reports whether it was created.
+func (nb *NetBox) upsert(ctx context.Context, path string, query url.Values, fields map[string]any) (bool, error) {
+	id, ok, err := nb.find(ctx, path, query)
+	if err != nil {
+		return false, err
+	}
+	if ok {
+		return false, nb.do(ctx, http.MethodPatch, path+strconv.Itoa(id)+"/", fields, nil)
+	}
+	return true, nb.do(ctx, http.MethodPost, path, fields, nil)
+}
+
+// find returns the id of the first object at path matching query.
+func (nb *NetBox) find(ctx context.Context, path string, query url.Values) (int, bool, error) {
+	query.Set("brief", "true")
+	var page struct {
+		Results []struct{ ID int }
+	}
+	err := nb.do(ctx, http.MethodGet, path+"?"+query.Encode(), nil, &page)
+	query.Del("brief")
+	if err != nil || len(page.Results) == 0 {
+		return 0, false, err
+	}
+	return page.Results[0].ID, true, nil
+}
+
+// do sends a request to the API and decodes the response into out, if not
+// nil.
+func (nb *NetBox) do(ctx context.Context, method, path string, body, out any) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, nb.url+path, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Token "+nb.token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := nb.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("NetBox %s %s: %s: %s", method, strings.SplitN(path, "?", 2)[0], resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// slugify returns a NetBox slug for name: lower case letters, digits, and
+// hyphens, at most 100 characters.
+func slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	s := strings.TrimSuffix(b.String(), "-")
+	if len(s) > 100 {
+		s = strings.TrimSuffix(s[:100], "-")
+	}
+	return s
+}
@@ -0,0 +1,353 @@
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// writeParquet writes the primary table as an Apache Parquet file: one row
+// group with a column chunk per column, each a single gzip-compressed data
+// page of PLAIN values. Columns are typed from their values (integers as
+// INT64, fractions as DOUBLE, everything else as UTF-8 strings) and are
+// optional, since a value may be missing. The format is written directly
+// rather than through a Parquet library, whose dependencies would far
+// outweigh the small subset a flat table needs.
+func writeParquet(w io.Writer, r *Report) error {
+	t := r.Tables[0]
+	cw := &countingWriter{w: w}
+	cw.Write([]byte("PAR1"))
+
+	schema := []parquetSchemaElement{{name: "schema", numChildren: len(t.Keys)}}
+	var chunks []parquetColumnChunk
+	var total int64
+	for i, key := range t.Keys {
+		col := make([]any, len(t.Rows))
+		for j, row := range t.Rows {
+			col[j] = row[i]
+		}
+		typ := parquetType(col)
+		schema = append(schema, parquetSchemaElement{name: key, typ: typ})
+		if len(t.Rows) == 0 {
+			continue
+		}
+		page, raw, err := parquetPage(typ, col)
+		if err != nil {
+			return err
+		}
+		offset := cw.n
+		cw.Write(page)
+		chunks = append(chunks, parquetColumnChunk{
+			name:         key,
+			typ:          typ,
+			offset:       offset,
+			values:       int64(len(col)),
+			uncompressed: int64(raw),
+			compressed:   int64(len(page)),
+		})
+		total += int64(raw)
+	}
+	if cw.err != nil {
+		return cw.err
+	}
+
+	var meta thriftWriter
+	meta.i32(1, 1) // version
+	meta.listBegin(2, thriftStruct, len(schema))
+	for _, s := range schema {
+		meta.structBegin()
+		if s.numChildren == 0 {
+			meta.i32(1, int32(s.typ))
+			meta.i32(3, 1) // OPTIONAL
+		}
+		meta.binary(4, s.name)
+		if s.numChildren > 0 {
+			meta.i32(5, int32(s.numChildren))
+		}
+		if s.typ == parquetByteArray {
+			meta.i32(6, 0) // converted type UTF8
+			meta.fieldBegin(10, thriftStruct)
+			meta.structBegin()
+			meta.fieldBegin(1, thriftStruct) // logical type STRING
+			meta.structBegin()
+			meta.structEnd()
+			meta.structEnd()
+		}
+		meta.structEnd()
+	}
+	meta.i64(3, int64(len(t.Rows)))
+	rowGroups := 0
+	if len(t.Rows) > 0 {
+		rowGroups = 1
+	}
+	meta.listBegin(4, thriftStruct, rowGroups)
+	if rowGroups > 0 {
+		meta.structBegin()
+		meta.listBegin(1, thriftStruct, len(chunks))
+		for _, c := range chunks {
+			meta.structBegin()
+			meta.i64(2, c.offset)
+			meta.fieldBegin(3, thriftStruct)
+			meta.structBegin()
+			meta.i32(1, int32(c.typ))
+			meta.listBegin(2, thriftI32, 2)
+			meta.zigzag(0) // PLAIN
+			meta.zigzag(3) // RLE, of definition levels
+			meta.listBegin(3, thriftBinary, 1)
+			meta.rawBinary(c.name)
+			meta.i32(4, 2) // GZIP
+			meta.i64(5, c.values)
+			meta.i64(6, c.uncompressed)
+			meta.i64(7, c.compressed)
+			meta.i64(9, c.offset)
+			meta.structEnd()
+			meta.structEnd()
+		}
+		meta.i64(2, total)
+		meta.i64(3, int64(len(t.Rows)))
+		meta.structEnd()
+	}
+	if r.Generator != "" {
+		meta.binary(6, r.Generator)
+	}
+	meta.stop()
+
+	cw.Write(meta.buf.Bytes())
+	binary.Write(cw, binary.LittleEndian, uint32(meta.buf.Len()))
+	cw.Write([]byte("PAR1"))
+	return cw.err
+}
+
+// Parquet physical types
+const (
+	parquetBoolean   = 0
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+)
+
+type parquetSchemaElement struct {
+	name        string
+	typ         int
+	numChildren int // of the root, which has no type
+}
+
+type parquetColumnChunk struct {
+	name                     string
+	typ                      int
+	offset                   int64
+	values                   int64
+	uncompressed, compressed int64
+}
+
+// parquetType returns the physical type for a column's values: the type
+// all non-nil values share, or BYTE_ARRAY if they differ or are not
+// numbers or booleans.
+func parquetType(col []any) int {
+	typ := -1
+	for _, v := range col {
+		var t int
+		switch v.(type) {
+		case nil:
+			continue
+		case int, int64:
+			t = parquetInt64
+		case float64, Fixed:
+			t = parquetDouble
+		case bool:
+			t = parquetBoolean
+		default:
+			t = parquetByteArray
+		}
+		if typ >= 0 && typ != t {
+			return parquetByteArray
+		}
+		typ = t
+	}
+	if typ < 0 {
+		return parquetByteArray
+	}
+	return typ
+}
+
+// parquetPage returns a data page holding col, with its header, and the
+// size of the header and uncompressed data.
+func parquetPage(typ int, col []any) ([]byte, int, error) {
+	var data bytes.Buffer
+
+	// Definition levels, 1 for a value and 0 for a null, as RLE runs
+	// prefixed by their length
+	var levels bytes.Buffer
+	for i := 0; i < len(col); {
+		level := byte(0)
+		if col[i] != nil {
+			level = 1
+		}
+		run := 1
+		for i+run < len(col) && (col[i+run] != nil) == (level == 1) {
+			run++
+		}
+		levels.Write(binary.AppendUvarint(nil, uint64(run)<<1))
+		levels.WriteByte(level)
+		i += run
+	}
+	binary.Write(&data, binary.LittleEndian, uint32(levels.Len()))
+	data.Write(levels.Bytes())
+
+	var bits, nbits byte
+	for _, v := range col {
+		if v == nil {
+			continue
+		}
+		switch typ {
+		case parquetInt64:
+			var n int64
+			switch v := v.(type) {
+			case int:
+				n = int64(v)
+			case int64:
+				n = v
+			}
+			binary.Write(&data, binary.LittleEndian, n)
+		case parquetDouble:
+			f, ok := v.(float64)
+			if !ok {
+				f = v.(Fixed).Number
+			}
+			binary.Write(&data, binary.LittleEndian, math.Float64bits(f))
+		case parquetBoolean:
+			if v.(bool) {
+				bits |= 1 << nbits
+			}
+			if nbits++; nbits == 8 {
+				data.WriteByte(bits)
+				bits, nbits = 0, 0
+			}
+		default:
+			s := FormatValue(v)
+			binary.Write(&data, binary.LittleEndian, uint32(len(s)))
+			data.WriteString(s)
+		}
+	}
+	if nbits > 0 {
+		data.WriteByte(bits)
+	}
+
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write(data.Bytes())
+	if err := zw.Close(); err != nil {
+		return nil, 0, err
+	}
+
+	var h thriftWriter
+	h.i32(1, 0) // DATA_PAGE
+	h.i32(2, int32(data.Len()))
+	h.i32(3, int32(compressed.Len()))
+	h.fieldBegin(5, thriftStruct)
+	h.structBegin()
+	h.i32(1, int32(len(col)))
+	h.i32(2, 0) // PLAIN
+	h.i32(3, 3) // RLE
+	h.i32(4, 3) // RLE
+	h.structEnd()
+	h.stop()
+	header := h.buf.Len()
+	h.buf.Write(compressed.Bytes())
+	return h.buf.Bytes(), header + data.Len(), nil
+}
+
+// Thrift compact protocol type codes
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes the Thrift compact protocol, enough of it for
+// Parquet's page headers and file metadata.
+type thriftWriter struct {
+	buf  bytes.Buffer
+	last []int16 // last field id of each open struct
+	id   int16
+}
+
+func (t *thriftWriter) varint(v uint64) { t.buf.Write(binary.AppendUvarint(nil, v)) }
+
+func (t *thriftWriter) zigzag(v int64) { t.varint(uint64(v<<1) ^ uint64(v>>63)) }
+
+func (t *thriftWriter) fieldBegin(id int16, typ byte) {
+	if delta := id - t.id; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.zigzag(int64(id))
+	}
+	t.id = id
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.fieldBegin(id, thriftI32)
+	t.zigzag(int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.fieldBegin(id, thriftI64)
+	t.zigzag(v)
+}
+
+func (t *thriftWriter) binary(id int16, s string) {
+	t.fieldBegin(id, thriftBinary)
+	t.rawBinary(s)
+}
+
+func (t *thriftWriter) rawBinary(s string) {
+	t.varint(uint64(len(s)))
+	t.buf.WriteString(s)
+}
+
+// listBegin starts a list field of n elements of type elem, which follow
+// as raw values or structs.
+func (t *thriftWriter) listBegin(id int16, elem byte, n int) {
+	t.fieldBegin(id, thriftList)
+	if n < 15 {
+		t.buf.WriteByte(byte(n)<<4 | elem)
+	} else {
+		t.buf.WriteByte(0xf0 | elem)
+		t.varint(uint64(n))
+	}
+}
+
+func (t *thriftWriter) structBegin() {
+	t.last = append(t.last, t.id)
+	t.id = 0
+}
+
+func (t *thriftWriter) structEnd() {
+	t.stop()
+	t.id = t.last[len(t.last)-1]
+	t.last = t.last[:len(t.last)-1]
+}
+
+func (t *thriftWriter) stop() { t.buf.WriteByte(0) }
+
+// countingWriter counts the bytes written to w and keeps the first error.
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
@@ -0,0 +1,69 @@
+package export
+
+import (
+	"math"
+	"time"
+
+	"vmware-inventory/pkg/collector"
+)
+
+// perfCounterColumns are the key and header of each counter's column in the
+// perf report, by PerfCounters key.
+var perfCounterColumns = map[string]struct{ key, header string }{
+	"cpu.usage":  {"cpuUsagePct", "CPU Usage %"},
+	"mem.usage":  {"memoryUsagePct", "Memory Usage %"},
+	"disk.usage": {"diskUsageKBps", "Disk Usage KBps"},
+}
+
+// PerfHostColumns returns the columns of the perf report's hosts, with a
+// column per counter of counters.
+func PerfHostColumns(counters []string) []Column[collector.PerfSample] {
+	cols := []Column[collector.PerfSample]{
+		{"vcenter", "vCenter", func(s collector.PerfSample) any { return s.VCenter }},
+		{"hostname", "Hostname", func(s collector.PerfSample) any { return s.Host }},
+		{"cluster", "Cluster", func(s collector.PerfSample) any { return s.Cluster }},
+		{"timestamp", "Timestamp", func(s collector.PerfSample) any { return s.Time.UTC().Format(time.RFC3339) }},
+	}
+	return append(cols, perfValueColumns(counters)...)
+}
+
+// PerfClusterColumns returns the columns of the perf report's clusters,
+// with a column per counter of counters.
+func PerfClusterColumns(counters []string) []Column[collector.PerfSample] {
+	cols := []Column[collector.PerfSample]{
+		{"vcenter", "vCenter", func(s collector.PerfSample) any { return s.VCenter }},
+		{"cluster", "Cluster", func(s collector.PerfSample) any { return s.Cluster }},
+		{"hosts", "Hosts", func(s collector.PerfSample) any { return s.Hosts }},
+		{"timestamp", "Timestamp", func(s collector.PerfSample) any { return s.Time.UTC().Format(time.RFC3339) }},
+	}
+	return append(cols, perfValueColumns(counters)...)
+}
+
+// perfValueColumns returns a column per counter, empty where a sample has
+// no value for it.
+func perfValueColumns(counters []string) []Column[collector.PerfSample] {
+	cols := make([]Column[collector.PerfSample], len(counters))
+	for i, name := range counters {
+		c := perfCounterColumns[name]
+		cols[i] = Column[collector.PerfSample]{c.key, c.header, func(s collector.PerfSample) any {
+			v, ok := s.Values[name]
+			if !ok {
+				return nil
+			}
+			if collector.PerfCounters[name].Unit == "%" {
+				return pct(v)
+			}
+			return int64(math.Round(v))
+		}}
+	}
+	return cols
+}
+
+// PerfTables returns the tables of the perf report: the samples of each
+// host followed by those combined per cluster.
+func PerfTables(samples []collector.PerfSample, counters []string) []*Table {
+	return []*Table{
+		NewTable("perfHosts", "Hosts", PerfHostColumns(counters), samples),
+		NewTable("perfClusters", "Clusters", PerfClusterColumns(counters), collector.PerfClusters(samples)),
+	}
+}
@@ -0,0 +1,178 @@
+package export
+
+import "vmware-inventory/pkg/collector"
+
+// The rvtools format writes a workbook laid out like an RVTools 4.x export,
+// so spreadsheets and macros that look up sheets by name and columns by
+// position keep working. Every RVTools column is present in its usual
+// place; those this tool does not collect are left empty. As in RVTools,
+// memory and capacity are in MiB.
+
+var rvtoolsHostHeaders = []string{
+	"Host", "Datacenter", "Cluster", "Config status", "in Maintenance Mode", "in Quarantine Mode",
+	"vSAN Fault Domain Name", "CPU Model", "Speed", "HT Available", "HT Active", "# CPU",
+	"Cores per CPU", "# Cores", "CPU usage %", "# Memory", "Memory usage %", "Console", "# NICs",
+	"# HBAs", "# VMs total", "# VMs", "VMs per Core", "# vCPUs", "vCPUs per Core", "vRAM",
+	"VM Used memory", "VM Memory Swapped", "VM Memory Ballooned", "VMotion support",
+	"Storage VMotion support", "Current EVC", "Max EVC", "Assigned License(s)", "ATS Heartbeat",
+	"ATS Locking", "Current CPU power man. policy", "Supported CPU power man.", "Host Power Policy",
+	"ESX Version", "Boot time", "DNS Servers", "DHCP", "Domain", "Domain List", "DNS Search Order",
+	"NTP Server(s)", "NTPD running", "Time Zone", "Time Zone Name", "GMT Offset", "Vendor", "Model",
+	"Serial number", "Service tag", "OEM specific string", "BIOS Vendor", "BIOS Version", "BIOS Date",
+	"Certificate Issuer", "Certificate Start Date", "Certificate Expiry Date", "Certificate Status",
+	"Certificate Subject", "Object ID", "AutoDeploy.MachineIdentity", "UUID", "VI SDK Server",
+	"VI SDK UUID",
+}
+
+var rvtoolsHostValues = map[string]func(h collector.Host) any{
+	"Host":                    func(h collector.Host) any { return h.Hostname },
+	"Cluster":                 func(h collector.Host) any { return h.Cluster },
+	"CPU Model":               func(h collector.Host) any { return h.CPUModel },
+	"# CPU":                   func(h collector.Host) any { return h.Sockets },
+	"Cores per CPU":           func(h collector.Host) any { return h.CoresPerSocket },
+	"# Cores":                 func(h collector.Host) any { return h.TotalCores },
+	"# Memory":                func(h collector.Host) any { return h.MemoryGB * 1024 },
+	"# NICs":                  func(h collector.Host) any { return h.NICs },
+	"ESX Version":             func(h collector.Host) any { return h.ESXiVersion },
+	"Vendor":                  func(h collector.Host) any { return h.Vendor },
+	"Model":                   func(h collector.Host) any { return h.ServerModel },
+	"Serial number":           func(h collector.Host) any { return h.SerialNumber },
+	"UUID":                    func(h collector.Host) any { return h.BIOSUUID },
+	"VI SDK Server":           func(h collector.Host) any { return h.VCenter },
+	"Certificate Expiry Date": func(h collector.Host) any { return certificateExpiry(h) },
+	"Certificate Status":      func(h collector.Host) any { return h.CertificateStatus },
+	"Host Power Policy":       func(h collector.Host) any { return h.PowerPolicy },
+	"in Maintenance Mode": func(h collector.Host) any {
+		return rvtoolsBool(h.ConnectionState, h.InMaintenanceMode)
+	},
+	"HT Available": func(h collector.Host) any {
+		return rvtoolsBool(h.Hyperthreading, h.Hyperthreading != "unavailable")
+	},
+	"HT Active": func(h collector.Host) any {
+		return rvtoolsBool(h.Hyperthreading, h.Hyperthreading == "active")
+	},
+}
+
+// rvtoolsBool returns b as RVTools writes it, True or False, or nil if
+// status, collected along with b, is empty because neither was.
+func rvtoolsBool(status string, b bool) any {
+	switch {
+	case status == "":
+		return nil
+	case b:
+		return "True"
+	}
+	return "False"
+}
+
+var rvtoolsClusterHeaders = []string{
+	"Name", "Config status", "OverallStatus", "NumHosts", "numEffectiveHosts", "TotalCpu",
+	"NumCpuCores", "NumCpuThreads", "Effective Cpu", "TotalMemory", "Effective Memory",
+	"Num VMotions", "HA enabled", "Failover Level", "AdmissionControlEnabled", "Host monitoring",
+	"HB Datastore Candidate Policy", "Isolation Response", "Restart Priority", "Cluster Settings",
+	"Max Failures", "Max Failure Window", "Failure Interval", "Min Up Time", "VM Monitoring",
+	"DRS enabled", "DRS default VM behavior", "DRS vmotion rate", "DPM enabled",
+	"DPM default behavior", "DPM Host Power Action Frequency", "Object ID", "VI SDK Server",
+	"VI SDK UUID",
+}
+
+var rvtoolsClusterValues = map[string]func(c collector.Cluster) any{
+	"Name":          func(c collector.Cluster) any { return c.Cluster },
+	"NumHosts":      func(c collector.Cluster) any { return c.Hosts },
+	"NumCpuCores":   func(c collector.Cluster) any { return c.TotalCores },
+	"TotalMemory":   func(c collector.Cluster) any { return c.MemoryGB * 1024 },
+	"VI SDK Server": func(c collector.Cluster) any { return c.VCenter },
+}
+
+var rvtoolsVMHeaders = []string{
+	"VM", "Powerstate", "Template", "SRM Placeholder", "Config status", "DNS Name",
+	"Connection state", "Guest state", "Heartbeat", "Consolidation Needed", "PowerOn",
+	"Suspended To Memory", "Suspend time", "Suspend Interval", "Creation date", "Change Version",
+	"CPUs", "Overall Cpu Readiness", "Memory", "Active Memory", "NICs", "Disks",
+	"Total disk capacity MiB", "Fixed Passthru HotPlug", "min Required EVC Mode Key",
+	"Latency Sensitivity", "Op Notification Timeout", "EnableUUID", "CBT", "Primary IP Address",
+	"Network #1", "Network #2", "Network #3", "Network #4", "Network #5", "Network #6",
+	"Network #7", "Network #8", "Num Monitors", "Video Ram KiB", "Resource pool", "Folder ID",
+	"Folder", "vApp", "DAS protection", "FT State", "FT Role", "FT Latency", "FT Bandwidth",
+	"FT Secondary Latency", "Vm Failover In Progress", "Provisioned MiB", "In Use MiB",
+	"Unshared MiB", "HA Restart Priority", "HA Isolation Response", "HA VM Monitoring",
+	"Cluster rule(s)", "Cluster rule name(s)", "Boot Required", "Boot delay", "Boot retry delay",
+	"Boot retry enabled", "Boot BIOS setup", "Reboot PowerOff", "EFI Secure boot", "Firmware",
+	"HW version", "HW upgrade status", "HW upgrade policy", "HW target", "Path", "Log directory",
+	"Snapshot directory", "Suspend directory", "Annotation", "Datacenter", "Cluster", "Host",
+	"OS according to the configuration file", "OS according to the VMware Tools",
+	"Customization Info", "Guest Detailed Data", "VM ID", "SMBIOS UUID", "VM UUID",
+	"VI SDK Server type", "VI SDK API Version", "VI SDK Server", "VI SDK UUID",
+}
+
+var rvtoolsVMValues = map[string]func(v collector.VM) any{
+	"VM":                                     func(v collector.VM) any { return v.Name },
+	"Powerstate":                             func(v collector.VM) any { return v.PowerState },
+	"Template":                               func(v collector.VM) any { return "False" }, // templates are skipped
+	"CPUs":                                   func(v collector.VM) any { return v.VCPUs },
+	"Memory":                                 func(v collector.VM) any { return int64(v.MemoryGB * 1024) },
+	"Cluster":                                func(v collector.VM) any { return v.Cluster },
+	"Host":                                   func(v collector.VM) any { return v.Host },
+	"OS according to the configuration file": func(v collector.VM) any { return v.GuestOS },
+	"VI SDK Server":                          func(v collector.VM) any { return v.VCenter },
+}
+
+var rvtoolsDatastoreHeaders = []string{
+	"Name", "Config status", "Address", "Accessible", "Type", "# VMs total", "# VMs",
+	"Capacity MiB", "Provisioned MiB", "In Use MiB", "Free MiB", "Free %", "SIOC enabled",
+	"SIOC Threshold", "# Hosts", "Hosts", "Cluster name", "Cluster capacity MiB",
+	"Cluster free space MiB", "Block size", "Max Blocks", "# Extents", "Major Version", "Version",
+	"VMFS Upgradeable", "MHA", "URL", "Object ID", "VI SDK Server", "VI SDK UUID",
+}
+
+var rvtoolsDatastoreValues = map[string]func(d collector.Datastore) any{
+	"Name":         func(d collector.Datastore) any { return d.Name },
+	"Type":         func(d collector.Datastore) any { return d.Type },
+	"Capacity MiB": func(d collector.Datastore) any { return int64(d.CapacityGB * 1024) },
+	"In Use MiB":   func(d collector.Datastore) any { return int64((d.CapacityGB - d.FreeGB) * 1024) },
+	"Free MiB":     func(d collector.Datastore) any { return int64(d.FreeGB * 1024) },
+	"Free %": func(d collector.Datastore) any {
+		if d.CapacityGB == 0 {
+			return nil
+		}
+		return int(d.FreeGB / d.CapacityGB * 100)
+	},
+	"# Hosts":       func(d collector.Datastore) any { return d.Hosts },
+	"Major Version": func(d collector.Datastore) any { return nonZero(d.VMFSMajor) },
+	"Version":       func(d collector.Datastore) any { return d.VMFSVersion },
+	"VI SDK Server": func(d collector.Datastore) any { return d.VCenter },
+}
+
+// rvtoolsColumns returns a column per RVTools header, taking values from
+// the matching entry of values and leaving the others empty.
+func rvtoolsColumns[T any](headers []string, values map[string]func(T) any) []Column[T] {
+	cols := make([]Column[T], len(headers))
+	for i, h := range headers {
+		value, ok := values[h]
+		if !ok {
+			value = func(T) any { return nil }
+		}
+		cols[i] = Column[T]{Key: h, Header: h, Value: value}
+	}
+	return cols
+}
+
+// RVToolsHostTables returns the vHost and vCluster sheets of an
+// RVTools-style workbook for hosts.
+func RVToolsHostTables(hosts []collector.Host) []*Table {
+	return []*Table{
+		NewTable("vHost", "vHost", rvtoolsColumns(rvtoolsHostHeaders, rvtoolsHostValues), hosts),
+		NewTable("vCluster", "vCluster", rvtoolsColumns(rvtoolsClusterHeaders, rvtoolsClusterValues), collector.RollupClusters(hosts)),
+	}
+}
+
+// RVToolsVMTables returns the vInfo sheet of an RVTools-style workbook.
+func RVToolsVMTables(vms []collector.VM) []*Table {
+	return []*Table{NewTable("vInfo", "vInfo", rvtoolsColumns(rvtoolsVMHeaders, rvtoolsVMValues), vms)}
+}
+
+// RVToolsDatastoreTables returns the vDatastore sheet of an RVTools-style
+// workbook.
+func RVToolsDatastoreTables(datastores []collector.Datastore) []*Table {
+	return []*Table{NewTable("vDatastore", "vDatastore", rvtoolsColumns(rvtoolsDatastoreHeaders, rvtoolsDatastoreValues), datastores)}
+}
@@ -0,0 +1,77 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// S3Uploader uploads output files to a bucket and key prefix, as given by
+// -upload s3://bucket/prefix/.
+type S3Uploader struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+// NewS3Uploader parses an s3://bucket/prefix/ URL and loads credentials
+// from the AWS SDK default chain: environment variables, the shared config
+// and credentials files, SSO, and instance or container roles. A non-empty
+// endpoint selects an S3-compatible store such as MinIO, addressed with
+// path-style URLs.
+func NewS3Uploader(ctx context.Context, url, endpoint string) (*S3Uploader, error) {
+	rest, ok := strings.CutPrefix(url, "s3://")
+	if !ok {
+		return nil, fmt.Errorf("%q is not an s3://bucket/prefix/ URL", url)
+	}
+	bucket, prefix, _ := strings.Cut(rest, "/")
+	if bucket == "" {
+		return nil, fmt.Errorf("%q names no bucket", url)
+	}
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading AWS configuration: %w", err)
+	}
+	if cfg.Region == "" {
+		// S3-compatible stores mostly ignore the region; on AWS set
+		// AWS_REGION or a profile region to the bucket's
+		cfg.Region = "us-east-1"
+	}
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+			o.UsePathStyle = true
+		}
+	})
+	return &S3Uploader{client: client, bucket: bucket, prefix: prefix}, nil
+}
+
+// Upload stores the file at path under the prefix with its base name and
+// returns the object's s3:// URL.
+func (u *S3Uploader) Upload(ctx context.Context, path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	key := u.prefix
+	if key != "" && !strings.HasSuffix(key, "/") {
+		key += "/"
+	}
+	key += filepath.Base(path)
+	_, err = u.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(u.bucket),
+		Key:    aws.String(key),
+		Body:   f,
+	})
+	if err != nil {
+		return "", fmt.Errorf("uploading %s: %w", path, err)
+	}
+	return "s3://" + u.bucket + "/" + key, nil
+}
@@ -0,0 +1,199 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+
+	"vmware-inventory/pkg/collector"
+)
+
+// SchemaVersion is the version of the layout of the json format, written
+// as its schemaVersion. It is incremented when a key is removed or renamed
+// or a value changes type; keys may be added within a version, so
+// consumers should ignore keys they do not know.
+const SchemaVersion = 1
+
+// optionalKeys are the tables and columns only written with a flag, such as
+// -per-cpu, -entitlements, -quickstats, or -utilization, so not required.
+var optionalKeys = map[string]bool{
+	"cpuLicenses": true, "entitlements": true,
+	"cpuUsageMHz": true, "memoryUsageGB": true,
+	"cpuAvgPct": true, "cpuPeakPct": true, "memoryAvgPct": true, "memoryPeakPct": true,
+}
+
+// nullableKeys are the columns that are null where a record has no value,
+// such as a host without performance samples.
+var nullableKeys = map[string]bool{
+	"cpuUsageMHz": true, "memoryUsageGB": true, "uptimeDays": true,
+	"cpuAvgPct": true, "cpuPeakPct": true, "memoryAvgPct": true, "memoryPeakPct": true,
+	"cpuUsagePct": true, "memoryUsagePct": true, "diskUsageKBps": true,
+	"sizeGB": true, "speedMTs": true, "sshRunning": true, "esxiShellRunning": true,
+	"linkSpeedMb": true, "secureBoot": true, "ntpRunning": true,
+	"powerPolicyAsExpected": true, "virtualFunctions": true, "activeVirtualFunctions": true,
+	"maxVirtualFunctions": true, "ftt": true, "encryption": true,
+}
+
+// schemaTables returns the tables of command with a single record of zero
+// values, from which each column's JSON type is taken.
+func schemaTables(command string) ([]*Table, error) {
+	switch command {
+	case "hosts":
+		host := collector.Host{ConnectionState: "connected", SSHRunning: new(bool), ESXiShellRunning: new(bool), SecureBoot: new(bool), Utilization: &collector.Utilization{}}
+		return HostTables([]collector.Host{host}, append(slices.Clone(QuickStatsColumns), UtilizationColumns...)...), nil
+	case "vms":
+		return VMTables([]collector.VM{{}}), nil
+	case "datastores":
+		return DatastoreTables([]collector.Datastore{{}}), nil
+	case "licensing":
+		opts := LicenseOptions{Edition: collector.Editions["vcf"], PerCPU: true, Entitlements: []collector.Entitlement{{Product: "vcf"}}}
+		return LicenseTables([]collector.Host{{}}, opts), nil
+	case "licenses":
+		return LicenseKeyTables([]collector.License{{}}, []collector.LicenseAssignment{{}}), nil
+	case "consolidation":
+		return ConsolidationTables([]collector.Host{{}}, nil), nil
+	case "headroom":
+		return HeadroomTables([]collector.Host{{}}, nil, collector.Targets{}), nil
+	case "perf":
+		sample := collector.PerfSample{Values: make(map[string]float64)}
+		for _, name := range collector.PerfCounterOrder {
+			sample.Values[name] = 0
+		}
+		return PerfTables([]collector.PerfSample{sample}, collector.PerfCounterOrder), nil
+	case "dimms":
+		return DIMMTables([]collector.DIMM{{SizeGB: 1, SpeedMTs: 1}}), nil
+	case "vibs":
+		return VIBTables([]collector.VIB{{}}), nil
+	case "nics":
+		return NICTables([]collector.PhysicalNIC{{LinkSpeedMb: 1, SRIOVCapable: true}}), nil
+	case "pci":
+		return PCIDeviceTables([]collector.PCIDevice{{}}), nil
+	case "vgpu":
+		return VGPUTables([]collector.VGPUProfile{{}}), nil
+	case "ntpdns":
+		return NTPDNSTables([]collector.NTPDNSConfig{{NTPRunning: new(bool)}}), nil
+	case "services":
+		return ServiceTables([]collector.HostService{{}}), nil
+	case "vmknics":
+		return VMKernelAdapterTables([]collector.VMKernelAdapter{{}}), nil
+	case "iscsi":
+		return ISCSIAdapterTables([]collector.ISCSIAdapter{{}}), nil
+	case "localdisks":
+		return LocalDiskTables([]collector.LocalDisk{{}}), nil
+	case "mounts":
+		return DatastoreMountTables([]collector.DatastoreMount{{}}), nil
+	case "paths":
+		return MultipathLUNTables([]collector.MultipathLUN{{}}), nil
+	case "policies":
+		return StoragePolicyTables([]collector.StoragePolicy{{FTT: new(int), Encryption: new(bool)}}), nil
+	case "vvols":
+		return VVolTables([]collector.VVolContainer{{}}, []collector.VASAProvider{{}}), nil
+	}
+	return nil, fmt.Errorf("no schema for command %q; choose hosts, vms, datastores, licensing, licenses, consolidation, headroom, perf, dimms, vibs, nics, pci, vgpu, ntpdns, services, vmknics, iscsi, localdisks, mounts, paths, policies, or vvols", command)
+}
+
+// WriteSchema writes the JSON Schema of the json format's output for
+// command: hosts, vms, datastores, licensing, licenses, consolidation,
+// headroom, perf, dimms, vibs, nics, pci, vgpu, ntpdns, services, vmknics,
+// iscsi, localdisks, mounts, paths, policies, or vvols. It describes the
+// default columns and units; -columns and -units change them.
+func WriteSchema(w io.Writer, command string) error {
+	tables, err := schemaTables(command)
+	if err != nil {
+		return err
+	}
+	props := jsonRow{
+		{"schemaVersion", jsonRow{{"const", SchemaVersion}}},
+		{"run", runSchema()},
+	}
+	required := []string{"schemaVersion"}
+	for _, t := range tables {
+		props = append(props, jsonField{t.Name, tableSchema(t)})
+		if !optionalKeys[t.Name] {
+			required = append(required, t.Name)
+		}
+	}
+	doc := jsonRow{
+		{"$schema", "https://json-schema.org/draft/2020-12/schema"},
+		{"title", fmt.Sprintf("vmware-inventory %s output, schema version %d", command, SchemaVersion)},
+		{"type", "object"},
+		{"properties", props},
+		{"required", required},
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// tableSchema returns the schema of the rows of t, typed from its first
+// row.
+func tableSchema(t *Table) jsonRow {
+	props := make(jsonRow, len(t.Keys))
+	var required []string
+	for i, key := range t.Keys {
+		if !optionalKeys[key] {
+			required = append(required, key)
+		}
+		prop := jsonRow{{"title", t.Headers[i]}}
+		if typ := schemaType(t.Rows[0][i]); typ != "" && nullableKeys[key] {
+			prop = append(prop, jsonField{"type", []string{typ, "null"}})
+		} else if typ != "" {
+			prop = append(prop, jsonField{"type", typ})
+		}
+		props[i] = jsonField{key, prop}
+	}
+	return jsonRow{
+		{"title", t.Title},
+		{"type", "array"},
+		{"items", jsonRow{
+			{"type", "object"},
+			{"properties", props},
+			{"required", required},
+		}},
+	}
+}
+
+// schemaType returns the JSON Schema type of a column value, or "" if it
+// cannot be told from v.
+func schemaType(v any) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case int, int64:
+		return "integer"
+	case float64, Fixed:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return ""
+}
+
+// runSchema returns the schema of the run metadata written by -metadata,
+// taken from an empty RunMetadata, whose filters include precision.
+func runSchema() jsonRow {
+	m := &RunMetadata{VCenters: []collector.VCenterInfo{{}}}
+	return valueSchema(m.doc()).(jsonRow)
+}
+
+// valueSchema returns the schema of a value built of jsonRows, slices,
+// and scalars, allowing anything it does not recognize.
+func valueSchema(v any) any {
+	switch v := v.(type) {
+	case jsonRow:
+		props := make(jsonRow, len(v))
+		for i, f := range v {
+			props[i] = jsonField{f.key, valueSchema(f.value)}
+		}
+		return jsonRow{{"type", "object"}, {"properties", props}}
+	case []jsonRow:
+		return jsonRow{{"type", "array"}, {"items", valueSchema(v[0])}}
+	case []string:
+		return jsonRow{{"type", "array"}, {"items", jsonRow{{"type", "string"}}}}
+	}
+	if typ := schemaType(v); typ != "" {
+		return jsonRow{{"type", typ}}
+	}
+	return jsonRow{}
+}
@@ -0,0 +1,133 @@
+package export
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SyslogSender sends one syslog event per record to a collector, as RFC
+// 5424 messages whose text is either key="value" pairs or a CEF event.
+// log/syslog is not used because it is unavailable on Windows and speaks
+// only the older BSD format.
+type SyslogSender struct {
+	network string // udp, tcp, or tls
+	addr    string
+	format  string // kv or cef
+	version string // product version for the CEF header
+}
+
+// syslogTimeout bounds connecting and sending all events.
+const syslogTimeout = time.Minute
+
+// NewSyslogSender parses a collector address, udp://host[:port],
+// tcp://host[:port], or tls://host[:port] (default ports 514, 514, and
+// 6514), and checks format, kv or cef. version is the tool version named
+// in CEF headers.
+func NewSyslogSender(target, format, version string) (*SyslogSender, error) {
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("%q is not a udp://, tcp://, or tls:// address", target)
+	}
+	port := "514"
+	switch u.Scheme {
+	case "udp", "tcp":
+	case "tls":
+		port = "6514"
+	default:
+		return nil, fmt.Errorf("unsupported syslog transport %q; use udp, tcp, or tls", u.Scheme)
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	if format != "kv" && format != "cef" {
+		return nil, fmt.Errorf("unknown syslog format %q; use kv or cef", format)
+	}
+	return &SyslogSender{network: u.Scheme, addr: net.JoinHostPort(u.Hostname(), port), format: format, version: version}, nil
+}
+
+// Send sends an event for each row of t, timestamped at, and returns the
+// number sent. Over TCP and TLS messages are framed by octet counting (RFC
+// 6587); over UDP each is a datagram.
+func (s *SyslogSender) Send(t *Table, at time.Time) (int, error) {
+	deadline := time.Now().Add(syslogTimeout)
+	dialer := &net.Dialer{Deadline: deadline}
+	var conn net.Conn
+	var err error
+	if s.network == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", s.addr, nil)
+	} else {
+		conn, err = dialer.Dial(s.network, s.addr)
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(deadline)
+
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+	// <134> is facility local0, severity informational
+	header := fmt.Sprintf("<134>1 %s %s vmware-inventory %d %s - ", at.UTC().Format(time.RFC3339Nano), hostname, os.Getpid(), t.Name)
+	for i, row := range t.Rows {
+		msg := header
+		if s.format == "cef" {
+			msg += s.cef(t, row, at)
+		} else {
+			msg += keyValues(t, row)
+		}
+		if s.network != "udp" {
+			msg = strconv.Itoa(len(msg)) + " " + msg
+		}
+		if _, err := conn.Write([]byte(msg)); err != nil {
+			return i, err
+		}
+	}
+	return len(t.Rows), nil
+}
+
+// keyValues renders row as space-separated key="value" pairs, leaving out
+// empty values and quoting only strings.
+func keyValues(t *Table, row []any) string {
+	var b bytes.Buffer
+	for i, v := range row {
+		if v == nil || v == "" {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(t.Keys[i] + "=")
+		if str, ok := v.(string); ok {
+			b.WriteString(strconv.Quote(str))
+		} else {
+			b.WriteString(FormatValue(v))
+		}
+	}
+	return b.String()
+}
+
+// cef renders row as an ArcSight Common Event Format event with a custom
+// extension field per column.
+func (s *SyslogSender) cef(t *Table, row []any, at time.Time) string {
+	header := strings.NewReplacer(`\`, `\\`, `|`, `\|`)
+	value := strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\n", `\n`, "\r", `\r`)
+	var b strings.Builder
+	fmt.Fprintf(&b, "CEF:0|Carahsoft|VMware Inventory|%s|inventory-%s|%s inventory|1|rt=%d",
+		header.Replace(s.version), header.Replace(t.Name), header.Replace(t.Title), at.UnixMilli())
+	for i, v := range row {
+		if v == nil || v == "" {
+			continue
+		}
+		b.WriteString(" " + t.Keys[i] + "=" + value.Replace(FormatValue(v)))
+	}
+	return b.String()
+}
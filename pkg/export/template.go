@@ -0,0 +1,81 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// templateFuncs are available to -format template templates in addition to
+// the text/template builtins.
+var templateFuncs = template.FuncMap{
+	"format": FormatValue,
+	"join":   strings.Join,
+	"lower":  strings.ToLower,
+	"upper":  strings.ToUpper,
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"sum": func(rows []map[string]any, key string) float64 {
+		var total float64
+		for _, row := range rows {
+			switch v := row[key].(type) {
+			case int:
+				total += float64(v)
+			case int64:
+				total += float64(v)
+			case float64:
+				total += v
+			case Fixed:
+				total += v.Number
+			}
+		}
+		return total
+	},
+}
+
+// ParseTemplate reads a text/template file for the template format.
+func ParseTemplate(path string) (*template.Template, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return template.New(filepath.Base(path)).Funcs(templateFuncs).Parse(string(text))
+}
+
+// templateData returns what a template receives: the run's metadata and,
+// under each table's name, its rows as maps keyed by column Key, so a
+// template can range over .hosts and use {{.hostname}} in each row.
+func templateData(r *Report) map[string]any {
+	data := map[string]any{
+		"CollectedAt": r.CollectedAt.UTC().Format(time.RFC3339),
+		"Generator":   r.Generator,
+		"Tables":      r.Tables,
+	}
+	for _, t := range r.Tables {
+		rows := make([]map[string]any, len(t.Rows))
+		for i, tr := range t.Rows {
+			row := make(map[string]any, len(tr))
+			for j, v := range tr {
+				row[t.Keys[j]] = v
+			}
+			rows[i] = row
+		}
+		data[t.Name] = rows
+	}
+	return data
+}
+
+// writeTemplate executes r.Template with the data of r.
+func writeTemplate(w io.Writer, r *Report) error {
+	if r.Template == nil {
+		return fmt.Errorf("the template format needs a template file")
+	}
+	return r.Template.Execute(w, templateData(r))
+}
@@ -0,0 +1,117 @@
+package export
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Units selects how capacity columns are written. Capacities are collected
+// in binary units, with columns named by their key suffix: GB columns hold
+// GiB, as vCenter reports memory, and TiB columns hold TiB, as vSAN is
+// licensed.
+type Units struct {
+	System    string // binary (GiB, TiB), decimal (GB, TB), or "" to keep the default names
+	Precision int    // decimal places of capacities, or -1 to keep the values as collected
+}
+
+// ParseUnits checks a -units system name.
+func ParseUnits(system string) error {
+	switch system {
+	case "", "binary", "decimal":
+		return nil
+	}
+	return fmt.Errorf("unknown units %q; choose binary or decimal", system)
+}
+
+// unitSuffixes maps the key suffix of a capacity column to its names in
+// each system and its size in the decimal unit.
+var unitSuffixes = []struct {
+	suffix, binary, decimal string
+	factor                  float64
+}{
+	{"GB", "GiB", "GB", 1 << 30 / 1e9},
+	{"TiB", "TiB", "TB", 1 << 40 / 1e12},
+}
+
+// WithUnits returns a copy of t with its capacity columns renamed and
+// converted to u. Other columns are unchanged.
+func (t *Table) WithUnits(u Units) *Table {
+	s := &Table{
+		Name:    t.Name,
+		Title:   t.Title,
+		Keys:    append([]string(nil), t.Keys...),
+		Headers: append([]string(nil), t.Headers...),
+	}
+	factor := make([]float64, len(t.Keys))
+	for i, key := range t.Keys {
+		for _, us := range unitSuffixes {
+			if !strings.HasSuffix(key, us.suffix) || !strings.HasSuffix(t.Headers[i], " "+us.suffix) {
+				continue
+			}
+			factor[i] = 1
+			name := us.suffix
+			switch u.System {
+			case "binary":
+				name = us.binary
+			case "decimal":
+				name = us.decimal
+				factor[i] = us.factor
+			}
+			s.Keys[i] = strings.TrimSuffix(key, us.suffix) + name
+			s.Headers[i] = strings.TrimSuffix(t.Headers[i], us.suffix) + name
+			break
+		}
+	}
+	for _, tr := range t.Rows {
+		row := append([]any(nil), tr...)
+		for i, f := range factor {
+			if f != 0 {
+				row[i] = convertUnit(row[i], f, u.Precision)
+			}
+		}
+		s.Rows = append(s.Rows, row)
+	}
+	return s
+}
+
+// convertUnit multiplies a capacity by factor and rounds it to precision
+// decimal places if precision is not negative.
+func convertUnit(v any, factor float64, precision int) any {
+	var n float64
+	switch v := v.(type) {
+	case int:
+		n = float64(v)
+	case int64:
+		n = float64(v)
+	case float64:
+		n = v
+	default:
+		return v
+	}
+	if precision < 0 {
+		if factor == 1 {
+			return v
+		}
+		return n * factor
+	}
+	scale := math.Pow10(precision)
+	return Fixed{Number: math.Round(n*factor*scale) / scale, Decimals: precision}
+}
+
+// Fixed is a number written with a fixed number of decimal places, such as
+// a capacity rounded by -precision.
+type Fixed struct {
+	Number   float64
+	Decimals int
+}
+
+func (f Fixed) String() string { return strconv.FormatFloat(f.Number, 'f', f.Decimals, 64) }
+
+// MarshalJSON writes f as a number with its decimal places.
+func (f Fixed) MarshalJSON() ([]byte, error) { return []byte(f.String()), nil }
+
+// Value stores f in a database as a number.
+func (f Fixed) Value() (driver.Value, error) { return f.Number, nil }
@@ -0,0 +1,131 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fact is a named value in a run summary, such as Cores: 4800.
+type Fact struct {
+	Key   string // JSON key, e.g. totalCores
+	Name  string // heading for people, e.g. Cores
+	Value any    // a number or string
+}
+
+// Text returns the value as shown to people.
+func (f Fact) Text() string {
+	if v, ok := f.Value.(float64); ok {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(f.Value)
+}
+
+// Notification is a run summary posted to a chat webhook by PostWebhook.
+type Notification struct {
+	Title string
+	Facts []Fact
+	Notes []string // further lines, e.g. failures or uploaded files
+}
+
+// webhookTimeout bounds each webhook request.
+const webhookTimeout = 30 * time.Second
+
+// PostWebhook posts n to a Slack or Microsoft Teams incoming webhook. The
+// payload is chosen from the URL: Teams webhooks (webhook.office.com, or
+// Power Automate workflows) get an Adaptive Card, and all others the Slack
+// format, which Mattermost and Rocket.Chat accept too.
+func PostWebhook(ctx context.Context, webhook string, n Notification) error {
+	u, err := url.Parse(webhook)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid webhook URL")
+	}
+	var payload any
+	if isTeams(u.Hostname()) {
+		payload = teamsPayload(n)
+	} else {
+		payload = slackPayload(n)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// The URL is a credential; keep it out of the error
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return fmt.Errorf("posting to %s: %w", u.Host, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("posting to %s: %s: %s", u.Host, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+func isTeams(host string) bool {
+	for _, suffix := range []string{".office.com", ".logic.azure.com", ".powerplatform.com"} {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func slackPayload(n Notification) any {
+	var b strings.Builder
+	b.WriteString("*" + n.Title + "*\n")
+	for _, f := range n.Facts {
+		b.WriteString(f.Name + ": *" + f.Text() + "*\n")
+	}
+	for _, l := range n.Notes {
+		b.WriteString(l + "\n")
+	}
+	return map[string]string{"text": strings.TrimSuffix(b.String(), "\n")}
+}
+
+func teamsPayload(n Notification) any {
+	facts := make([]map[string]string, len(n.Facts))
+	for i, f := range n.Facts {
+		facts[i] = map[string]string{"title": f.Name, "value": f.Text()}
+	}
+	body := []any{
+		map[string]any{"type": "TextBlock", "text": n.Title, "weight": "Bolder", "size": "Medium", "wrap": true},
+		map[string]any{"type": "FactSet", "facts": facts},
+	}
+	for _, l := range n.Notes {
+		body = append(body, map[string]any{"type": "TextBlock", "text": l, "wrap": true})
+	}
+	return map[string]any{
+		"type": "message",
+		"attachments": []any{map[string]any{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content": map[string]any{
+				"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+				"type":    "AdaptiveCard",
+				"version": "1.4",
+				"body":    body,
+			},
+		}},
+	}
+}
@@ -0,0 +1,89 @@
+package export
+
+import (
+	"io"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// writeXLSX writes each table to its own worksheet, with a bold, frozen,
+// filterable header row and numeric values stored as numbers.
+func writeXLSX(w io.Writer, r *Report) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	header, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return err
+	}
+
+	for i, t := range r.Tables {
+		sheet := t.Title
+		if i == 0 {
+			if err := f.SetSheetName("Sheet1", sheet); err != nil {
+				return err
+			}
+		} else if _, err := f.NewSheet(sheet); err != nil {
+			return err
+		}
+
+		if err := f.SetSheetRow(sheet, "A1", &t.Headers); err != nil {
+			return err
+		}
+		for r, row := range t.Rows {
+			row = xlsxRow(row)
+			cell, _ := excelize.CoordinatesToCellName(1, r+2)
+			if err := f.SetSheetRow(sheet, cell, &row); err != nil {
+				return err
+			}
+		}
+
+		last, _ := excelize.ColumnNumberToName(len(t.Headers))
+		if err := f.SetCellStyle(sheet, "A1", last+"1", header); err != nil {
+			return err
+		}
+		if err := f.SetColWidth(sheet, "A", last, 18); err != nil {
+			return err
+		}
+		if err := f.SetPanes(sheet, &excelize.Panes{
+			Freeze:      true,
+			YSplit:      1,
+			TopLeftCell: "A2",
+			ActivePane:  "bottomLeft",
+		}); err != nil {
+			return err
+		}
+		lastCell, _ := excelize.CoordinatesToCellName(len(t.Headers), len(t.Rows)+1)
+		if err := f.AutoFilter(sheet, "A1:"+lastCell, nil); err != nil {
+			return err
+		}
+	}
+
+	props := &excelize.DocProperties{Title: "VMware Inventory", Creator: r.Generator}
+	if !r.CollectedAt.IsZero() {
+		props.Created = r.CollectedAt.UTC().Format(time.RFC3339)
+	}
+	if err := f.SetDocProps(props); err != nil {
+		return err
+	}
+	return f.Write(w)
+}
+
+// xlsxRow returns row with Fixed values as plain numbers, which excelize
+// would otherwise store as text.
+func xlsxRow(row []any) []any {
+	var out []any
+	for i, v := range row {
+		if f, ok := v.(Fixed); ok {
+			if out == nil {
+				out = append([]any(nil), row...)
+			}
+			out[i] = f.Number
+		}
+	}
+	if out == nil {
+		return row
+	}
+	return out
+}
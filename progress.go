@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// progress reports per-host collection progress. With text logs on a
+// terminal the line on stderr is rewritten in place; otherwise, e.g. when
+// stderr is shipped to a log pipeline, a log message is emitted at most
+// every interval so the log stays short.
+type progress struct {
+	w        io.Writer // nil to log instead of rewriting a line
+	interval time.Duration
+	last     time.Time
+}
+
+func newProgress(text bool) *progress {
+	if text && term.IsTerminal(int(os.Stderr.Fd())) {
+		return &progress{w: os.Stderr, interval: 100 * time.Millisecond}
+	}
+	return &progress{interval: 10 * time.Second}
+}
+
+// reporter returns a collector.Options.Progress func labelled with vcenter.
+func (p *progress) reporter(vcenter string) func(done, total int) {
+	return func(done, total int) {
+		now := time.Now()
+		if done > 0 && done < total && now.Sub(p.last) < p.interval {
+			return
+		}
+		p.last = now
+		if p.w == nil {
+			slog.Info("Progress", "vcenter", vcenter, "done", done, "total", total)
+			return
+		}
+		fmt.Fprintf(p.w, "\r%s: collected %d/%d hosts", vcenter, done, total)
+		if done == total {
+			fmt.Fprintln(p.w)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer returns the OpenTelemetry tracer of the run and per-vCenter
+// spans, under which the collector's spans nest.
+func tracer() trace.Tracer { return otel.Tracer("vmware-inventory") }
+
+// startTracing installs a tracer provider that sends spans to the OTLP/HTTP
+// endpoint, e.g. http://localhost:4318, and returns a func that sends any
+// spans still buffered and stops it. The standard OTEL_EXPORTER_OTLP_*
+// variables, such as OTEL_EXPORTER_OTLP_HEADERS, also apply.
+func startTracing(ctx context.Context, endpoint string) (func(), error) {
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, err
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", "vmware-inventory"),
+			attribute.String("service.version", version),
+		)),
+	)
+	otel.SetTracerProvider(tp)
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := tp.Shutdown(ctx); err != nil {
+			slog.Warn("Could not send traces", "endpoint", endpoint, "err", err)
+		}
+	}, nil
+}
+
+// endSpan ends span, marking it failed with err if err is non-nil.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// Build information, set by build.sh with
+// -ldflags "-X main.version=... -X main.commit=... -X main.date=...".
+var (
+	version = "dev"
+	commit  = ""
+	date    = ""
+)
+
+// versionString describes this build, e.g.
+// "vmware-inventory 1.4.0 (commit 3cde9a4, built 2026-10-16T09:12:44Z)".
+// Without ldflags the commit and time recorded by go build are used.
+func versionString() string {
+	c, d := commit, date
+	if info, ok := debug.ReadBuildInfo(); ok && c == "" {
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				c = s.Value
+			case "vcs.time":
+				d = s.Value
+			}
+		}
+	}
+	if len(c) > 7 {
+		c = c[:7]
+	}
+	s := "vmware-inventory " + version
+	switch {
+	case c != "" && d != "":
+		s += fmt.Sprintf(" (commit %s, built %s)", c, d)
+	case c != "":
+		s += fmt.Sprintf(" (commit %s)", c)
+	}
+	return s
+}